@@ -0,0 +1,20 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/wilfridterry/contact-list/internal/app"
+)
+
+// privacy answers data subject requests from the command line:
+//
+//	privacy export -user 1 -out user-1.zip
+//	privacy erase-user -user 1 -mode delete
+//	privacy erase-contact -contact 7 -mode pseudonymize
+func main() {
+	if err := app.RunPrivacy(os.Args[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
@@ -2,23 +2,36 @@ package app
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
 	"os/signal"
 	"path/filepath"
+	"sync"
 	"syscall"
 	"time"
 
 	"github.com/wilfridterry/contact-list/internal/config"
+	"github.com/wilfridterry/contact-list/internal/metrics"
 	"github.com/wilfridterry/contact-list/internal/repository/psql"
 	"github.com/wilfridterry/contact-list/internal/service"
+	graphql_api "github.com/wilfridterry/contact-list/internal/transport/graphql"
 	grpc_client "github.com/wilfridterry/contact-list/internal/transport/grpc"
+	grpc_server "github.com/wilfridterry/contact-list/internal/transport/grpc/server"
 	"github.com/wilfridterry/contact-list/internal/transport/rest"
 	amqplog "github.com/wilfridterry/contact-list/pkg/amqp_log"
 	"github.com/wilfridterry/contact-list/pkg/database"
 	"github.com/wilfridterry/contact-list/pkg/hashier"
+	"github.com/wilfridterry/contact-list/pkg/logger"
+	"github.com/wilfridterry/contact-list/pkg/mailer"
+	"github.com/wilfridterry/contact-list/pkg/oidc"
+	"github.com/wilfridterry/contact-list/pkg/tracing"
+	"github.com/wilfridterry/contact-list/pkg/webhook"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
 
 	log "github.com/sirupsen/logrus"
 )
@@ -28,20 +41,117 @@ var (
 	CONFFILENAME = "main"
 )
 
-func initLogger(dir, filename string) (io.Closer, error) {
-	_ = os.Mkdir(dir, 0666)
-	path := filepath.Join(dir, filename)
+const (
+	idempotencyPurgeInterval = time.Hour
+	tombstonePurgeInterval   = time.Hour
+)
 
-	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_RDWR, 0666)
-	if err != nil {
-		return nil, err
+func initLogger(cf config.Logger) (io.Closer, error) {
+	return logger.Init(logger.Config{
+		Format:      cf.Format,
+		Level:       cf.Level,
+		Outputs:     cf.Outputs,
+		File:        filepath.Join(cf.Dir, cf.Filename),
+		MaxSizeMB:   cf.MaxSizeMB,
+		RotateEvery: cf.RotateEvery,
+		MaxAge:      cf.MaxAge,
+		MaxBackups:  cf.MaxBackups,
+	})
+}
+
+func amqpOptions(cf config.Rabbitmq) *amqplog.ConfigOptions {
+	return &amqplog.ConfigOptions{
+		Host:           cf.Host,
+		Port:           int(cf.Port),
+		Username:       cf.Username,
+		Password:       cf.Password,
+		Queue:          cf.Queue,
+		Exchange:       cf.Exchange,
+		ExchangeType:   cf.ExchangeType,
+		RoutingKeys:    cf.RoutingKeys,
+		ConfirmTimeout: cf.ConfirmTimeout,
+		CloseTimeout:   cf.CloseTimeout,
+		BackoffBase:    cf.BackoffBase,
+		BackoffMax:     cf.BackoffMax,
+		BufferSize:     cf.BufferSize,
+	}
+}
+
+func initOIDCProviders(cf config.OIDC) map[string]service.OIDCProvider {
+	providers := make(map[string]service.OIDCProvider, len(cf.Providers))
+	for _, p := range cf.Providers {
+		providers[p.Name] = oidc.NewProvider(oidc.Config{
+			Name:         p.Name,
+			Issuer:       p.Issuer,
+			ClientID:     p.ClientID,
+			ClientSecret: p.ClientSecret,
+			RedirectURL:  p.RedirectURL,
+			Scopes:       p.Scopes,
+		})
 	}
 
-	log.SetFormatter(&log.TextFormatter{})
-	log.SetOutput(file)
-	log.SetLevel(log.DebugLevel)
+	return providers
+}
+
+func initMailer(cf config.Mailer) service.Mailer {
+	if cf.Driver == "smtp" {
+		return mailer.NewSMTP(&mailer.SMTPConfig{
+			Host:     cf.Host,
+			Port:     cf.Port,
+			Username: cf.Username,
+			Password: cf.Password,
+			From:     cf.From,
+		})
+	}
 
-	return file, nil
+	return mailer.NewFile(cf.Dir, cf.From)
+}
+
+// purgeIdempotencyKeys deletes expired idempotency keys once in a while, an
+// expired key is also replaced when it is reused.
+func purgeIdempotencyKeys(ctx context.Context, idempotency *service.Idempotency) {
+	ticker := time.NewTicker(idempotencyPurgeInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := idempotency.Purge(ctx); err != nil {
+				log.Error("failed to purge idempotency keys:", err)
+			}
+		}
+	}
+}
+
+// purgeContactTombstones forgets long deleted contacts, sync tokens issued
+// before the deletions are refused afterwards.
+func purgeContactTombstones(ctx context.Context, contacts *service.Contacts, ttl time.Duration) {
+	ticker := time.NewTicker(tombstonePurgeInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := contacts.PurgeTombstones(ctx, ttl); err != nil {
+				log.Error("failed to purge contact tombstones:", err)
+			}
+		}
+	}
+}
+
+// pingPostgres checks the connection, which is nil when connecting failed.
+func pingPostgres(pool *pgxpool.Pool) service.HealthCheck {
+	return func(ctx context.Context) error {
+		if pool == nil {
+			return errors.New("postgres: not connected")
+		}
+
+		return pool.Ping(ctx)
+	}
 }
 
 func Run() {
@@ -52,57 +162,147 @@ func Run() {
 		log.Error(err)
 	}
 
-	filelog, err := initLogger(cf.Logger.Dir, cf.Logger.Filename)
+	logs, err := initLogger(cf.Logger)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer logs.Close()
+
+	tracerProvider, err := tracing.NewProvider(ctx, tracing.Config{
+		ServiceName: cf.Tracing.ServiceName,
+		Exporter:    cf.Tracing.Exporter,
+		Endpoint:    cf.Tracing.Endpoint,
+		File:        cf.Tracing.File,
+		SampleRatio: cf.Tracing.SampleRatio,
+	})
 	if err != nil {
 		log.Error(err)
 	}
-	defer filelog.Close()
 
-	conn, err := database.NewConnection(ctx, &database.ConnectionConfig{
+	pool, err := database.NewPool(ctx, &database.ConnectionConfig{
 		Host:     cf.DB.Host,
 		Port:     cf.DB.Port,
 		Database: cf.DB.Database,
 		Username: cf.DB.Username,
 		Password: cf.DB.Password,
+		MaxConns: cf.DB.MaxConns,
+		Tracers:  []pgx.QueryTracer{metrics.QueryTracer{}, tracing.QueryTracer{}},
 	})
 
 	if err != nil {
 		log.Error(err)
 	}
-	defer conn.Close(ctx)
+	if pool != nil {
+		defer pool.Close()
+		metrics.RegisterPool(pool)
+	}
+
+	// Background workers share the pool with the requests. They are stopped,
+	// and waited for, before the pool is closed.
+	workersCtx, stopWorkers := context.WithCancel(ctx)
+	var workers sync.WaitGroup
+	runWorker := func(work func(context.Context)) {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			work(workersCtx)
+		}()
+	}
 
 	auditClient, err := grpc_client.NewClient(cf.Grpc.Port)
 	if err != nil {
 		log.Error(err)
 	}
 
-	amqpClient, err := amqplog.New(&amqplog.ConfigOptions{
-		Host:     cf.Rabbitmq.Host,
-		Port:     int(cf.Rabbitmq.Port),
-		Username: cf.Rabbitmq.Username,
-		Password: cf.Rabbitmq.Password,
-		Queue:    cf.Rabbitmq.Queue,
-	})
+	// The client keeps reconnecting when the broker is down, audit messages
+	// are buffered meanwhile.
+	amqpClient, err := amqplog.New(amqpOptions(cf.Rabbitmq))
 	if err != nil {
 		log.Error(err)
 	}
 	defer amqpClient.Close()
 
-	auditLogService := service.NewAuditLog(amqpClient)
-	contactsRepo := psql.NewContacts(conn)
-	contactsService := service.NewContacts(contactsRepo, auditClient, auditLogService)
+	auditLogService := service.NewAuditLog(metrics.NewAuditPublisher(amqpClient), psql.NewAuditEntries(pool))
+	contactsRepo := psql.NewContacts(pool)
+	contactEvents := service.NewContactEvents()
+	contactsService := service.NewContacts(contactsRepo, auditClient, auditLogService, contactEvents)
+	runWorker(func(ctx context.Context) {
+		purgeContactTombstones(ctx, contactsService, cf.Sync.TombstoneTTL)
+	})
 
-	userRepo := psql.NewUsers(conn)
+	webhooksRepo := psql.NewWebhooks(pool)
+	webhooksService := service.NewWebhooks(webhooksRepo, contactsRepo, webhook.NewClient(webhook.Config{
+		Timeout:              cf.Webhooks.Timeout,
+		AllowPrivateNetworks: cf.Webhooks.AllowPrivateNetworks,
+	}), auditLogService, service.WebhookConfig{
+		MaxAttempts:  cf.Webhooks.MaxAttempts,
+		BackoffBase:  cf.Webhooks.BackoffBase,
+		BackoffMax:   cf.Webhooks.BackoffMax,
+		PollInterval: cf.Webhooks.PollInterval,
+		Timeout:      cf.Webhooks.Timeout,
+	})
+	runWorker(func(ctx context.Context) {
+		webhooksService.Run(ctx, contactEvents)
+	})
+
+	userRepo := psql.NewUsers(pool)
 	hashier := hashier.NewHashier(cf.Secret)
-	sessionRepo := psql.NewTokens(conn)
-	authService := service.New(userRepo, sessionRepo, auditClient, auditLogService, hashier, []byte(cf.Secret), cf.Auth.TokenTTL)
+	sessionRepo := psql.NewTokens(pool)
+	mfaRepo := psql.NewMFA(pool)
+	attemptsRepo := psql.NewLoginAttempts(pool)
+	authService := service.New(userRepo, sessionRepo, mfaRepo, attemptsRepo, auditClient, auditLogService, hashier, initMailer(cf.Mailer), service.AuthConfig{
+		Secret:               []byte(cf.Secret),
+		TokenTTL:             cf.Auth.TokenTTL,
+		RequireVerifiedEmail: cf.Auth.RequireVerifiedEmail,
+		AppURL:               cf.Auth.AppURL,
+		Lockout: service.LockoutConfig{
+			MaxFailures:   cf.Auth.Lockout.MaxFailures,
+			IPMaxFailures: cf.Auth.Lockout.IPMaxFailures,
+			FreeAttempts:  cf.Auth.Lockout.FreeAttempts,
+			Window:        cf.Auth.Lockout.Window,
+			Duration:      cf.Auth.Lockout.Duration,
+			BackoffBase:   cf.Auth.Lockout.BackoffBase,
+			BackoffMax:    cf.Auth.Lockout.BackoffMax,
+		},
+	})
 
-	handler := rest.NewHandler(contactsService, authService)
+	apiKeysRepo := psql.NewAPIKeys(pool)
+	apiKeysService := service.NewAPIKeys(apiKeysRepo, userRepo, auditLogService)
+
+	identityRepo := psql.NewIdentities(pool)
+	oidcService := service.NewOIDC(initOIDCProviders(cf.OIDC), identityRepo, userRepo, hashier, authService, auditLogService, []byte(cf.Secret))
+
+	oauthRepo := psql.NewOAuth(pool)
+	oauthService := service.NewOAuth(oauthRepo, userRepo, auditLogService)
+
+	idempotencyRepo := psql.NewIdempotency(pool)
+	privacyService := service.NewPrivacy(userRepo, sessionRepo, contactsRepo, apiKeysRepo, attemptsRepo, webhooksRepo, idempotencyRepo, psql.NewAuditEntries(pool), auditLogService, hashier)
+
+	idempotencyService := service.NewIdempotency(idempotencyRepo, cf.Idempotency.TTL)
+	runWorker(func(ctx context.Context) {
+		purgeIdempotencyKeys(ctx, idempotencyService)
+	})
+
+	carddavService := service.NewCardDAV(psql.NewCardDAV(pool), contactsService)
+
+	graphqlHandler, err := graphql_api.NewHandler(contactsService, authService, auditLogService)
+	if err != nil {
+		log.WithField("error", err).Fatal("graphql schema err")
+	}
+
+	healthService := service.NewHealth(map[string]service.HealthCheck{
+		"postgres": pingPostgres(pool),
+		"amqp":     amqpClient.Ping,
+		"audit":    auditClient.Ping,
+	}, cf.Health.Timeout)
+
+	handler := rest.NewHandler(contactsService, authService, apiKeysService, oidcService, oauthService, privacyService, idempotencyService, webhooksService, carddavService, graphqlHandler, healthService)
 
 	srv := &http.Server{
 		Addr:    fmt.Sprintf(":%d", cf.Server.Port),
 		Handler: handler.InitRouter(),
 	}
+	srv.RegisterOnShutdown(handler.StopStreams)
 
 	go func() {
 		if err := srv.ListenAndServe(); err != http.ErrServerClosed {
@@ -110,6 +310,29 @@ func Run() {
 		}
 	}()
 
+	// Metrics have a listener of their own, out of reach of the clients.
+	var metricsSrv *http.Server
+	if cf.Server.MetricsPort != 0 {
+		metricsSrv = &http.Server{
+			Addr:    fmt.Sprintf(":%d", cf.Server.MetricsPort),
+			Handler: metrics.Handler(),
+		}
+
+		go func() {
+			if err := metricsSrv.ListenAndServe(); err != http.ErrServerClosed {
+				log.WithField("error", err).Fatal("metrics listening err")
+			}
+		}()
+	}
+
+	grpcSrv := grpc_server.NewServer(contactsService, authService)
+
+	go func() {
+		if err := grpcSrv.ListenAndServe(cf.Server.GrpcPort); err != nil {
+			log.WithField("error", err).Fatal("grpc listening err")
+		}
+	}()
+
 	quit := make(chan os.Signal, 1)
 
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
@@ -117,10 +340,36 @@ func Run() {
 	<-quit
 	log.Info("Shuting down server...")
 
+	// Readiness fails from now on, requests keep being served until the
+	// orchestrator stops sending them.
+	healthService.Drain()
+	time.Sleep(cf.Health.ShutdownDelay)
+
+	// Requests still running when the time is up are cut off, the shutdown
+	// goes on so the workers stop and audit messages and traces are flushed.
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 	if err := srv.Shutdown(ctx); err != nil {
-		log.WithField("error", err).Fatal("Server forced to shutdown:")
+		log.WithField("error", err).Error("Server forced to shutdown:")
+		srv.Close()
+	}
+
+	grpcSrv.Stop(ctx)
+
+	if metricsSrv != nil {
+		metricsSrv.Close()
+	}
+
+	stopWorkers()
+	workers.Wait()
+
+	if tracerProvider != nil {
+		flushCtx, cancelFlush := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancelFlush()
+
+		if err := tracerProvider.Shutdown(flushCtx); err != nil {
+			log.WithField("error", err).Error("failed to flush traces")
+		}
 	}
 
 	log.Info("Exiting server")
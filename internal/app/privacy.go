@@ -0,0 +1,136 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/wilfridterry/contact-list/internal/config"
+	"github.com/wilfridterry/contact-list/internal/domain"
+	"github.com/wilfridterry/contact-list/internal/repository/psql"
+	"github.com/wilfridterry/contact-list/internal/service"
+	amqplog "github.com/wilfridterry/contact-list/pkg/amqp_log"
+	"github.com/wilfridterry/contact-list/pkg/database"
+	"github.com/wilfridterry/contact-list/pkg/hashier"
+)
+
+const privacyUsage = "usage: privacy export -user ID [-out FILE] | erase-user -user ID -mode delete|pseudonymize | erase-contact -contact ID -mode delete|pseudonymize"
+
+// confirmedAMQP publishes audit messages without buffering them, a buffered
+// message would be lost when the command exits.
+type confirmedAMQP struct {
+	*amqplog.Client
+}
+
+func (c confirmedAMQP) Log(ctx context.Context, msg map[string]any) error {
+	return c.Publish(ctx, msg)
+}
+
+// RunPrivacy runs the privacy command line tool. Unlike the server it stops
+// on any connection error, and fails unless the broker confirmed the erasure
+// tombstone.
+func RunPrivacy(args []string) error {
+	if len(args) == 0 {
+		return errors.New(privacyUsage)
+	}
+
+	flags := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	userId := flags.Int64("user", 0, "user ID")
+	contactId := flags.Int64("contact", 0, "contact ID")
+	mode := flags.String("mode", "", "erasure mode: delete or pseudonymize")
+	out := flags.String("out", "", "export file, defaults to user-<ID>-export.zip")
+
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+
+	cf, err := config.NewConfig(CONFDIR, CONFFILENAME)
+	if err != nil {
+		return err
+	}
+
+	pool, err := database.NewPool(ctx, &database.ConnectionConfig{
+		Host:     cf.DB.Host,
+		Port:     cf.DB.Port,
+		Database: cf.DB.Database,
+		Username: cf.DB.Username,
+		Password: cf.DB.Password,
+		MaxConns: cf.DB.MaxConns,
+	})
+	if err != nil {
+		return err
+	}
+	defer pool.Close()
+
+	amqpClient, err := amqplog.New(amqpOptions(cf.Rabbitmq))
+	defer amqpClient.Close()
+	if err != nil {
+		return err
+	}
+
+	journal := psql.NewAuditEntries(pool)
+	privacy := service.NewPrivacy(
+		psql.NewUsers(pool),
+		psql.NewTokens(pool),
+		psql.NewContacts(pool),
+		psql.NewAPIKeys(pool),
+		psql.NewLoginAttempts(pool),
+		psql.NewWebhooks(pool),
+		psql.NewIdempotency(pool),
+		journal,
+		service.NewAuditLog(confirmedAMQP{amqpClient}, journal),
+		hashier.NewHashier(cf.Secret),
+	)
+
+	switch args[0] {
+	case "export":
+		if *userId == 0 {
+			return errors.New(privacyUsage)
+		}
+
+		path := *out
+		if path == "" {
+			path = fmt.Sprintf("user-%d-export.zip", *userId)
+		}
+
+		file, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		if err := privacy.Export(ctx, *userId, file); err != nil {
+			return err
+		}
+
+		fmt.Println("exported to", path)
+	case "erase-user":
+		if *userId == 0 {
+			return errors.New(privacyUsage)
+		}
+
+		if err := privacy.EraseUser(ctx, *userId, domain.ErasureMode(*mode)); err != nil {
+			return err
+		}
+
+		fmt.Println("erased user", *userId)
+	case "erase-contact":
+		if *contactId == 0 {
+			return errors.New(privacyUsage)
+		}
+
+		if err := privacy.EraseContact(ctx, *contactId, domain.ErasureMode(*mode)); err != nil {
+			return err
+		}
+
+		fmt.Println("erased contact", *contactId)
+	default:
+		return errors.New(privacyUsage)
+	}
+
+	return nil
+}
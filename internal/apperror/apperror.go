@@ -0,0 +1,96 @@
+// Package apperror turns errors of any layer into application errors with a
+// stable machine readable code and the HTTP status they are reported with.
+package apperror
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// Stable error codes which are not tied to a single domain error.
+const (
+	CodeInternal         = "internal_error"
+	CodeMalformedRequest = "malformed_request"
+	CodeValidation       = "validation_failed"
+	CodeUnauthenticated  = "unauthenticated"
+	CodeForbidden        = "forbidden"
+)
+
+// Error is an error the API reports to the client. Message is safe to show,
+// the wrapped Err is kept for logging only.
+type Error struct {
+	Status  int
+	Code    string
+	Message string
+	Fields  []FieldError
+	Err     error
+}
+
+// FieldError describes why a single request field failed validation.
+type FieldError struct {
+	Field   string `json:"field" example:"email"`
+	Code    string `json:"code" example:"required"`
+	Message string `json:"message" example:"is required"`
+}
+
+func New(status int, code, message string) *Error {
+	return &Error{Status: status, Code: code, Message: message}
+}
+
+// Wrap reports err with the given status and code, using its message.
+func Wrap(err error, status int, code string) *Error {
+	return &Error{Status: status, Code: code, Message: err.Error(), Err: err}
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// From maps err to an application error. Errors nobody expects become
+// internal errors with a generic message, so driver details never leak.
+func From(err error) *Error {
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return appErr
+	}
+
+	var validationErrs validator.ValidationErrors
+	if errors.As(err, &validationErrs) {
+		return validation(validationErrs)
+	}
+
+	if malformed(err) {
+		return &Error{Status: http.StatusBadRequest, Code: CodeMalformedRequest, Message: "malformed request", Err: err}
+	}
+
+	for _, m := range domainErrors {
+		if errors.Is(err, m.err) {
+			return Wrap(err, m.status, m.code)
+		}
+	}
+
+	return &Error{Status: http.StatusInternalServerError, Code: CodeInternal, Message: "internal server error", Err: err}
+}
+
+func malformed(err error) bool {
+	var (
+		syntaxErr *json.SyntaxError
+		typeErr   *json.UnmarshalTypeError
+		numErr    *strconv.NumError
+	)
+
+	return errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.As(err, &syntaxErr) ||
+		errors.As(err, &typeErr) ||
+		errors.As(err, &numErr)
+}
@@ -0,0 +1,81 @@
+package apperror
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/wilfridterry/contact-list/internal/domain"
+)
+
+func TestFrom(t *testing.T) {
+	testTable := []struct {
+		name            string
+		err             error
+		expectedStatus  int
+		expectedCode    string
+		expectedMessage string
+	}{
+		{
+			name:            "Domain error",
+			err:             domain.ErrContactNotFound,
+			expectedStatus:  http.StatusNotFound,
+			expectedCode:    "contact_not_found",
+			expectedMessage: "contact not found",
+		},
+		{
+			name:            "Wrapped domain error",
+			err:             fmt.Errorf("refresh: %w", domain.ErrRefreshTokenExpired),
+			expectedStatus:  http.StatusUnauthorized,
+			expectedCode:    "refresh_token_expired",
+			expectedMessage: "refresh: refresh token expired",
+		},
+		{
+			name:            "Lockout",
+			err:             &domain.LoginLockedError{},
+			expectedStatus:  http.StatusTooManyRequests,
+			expectedCode:    "too_many_attempts",
+			expectedMessage: "too many failed sign in attempts",
+		},
+		{
+			name:            "Application error",
+			err:             New(http.StatusForbidden, "delegated_credential", "not allowed"),
+			expectedStatus:  http.StatusForbidden,
+			expectedCode:    "delegated_credential",
+			expectedMessage: "not allowed",
+		},
+		{
+			name:            "Conflict",
+			err:             domain.ErrConflict,
+			expectedStatus:  http.StatusConflict,
+			expectedCode:    "conflict",
+			expectedMessage: "resource already exists",
+		},
+		{
+			name:            "Unexpected error",
+			err:             errors.New("conn closed"),
+			expectedStatus:  http.StatusInternalServerError,
+			expectedCode:    CodeInternal,
+			expectedMessage: "internal server error",
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			appErr := From(testCase.err)
+
+			if appErr.Status != testCase.expectedStatus {
+				t.Errorf("status = %d, want %d", appErr.Status, testCase.expectedStatus)
+			}
+
+			if appErr.Code != testCase.expectedCode {
+				t.Errorf("code = %q, want %q", appErr.Code, testCase.expectedCode)
+			}
+
+			if appErr.Message != testCase.expectedMessage {
+				t.Errorf("message = %q, want %q", appErr.Message, testCase.expectedMessage)
+			}
+		})
+	}
+}
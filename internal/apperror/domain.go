@@ -0,0 +1,74 @@
+package apperror
+
+import (
+	"net/http"
+
+	"github.com/wilfridterry/contact-list/internal/domain"
+)
+
+// domainErrors maps domain errors to their code and status. The codes are part
+// of the API contract, so never change one once released.
+var domainErrors = []struct {
+	err    error
+	status int
+	code   string
+}{
+	{domain.ErrConflict, http.StatusConflict, "conflict"},
+	{domain.ErrInvalidReference, http.StatusUnprocessableEntity, "invalid_reference"},
+	{domain.ErrConcurrentUpdate, http.StatusConflict, "concurrent_update"},
+
+	{domain.ErrContactNotFound, http.StatusNotFound, "contact_not_found"},
+	{domain.ErrContactModified, http.StatusPreconditionFailed, "contact_modified"},
+	{domain.ErrBatchTooLarge, http.StatusUnprocessableEntity, "batch_too_large"},
+	{domain.ErrInvalidBatchOperation, http.StatusUnprocessableEntity, "invalid_batch_operation"},
+	{domain.ErrCardNotFound, http.StatusNotFound, "card_not_found"},
+	{domain.ErrCardModified, http.StatusPreconditionFailed, "card_modified"},
+	{domain.ErrInvalidSyncToken, http.StatusBadRequest, "invalid_sync_token"},
+	{domain.ErrSyncTokenExpired, http.StatusGone, "sync_token_expired"},
+	{domain.ErrStreamOriginForbidden, http.StatusForbidden, "stream_origin_forbidden"},
+	{domain.ErrNotFoundUser, http.StatusNotFound, "user_not_found"},
+	{domain.ErrEmailTaken, http.StatusConflict, "email_taken"},
+	{domain.ErrInvalidPassword, http.StatusForbidden, "invalid_password"},
+	{domain.ErrEmailNotVerified, http.StatusForbidden, "email_not_verified"},
+	{domain.ErrInvalidEmailToken, http.StatusBadRequest, "invalid_email_token"},
+	{domain.ErrUserDisabled, http.StatusForbidden, "user_disabled"},
+	{domain.ErrPasswordResetRequired, http.StatusForbidden, "password_reset_required"},
+	{domain.ErrSelfAdminAction, http.StatusConflict, "self_admin_action"},
+	{domain.ErrImpersonationForbidden, http.StatusForbidden, "impersonation_forbidden"},
+	{domain.ErrUnknownRole, http.StatusBadRequest, "unknown_role"},
+	{domain.ErrRefreshTokenExpired, http.StatusUnauthorized, "refresh_token_expired"},
+	{domain.ErrRefreshTokenNotFound, http.StatusUnauthorized, "invalid_refresh_token"},
+	{domain.ErrInvalidAccessToken, http.StatusUnauthorized, "invalid_access_token"},
+	{domain.ErrTooManyAttempts, http.StatusTooManyRequests, "too_many_attempts"},
+
+	{domain.ErrMFANotEnrolled, http.StatusNotFound, "mfa_not_enrolled"},
+	{domain.ErrMFAAlreadyEnabled, http.StatusConflict, "mfa_already_enabled"},
+	{domain.ErrInvalidMFACode, http.StatusBadRequest, "invalid_mfa_code"},
+	{domain.ErrInvalidMFAChallenge, http.StatusUnauthorized, "invalid_mfa_challenge"},
+
+	{domain.ErrAPIKeyNotFound, http.StatusNotFound, "api_key_not_found"},
+	{domain.ErrAPIKeyInvalid, http.StatusUnauthorized, "invalid_api_key"},
+	{domain.ErrAPIKeyExpired, http.StatusUnauthorized, "api_key_expired"},
+	{domain.ErrAPIKeyRevoked, http.StatusUnauthorized, "api_key_revoked"},
+	{domain.ErrInvalidScope, http.StatusUnprocessableEntity, "invalid_scope"},
+
+	{domain.ErrUnknownProvider, http.StatusNotFound, "unknown_provider"},
+	{domain.ErrInvalidOIDCState, http.StatusBadRequest, "invalid_oidc_state"},
+	{domain.ErrIdentityLinkRequired, http.StatusConflict, "identity_link_required"},
+	{domain.ErrIdentityAlreadyLinked, http.StatusConflict, "identity_already_linked"},
+
+	{domain.ErrOAuthClientNotFound, http.StatusNotFound, "oauth_client_not_found"},
+	{domain.ErrOAuthConsentNotFound, http.StatusNotFound, "oauth_consent_not_found"},
+	{domain.ErrInvalidOAuthScope, http.StatusBadRequest, "invalid_oauth_scope"},
+	{domain.ErrInvalidRedirectURI, http.StatusBadRequest, "invalid_redirect_uri"},
+	{domain.ErrInvalidOAuthToken, http.StatusUnauthorized, "invalid_oauth_token"},
+
+	{domain.ErrUnknownErasureMode, http.StatusUnprocessableEntity, "unknown_erasure_mode"},
+
+	{domain.ErrWebhookNotFound, http.StatusNotFound, "webhook_not_found"},
+	{domain.ErrWebhookDeliveryNotFound, http.StatusNotFound, "webhook_delivery_not_found"},
+
+	{domain.ErrInvalidIdempotencyKey, http.StatusBadRequest, "invalid_idempotency_key"},
+	{domain.ErrIdempotencyKeyReused, http.StatusConflict, "idempotency_key_reused"},
+	{domain.ErrIdempotencyInProgress, http.StatusConflict, "idempotency_in_progress"},
+}
@@ -0,0 +1,57 @@
+package apperror
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+)
+
+func validation(errs validator.ValidationErrors) *Error {
+	fields := make([]FieldError, 0, len(errs))
+	for _, fe := range errs {
+		fields = append(fields, FieldError{
+			Field:   fieldName(fe),
+			Code:    fe.Tag(),
+			Message: fieldMessage(fe),
+		})
+	}
+
+	return &Error{
+		Status:  http.StatusUnprocessableEntity,
+		Code:    CodeValidation,
+		Message: "request validation failed",
+		Fields:  fields,
+		Err:     errs,
+	}
+}
+
+// fieldName drops the name of the top level struct from the namespace, e.g.
+// "SignUpInput.email" becomes "email".
+func fieldName(fe validator.FieldError) string {
+	namespace := fe.Namespace()
+	if i := strings.Index(namespace, "."); i >= 0 {
+		return namespace[i+1:]
+	}
+
+	return namespace
+}
+
+func fieldMessage(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required", "required_unless":
+		return "is required"
+	case "email":
+		return "must be a valid email"
+	case "oneof":
+		return "must be one of: " + fe.Param()
+	case "gte", "min":
+		return "must be at least " + fe.Param()
+	case "lte", "max":
+		return "must be at most " + fe.Param()
+	case "len":
+		return "must have length " + fe.Param()
+	default:
+		return "is invalid"
+	}
+}
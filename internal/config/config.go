@@ -1,6 +1,8 @@
 package config
 
 import (
+	"errors"
+	"io/fs"
 	"log"
 	"time"
 
@@ -24,14 +26,43 @@ type Config struct {
 	Grpc Grpc
 
 	Logger Logger
+
+	Mailer Mailer
+
+	OIDC OIDC
+
+	Idempotency Idempotency
+
+	Sync Sync
+
+	Webhooks Webhooks
+
+	Health Health
+
+	Tracing Tracing
 }
 
 type Auth struct {
-	TokenTTL time.Duration `mapstructure:"token_ttl"`
+	TokenTTL             time.Duration `mapstructure:"token_ttl"`
+	RequireVerifiedEmail bool          `mapstructure:"require_verified_email"`
+	AppURL               string        `mapstructure:"app_url"`
+	Lockout              Lockout       `mapstructure:"lockout"`
+}
+
+type Lockout struct {
+	MaxFailures   int           `mapstructure:"max_failures"`
+	IPMaxFailures int           `mapstructure:"ip_max_failures"`
+	FreeAttempts  int           `mapstructure:"free_attempts"`
+	Window        time.Duration `mapstructure:"window"`
+	Duration      time.Duration `mapstructure:"duration"`
+	BackoffBase   time.Duration `mapstructure:"backoff_base"`
+	BackoffMax    time.Duration `mapstructure:"backoff_max"`
 }
 
 type Server struct {
-	Port int `mapstructure:"port"`
+	Port        int `mapstructure:"port"`
+	GrpcPort    int `mapstructure:"grpc_port"`
+	MetricsPort int `mapstructure:"metrics_port"`
 }
 
 type Grpc struct {
@@ -39,8 +70,68 @@ type Grpc struct {
 }
 
 type Logger struct {
+	Dir         string        `mapstructure:"dir"`
+	Filename    string        `mapstructure:"filename"`
+	Format      string        `mapstructure:"format"`
+	Level       string        `mapstructure:"level"`
+	Outputs     []string      `mapstructure:"outputs"`
+	MaxSizeMB   int           `mapstructure:"max_size_mb"`
+	RotateEvery time.Duration `mapstructure:"rotate_every"`
+	MaxAge      time.Duration `mapstructure:"max_age"`
+	MaxBackups  int           `mapstructure:"max_backups"`
+}
+
+type Mailer struct {
+	Driver   string `mapstructure:"driver"`
+	Host     string `mapstructure:"host"`
+	Port     int    `mapstructure:"port"`
+	Username string `mapstructure:"username"`
+	Password string `mapstructure:"password"`
+	From     string `mapstructure:"from"`
 	Dir      string `mapstructure:"dir"`
-	Filename string `mapstructure:"filename"`
+}
+
+type OIDC struct {
+	Providers []OIDCProvider `mapstructure:"providers"`
+}
+
+type OIDCProvider struct {
+	Name         string   `mapstructure:"name"`
+	Issuer       string   `mapstructure:"issuer"`
+	ClientID     string   `mapstructure:"client_id"`
+	ClientSecret string   `mapstructure:"client_secret"`
+	RedirectURL  string   `mapstructure:"redirect_url"`
+	Scopes       []string `mapstructure:"scopes"`
+}
+
+type Idempotency struct {
+	TTL time.Duration `mapstructure:"ttl"`
+}
+
+type Sync struct {
+	TombstoneTTL time.Duration `mapstructure:"tombstone_ttl"`
+}
+
+type Webhooks struct {
+	MaxAttempts          int           `mapstructure:"max_attempts"`
+	BackoffBase          time.Duration `mapstructure:"backoff_base"`
+	BackoffMax           time.Duration `mapstructure:"backoff_max"`
+	PollInterval         time.Duration `mapstructure:"poll_interval"`
+	Timeout              time.Duration `mapstructure:"timeout"`
+	AllowPrivateNetworks bool          `mapstructure:"allow_private_networks"`
+}
+
+type Health struct {
+	Timeout       time.Duration `mapstructure:"timeout"`
+	ShutdownDelay time.Duration `mapstructure:"shutdown_delay"`
+}
+
+type Tracing struct {
+	ServiceName string  `mapstructure:"service_name"`
+	Exporter    string  `mapstructure:"exporter"`
+	Endpoint    string  `mapstructure:"endpoint"`
+	File        string  `mapstructure:"file"`
+	SampleRatio float64 `mapstructure:"sample_ratio"`
 }
 
 type Postgres struct {
@@ -49,14 +140,23 @@ type Postgres struct {
 	Database string
 	Username string
 	Password string
+	MaxConns int32 `mapstructure:"max_conns" split_words:"true"`
 }
 
 type Rabbitmq struct {
-	Host     string
-	Port     uint16
-	Queue    string
-	Username string
-	Password string
+	Host           string
+	Port           uint16
+	Queue          string
+	Username       string
+	Password       string
+	Exchange       string
+	ExchangeType   string        `mapstructure:"exchange_type" split_words:"true"`
+	RoutingKeys    []string      `mapstructure:"routing_keys" split_words:"true"`
+	ConfirmTimeout time.Duration `mapstructure:"confirm_timeout" split_words:"true"`
+	CloseTimeout   time.Duration `mapstructure:"close_timeout" split_words:"true"`
+	BackoffBase    time.Duration `mapstructure:"backoff_base" split_words:"true"`
+	BackoffMax     time.Duration `mapstructure:"backoff_max" split_words:"true"`
+	BufferSize     int           `mapstructure:"buffer_size" split_words:"true"`
 }
 
 func NewConfig(dirname, filename string) (*Config, error) {
@@ -72,14 +172,14 @@ func NewConfig(dirname, filename string) (*Config, error) {
 		return nil, err
 	}
 
-	if err := godotenv.Load(); err != nil {
+	if err := godotenv.Load(); err != nil && !errors.Is(err, fs.ErrNotExist) {
 		log.Fatal("error with load env file")
 	}
 
 	viper.AutomaticEnv()
 
 	viper.BindEnv("enviroment", "ENVIROMENT")
-	viper.BindEnv("SECRET", "secret")
+	viper.BindEnv("secret", "SECRET")
 
 	viper.SetEnvPrefix("db")
 	viper.BindEnv("db.host", "DB_HOST")
@@ -97,16 +197,67 @@ func NewConfig(dirname, filename string) (*Config, error) {
 	
 	viper.SetEnvPrefix("server")
 	viper.BindEnv("server.port", "SERVER_PORT")
+	viper.BindEnv("server.grpc_port", "SERVER_GRPC_PORT")
 	
 	viper.SetEnvPrefix("grpc")
 	viper.BindEnv("grpc.port", "GRPC_PORT")
 	
 	viper.SetEnvPrefix("auth")
 	viper.BindEnv("auth.token_ttl", "AUTH_TOKEN_TTL")
+	viper.BindEnv("auth.require_verified_email", "AUTH_REQUIRE_VERIFIED_EMAIL")
+	viper.BindEnv("auth.app_url", "AUTH_APP_URL")
+	viper.BindEnv("auth.lockout.max_failures", "AUTH_LOCKOUT_MAX_FAILURES")
+	viper.BindEnv("auth.lockout.ip_max_failures", "AUTH_LOCKOUT_IP_MAX_FAILURES")
+	viper.BindEnv("auth.lockout.free_attempts", "AUTH_LOCKOUT_FREE_ATTEMPTS")
+	viper.BindEnv("auth.lockout.window", "AUTH_LOCKOUT_WINDOW")
+	viper.BindEnv("auth.lockout.duration", "AUTH_LOCKOUT_DURATION")
+	viper.BindEnv("auth.lockout.backoff_base", "AUTH_LOCKOUT_BACKOFF_BASE")
+	viper.BindEnv("auth.lockout.backoff_max", "AUTH_LOCKOUT_BACKOFF_MAX")
 	
 	viper.SetEnvPrefix("logger")
 	viper.BindEnv("logger.dir", "LOGGER_DIR")
 	viper.BindEnv("logger.filename", "LOGGER_FILENAME")
+	viper.BindEnv("logger.format", "LOGGER_FORMAT")
+	viper.BindEnv("logger.level", "LOGGER_LEVEL")
+	viper.BindEnv("logger.outputs", "LOGGER_OUTPUTS")
+	viper.BindEnv("logger.max_size_mb", "LOGGER_MAX_SIZE_MB")
+	viper.BindEnv("logger.rotate_every", "LOGGER_ROTATE_EVERY")
+	viper.BindEnv("logger.max_age", "LOGGER_MAX_AGE")
+	viper.BindEnv("logger.max_backups", "LOGGER_MAX_BACKUPS")
+
+	viper.SetEnvPrefix("mailer")
+	viper.BindEnv("mailer.driver", "MAILER_DRIVER")
+	viper.BindEnv("mailer.host", "MAILER_HOST")
+	viper.BindEnv("mailer.port", "MAILER_PORT")
+	viper.BindEnv("mailer.username", "MAILER_USERNAME")
+	viper.BindEnv("mailer.password", "MAILER_PASSWORD")
+	viper.BindEnv("mailer.from", "MAILER_FROM")
+	viper.BindEnv("mailer.dir", "MAILER_DIR")
+
+	viper.SetEnvPrefix("idempotency")
+	viper.BindEnv("idempotency.ttl", "IDEMPOTENCY_TTL")
+
+	viper.SetEnvPrefix("sync")
+	viper.BindEnv("sync.tombstone_ttl", "SYNC_TOMBSTONE_TTL")
+
+	viper.SetEnvPrefix("webhooks")
+	viper.BindEnv("webhooks.max_attempts", "WEBHOOKS_MAX_ATTEMPTS")
+	viper.BindEnv("webhooks.backoff_base", "WEBHOOKS_BACKOFF_BASE")
+	viper.BindEnv("webhooks.backoff_max", "WEBHOOKS_BACKOFF_MAX")
+	viper.BindEnv("webhooks.poll_interval", "WEBHOOKS_POLL_INTERVAL")
+	viper.BindEnv("webhooks.timeout", "WEBHOOKS_TIMEOUT")
+	viper.BindEnv("webhooks.allow_private_networks", "WEBHOOKS_ALLOW_PRIVATE_NETWORKS")
+
+	viper.SetEnvPrefix("health")
+	viper.BindEnv("health.timeout", "HEALTH_TIMEOUT")
+	viper.BindEnv("health.shutdown_delay", "HEALTH_SHUTDOWN_DELAY")
+
+	viper.SetEnvPrefix("tracing")
+	viper.BindEnv("tracing.service_name", "TRACING_SERVICE_NAME")
+	viper.BindEnv("tracing.exporter", "TRACING_EXPORTER")
+	viper.BindEnv("tracing.endpoint", "TRACING_ENDPOINT")
+	viper.BindEnv("tracing.file", "TRACING_FILE")
+	viper.BindEnv("tracing.sample_ratio", "TRACING_SAMPLE_RATIO")
 
 	if err := envconfig.Process("db", &cf.DB); err != nil {
 		return nil, err
@@ -26,6 +26,8 @@ func TestNewConfig(t *testing.T) {
 		grpcPort         string
 		loggerDir        string
 		loggerFilename   string
+		mailerDriver     string
+		mailerFrom       string
 	}
 
 	type args struct {
@@ -52,6 +54,8 @@ func TestNewConfig(t *testing.T) {
 		os.Unsetenv("GRPC_PORT")
 		os.Unsetenv("LOGGER_DIR")
 		os.Unsetenv("LOGGER_FILENAME")
+		os.Unsetenv("MAILER_DRIVER")
+		os.Unsetenv("MAILER_FROM")
 
 		if env.enviroment != "" {
 			os.Setenv("ENVIROMENT", env.enviroment)
@@ -104,6 +108,12 @@ func TestNewConfig(t *testing.T) {
 		if env.loggerFilename != "" {
 			os.Setenv("LOGGER_FILENAME", env.loggerFilename)
 		}
+		if env.mailerDriver != "" {
+			os.Setenv("MAILER_DRIVER", env.mailerDriver)
+		}
+		if env.mailerFrom != "" {
+			os.Setenv("MAILER_FROM", env.mailerFrom)
+		}
 	}
 
 	testCases := []struct{
@@ -127,6 +137,7 @@ func TestNewConfig(t *testing.T) {
 					Database: "postgres",
 					Username: "root",
 					Password: "password",
+					MaxConns: 10,
 				},
 				Rabbitmq: Rabbitmq{
 					Host: "localhost",
@@ -134,12 +145,30 @@ func TestNewConfig(t *testing.T) {
 					Queue: "queue",
 					Username: "root",
 					Password: "password",
+					ExchangeType: "direct",
+					ConfirmTimeout: time.Second * 5,
+					CloseTimeout: time.Second * 10,
+					BackoffBase: time.Second,
+					BackoffMax: time.Second * 30,
+					BufferSize: 1000,
 				},
 				Auth: Auth{
 					TokenTTL: time.Minute * 15,
+					AppURL: "http://localhost:8081",
+					Lockout: Lockout{
+						MaxFailures: 10,
+						IPMaxFailures: 50,
+						FreeAttempts: 3,
+						Window: time.Minute * 15,
+						Duration: time.Minute * 15,
+						BackoffBase: time.Second,
+						BackoffMax: time.Minute,
+					},
 				},
 				Server: Server{
 					Port: 8081,
+					GrpcPort: 9090,
+					MetricsPort: 9100,
 				},
 				Grpc: Grpc{
 					Port: 9000,
@@ -147,6 +176,56 @@ func TestNewConfig(t *testing.T) {
 				Logger: Logger{
 					Dir: "storage/logs",
 					Filename: "test.log",
+					Format: "json",
+					Level: "info",
+					Outputs: []string{"file"},
+					MaxSizeMB: 100,
+					RotateEvery: time.Hour * 24,
+					MaxAge: time.Hour * 168,
+					MaxBackups: 7,
+				},
+				Mailer: Mailer{
+					Driver: "file",
+					Host: "localhost",
+					Port: 1025,
+					From: "noreply@contacts.local",
+					Dir: "storage/mails",
+				},
+				Idempotency: Idempotency{
+					TTL: time.Hour * 24,
+				},
+				Sync: Sync{
+					TombstoneTTL: time.Hour * 720,
+				},
+				Webhooks: Webhooks{
+					MaxAttempts: 8,
+					BackoffBase: time.Second * 30,
+					BackoffMax: time.Hour * 6,
+					PollInterval: time.Second * 5,
+					Timeout: time.Second * 10,
+				},
+				Health: Health{
+					Timeout: time.Second * 2,
+					ShutdownDelay: time.Second * 5,
+				},
+				Tracing: Tracing{
+					ServiceName: "contact-list",
+					Exporter: "otlp",
+					Endpoint: "http://localhost:4318",
+					File: "storage/traces/traces.json",
+					SampleRatio: 1,
+				},
+				OIDC: OIDC{
+					Providers: []OIDCProvider{
+						{
+							Name: "company",
+							Issuer: "https://idp.example.com",
+							ClientID: "contacts",
+							ClientSecret: "secret",
+							RedirectURL: "http://localhost:8081/api/v1/auth/oidc/company/callback",
+							Scopes: []string{"openid", "email", "profile"},
+						},
+					},
 				},
 			},
 			wantErr: false,
@@ -174,6 +253,8 @@ func TestNewConfig(t *testing.T) {
 					grpcPort: "9001",
 					loggerDir: "storage/env_logs",
 					loggerFilename: "env_test.log",
+					mailerDriver: "smtp",
+					mailerFrom: "env@contacts.local",
 				},
 			},
 			want: &Config{
@@ -185,6 +266,7 @@ func TestNewConfig(t *testing.T) {
 					Database: "env_postgres",
 					Username: "env_root",
 					Password: "env_password",
+					MaxConns: 10,
 				},
 				Rabbitmq: Rabbitmq{
 					Host: "127.0.0.1",
@@ -192,12 +274,30 @@ func TestNewConfig(t *testing.T) {
 					Queue: "env_queue",
 					Username: "env_root",
 					Password: "env_password",
+					ExchangeType: "direct",
+					ConfirmTimeout: time.Second * 5,
+					CloseTimeout: time.Second * 10,
+					BackoffBase: time.Second,
+					BackoffMax: time.Second * 30,
+					BufferSize: 1000,
 				},
 				Auth: Auth{
 					TokenTTL: time.Minute * 30,
+					AppURL: "http://localhost:8081",
+					Lockout: Lockout{
+						MaxFailures: 10,
+						IPMaxFailures: 50,
+						FreeAttempts: 3,
+						Window: time.Minute * 15,
+						Duration: time.Minute * 15,
+						BackoffBase: time.Second,
+						BackoffMax: time.Minute,
+					},
 				},
 				Server: Server{
 					Port: 8082,
+					GrpcPort: 9090,
+					MetricsPort: 9100,
 				},
 				Grpc: Grpc{
 					Port: 9001,
@@ -205,6 +305,56 @@ func TestNewConfig(t *testing.T) {
 				Logger: Logger{
 					Dir: "storage/env_logs",
 					Filename: "env_test.log",
+					Format: "json",
+					Level: "info",
+					Outputs: []string{"file"},
+					MaxSizeMB: 100,
+					RotateEvery: time.Hour * 24,
+					MaxAge: time.Hour * 168,
+					MaxBackups: 7,
+				},
+				Mailer: Mailer{
+					Driver: "smtp",
+					Host: "localhost",
+					Port: 1025,
+					From: "env@contacts.local",
+					Dir: "storage/mails",
+				},
+				Idempotency: Idempotency{
+					TTL: time.Hour * 24,
+				},
+				Sync: Sync{
+					TombstoneTTL: time.Hour * 720,
+				},
+				Webhooks: Webhooks{
+					MaxAttempts: 8,
+					BackoffBase: time.Second * 30,
+					BackoffMax: time.Hour * 6,
+					PollInterval: time.Second * 5,
+					Timeout: time.Second * 10,
+				},
+				Health: Health{
+					Timeout: time.Second * 2,
+					ShutdownDelay: time.Second * 5,
+				},
+				Tracing: Tracing{
+					ServiceName: "contact-list",
+					Exporter: "otlp",
+					Endpoint: "http://localhost:4318",
+					File: "storage/traces/traces.json",
+					SampleRatio: 1,
+				},
+				OIDC: OIDC{
+					Providers: []OIDCProvider{
+						{
+							Name: "company",
+							Issuer: "https://idp.example.com",
+							ClientID: "contacts",
+							ClientSecret: "secret",
+							RedirectURL: "http://localhost:8081/api/v1/auth/oidc/company/callback",
+							Scopes: []string{"openid", "email", "profile"},
+						},
+					},
 				},
 			},
 			wantErr: false,
@@ -235,6 +385,7 @@ func TestNewConfig(t *testing.T) {
 					Database: "env_postgres",
 					Username: "root",
 					Password: "env_password",
+					MaxConns: 10,
 				},
 				Rabbitmq: Rabbitmq{
 					Host: "127.0.0.1",
@@ -242,12 +393,30 @@ func TestNewConfig(t *testing.T) {
 					Queue: "env_queue",
 					Username: "root",
 					Password: "env_password",
+					ExchangeType: "direct",
+					ConfirmTimeout: time.Second * 5,
+					CloseTimeout: time.Second * 10,
+					BackoffBase: time.Second,
+					BackoffMax: time.Second * 30,
+					BufferSize: 1000,
 				},
 				Auth: Auth{
 					TokenTTL: time.Minute * 15,
+					AppURL: "http://localhost:8081",
+					Lockout: Lockout{
+						MaxFailures: 10,
+						IPMaxFailures: 50,
+						FreeAttempts: 3,
+						Window: time.Minute * 15,
+						Duration: time.Minute * 15,
+						BackoffBase: time.Second,
+						BackoffMax: time.Minute,
+					},
 				},
 				Server: Server{
 					Port: 8081,
+					GrpcPort: 9090,
+					MetricsPort: 9100,
 				},
 				Grpc: Grpc{
 					Port: 9001,
@@ -255,6 +424,56 @@ func TestNewConfig(t *testing.T) {
 				Logger: Logger{
 					Dir: "storage/env_logs",
 					Filename: "test.log",
+					Format: "json",
+					Level: "info",
+					Outputs: []string{"file"},
+					MaxSizeMB: 100,
+					RotateEvery: time.Hour * 24,
+					MaxAge: time.Hour * 168,
+					MaxBackups: 7,
+				},
+				Mailer: Mailer{
+					Driver: "file",
+					Host: "localhost",
+					Port: 1025,
+					From: "noreply@contacts.local",
+					Dir: "storage/mails",
+				},
+				Idempotency: Idempotency{
+					TTL: time.Hour * 24,
+				},
+				Sync: Sync{
+					TombstoneTTL: time.Hour * 720,
+				},
+				Webhooks: Webhooks{
+					MaxAttempts: 8,
+					BackoffBase: time.Second * 30,
+					BackoffMax: time.Hour * 6,
+					PollInterval: time.Second * 5,
+					Timeout: time.Second * 10,
+				},
+				Health: Health{
+					Timeout: time.Second * 2,
+					ShutdownDelay: time.Second * 5,
+				},
+				Tracing: Tracing{
+					ServiceName: "contact-list",
+					Exporter: "otlp",
+					Endpoint: "http://localhost:4318",
+					File: "storage/traces/traces.json",
+					SampleRatio: 1,
+				},
+				OIDC: OIDC{
+					Providers: []OIDCProvider{
+						{
+							Name: "company",
+							Issuer: "https://idp.example.com",
+							ClientID: "contacts",
+							ClientSecret: "secret",
+							RedirectURL: "http://localhost:8081/api/v1/auth/oidc/company/callback",
+							Scopes: []string{"openid", "email", "profile"},
+						},
+					},
 				},
 			},
 			wantErr: false,
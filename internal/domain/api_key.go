@@ -0,0 +1,34 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrAPIKeyNotFound = errors.New("api key not found")
+	ErrAPIKeyInvalid  = errors.New("invalid api key")
+	ErrAPIKeyExpired  = errors.New("api key expired")
+	ErrAPIKeyRevoked  = errors.New("api key revoked")
+	ErrInvalidScope   = errors.New("invalid api key scope")
+)
+
+type APIKey struct {
+	ID         int64        `json:"id"`
+	UserID     int64        `json:"user_id"`
+	Name       string       `json:"name"`
+	Prefix     string       `json:"prefix"`
+	Hash       string       `json:"-"`
+	Scopes     []Permission `json:"scopes"`
+	ExpiresAt  *time.Time   `json:"expires_at"`
+	LastUsedAt *time.Time   `json:"last_used_at"`
+	RevokedAt  *time.Time   `json:"revoked_at"`
+	CreatedAt  time.Time    `json:"created_at"`
+	UpdatedAt  time.Time    `json:"updated_at"`
+}
+
+type CreateAPIKeyInput struct {
+	Name      string       `json:"name" binding:"required,gte=1,lte=255"`
+	Scopes    []Permission `json:"scopes" binding:"required,min=1"`
+	ExpiresAt *time.Time   `json:"expires_at"`
+}
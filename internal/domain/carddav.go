@@ -0,0 +1,33 @@
+package domain
+
+import "errors"
+
+var (
+	ErrCardNotFound = errors.New("card not found")
+	ErrCardModified = errors.New("card was modified")
+)
+
+// CardDAVObject names a contact in the address book of a user. Clients pick
+// the name when they create a card, the object outlives the contact so its
+// deletion can be reported to them.
+type CardDAVObject struct {
+	ContactID int64
+	UserID    int64
+	Name      string
+	UID       string
+}
+
+// Card is a contact of the address book under its resource name.
+type Card struct {
+	Name    string
+	UID     string
+	Contact Contact
+}
+
+// CardChanges lists the cards changed and the names of the cards removed since
+// a sync token.
+type CardChanges struct {
+	Cards     []Card
+	Deleted   []string
+	SyncToken string
+}
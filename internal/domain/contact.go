@@ -1,6 +1,7 @@
 package domain
 
 import (
+	"fmt"
 	"time"
 )
 
@@ -12,6 +13,7 @@ type Contact struct {
 	Email     string    `json:"email"`
 	Address   string    `json:"address"`
 	Author    string    `json:"author"`
+	UserID    *int64    `json:"user_id"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
@@ -20,7 +22,73 @@ type SaveInputContact struct {
 	Name     string `json:"name" binding:"required"`
 	LastName string `json:"last_name" binding:"required"`
 	Phone    string `json:"phone" binding:"required,e164"`
-	Email    string `json:"email" binding:"required,email,unique"`
+	Email    string `json:"email" binding:"required,email"`
 	Address  string `json:"address" binding:"required"`
 	Author   string `json:"author" binding:"required"`
+	UserID   int64  `json:"-"`
+}
+
+// Limits of the contacts returned by a search.
+const (
+	DefaultSearchLimit = 20
+	MaxSearchLimit     = 100
+)
+
+// ContactSearch finds the contacts whose name, last name, phone or email
+// contains Query.
+type ContactSearch struct {
+	Query string `binding:"required,lte=255"`
+	Limit int    `binding:"omitempty,min=1,max=100"`
+}
+
+// Operations of a contacts batch.
+const (
+	BatchCreate = "create"
+	BatchUpdate = "update"
+	BatchDelete = "delete"
+)
+
+// Modes of a contacts batch. An atomic batch is applied in one transaction and
+// nothing is changed if any operation fails, in a partial batch every
+// operation succeeds or fails on its own.
+const (
+	BatchAtomic  = "atomic"
+	BatchPartial = "partial"
+)
+
+const MaxBatchOperations = 100
+
+type BatchInput struct {
+	Mode       string           `json:"mode" binding:"omitempty,oneof=atomic partial" example:"atomic"`
+	Operations []BatchOperation `json:"operations" binding:"required,min=1,max=100,dive"`
+}
+
+// BatchOperation creates a contact, or updates or deletes the contact ID.
+type BatchOperation struct {
+	Op      string            `json:"op" binding:"required,oneof=create update delete" example:"update"`
+	ID      int64             `json:"id" binding:"required_unless=Op create"`
+	Contact *SaveInputContact `json:"contact" binding:"required_unless=Op delete"`
+}
+
+// BatchResult is the outcome of the operation at Index. ID is the affected
+// contact, Err is set when the operation failed.
+type BatchResult struct {
+	Index int
+	Op    string
+	ID    int64
+	Err   error
+}
+
+// BatchOperationError fails an atomic batch because of the operation at Index.
+type BatchOperationError struct {
+	Index int
+	Err   error
+}
+
+func (e *BatchOperationError) Error() string {
+	return fmt.Sprintf("operation %d: %s", e.Index, e.Err)
+}
+
+func (e *BatchOperationError) Unwrap() error {
+	return e.Err
 }
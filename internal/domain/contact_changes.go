@@ -0,0 +1,35 @@
+package domain
+
+import "errors"
+
+var (
+	ErrInvalidSyncToken = errors.New("invalid sync token")
+	ErrSyncTokenExpired = errors.New("sync token is too old, a full sync is required")
+)
+
+const MaxContactChanges = 500
+
+// ContactChange is the latest change to a contact, made at Seq of the contact
+// change sequence. Contact is nil when the contact was deleted.
+type ContactChange struct {
+	Seq       int64
+	ContactID int64
+	Created   bool
+	Contact   *Contact
+}
+
+type ContactChangesFilter struct {
+	Since string `form:"since" binding:"omitempty,lte=64"`
+	Limit int    `form:"limit" binding:"omitempty,min=1,max=500"`
+}
+
+// ContactChanges lists the contacts changed after a sync token. SyncToken is
+// passed as since to get the next changes, HasMore tells they are already
+// waiting.
+type ContactChanges struct {
+	Created   []Contact `json:"created"`
+	Updated   []Contact `json:"updated"`
+	Deleted   []int64   `json:"deleted"`
+	SyncToken string    `json:"sync_token" example:"AAAAAAAAACo"`
+	HasMore   bool      `json:"has_more"`
+}
@@ -0,0 +1,43 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+var ErrStreamOriginForbidden = errors.New("stream token used from a foreign origin")
+
+const (
+	ContactCreated = "contact.created"
+	ContactUpdated = "contact.updated"
+	ContactDeleted = "contact.deleted"
+)
+
+// ContactEvent tells that a contact changed. ID grows with every event of
+// the running instance and is used to resume a stream after the last event a
+// client has seen.
+type ContactEvent struct {
+	ID        int64     `json:"id"`
+	Type      string    `json:"type"`
+	ContactID int64     `json:"contact_id"`
+	Time      time.Time `json:"time"`
+}
+
+// ContactSubscription delivers contact events to a single client. Missed are
+// the events after the requested one, sent before anything from Events. Reset
+// is set when those can not be replayed anymore and the client has to reload
+// its contacts. Events is closed when the subscriber falls behind, Close must
+// be called when the client goes away.
+type ContactSubscription struct {
+	Missed []ContactEvent
+	Reset  bool
+	Events <-chan ContactEvent
+	Close  func()
+}
+
+// StreamToken lets a browser open a contact stream, as EventSource and
+// WebSocket requests can not carry the Authorization header.
+type StreamToken struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
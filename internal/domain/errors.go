@@ -3,6 +3,19 @@ package domain
 import "errors"
 
 var (
-	ErrContactNotFound = errors.New("contact not found")
-	ErrRefreshTokenExpired = errors.New("refresh token expired")
+	ErrContactNotFound      = errors.New("contact not found")
+	ErrContactModified      = errors.New("contact was modified")
+	ErrRefreshTokenExpired  = errors.New("refresh token expired")
+	ErrRefreshTokenNotFound = errors.New("refresh token not found")
+
+	ErrBatchTooLarge         = errors.New("too many operations in a batch")
+	ErrInvalidBatchOperation = errors.New("invalid batch operation")
+)
+
+// Generic storage errors for constraint violations without a more specific
+// domain error.
+var (
+	ErrConflict         = errors.New("resource already exists")
+	ErrInvalidReference = errors.New("referenced resource does not exist")
+	ErrConcurrentUpdate = errors.New("concurrent update, please retry")
 )
@@ -0,0 +1,25 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrUnknownProvider          = errors.New("unknown identity provider")
+	ErrExternalIdentityNotFound = errors.New("external identity not found")
+	ErrInvalidOIDCState         = errors.New("invalid or expired sign in state")
+	ErrIdentityLinkRequired     = errors.New("an account with this email exists, sign in and link the provider from it")
+	ErrIdentityAlreadyLinked    = errors.New("the provider account is linked to another user")
+)
+
+// ExternalIdentity links an account of an OpenID Connect provider to a user.
+type ExternalIdentity struct {
+	ID        int64     `json:"id"`
+	UserID    int64     `json:"user_id"`
+	Provider  string    `json:"provider"`
+	Subject   string    `json:"subject"`
+	Email     string    `json:"email"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
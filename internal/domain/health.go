@@ -0,0 +1,30 @@
+package domain
+
+import "time"
+
+const (
+	HealthUp       = "up"
+	HealthDown     = "down"
+	HealthDraining = "draining"
+)
+
+// DependencyHealth is the outcome of checking a service the app depends on.
+type DependencyHealth struct {
+	Name      string `json:"name" example:"postgres"`
+	Status    string `json:"status" example:"up"`
+	LatencyMs int64  `json:"latency_ms" example:"3"`
+	Error     string `json:"error,omitempty" example:"context deadline exceeded"`
+}
+
+// HealthReport tells whether the app is ready to serve requests. It is down
+// when a dependency is and draining once the app is shutting down.
+type HealthReport struct {
+	Status       string             `json:"status" example:"up"`
+	StartedAt    time.Time          `json:"started_at"`
+	CheckedAt    time.Time          `json:"checked_at"`
+	Dependencies []DependencyHealth `json:"dependencies"`
+}
+
+func (r *HealthReport) Ready() bool {
+	return r.Status == HealthUp
+}
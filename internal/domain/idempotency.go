@@ -0,0 +1,31 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrInvalidIdempotencyKey = errors.New("invalid idempotency key")
+	ErrIdempotencyKeyReused  = errors.New("idempotency key was already used with another request")
+	ErrIdempotencyInProgress = errors.New("a request with the same idempotency key is in progress")
+)
+
+// IdempotencyRecord remembers a request sent with an Idempotency-Key header.
+// Once the request completes it holds the response replayed to its retries,
+// with the response headers worth replaying keyed by canonical name.
+type IdempotencyRecord struct {
+	UserID      int64
+	Key         string
+	Fingerprint string
+	Status      int
+	Header      map[string][]string
+	Body        []byte
+	CompletedAt *time.Time
+	ExpiresAt   time.Time
+	CreatedAt   time.Time
+}
+
+func (r *IdempotencyRecord) Completed() bool {
+	return r.CompletedAt != nil
+}
@@ -0,0 +1,33 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrTooManyAttempts = errors.New("too many failed sign in attempts")
+)
+
+// LoginAttempt counts failed sign in attempts for a key, which is either an
+// account ("email:<email>") or a client ip ("ip:<ip>").
+type LoginAttempt struct {
+	Key           string     `json:"key"`
+	Failures      int        `json:"failures"`
+	FirstFailedAt time.Time  `json:"first_failed_at"`
+	LastFailedAt  time.Time  `json:"last_failed_at"`
+	LockedUntil   *time.Time `json:"locked_until"`
+}
+
+// LoginLockedError is returned while the account or the client ip is locked.
+type LoginLockedError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LoginLockedError) Error() string {
+	return ErrTooManyAttempts.Error()
+}
+
+func (e *LoginLockedError) Unwrap() error {
+	return ErrTooManyAttempts
+}
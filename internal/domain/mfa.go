@@ -0,0 +1,52 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrMFARequired         = errors.New("mfa required")
+	ErrMFANotEnrolled      = errors.New("mfa is not enrolled")
+	ErrMFAAlreadyEnabled   = errors.New("mfa is already enabled")
+	ErrInvalidMFACode      = errors.New("invalid mfa code")
+	ErrInvalidMFAChallenge = errors.New("invalid or expired mfa challenge")
+)
+
+// MFARequiredError is returned by sign in when the password was correct but
+// the user has to pass the second factor with the challenge token.
+type MFARequiredError struct {
+	Challenge string
+	ExpiresAt time.Time
+}
+
+func (e *MFARequiredError) Error() string {
+	return ErrMFARequired.Error()
+}
+
+func (e *MFARequiredError) Unwrap() error {
+	return ErrMFARequired
+}
+
+type UserMFA struct {
+	UserID    int64      `json:"user_id"`
+	Secret    string     `json:"-"`
+	EnabledAt *time.Time `json:"enabled_at"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+type MFAEnrollment struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+}
+
+type MFACodeInput struct {
+	Code string `json:"code" binding:"required"`
+}
+
+type MFAVerifyInput struct {
+	Challenge string `json:"challenge" binding:"required"`
+	Code      string `json:"code" binding:"required"`
+	IP        string `json:"-"`
+}
@@ -0,0 +1,172 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+// Errors of the authorization server, they are rendered with the matching
+// RFC 6749 error codes by the transport layer.
+var (
+	ErrOAuthClientNotFound     = errors.New("oauth client not found")
+	ErrOAuthConsentNotFound    = errors.New("oauth consent not found")
+	ErrOAuthTokenNotFound      = errors.New("oauth token not found")
+	ErrOAuthCodeNotFound       = errors.New("oauth authorization code not found")
+	ErrInvalidOAuthClient      = errors.New("client authentication failed")
+	ErrInvalidOAuthGrant       = errors.New("invalid, expired or revoked grant")
+	ErrInvalidOAuthRequest     = errors.New("invalid authorization request")
+	ErrInvalidOAuthScope       = errors.New("invalid scope")
+	ErrInvalidRedirectURI      = errors.New("invalid redirect uri")
+	ErrUnauthorizedOAuthClient = errors.New("client is not allowed to use this grant type")
+	ErrUnsupportedGrantType    = errors.New("unsupported grant type")
+	ErrUnsupportedResponseType = errors.New("unsupported response type")
+	ErrInvalidOAuthToken       = errors.New("invalid or expired access token")
+)
+
+const (
+	GrantTypeAuthorizationCode = "authorization_code"
+	GrantTypeClientCredentials = "client_credentials"
+	GrantTypeRefreshToken      = "refresh_token"
+
+	OAuthTokenAccess  = "access"
+	OAuthTokenRefresh = "refresh"
+
+	// OAuthAccessTokenPrefix tells OAuth access tokens apart from our JWTs.
+	OAuthAccessTokenPrefix = "oat_"
+)
+
+// oauthScopes are the permissions third-party applications may ask for.
+var oauthScopes = map[Permission]bool{
+	PermissionContactsRead:  true,
+	PermissionContactsWrite: true,
+}
+
+func (p Permission) OAuthScope() bool {
+	return oauthScopes[p]
+}
+
+// OAuthClient is a third-party application registered by a user. Public
+// clients have no secret and can only use the authorization code grant.
+type OAuthClient struct {
+	ID           int64        `json:"id"`
+	ClientID     string       `json:"client_id"`
+	SecretHash   string       `json:"-"`
+	UserID       int64        `json:"user_id"`
+	Name         string       `json:"name"`
+	RedirectURIs []string     `json:"redirect_uris"`
+	Scopes       []Permission `json:"scopes"`
+	Confidential bool         `json:"confidential"`
+	CreatedAt    time.Time    `json:"created_at"`
+	UpdatedAt    time.Time    `json:"updated_at"`
+}
+
+type RegisterOAuthClientInput struct {
+	Name         string       `json:"name" binding:"required,gte=1,lte=255"`
+	RedirectURIs []string     `json:"redirect_uris"`
+	Scopes       []Permission `json:"scopes" binding:"required,min=1"`
+	Confidential bool         `json:"confidential"`
+}
+
+// OAuthCode is an issued authorization code. RedirectURI is the one given in
+// the authorization request, empty when the request relied on the only
+// registered one.
+type OAuthCode struct {
+	ID            int64
+	CodeHash      string
+	ClientID      int64
+	UserID        int64
+	RedirectURI   string
+	Scopes        []Permission
+	CodeChallenge string
+	ExpiresAt     time.Time
+	UsedAt        *time.Time
+}
+
+// OAuthToken is an issued access or refresh token. Access tokens obtained with
+// a refresh token point to it with ParentID, so they are revoked together.
+type OAuthToken struct {
+	ID        int64
+	TokenHash string
+	Kind      string
+	ClientID  int64
+	UserID    int64
+	Scopes    []Permission
+	ParentID  *int64
+	ExpiresAt time.Time
+	RevokedAt *time.Time
+	CreatedAt time.Time
+}
+
+// OAuthConsent records the scopes a user granted to a client.
+type OAuthConsent struct {
+	UserID     int64        `json:"user_id"`
+	ClientID   int64        `json:"-"`
+	ClientName string       `json:"client_name"`
+	PublicID   string       `json:"client_id"`
+	Scopes     []Permission `json:"scopes"`
+	GrantedAt  time.Time    `json:"granted_at"`
+}
+
+type AuthorizeInput struct {
+	ResponseType        string `form:"response_type" json:"response_type" binding:"required"`
+	ClientID            string `form:"client_id" json:"client_id" binding:"required"`
+	RedirectURI         string `form:"redirect_uri" json:"redirect_uri"`
+	Scope               string `form:"scope" json:"scope"`
+	State               string `form:"state" json:"state"`
+	CodeChallenge       string `form:"code_challenge" json:"code_challenge"`
+	CodeChallengeMethod string `form:"code_challenge_method" json:"code_challenge_method"`
+}
+
+type ConsentInput struct {
+	AuthorizeInput
+	Approve bool `json:"approve"`
+}
+
+// AuthorizePrompt is what the consent screen shows. RedirectTo is set right
+// away when the user already granted the requested scopes.
+type AuthorizePrompt struct {
+	ClientID        string       `json:"client_id"`
+	ClientName      string       `json:"client_name"`
+	Scopes          []Permission `json:"scopes"`
+	ConsentRequired bool         `json:"consent_required"`
+	RedirectTo      string       `json:"redirect_to,omitempty"`
+}
+
+type TokenInput struct {
+	GrantType    string `form:"grant_type" binding:"required"`
+	Code         string `form:"code"`
+	RedirectURI  string `form:"redirect_uri"`
+	CodeVerifier string `form:"code_verifier"`
+	RefreshToken string `form:"refresh_token"`
+	Scope        string `form:"scope"`
+	ClientID     string `form:"client_id"`
+	ClientSecret string `form:"client_secret"`
+}
+
+type TokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	Scope        string `json:"scope"`
+}
+
+// TokenIntrospection is the RFC 7662 response, only Active is set for unknown
+// or inactive tokens.
+type TokenIntrospection struct {
+	Active    bool   `json:"active"`
+	Scope     string `json:"scope,omitempty"`
+	ClientID  string `json:"client_id,omitempty"`
+	Subject   string `json:"sub,omitempty"`
+	TokenType string `json:"token_type,omitempty"`
+	ExpiresAt int64  `json:"exp,omitempty"`
+	IssuedAt  int64  `json:"iat,omitempty"`
+}
+
+// OAuthTokenInput is the body of the introspection and revocation endpoints.
+type OAuthTokenInput struct {
+	Token         string `form:"token" binding:"required"`
+	TokenTypeHint string `form:"token_type_hint"`
+	ClientID      string `form:"client_id"`
+	ClientSecret  string `form:"client_secret"`
+}
@@ -0,0 +1,30 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+var ErrUnknownErasureMode = errors.New("unknown erasure mode")
+
+// ErasureMode tells how the data of a data subject is erased.
+type ErasureMode string
+
+const (
+	ErasureDelete       ErasureMode = "delete"
+	ErasurePseudonymize ErasureMode = "pseudonymize"
+)
+
+type EraseInput struct {
+	Mode ErasureMode `json:"mode" binding:"required,oneof=delete pseudonymize"`
+}
+
+// AuditEntry is the local copy of an audit message sent to the audit queue.
+type AuditEntry struct {
+	ID        int64     `json:"id"`
+	Action    string    `json:"action"`
+	Entity    string    `json:"entity"`
+	EntityID  int64     `json:"entity_id"`
+	ActorID   int64     `json:"actor_id,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+}
@@ -0,0 +1,108 @@
+package domain
+
+import (
+	"context"
+	"errors"
+)
+
+var (
+	ErrUnknownRole = errors.New("unknown role")
+)
+
+type Role string
+
+type Permission string
+
+const (
+	RoleUser  Role = "user"
+	RoleAdmin Role = "admin"
+
+	PermissionContactsRead  Permission = "contacts:read"
+	PermissionContactsWrite Permission = "contacts:write"
+	PermissionUsersAdmin    Permission = "users:admin"
+)
+
+var rolePermissions = map[Role][]Permission{
+	RoleUser: {
+		PermissionContactsRead,
+		PermissionContactsWrite,
+	},
+	RoleAdmin: {
+		PermissionContactsRead,
+		PermissionContactsWrite,
+		PermissionUsersAdmin,
+	},
+}
+
+func (r Role) Valid() bool {
+	_, ok := rolePermissions[r]
+
+	return ok
+}
+
+func (r Role) Permissions() []Permission {
+	return append([]Permission(nil), rolePermissions[r]...)
+}
+
+// Identity is the authenticated caller as described by the access token claims,
+// by the API key or by the OAuth access token used for the request.
+type Identity struct {
+	UserID        int64
+	Role          Role
+	Permissions   []Permission
+	APIKeyID      int64
+	OAuthClientID int64
+	// ImpersonatorID is the administrator acting as the user, if any.
+	ImpersonatorID int64
+}
+
+// Delegated reports whether the caller acts through a credential other than
+// the user's own session, so it must not manage credentials of the account.
+// Impersonation tokens are delegated as well.
+func (i *Identity) Delegated() bool {
+	return i.APIKeyID != 0 || i.OAuthClientID != 0 || i.ImpersonatorID != 0
+}
+
+func (i *Identity) HasPermission(permission Permission) bool {
+	for _, p := range i.Permissions {
+		if p == permission {
+			return true
+		}
+	}
+
+	return false
+}
+
+type impersonatorKey struct{}
+
+// WithImpersonator marks the context of a request made with an impersonation
+// token, so audit messages can name the administrator behind it.
+func WithImpersonator(ctx context.Context, adminId int64) context.Context {
+	return context.WithValue(ctx, impersonatorKey{}, adminId)
+}
+
+// ImpersonatorFromContext returns the impersonating administrator or zero.
+func ImpersonatorFromContext(ctx context.Context) int64 {
+	adminId, _ := ctx.Value(impersonatorKey{}).(int64)
+
+	return adminId
+}
+
+type identityKey struct{}
+
+// WithIdentity passes the authenticated caller on to code which does not know
+// the transport the request came through.
+func WithIdentity(ctx context.Context, identity *Identity) context.Context {
+	return context.WithValue(ctx, identityKey{}, identity)
+}
+
+// IdentityFromContext returns the caller, if the request is authenticated.
+func IdentityFromContext(ctx context.Context) (*Identity, bool) {
+	identity, ok := ctx.Value(identityKey{}).(*Identity)
+
+	return identity, ok && identity != nil
+}
+
+type AssignRoleInput struct {
+	Role Role `json:"role" binding:"required"`
+}
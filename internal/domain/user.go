@@ -6,17 +6,40 @@ import (
 )
 
 var (
-	ErrNotFoundUser = errors.New("Not found user")
+	ErrNotFoundUser       = errors.New("Not found user")
+	ErrEmailNotVerified   = errors.New("email is not verified")
+	ErrInvalidEmailToken  = errors.New("invalid or expired token")
+	ErrEmailTaken         = errors.New("email is already taken")
+	ErrInvalidPassword    = errors.New("current password is incorrect")
+	ErrInvalidAccessToken = errors.New("invalid or expired access token")
+
+	ErrUserDisabled           = errors.New("user is disabled")
+	ErrPasswordResetRequired  = errors.New("password reset required")
+	ErrSelfAdminAction        = errors.New("administrators can not do this to their own account")
+	ErrImpersonationForbidden = errors.New("administrators can not be impersonated")
+)
+
+// OwnedContactsPolicy tells what happens to the contacts of a deleted account.
+type OwnedContactsPolicy string
+
+const (
+	OwnedContactsDelete    OwnedContactsPolicy = "delete"
+	OwnedContactsAnonymize OwnedContactsPolicy = "anonymize"
 )
 
 type User struct {
-	ID           int64     `json:"id"`
-	Name         string    `json:"name"`
-	Email        string    `json:"email"`
-	Password     string    `json:"-"`
-	RegisteredAt time.Time `json:"registered_at"`
-	CreatedAt    time.Time `json:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at"`
+	ID              int64      `json:"id"`
+	Name            string     `json:"name"`
+	Email           string     `json:"email"`
+	Password        string     `json:"-"`
+	Role            Role       `json:"role"`
+	EmailVerifiedAt *time.Time `json:"email_verified_at"`
+	DisabledAt      *time.Time `json:"disabled_at"`
+	// PasswordResetRequired blocks sign in until the password is reset.
+	PasswordResetRequired bool      `json:"password_reset_required"`
+	RegisteredAt          time.Time `json:"registered_at"`
+	CreatedAt             time.Time `json:"created_at"`
+	UpdatedAt             time.Time `json:"updated_at"`
 }
 
 type SignUpInput struct {
@@ -28,4 +51,58 @@ type SignUpInput struct {
 type SignInInput struct {
 	Email    string `json:"email" binding:"required,email,gte=4,lte=255"`
 	Password string `json:"password" binding:"required,gte=6,lte=70"`
+	IP       string `json:"-"`
+}
+
+type VerifyEmailInput struct {
+	Token string `json:"token" binding:"required"`
+}
+
+type EmailInput struct {
+	Email string `json:"email" binding:"required,email,gte=4,lte=255"`
+}
+
+type ResetPasswordInput struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required,gte=6,lte=70"`
+}
+
+type UpdateProfileInput struct {
+	Name  *string `json:"name" binding:"omitempty,gte=2,lte=255"`
+	Email *string `json:"email" binding:"omitempty,email,gte=4,lte=255"`
+}
+
+type ChangePasswordInput struct {
+	CurrentPassword string `json:"current_password" binding:"required,gte=6,lte=70"`
+	NewPassword     string `json:"new_password" binding:"required,gte=6,lte=70"`
+}
+
+type DeleteAccountInput struct {
+	Password string              `json:"password" binding:"required,gte=6,lte=70"`
+	Contacts OwnedContactsPolicy `json:"contacts" binding:"required,oneof=delete anonymize"`
+}
+
+type UserFilter struct {
+	Search  string `form:"search" binding:"omitempty,lte=255"`
+	Page    int    `form:"page" binding:"omitempty,min=1"`
+	PerPage int    `form:"per_page" binding:"omitempty,min=1,max=100"`
+}
+
+type UserPage struct {
+	Users   []User `json:"users"`
+	Total   int64  `json:"total"`
+	Page    int    `json:"page"`
+	PerPage int    `json:"per_page"`
+}
+
+// UserDetails is what an administrator sees when inspecting a user.
+type UserDetails struct {
+	User       *User `json:"user"`
+	MFAEnabled bool  `json:"mfa_enabled"`
+	Sessions   int   `json:"sessions"`
+}
+
+type ImpersonationToken struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
 }
@@ -0,0 +1,81 @@
+package domain
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+var (
+	ErrWebhookNotFound         = errors.New("webhook not found")
+	ErrWebhookDeliveryNotFound = errors.New("webhook delivery not found")
+)
+
+// Delivery states. A failed delivery stays pending until it succeeds or runs
+// out of attempts and becomes dead, it can be redelivered in either state.
+const (
+	DeliveryPending   = "pending"
+	DeliverySucceeded = "succeeded"
+	DeliveryDead      = "dead"
+)
+
+// Webhook posts contact events of the listed types to URL. The secret signs
+// every delivery.
+type Webhook struct {
+	ID        int64     `json:"id"`
+	UserID    int64     `json:"user_id"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"`
+	Secret    string    `json:"-"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (w *Webhook) Subscribed(eventType string) bool {
+	for _, e := range w.Events {
+		if e == eventType {
+			return true
+		}
+	}
+
+	return false
+}
+
+type CreateWebhookInput struct {
+	URL    string   `json:"url" binding:"required,url,startswith=http" example:"https://crm.example.com/hooks/contacts"`
+	Events []string `json:"events" binding:"required,min=1,dive,oneof=contact.created contact.updated contact.deleted" example:"contact.created,contact.deleted"`
+	// Secret is generated when empty.
+	Secret string `json:"secret" binding:"omitempty,min=16,max=255"`
+}
+
+// WebhookPayload is the JSON body of a delivery. Contact is the contact at the
+// time of the event, it is missing for deleted contacts.
+type WebhookPayload struct {
+	EventID   int64     `json:"event_id"`
+	Type      string    `json:"type"`
+	ContactID int64     `json:"contact_id"`
+	Contact   *Contact  `json:"contact,omitempty"`
+	Time      time.Time `json:"time"`
+}
+
+type WebhookDelivery struct {
+	ID            int64           `json:"id"`
+	WebhookID     int64           `json:"webhook_id"`
+	Event         string          `json:"event"`
+	Payload       json.RawMessage `json:"payload" swaggertype:"object"`
+	Status        string          `json:"status"`
+	Attempts      int             `json:"attempts"`
+	NextAttemptAt *time.Time      `json:"next_attempt_at"`
+	DeliveredAt   *time.Time      `json:"delivered_at"`
+	CreatedAt     time.Time       `json:"created_at"`
+	// Log lists the attempts, it is only filled for a single delivery.
+	Log []WebhookAttempt `json:"log,omitempty"`
+}
+
+// WebhookAttempt logs a single delivery attempt. StatusCode is zero when no
+// response was received, Error tells why.
+type WebhookAttempt struct {
+	StatusCode  int       `json:"status_code"`
+	Error       string    `json:"error,omitempty"`
+	Duration    int64     `json:"duration_ms"`
+	AttemptedAt time.Time `json:"attempted_at"`
+}
@@ -0,0 +1,53 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	auditPublishes = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "audit",
+		Name:      "publishes_total",
+		Help:      "Audit messages published to the queue by outcome.",
+	}, []string{"result"})
+
+	auditPublishDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "audit",
+		Name:      "publish_duration_seconds",
+		Help:      "Latency of publishing an audit message to the queue.",
+		Buckets:   prometheus.DefBuckets,
+	})
+)
+
+type publisher interface {
+	Log(ctx context.Context, msg map[string]any) error
+}
+
+// AuditPublisher records the outcome and latency of every audit message the
+// wrapped client publishes.
+type AuditPublisher struct {
+	next publisher
+}
+
+func NewAuditPublisher(next publisher) *AuditPublisher {
+	return &AuditPublisher{next: next}
+}
+
+func (p *AuditPublisher) Log(ctx context.Context, msg map[string]any) error {
+	start := time.Now()
+	err := p.next.Log(ctx, msg)
+	auditPublishDuration.Observe(time.Since(start).Seconds())
+
+	result := "success"
+	if err != nil {
+		result = "failure"
+	}
+	auditPublishes.WithLabelValues(result).Inc()
+
+	return err
+}
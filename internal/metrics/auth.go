@@ -0,0 +1,38 @@
+package metrics
+
+import (
+	"errors"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/wilfridterry/contact-list/internal/apperror"
+	"github.com/wilfridterry/contact-list/internal/domain"
+)
+
+const (
+	SignInPassword = "password"
+	SignInMFA      = "mfa"
+	SignInOIDC     = "oidc"
+)
+
+var signIns = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Subsystem: "auth",
+	Name:      "sign_ins_total",
+	Help:      "Sign in attempts by step, result and, for failures, the error code.",
+}, []string{"method", "result", "reason"})
+
+// ObserveSignIn records the outcome of a sign in step. A password accepted for
+// a user with MFA enabled counts as mfa_required, the MFA step then as success
+// or failure.
+func ObserveSignIn(method string, err error) {
+	result, reason := "success", ""
+
+	switch {
+	case errors.Is(err, domain.ErrMFARequired):
+		result = "mfa_required"
+	case err != nil:
+		result, reason = "failure", apperror.From(err).Code
+	}
+
+	signIns.WithLabelValues(method, result, reason).Inc()
+}
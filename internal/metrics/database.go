@@ -0,0 +1,130 @@
+package metrics
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var dbQueryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+	Namespace: namespace,
+	Subsystem: "db",
+	Name:      "query_duration_seconds",
+	Help:      "Postgres query latency by statement type and outcome.",
+	Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+}, []string{"operation", "status"})
+
+type queryStartKey struct{}
+
+type queryStart struct {
+	operation string
+	time      time.Time
+}
+
+// QueryTracer times the queries run on a pgx connection. Queries are labeled
+// by statement type only, the SQL itself would make a series per query.
+type QueryTracer struct{}
+
+func (QueryTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	return context.WithValue(ctx, queryStartKey{}, queryStart{operation: operation(data.SQL), time: time.Now()})
+}
+
+func (QueryTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	start, ok := ctx.Value(queryStartKey{}).(queryStart)
+	if !ok {
+		return
+	}
+
+	status := "ok"
+	if data.Err != nil {
+		status = "error"
+	}
+
+	dbQueryDuration.WithLabelValues(start.operation, status).Observe(time.Since(start.time).Seconds())
+}
+
+func operation(sql string) string {
+	keyword, _, _ := strings.Cut(strings.TrimSpace(sql), " ")
+
+	switch keyword = strings.ToLower(keyword); keyword {
+	case "select", "insert", "update", "delete", "with":
+		return keyword
+	default:
+		return "other"
+	}
+}
+
+var (
+	dbPoolConns = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "db_pool", "connections"),
+		"Connections of the Postgres pool by state.",
+		[]string{"state"}, nil,
+	)
+	dbPoolMaxConns = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "db_pool", "max_connections"),
+		"Maximum size of the Postgres pool.",
+		nil, nil,
+	)
+	dbPoolAcquires = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "db_pool", "acquires_total"),
+		"Connections acquired from the Postgres pool, empty acquires waited for a connection.",
+		[]string{"outcome"}, nil,
+	)
+	dbPoolAcquireDuration = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "db_pool", "acquire_duration_seconds_total"),
+		"Time spent acquiring connections from the Postgres pool.",
+		nil, nil,
+	)
+	dbPoolOpened = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "db_pool", "opened_connections_total"),
+		"Connections opened by the Postgres pool.",
+		nil, nil,
+	)
+	dbPoolClosed = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "db_pool", "closed_connections_total"),
+		"Connections closed by the Postgres pool for reaching their maximum lifetime or idle time.",
+		[]string{"reason"}, nil,
+	)
+)
+
+// poolCollector reads the statistics of a pgx pool when scraped. The state of
+// the pool is exported as gauges, the running totals as counters.
+type poolCollector struct {
+	pool *pgxpool.Pool
+}
+
+// RegisterPool exports the statistics of the pool.
+func RegisterPool(pool *pgxpool.Pool) {
+	registry.MustRegister(&poolCollector{pool: pool})
+}
+
+func (c *poolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- dbPoolConns
+	ch <- dbPoolMaxConns
+	ch <- dbPoolAcquires
+	ch <- dbPoolAcquireDuration
+	ch <- dbPoolOpened
+	ch <- dbPoolClosed
+}
+
+func (c *poolCollector) Collect(ch chan<- prometheus.Metric) {
+	stat := c.pool.Stat()
+
+	ch <- prometheus.MustNewConstMetric(dbPoolConns, prometheus.GaugeValue, float64(stat.AcquiredConns()), "acquired")
+	ch <- prometheus.MustNewConstMetric(dbPoolConns, prometheus.GaugeValue, float64(stat.IdleConns()), "idle")
+	ch <- prometheus.MustNewConstMetric(dbPoolConns, prometheus.GaugeValue, float64(stat.ConstructingConns()), "constructing")
+	ch <- prometheus.MustNewConstMetric(dbPoolMaxConns, prometheus.GaugeValue, float64(stat.MaxConns()))
+
+	nonEmpty := stat.AcquireCount() - stat.EmptyAcquireCount()
+	ch <- prometheus.MustNewConstMetric(dbPoolAcquires, prometheus.CounterValue, float64(nonEmpty), "immediate")
+	ch <- prometheus.MustNewConstMetric(dbPoolAcquires, prometheus.CounterValue, float64(stat.EmptyAcquireCount()), "empty")
+	ch <- prometheus.MustNewConstMetric(dbPoolAcquires, prometheus.CounterValue, float64(stat.CanceledAcquireCount()), "canceled")
+	ch <- prometheus.MustNewConstMetric(dbPoolAcquireDuration, prometheus.CounterValue, stat.AcquireDuration().Seconds())
+	ch <- prometheus.MustNewConstMetric(dbPoolOpened, prometheus.CounterValue, float64(stat.NewConnsCount()))
+	ch <- prometheus.MustNewConstMetric(dbPoolClosed, prometheus.CounterValue, float64(stat.MaxLifetimeDestroyCount()), "max_lifetime")
+	ch <- prometheus.MustNewConstMetric(dbPoolClosed, prometheus.CounterValue, float64(stat.MaxIdleDestroyCount()), "max_idle")
+}
@@ -0,0 +1,70 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// UnmatchedRoute labels requests no route matched, so scanners probing random
+// paths do not add a series each.
+const UnmatchedRoute = "unmatched"
+
+// OtherMethod labels requests with a method outside the HTTP and WebDAV ones,
+// for the same reason.
+const OtherMethod = "OTHER"
+
+var knownMethods = map[string]bool{
+	http.MethodGet:     true,
+	http.MethodHead:    true,
+	http.MethodPost:    true,
+	http.MethodPut:     true,
+	http.MethodPatch:   true,
+	http.MethodDelete:  true,
+	http.MethodConnect: true,
+	http.MethodOptions: true,
+	http.MethodTrace:   true,
+	"PROPFIND":         true,
+	"PROPPATCH":        true,
+	"MKCOL":            true,
+	"COPY":             true,
+	"MOVE":             true,
+	"LOCK":             true,
+	"UNLOCK":           true,
+	"REPORT":           true,
+}
+
+var (
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "requests_total",
+		Help:      "HTTP requests by method, route template and status.",
+	}, []string{"method", "route", "status"})
+
+	httpRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "HTTP request latency by method, route template and status.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+)
+
+// ObserveRequest records a served request, route is the template it matched,
+// e.g. /api/v1/contacts/:id.
+func ObserveRequest(method, route string, status int, duration time.Duration) {
+	if route == "" {
+		route = UnmatchedRoute
+	}
+
+	if !knownMethods[method] {
+		method = OtherMethod
+	}
+
+	labels := prometheus.Labels{"method": method, "route": route, "status": strconv.Itoa(status)}
+	httpRequests.With(labels).Inc()
+	httpRequestDuration.With(labels).Observe(duration.Seconds())
+}
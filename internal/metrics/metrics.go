@@ -0,0 +1,34 @@
+// Package metrics holds the Prometheus metrics of the app. They are kept in a
+// registry of their own, along with the Go runtime and process metrics, which
+// Handler serves.
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "contact_list"
+
+var registry = prometheus.NewRegistry()
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequests,
+		httpRequestDuration,
+		dbQueryDuration,
+		signIns,
+		auditPublishes,
+		auditPublishDuration,
+	)
+}
+
+// Handler serves the metrics in the Prometheus text format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{Registry: registry})
+}
@@ -0,0 +1,127 @@
+package psql
+
+import (
+	"context"
+	"time"
+
+	"github.com/wilfridterry/contact-list/internal/domain"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const apiKeyColumns = "id, user_id, name, prefix, key_hash, scopes, expires_at, last_used_at, revoked_at, created_at, updated_at"
+
+type APIKeys struct {
+	Pool *pgxpool.Pool
+}
+
+func NewAPIKeys(pool *pgxpool.Pool) *APIKeys {
+	return &APIKeys{pool}
+}
+
+func (repo *APIKeys) Create(ctx context.Context, key *domain.APIKey) (int64, error) {
+	var lastInsertId int64
+
+	err := repo.Pool.QueryRow(
+		ctx,
+		"INSERT INTO api_keys (user_id, name, prefix, key_hash, scopes, expires_at) values ($1, $2, $3, $4, $5, $6) RETURNING id",
+		key.UserID,
+		key.Name,
+		key.Prefix,
+		key.Hash,
+		permissionsToStrings(key.Scopes),
+		key.ExpiresAt,
+	).Scan(&lastInsertId)
+
+	return lastInsertId, translate(err, nil)
+}
+
+func (repo *APIKeys) GetByPrefix(ctx context.Context, prefix string) (*domain.APIKey, error) {
+	row := repo.Pool.QueryRow(ctx, "SELECT "+apiKeyColumns+" FROM api_keys WHERE prefix = $1", prefix)
+
+	key, err := scanAPIKey(row)
+	if err != nil {
+		return nil, translate(err, domain.ErrAPIKeyNotFound)
+	}
+
+	return key, nil
+}
+
+func (repo *APIKeys) GetAllByUser(ctx context.Context, userId int64) ([]domain.APIKey, error) {
+	rows, err := repo.Pool.Query(ctx, "SELECT "+apiKeyColumns+" FROM api_keys WHERE user_id = $1 ORDER BY id", userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := make([]domain.APIKey, 0)
+
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		keys = append(keys, *key)
+	}
+
+	return keys, rows.Err()
+}
+
+func (repo *APIKeys) Revoke(ctx context.Context, userId, id int64) error {
+	tag, err := repo.Pool.Exec(
+		ctx,
+		"UPDATE api_keys SET revoked_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL",
+		id,
+		userId,
+	)
+	if err != nil {
+		return translate(err, nil)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return domain.ErrAPIKeyNotFound
+	}
+
+	return nil
+}
+
+func (repo *APIKeys) TouchLastUsed(ctx context.Context, id int64, usedAt time.Time) error {
+	_, err := repo.Pool.Exec(ctx, "UPDATE api_keys SET last_used_at = $1 WHERE id = $2", usedAt, id)
+
+	return translate(err, nil)
+}
+
+func scanAPIKey(row pgx.Row) (*domain.APIKey, error) {
+	var (
+		k      domain.APIKey
+		scopes []string
+	)
+
+	if err := row.Scan(&k.ID, &k.UserID, &k.Name, &k.Prefix, &k.Hash, &scopes, &k.ExpiresAt, &k.LastUsedAt, &k.RevokedAt, &k.CreatedAt, &k.UpdatedAt); err != nil {
+		return nil, err
+	}
+
+	k.Scopes = stringsToPermissions(scopes)
+
+	return &k, nil
+}
+
+func permissionsToStrings(permissions []domain.Permission) []string {
+	res := make([]string, 0, len(permissions))
+	for _, p := range permissions {
+		res = append(res, string(p))
+	}
+
+	return res
+}
+
+func stringsToPermissions(values []string) []domain.Permission {
+	res := make([]domain.Permission, 0, len(values))
+	for _, v := range values {
+		res = append(res, domain.Permission(v))
+	}
+
+	return res
+}
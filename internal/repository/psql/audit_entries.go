@@ -0,0 +1,63 @@
+package psql
+
+import (
+	"context"
+
+	"github.com/wilfridterry/contact-list/internal/domain"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type AuditEntries struct {
+	Pool *pgxpool.Pool
+}
+
+func NewAuditEntries(pool *pgxpool.Pool) *AuditEntries {
+	return &AuditEntries{pool}
+}
+
+func (repo *AuditEntries) Create(ctx context.Context, entry *domain.AuditEntry) error {
+	_, err := repo.Pool.Exec(
+		ctx,
+		"INSERT INTO audit_entries (action, entity, entity_id, actor_id, timestamp) values ($1, $2, $3, NULLIF($4, 0), $5)",
+		entry.Action,
+		entry.Entity,
+		entry.EntityID,
+		entry.ActorID,
+		entry.Timestamp,
+	)
+
+	return translate(err, nil)
+}
+
+func (repo *AuditEntries) GetByEntity(ctx context.Context, entity string, ids []int64) ([]domain.AuditEntry, error) {
+	rows, err := repo.Pool.Query(
+		ctx,
+		"SELECT id, action, entity, entity_id, COALESCE(actor_id, 0), timestamp FROM audit_entries WHERE entity = $1 AND entity_id = ANY($2) ORDER BY id",
+		entity,
+		ids,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := make([]domain.AuditEntry, 0)
+
+	for rows.Next() {
+		var e domain.AuditEntry
+		if err := rows.Scan(&e.ID, &e.Action, &e.Entity, &e.EntityID, &e.ActorID, &e.Timestamp); err != nil {
+			return nil, err
+		}
+
+		entries = append(entries, e)
+	}
+
+	return entries, rows.Err()
+}
+
+func (repo *AuditEntries) DeleteByEntity(ctx context.Context, entity string, ids []int64) error {
+	_, err := repo.Pool.Exec(ctx, "DELETE FROM audit_entries WHERE entity = $1 AND entity_id = ANY($2)", entity, ids)
+
+	return translate(err, nil)
+}
@@ -0,0 +1,97 @@
+package psql
+
+import (
+	"context"
+
+	"github.com/wilfridterry/contact-list/internal/domain"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const cardDAVObjectColumns = "contact_id, user_id, name, uid"
+
+type CardDAV struct {
+	Pool *pgxpool.Pool
+}
+
+func NewCardDAV(pool *pgxpool.Pool) *CardDAV {
+	return &CardDAV{pool}
+}
+
+func (repo *CardDAV) GetByName(ctx context.Context, userId int64, name string) (*domain.CardDAVObject, error) {
+	row := repo.Pool.QueryRow(ctx, "SELECT "+cardDAVObjectColumns+" FROM carddav_objects WHERE user_id = $1 AND name = $2", userId, name)
+
+	o, err := scanCardDAVObject(row)
+	if err != nil {
+		return nil, translate(err, domain.ErrCardNotFound)
+	}
+
+	return o, nil
+}
+
+func (repo *CardDAV) GetAllByUser(ctx context.Context, userId int64) ([]domain.CardDAVObject, error) {
+	rows, err := repo.Pool.Query(ctx, "SELECT "+cardDAVObjectColumns+" FROM carddav_objects WHERE user_id = $1", userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	objects := make([]domain.CardDAVObject, 0)
+
+	for rows.Next() {
+		o, err := scanCardDAVObject(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		objects = append(objects, *o)
+	}
+
+	return objects, rows.Err()
+}
+
+// CreateMissing stores the objects unless their contact or name is already
+// taken.
+func (repo *CardDAV) CreateMissing(ctx context.Context, objects []domain.CardDAVObject) error {
+	batch := &pgx.Batch{}
+	for _, o := range objects {
+		batch.Queue(
+			"INSERT INTO carddav_objects (contact_id, user_id, name, uid) VALUES ($1, $2, $3, $4) ON CONFLICT DO NOTHING",
+			o.ContactID, o.UserID, o.Name, o.UID,
+		)
+	}
+
+	return translate(repo.Pool.SendBatch(ctx, batch).Close(), nil)
+}
+
+// Save stores the object. An object with the same name is only replaced when
+// it belongs to the replaced contact, that of a deleted card, otherwise the
+// name was taken meanwhile and ErrCardModified is returned.
+func (repo *CardDAV) Save(ctx context.Context, o *domain.CardDAVObject, replaced int64) error {
+	tag, err := repo.Pool.Exec(
+		ctx,
+		`INSERT INTO carddav_objects (contact_id, user_id, name, uid) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id, name) DO UPDATE SET contact_id = EXCLUDED.contact_id, uid = EXCLUDED.uid, created_at = CURRENT_TIMESTAMP
+		WHERE carddav_objects.contact_id = $5`,
+		o.ContactID, o.UserID, o.Name, o.UID, replaced,
+	)
+	if err != nil {
+		return translate(err, nil)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return domain.ErrCardModified
+	}
+
+	return nil
+}
+
+func scanCardDAVObject(row pgx.Row) (*domain.CardDAVObject, error) {
+	var o domain.CardDAVObject
+	if err := row.Scan(&o.ContactID, &o.UserID, &o.Name, &o.UID); err != nil {
+		return nil, err
+	}
+
+	return &o, nil
+}
@@ -2,80 +2,279 @@ package psql
 
 import (
 	"context"
-	"fmt"
-	"strings"
+	"time"
 
 	"github.com/wilfridterry/contact-list/internal/domain"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgx/v5/pgconn"
 )
 
+const contactColumns = "id, name, last_name, phone, email, address, author, user_id, created_at, updated_at"
+
 type Contacts struct {
-	Conn *pgx.Conn
+	Pool *pgxpool.Pool
 }
 
-func NewContacts(conn *pgx.Conn) *Contacts {
-	return &Contacts{conn}
+func NewContacts(pool *pgxpool.Pool) *Contacts {
+	return &Contacts{pool}
 }
 
 func (repo *Contacts) GetAll(ctx context.Context) ([]domain.Contact, error) {
-	rows, err := repo.Conn.Query(ctx, "SELECT * FROM contacts")
+	rows, err := repo.Pool.Query(ctx, "SELECT "+contactColumns+" FROM contacts")
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	contacts := make([]domain.Contact, 0)
+
+	for rows.Next() {
+		c, err := scanContact(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		contacts = append(contacts, *c)
+	}
+
+	return contacts, rows.Err()
+}
+
+func (repo *Contacts) GetAllByUser(ctx context.Context, userId int64) ([]domain.Contact, error) {
+	rows, err := repo.Pool.Query(ctx, "SELECT "+contactColumns+" FROM contacts WHERE user_id = $1 ORDER BY id", userId)
 	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
 
-	var contacts []domain.Contact
+	contacts := make([]domain.Contact, 0)
 
 	for rows.Next() {
-		c := domain.Contact{}
-		if err := rows.Scan(&c.ID, &c.Name, &c.LastName, &c.Phone, &c.Email, &c.Address, &c.Author, &c.CreatedAt, &c.UpdatedAt); err != nil {
+		c, err := scanContact(rows)
+		if err != nil {
 			return nil, err
 		}
 
-		contacts = append(contacts, c)
+		contacts = append(contacts, *c)
 	}
 
-	return contacts, nil
+	return contacts, rows.Err()
+}
+
+// Search matches the query case-insensitively anywhere in the name, last name,
+// phone or email.
+func (repo *Contacts) Search(ctx context.Context, query string, limit int) ([]domain.Contact, error) {
+	pattern := "%" + escapeLike(query) + "%"
+
+	rows, err := repo.Pool.Query(ctx, "SELECT "+contactColumns+` FROM contacts
+		WHERE name ILIKE $1 OR last_name ILIKE $1 OR phone ILIKE $1 OR email ILIKE $1
+		ORDER BY id LIMIT $2`, pattern, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	contacts := make([]domain.Contact, 0)
+
+	for rows.Next() {
+		c, err := scanContact(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		contacts = append(contacts, *c)
+	}
+
+	return contacts, rows.Err()
 }
 
 func (repo *Contacts) GetById(ctx context.Context, id int64) (*domain.Contact, error) {
-	c := domain.Contact{}
-	row := repo.Conn.QueryRow(ctx, "SELECT * from contacts WHERE id = $1", id)
+	row := repo.Pool.QueryRow(ctx, "SELECT "+contactColumns+" from contacts WHERE id = $1", id)
 
-	if err := row.Scan(&c.ID, &c.Name, &c.LastName, &c.Phone, &c.Email, &c.Address, &c.Author, &c.CreatedAt, &c.UpdatedAt); err != nil {
-		return &domain.Contact{}, err
+	c, err := scanContact(row)
+	if err != nil {
+		return nil, translate(err, domain.ErrContactNotFound)
 	}
 
-	return &c, nil
+	return c, nil
 }
 
 func (repo *Contacts) Create(ctx context.Context, inp *domain.SaveInputContact) (int64, error) {
+	return createContact(ctx, repo.Pool, inp)
+}
+
+func (repo *Contacts) Delete(ctx context.Context, id int64) error {
+	return deleteContact(ctx, repo.Pool, id)
+}
+
+// Pseudonymize replaces the personal data of the contact and detaches it from
+// its owner, the row itself is kept.
+func (repo *Contacts) Pseudonymize(ctx context.Context, contact *domain.Contact) error {
+	tag, err := repo.Pool.Exec(
+		ctx,
+		"UPDATE contacts SET name=$1, last_name=$2, phone=$3, email=$4, address=$5, author=$6, user_id=NULL, updated_at=CURRENT_TIMESTAMP WHERE id=$7",
+		contact.Name, contact.LastName, contact.Phone, contact.Email, contact.Address, contact.Author, contact.ID,
+	)
+	if err != nil {
+		return translate(err, nil)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return domain.ErrContactNotFound
+	}
+
+	return nil
+}
+
+func (repo *Contacts) Update(ctx context.Context, id int64, inp *domain.SaveInputContact) error {
+	return updateContact(ctx, repo.Pool, id, inp)
+}
+
+// UpdateIfUnmodified updates the contact unless it was written after the
+// updatedAt it was read with, then nothing matches and ErrContactModified is
+// returned. Gone contacts are reported the same way.
+func (repo *Contacts) UpdateIfUnmodified(ctx context.Context, id int64, updatedAt time.Time, inp *domain.SaveInputContact) error {
+	tag, err := repo.Pool.Exec(
+		ctx,
+		"UPDATE contacts SET name=$1, last_name=$2, phone=$3, email=$4, address=$5, author=$6, updated_at=CURRENT_TIMESTAMP WHERE id=$7 AND updated_at=$8",
+		inp.Name, inp.LastName, inp.Phone, inp.Email, inp.Address, inp.Author, id, updatedAt,
+	)
+	if err != nil {
+		return translate(err, nil)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return domain.ErrContactModified
+	}
+
+	return nil
+}
+
+// DeleteIfUnmodified deletes the contact unless it was written after
+// updatedAt, like UpdateIfUnmodified.
+func (repo *Contacts) DeleteIfUnmodified(ctx context.Context, id int64, updatedAt time.Time) error {
+	tag, err := repo.Pool.Exec(ctx, "DELETE FROM contacts WHERE id = $1 AND updated_at = $2", id, updatedAt)
+	if err != nil {
+		return translate(err, nil)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return domain.ErrContactModified
+	}
+
+	return nil
+}
+
+// Batch applies the operations in order. An atomic batch runs in a single
+// transaction, the first failure rolls it back and is returned as
+// *domain.BatchOperationError. Otherwise the operations run one by one and
+// failures are only reported in their results.
+func (repo *Contacts) Batch(ctx context.Context, ops []domain.BatchOperation, atomic bool) ([]domain.BatchResult, error) {
+	results := make([]domain.BatchResult, 0, len(ops))
+
+	if !atomic {
+		for i, op := range ops {
+			results = append(results, applyBatchOperation(ctx, repo.Pool, i, op))
+		}
+
+		return results, nil
+	}
+
+	tx, err := repo.Pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	for i, op := range ops {
+		result := applyBatchOperation(ctx, tx, i, op)
+		if result.Err != nil {
+			return nil, &domain.BatchOperationError{Index: i, Err: result.Err}
+		}
+
+		results = append(results, result)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, translate(err, nil)
+	}
+
+	return results, nil
+}
+
+// querier is implemented by both the pool and a transaction.
+type querier interface {
+	Exec(context.Context, string, ...any) (pgconn.CommandTag, error)
+	QueryRow(context.Context, string, ...any) pgx.Row
+}
+
+func applyBatchOperation(ctx context.Context, q querier, index int, op domain.BatchOperation) domain.BatchResult {
+	result := domain.BatchResult{Index: index, Op: op.Op, ID: op.ID}
+
+	switch op.Op {
+	case domain.BatchCreate:
+		result.ID, result.Err = createContact(ctx, q, op.Contact)
+	case domain.BatchUpdate:
+		result.Err = updateContact(ctx, q, op.ID, op.Contact)
+	case domain.BatchDelete:
+		result.Err = deleteContact(ctx, q, op.ID)
+	default:
+		result.Err = domain.ErrInvalidBatchOperation
+	}
+
+	return result
+}
+
+func createContact(ctx context.Context, q querier, inp *domain.SaveInputContact) (int64, error) {
 	var lastInsertId int64
 
-	err := repo.Conn.QueryRow(
+	err := q.QueryRow(
 		ctx,
-		"INSERT INTO contacts (name, last_name, phone, email, address, author) values ($1, $2, $3, $4, $5, $6) RETURNING id",
-		inp.Name, inp.LastName, inp.Phone, inp.Email, inp.Address, inp.Author,
+		"INSERT INTO contacts (name, last_name, phone, email, address, author, user_id) values ($1, $2, $3, $4, $5, $6, $7) RETURNING id",
+		inp.Name, inp.LastName, inp.Phone, inp.Email, inp.Address, inp.Author, inp.UserID,
 	).Scan(&lastInsertId)
 
-	return lastInsertId, err
+	return lastInsertId, translate(err, nil)
 }
 
-func (repo *Contacts) Delete(ctx context.Context, id int64) error {
-	_, err := repo.Conn.Exec(ctx, "DELETE FROM contacts WHERE id = $1", id)
+func updateContact(ctx context.Context, q querier, id int64, inp *domain.SaveInputContact) error {
+	tag, err := q.Exec(
+		ctx,
+		"UPDATE contacts SET name=$1, last_name=$2, phone=$3, email=$4, address=$5, author=$6, updated_at=CURRENT_TIMESTAMP WHERE id=$7",
+		inp.Name, inp.LastName, inp.Phone, inp.Email, inp.Address, inp.Author, id,
+	)
+	if err != nil {
+		return translate(err, nil)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return domain.ErrContactNotFound
+	}
 
-	return err
+	return nil
 }
 
-func (repo *Contacts) Update(ctx context.Context, id int64, inp *domain.SaveInputContact) error {
-	args := make([]interface{}, 0)
-	fields := make([]string, 0)
-	argInd := 1
+func deleteContact(ctx context.Context, q querier, id int64) error {
+	tag, err := q.Exec(ctx, "DELETE FROM contacts WHERE id = $1", id)
+	if err != nil {
+		return translate(err, nil)
+	}
 
-	setQuery := strings.Join(fields, ", ")
-	query := fmt.Sprintf("UPDATE contacts set %s WHERE id=$%d", setQuery, argInd)
+	if tag.RowsAffected() == 0 {
+		return domain.ErrContactNotFound
+	}
+
+	return nil
+}
 
-	_, err := repo.Conn.Exec(ctx, query, args...)
+func scanContact(row pgx.Row) (*domain.Contact, error) {
+	var c domain.Contact
+	if err := row.Scan(&c.ID, &c.Name, &c.LastName, &c.Phone, &c.Email, &c.Address, &c.Author, &c.UserID, &c.CreatedAt, &c.UpdatedAt); err != nil {
+		return nil, err
+	}
 
-	return err
+	return &c, nil
 }
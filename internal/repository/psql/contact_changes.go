@@ -0,0 +1,143 @@
+package psql
+
+import (
+	"context"
+	"time"
+
+	"github.com/wilfridterry/contact-list/internal/domain"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Changes returns up to limit changes after the since sequence ordered by
+// sequence, together with the current sequence. Deleted contacts are only
+// looked up when since is set, a full sync has nothing to forget.
+func (repo *Contacts) Changes(ctx context.Context, since int64, limit int) ([]domain.ContactChange, int64, error) {
+	// The sequence and the changes must come from the same snapshot.
+	tx, err := repo.Pool.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return nil, 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	var seq, purgedSeq int64
+	if err := tx.QueryRow(ctx, "SELECT seq, purged_seq FROM contact_sync").Scan(&seq, &purgedSeq); err != nil {
+		return nil, 0, translate(err, nil)
+	}
+
+	if since > seq {
+		return nil, 0, domain.ErrInvalidSyncToken
+	}
+
+	if since > 0 && since < purgedSeq {
+		return nil, 0, domain.ErrSyncTokenExpired
+	}
+
+	updated, err := contactChanges(ctx, tx, since, limit)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	deleted := make([]domain.ContactChange, 0)
+	if since > 0 {
+		if deleted, err = contactTombstones(ctx, tx, since, limit); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	changes := make([]domain.ContactChange, 0, len(updated)+len(deleted))
+	for len(changes) < limit && (len(updated) > 0 || len(deleted) > 0) {
+		if len(deleted) == 0 || (len(updated) > 0 && updated[0].Seq < deleted[0].Seq) {
+			changes = append(changes, updated[0])
+			updated = updated[1:]
+		} else {
+			changes = append(changes, deleted[0])
+			deleted = deleted[1:]
+		}
+	}
+
+	return changes, seq, translate(tx.Commit(ctx), nil)
+}
+
+// DeleteTombstones forgets the contacts deleted before the time, along with
+// their CardDAV names. Sync tokens older than the forgotten deletions are
+// refused from then on.
+func (repo *Contacts) DeleteTombstones(ctx context.Context, before time.Time) (int64, error) {
+	var count int64
+
+	err := repo.Pool.QueryRow(
+		ctx,
+		`WITH purged AS (DELETE FROM contact_tombstones WHERE deleted_at < $1 RETURNING contact_id, change_seq),
+		objects AS (DELETE FROM carddav_objects WHERE contact_id IN (SELECT contact_id FROM purged))
+		UPDATE contact_sync SET purged_seq = GREATEST(purged_seq, (SELECT COALESCE(MAX(change_seq), 0) FROM purged))
+		RETURNING (SELECT COUNT(*) FROM purged)`,
+		before,
+	).Scan(&count)
+
+	return count, translate(err, nil)
+}
+
+// LastChangeSeq returns the sequence of the latest change to the contacts.
+func (repo *Contacts) LastChangeSeq(ctx context.Context) (int64, error) {
+	var seq int64
+	err := repo.Pool.QueryRow(ctx, "SELECT seq FROM contact_sync").Scan(&seq)
+
+	return seq, translate(err, nil)
+}
+
+func contactChanges(ctx context.Context, tx pgx.Tx, since int64, limit int) ([]domain.ContactChange, error) {
+	rows, err := tx.Query(
+		ctx,
+		"SELECT change_seq, created_seq, "+contactColumns+" FROM contacts WHERE change_seq > $1 ORDER BY change_seq LIMIT $2",
+		since, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	changes := make([]domain.ContactChange, 0)
+
+	for rows.Next() {
+		var change domain.ContactChange
+		var createdSeq int64
+		var c domain.Contact
+
+		if err := rows.Scan(&change.Seq, &createdSeq, &c.ID, &c.Name, &c.LastName, &c.Phone, &c.Email, &c.Address, &c.Author, &c.UserID, &c.CreatedAt, &c.UpdatedAt); err != nil {
+			return nil, err
+		}
+
+		change.ContactID = c.ID
+		change.Created = createdSeq > since
+		change.Contact = &c
+
+		changes = append(changes, change)
+	}
+
+	return changes, rows.Err()
+}
+
+func contactTombstones(ctx context.Context, tx pgx.Tx, since int64, limit int) ([]domain.ContactChange, error) {
+	rows, err := tx.Query(
+		ctx,
+		"SELECT change_seq, contact_id FROM contact_tombstones WHERE change_seq > $1 ORDER BY change_seq LIMIT $2",
+		since, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	changes := make([]domain.ContactChange, 0)
+
+	for rows.Next() {
+		var change domain.ContactChange
+		if err := rows.Scan(&change.Seq, &change.ContactID); err != nil {
+			return nil, err
+		}
+
+		changes = append(changes, change)
+	}
+
+	return changes, rows.Err()
+}
@@ -0,0 +1,58 @@
+package psql
+
+import (
+	"errors"
+
+	"github.com/wilfridterry/contact-list/internal/domain"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// SQLSTATE codes translated to domain errors.
+const (
+	pgUniqueViolation      = "23505"
+	pgForeignKeyViolation  = "23503"
+	pgSerializationFailure = "40001"
+	pgDeadlockDetected     = "40P01"
+)
+
+// constraintErrors names the domain error of constraints the callers care
+// about, other violations get the generic error of their kind.
+var constraintErrors = map[string]error{
+	"users_email_key":    domain.ErrEmailTaken,
+	"contacts_email_key": domain.ErrEmailTaken,
+}
+
+// translate converts driver errors to domain errors, so nothing above the
+// repositories depends on pgx. notFound is returned for pgx.ErrNoRows, pass
+// nil where no rows is not an error of its own.
+func translate(err error, notFound error) error {
+	if err == nil {
+		return nil
+	}
+
+	if errors.Is(err, pgx.ErrNoRows) && notFound != nil {
+		return notFound
+	}
+
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return err
+	}
+
+	switch pgErr.Code {
+	case pgUniqueViolation:
+		if domainErr, ok := constraintErrors[pgErr.ConstraintName]; ok {
+			return domainErr
+		}
+
+		return domain.ErrConflict
+	case pgForeignKeyViolation:
+		return domain.ErrInvalidReference
+	case pgSerializationFailure, pgDeadlockDetected:
+		return domain.ErrConcurrentUpdate
+	}
+
+	return err
+}
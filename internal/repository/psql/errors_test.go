@@ -0,0 +1,77 @@
+package psql
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/wilfridterry/contact-list/internal/domain"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/magiconair/properties/assert"
+)
+
+func TestTranslate(t *testing.T) {
+	dbErr := errors.New("connection reset")
+
+	tests := []struct {
+		name     string
+		err      error
+		notFound error
+		want     error
+	}{
+		{
+			name: "nil",
+			err:  nil,
+			want: nil,
+		},
+		{
+			name:     "no rows",
+			err:      pgx.ErrNoRows,
+			notFound: domain.ErrContactNotFound,
+			want:     domain.ErrContactNotFound,
+		},
+		{
+			name: "no rows without not found error",
+			err:  pgx.ErrNoRows,
+			want: pgx.ErrNoRows,
+		},
+		{
+			name: "known unique constraint",
+			err:  fmt.Errorf("insert: %w", &pgconn.PgError{Code: "23505", ConstraintName: "users_email_key"}),
+			want: domain.ErrEmailTaken,
+		},
+		{
+			name: "other unique constraint",
+			err:  &pgconn.PgError{Code: "23505", ConstraintName: "identities_provider_subject_key"},
+			want: domain.ErrConflict,
+		},
+		{
+			name: "foreign key",
+			err:  &pgconn.PgError{Code: "23503"},
+			want: domain.ErrInvalidReference,
+		},
+		{
+			name: "serialization failure",
+			err:  &pgconn.PgError{Code: "40001"},
+			want: domain.ErrConcurrentUpdate,
+		},
+		{
+			name: "deadlock",
+			err:  &pgconn.PgError{Code: "40P01"},
+			want: domain.ErrConcurrentUpdate,
+		},
+		{
+			name: "other driver error",
+			err:  dbErr,
+			want: dbErr,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, translate(tt.err, tt.notFound), tt.want)
+		})
+	}
+}
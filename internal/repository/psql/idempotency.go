@@ -0,0 +1,145 @@
+package psql
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/wilfridterry/contact-list/internal/domain"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Records completed before the headers were stored only have their content
+// type.
+const idempotencyColumns = `user_id, key, fingerprint, COALESCE(status, 0),
+	COALESCE(headers, CASE WHEN content_type IS NOT NULL THEN jsonb_build_object('Content-Type', jsonb_build_array(content_type)) END, '{}'),
+	body, completed_at, expires_at, created_at`
+
+type Idempotency struct {
+	Pool *pgxpool.Pool
+}
+
+func NewIdempotency(pool *pgxpool.Pool) *Idempotency {
+	return &Idempotency{pool}
+}
+
+// Reserve stores the record unless a live one exists for the user and key, an
+// expired record is replaced. It returns the stored record and whether it is
+// the given one. The primary key makes concurrent reservations of a key fail
+// for all callers but one.
+func (repo *Idempotency) Reserve(ctx context.Context, record *domain.IdempotencyRecord) (*domain.IdempotencyRecord, bool, error) {
+	row := repo.Pool.QueryRow(
+		ctx,
+		`INSERT INTO idempotency_keys (user_id, key, fingerprint, expires_at) values ($1, $2, $3, $4)
+		ON CONFLICT (user_id, key) DO UPDATE SET
+			fingerprint = EXCLUDED.fingerprint,
+			status = NULL,
+			content_type = NULL,
+			headers = NULL,
+			body = NULL,
+			completed_at = NULL,
+			expires_at = EXCLUDED.expires_at,
+			created_at = CURRENT_TIMESTAMP
+		WHERE idempotency_keys.expires_at < CURRENT_TIMESTAMP
+		RETURNING `+idempotencyColumns,
+		record.UserID,
+		record.Key,
+		record.Fingerprint,
+		record.ExpiresAt,
+	)
+
+	reserved, err := scanIdempotencyRecord(row)
+	if err == nil {
+		return reserved, true, nil
+	}
+
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return nil, false, translate(err, nil)
+	}
+
+	// A live record already exists, it may have been released meanwhile.
+	row = repo.Pool.QueryRow(ctx, "SELECT "+idempotencyColumns+" FROM idempotency_keys WHERE user_id = $1 AND key = $2", record.UserID, record.Key)
+
+	existing, err := scanIdempotencyRecord(row)
+	if err != nil {
+		return nil, false, translate(err, domain.ErrIdempotencyInProgress)
+	}
+
+	return existing, false, nil
+}
+
+func (repo *Idempotency) Complete(ctx context.Context, record *domain.IdempotencyRecord) error {
+	tag, err := repo.Pool.Exec(
+		ctx,
+		"UPDATE idempotency_keys SET status = $1, headers = $2, body = $3, completed_at = CURRENT_TIMESTAMP WHERE user_id = $4 AND key = $5 AND completed_at IS NULL",
+		record.Status,
+		record.Header,
+		record.Body,
+		record.UserID,
+		record.Key,
+	)
+	if err != nil {
+		return translate(err, nil)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return domain.ErrIdempotencyInProgress
+	}
+
+	return nil
+}
+
+// Release forgets a key whose request did not complete, so it can be retried.
+func (repo *Idempotency) Release(ctx context.Context, userId int64, key string) error {
+	_, err := repo.Pool.Exec(ctx, "DELETE FROM idempotency_keys WHERE user_id = $1 AND key = $2 AND completed_at IS NULL", userId, key)
+
+	return translate(err, nil)
+}
+
+func (repo *Idempotency) DeleteByUser(ctx context.Context, userId int64) error {
+	_, err := repo.Pool.Exec(ctx, "DELETE FROM idempotency_keys WHERE user_id = $1", userId)
+
+	return translate(err, nil)
+}
+
+// DeleteByContacts deletes the records of any user whose stored JSON response
+// holds one of the contacts, alone or in a list.
+func (repo *Idempotency) DeleteByContacts(ctx context.Context, contactIds []int64) error {
+	if len(contactIds) == 0 {
+		return nil
+	}
+
+	_, err := repo.Pool.Exec(
+		ctx,
+		`DELETE FROM idempotency_keys
+		WHERE body IS NOT NULL
+			AND COALESCE(headers->'Content-Type'->>0, content_type) LIKE 'application/json%'
+			AND EXISTS (
+				SELECT 1 FROM unnest($1::bigint[]) AS c(id)
+				WHERE jsonb_path_exists(convert_from(body, 'UTF8')::jsonb, 'lax $.** ? (@.id == $id && exists(@.last_name))', jsonb_build_object('id', c.id))
+			)`,
+		contactIds,
+	)
+
+	return translate(err, nil)
+}
+
+func (repo *Idempotency) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	tag, err := repo.Pool.Exec(ctx, "DELETE FROM idempotency_keys WHERE expires_at < $1", now)
+	if err != nil {
+		return 0, translate(err, nil)
+	}
+
+	return tag.RowsAffected(), nil
+}
+
+func scanIdempotencyRecord(row pgx.Row) (*domain.IdempotencyRecord, error) {
+	var r domain.IdempotencyRecord
+	if err := row.Scan(&r.UserID, &r.Key, &r.Fingerprint, &r.Status, &r.Header, &r.Body, &r.CompletedAt, &r.ExpiresAt, &r.CreatedAt); err != nil {
+		return nil, err
+	}
+
+	return &r, nil
+}
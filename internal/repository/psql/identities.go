@@ -0,0 +1,48 @@
+package psql
+
+import (
+	"context"
+
+	"github.com/wilfridterry/contact-list/internal/domain"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type Identities struct {
+	Pool *pgxpool.Pool
+}
+
+func NewIdentities(pool *pgxpool.Pool) *Identities {
+	return &Identities{pool}
+}
+
+func (repo *Identities) Create(ctx context.Context, identity *domain.ExternalIdentity) (int64, error) {
+	var lastInsertId int64
+
+	err := repo.Pool.QueryRow(
+		ctx,
+		"INSERT INTO user_identities (user_id, provider, subject, email) values ($1, $2, $3, $4) RETURNING id",
+		identity.UserID,
+		identity.Provider,
+		identity.Subject,
+		identity.Email,
+	).Scan(&lastInsertId)
+
+	return lastInsertId, translate(err, nil)
+}
+
+func (repo *Identities) GetByProviderSubject(ctx context.Context, provider, subject string) (*domain.ExternalIdentity, error) {
+	var i domain.ExternalIdentity
+	err := repo.Pool.QueryRow(
+		ctx,
+		"SELECT id, user_id, provider, subject, email, created_at, updated_at FROM user_identities WHERE provider = $1 AND subject = $2",
+		provider,
+		subject,
+	).Scan(&i.ID, &i.UserID, &i.Provider, &i.Subject, &i.Email, &i.CreatedAt, &i.UpdatedAt)
+
+	if err != nil {
+		return nil, translate(err, domain.ErrExternalIdentityNotFound)
+	}
+
+	return &i, nil
+}
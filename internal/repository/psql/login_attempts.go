@@ -0,0 +1,69 @@
+package psql
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/wilfridterry/contact-list/internal/domain"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type LoginAttempts struct {
+	Pool *pgxpool.Pool
+}
+
+func NewLoginAttempts(pool *pgxpool.Pool) *LoginAttempts {
+	return &LoginAttempts{pool}
+}
+
+// Get returns nil without an error when there are no failures for the key.
+func (repo *LoginAttempts) Get(ctx context.Context, key string) (*domain.LoginAttempt, error) {
+	var a domain.LoginAttempt
+	err := repo.Pool.QueryRow(ctx, "SELECT key, failures, first_failed_at, last_failed_at, locked_until FROM login_attempts WHERE key = $1", key).
+		Scan(&a.Key, &a.Failures, &a.FirstFailedAt, &a.LastFailedAt, &a.LockedUntil)
+
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+
+		return nil, translate(err, nil)
+	}
+
+	return &a, nil
+}
+
+// RegisterFailure increments the failures of the key. Failures older than the
+// window are forgotten and counting starts again.
+func (repo *LoginAttempts) RegisterFailure(ctx context.Context, key string, now time.Time, window time.Duration) (*domain.LoginAttempt, error) {
+	var a domain.LoginAttempt
+	err := repo.Pool.QueryRow(
+		ctx,
+		`INSERT INTO login_attempts (key, failures, first_failed_at, last_failed_at) values ($1, 1, $2, $2)
+		ON CONFLICT (key) DO UPDATE SET
+			failures = CASE WHEN login_attempts.first_failed_at < $3 THEN 1 ELSE login_attempts.failures + 1 END,
+			first_failed_at = CASE WHEN login_attempts.first_failed_at < $3 THEN $2 ELSE login_attempts.first_failed_at END,
+			last_failed_at = $2
+		RETURNING key, failures, first_failed_at, last_failed_at, locked_until`,
+		key,
+		now,
+		now.Add(-window),
+	).Scan(&a.Key, &a.Failures, &a.FirstFailedAt, &a.LastFailedAt, &a.LockedUntil)
+
+	return &a, err
+}
+
+func (repo *LoginAttempts) Lock(ctx context.Context, key string, until time.Time) error {
+	_, err := repo.Pool.Exec(ctx, "UPDATE login_attempts SET locked_until = $1 WHERE key = $2", until, key)
+
+	return translate(err, nil)
+}
+
+func (repo *LoginAttempts) Reset(ctx context.Context, key string) error {
+	_, err := repo.Pool.Exec(ctx, "DELETE FROM login_attempts WHERE key = $1", key)
+
+	return translate(err, nil)
+}
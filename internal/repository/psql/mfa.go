@@ -0,0 +1,120 @@
+package psql
+
+import (
+	"context"
+
+	"github.com/wilfridterry/contact-list/internal/domain"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type MFA struct {
+	Pool *pgxpool.Pool
+}
+
+func NewMFA(pool *pgxpool.Pool) *MFA {
+	return &MFA{pool}
+}
+
+// SaveSecret stores a pending (not yet enabled) secret, replacing a previous
+// pending enrollment.
+func (repo *MFA) SaveSecret(ctx context.Context, userId int64, secret string) error {
+	_, err := repo.Pool.Exec(
+		ctx,
+		`INSERT INTO user_mfa (user_id, secret) values ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE SET secret = EXCLUDED.secret, enabled_at = NULL, updated_at = CURRENT_TIMESTAMP`,
+		userId,
+		secret,
+	)
+
+	return translate(err, nil)
+}
+
+func (repo *MFA) GetByUser(ctx context.Context, userId int64) (*domain.UserMFA, error) {
+	var m domain.UserMFA
+	err := repo.Pool.QueryRow(ctx, "SELECT user_id, secret, enabled_at, created_at, updated_at FROM user_mfa WHERE user_id = $1", userId).
+		Scan(&m.UserID, &m.Secret, &m.EnabledAt, &m.CreatedAt, &m.UpdatedAt)
+
+	if err != nil {
+		return nil, translate(err, domain.ErrMFANotEnrolled)
+	}
+
+	return &m, nil
+}
+
+// Enable marks the enrollment as active and replaces the recovery codes.
+func (repo *MFA) Enable(ctx context.Context, userId int64, recoveryCodeHashes []string) error {
+	tx, err := repo.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, "UPDATE user_mfa SET enabled_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP WHERE user_id = $1", userId); err != nil {
+		return translate(err, nil)
+	}
+
+	if _, err := tx.Exec(ctx, "DELETE FROM mfa_recovery_codes WHERE user_id = $1", userId); err != nil {
+		return translate(err, nil)
+	}
+
+	for _, hash := range recoveryCodeHashes {
+		if _, err := tx.Exec(ctx, "INSERT INTO mfa_recovery_codes (user_id, code_hash) values ($1, $2)", userId, hash); err != nil {
+			return translate(err, nil)
+		}
+	}
+
+	return translate(tx.Commit(ctx), nil)
+}
+
+func (repo *MFA) Disable(ctx context.Context, userId int64) error {
+	tag, err := repo.Pool.Exec(ctx, "DELETE FROM user_mfa WHERE user_id = $1", userId)
+	if err != nil {
+		return translate(err, nil)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return domain.ErrMFANotEnrolled
+	}
+
+	_, err = repo.Pool.Exec(ctx, "DELETE FROM mfa_recovery_codes WHERE user_id = $1", userId)
+
+	return translate(err, nil)
+}
+
+// UseStep records the time step of an accepted TOTP code and reports whether
+// it is later than the last one, a code is never accepted twice. Like
+// UseRecoveryCode it bumps updated_at, which MFA challenges are bound to.
+func (repo *MFA) UseStep(ctx context.Context, userId, step int64) (bool, error) {
+	tag, err := repo.Pool.Exec(
+		ctx,
+		"UPDATE user_mfa SET last_used_step = $2, updated_at = clock_timestamp() WHERE user_id = $1 AND last_used_step < $2",
+		userId,
+		step,
+	)
+	if err != nil {
+		return false, translate(err, nil)
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
+
+// UseRecoveryCode burns the recovery code and reports whether it was valid.
+func (repo *MFA) UseRecoveryCode(ctx context.Context, userId int64, codeHash string) (bool, error) {
+	tag, err := repo.Pool.Exec(
+		ctx,
+		`WITH used AS (
+			UPDATE mfa_recovery_codes SET used_at = CURRENT_TIMESTAMP
+			WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL
+			RETURNING user_id
+		)
+		UPDATE user_mfa SET updated_at = clock_timestamp() WHERE user_id IN (SELECT user_id FROM used)`,
+		userId,
+		codeHash,
+	)
+	if err != nil {
+		return false, translate(err, nil)
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
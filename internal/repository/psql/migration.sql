@@ -29,4 +29,278 @@ CREATE TABLE refresh_tokens (
     created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
     CONSTRAINT fk_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
-);
\ No newline at end of file
+);
+
+ALTER TABLE users ADD COLUMN role VARCHAR(32) NOT NULL DEFAULT 'user';
+ALTER TABLE users ADD COLUMN email_verified_at TIMESTAMPTZ;
+-- Accounts created before addresses were verified are not locked out.
+UPDATE users SET email_verified_at = COALESCE(registered_at, created_at, CURRENT_TIMESTAMP);
+ALTER TABLE users ADD COLUMN disabled_at TIMESTAMPTZ;
+ALTER TABLE users ADD COLUMN password_reset_required BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE TABLE api_keys (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    prefix VARCHAR(16) NOT NULL UNIQUE,
+    key_hash VARCHAR(64) NOT NULL,
+    scopes TEXT[] NOT NULL DEFAULT '{}',
+    expires_at TIMESTAMPTZ,
+    last_used_at TIMESTAMPTZ,
+    revoked_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT fk_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE TABLE user_mfa (
+    user_id INTEGER PRIMARY KEY,
+    secret VARCHAR(64) NOT NULL,
+    enabled_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT fk_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE TABLE mfa_recovery_codes (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    code_hash VARCHAR(64) NOT NULL,
+    used_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT fk_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE TABLE login_attempts (
+    key VARCHAR(320) PRIMARY KEY,
+    failures INTEGER NOT NULL DEFAULT 0,
+    first_failed_at TIMESTAMPTZ NOT NULL,
+    last_failed_at TIMESTAMPTZ NOT NULL,
+    locked_until TIMESTAMPTZ
+);
+
+CREATE TABLE user_identities (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    provider VARCHAR(64) NOT NULL,
+    subject VARCHAR(255) NOT NULL,
+    email VARCHAR(255),
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT uq_provider_subject UNIQUE (provider, subject),
+    CONSTRAINT fk_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE TABLE oauth_clients (
+    id SERIAL PRIMARY KEY,
+    client_id VARCHAR(64) NOT NULL UNIQUE,
+    secret_hash VARCHAR(64),
+    user_id INTEGER NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    redirect_uris TEXT[] NOT NULL DEFAULT '{}',
+    scopes TEXT[] NOT NULL DEFAULT '{}',
+    confidential BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT fk_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE TABLE oauth_codes (
+    id SERIAL PRIMARY KEY,
+    code_hash VARCHAR(64) NOT NULL UNIQUE,
+    client_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    redirect_uri TEXT NOT NULL DEFAULT '',
+    scopes TEXT[] NOT NULL DEFAULT '{}',
+    code_challenge VARCHAR(128) NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    used_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT fk_client FOREIGN KEY (client_id) REFERENCES oauth_clients(id) ON DELETE CASCADE,
+    CONSTRAINT fk_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE TABLE oauth_tokens (
+    id SERIAL PRIMARY KEY,
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    kind VARCHAR(16) NOT NULL,
+    client_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    scopes TEXT[] NOT NULL DEFAULT '{}',
+    parent_id INTEGER,
+    expires_at TIMESTAMPTZ NOT NULL,
+    revoked_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT fk_client FOREIGN KEY (client_id) REFERENCES oauth_clients(id) ON DELETE CASCADE,
+    CONSTRAINT fk_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    CONSTRAINT fk_parent FOREIGN KEY (parent_id) REFERENCES oauth_tokens(id) ON DELETE CASCADE
+);
+
+CREATE TABLE oauth_consents (
+    user_id INTEGER NOT NULL,
+    client_id INTEGER NOT NULL,
+    scopes TEXT[] NOT NULL DEFAULT '{}',
+    granted_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, client_id),
+    CONSTRAINT fk_client FOREIGN KEY (client_id) REFERENCES oauth_clients(id) ON DELETE CASCADE,
+    CONSTRAINT fk_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+ALTER TABLE contacts ADD COLUMN user_id INTEGER REFERENCES users(id) ON DELETE SET NULL;
+
+CREATE TABLE audit_entries (
+    id SERIAL PRIMARY KEY,
+    action VARCHAR(64) NOT NULL,
+    entity VARCHAR(64) NOT NULL,
+    entity_id INTEGER NOT NULL,
+    actor_id INTEGER,
+    timestamp TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX idx_audit_entries_entity ON audit_entries (entity, entity_id);
+
+CREATE TABLE idempotency_keys (
+    user_id INTEGER NOT NULL,
+    key VARCHAR(255) NOT NULL,
+    fingerprint VARCHAR(64) NOT NULL,
+    status INTEGER,
+    content_type VARCHAR(255),
+    body BYTEA,
+    completed_at TIMESTAMPTZ,
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, key),
+    CONSTRAINT fk_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_idempotency_keys_expires_at ON idempotency_keys (expires_at);
+
+CREATE TABLE webhooks (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    url VARCHAR(2048) NOT NULL,
+    events TEXT[] NOT NULL DEFAULT '{}',
+    secret VARCHAR(255) NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT fk_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE TABLE webhook_deliveries (
+    id SERIAL PRIMARY KEY,
+    webhook_id INTEGER NOT NULL,
+    event VARCHAR(64) NOT NULL,
+    payload JSONB NOT NULL,
+    status VARCHAR(16) NOT NULL DEFAULT 'pending',
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMPTZ,
+    delivered_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT fk_webhook FOREIGN KEY (webhook_id) REFERENCES webhooks(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_webhook_deliveries_due ON webhook_deliveries (next_attempt_at) WHERE status = 'pending';
+
+CREATE TABLE webhook_attempts (
+    id SERIAL PRIMARY KEY,
+    delivery_id INTEGER NOT NULL,
+    status_code INTEGER NOT NULL DEFAULT 0,
+    error TEXT NOT NULL DEFAULT '',
+    duration_ms INTEGER NOT NULL,
+    attempted_at TIMESTAMPTZ NOT NULL,
+    CONSTRAINT fk_delivery FOREIGN KEY (delivery_id) REFERENCES webhook_deliveries(id) ON DELETE CASCADE
+);
+
+CREATE TABLE contact_sync (
+    id BOOLEAN PRIMARY KEY DEFAULT TRUE CHECK (id),
+    seq BIGINT NOT NULL DEFAULT 0,
+    purged_seq BIGINT NOT NULL DEFAULT 0
+);
+
+INSERT INTO contact_sync DEFAULT VALUES;
+
+ALTER TABLE contacts ADD COLUMN created_seq BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE contacts ADD COLUMN change_seq BIGINT NOT NULL DEFAULT 0;
+
+-- Contacts created before changes were tracked get sequences in id order, so
+-- a first sync returns them too. It runs before the trigger exists, which
+-- would count the update as another change.
+UPDATE contacts SET created_seq = backfill.seq, change_seq = backfill.seq
+FROM (SELECT id, ROW_NUMBER() OVER (ORDER BY id) AS seq FROM contacts) AS backfill
+WHERE contacts.id = backfill.id;
+
+UPDATE contact_sync SET seq = (SELECT COALESCE(MAX(change_seq), 0) FROM contacts);
+
+CREATE INDEX idx_contacts_change_seq ON contacts (change_seq);
+
+CREATE TABLE contact_tombstones (
+    contact_id INTEGER PRIMARY KEY,
+    change_seq BIGINT NOT NULL,
+    deleted_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_contact_tombstones_change_seq ON contact_tombstones (change_seq);
+
+-- Every write to contacts takes the next change sequence from the single
+-- contact_sync row when its transaction commits, through a deferred trigger.
+-- The row stays locked from then until the transaction ends, so changes
+-- commit in sequence order and a reader never misses one that commits late,
+-- while writers only wait for each other while committing. The trigger sets
+-- the sequence with an update of its own, which changes change_seq and so is
+-- not tracked again.
+CREATE FUNCTION contacts_track_change() RETURNS TRIGGER AS $$
+DECLARE
+    next_seq BIGINT;
+BEGIN
+    UPDATE contact_sync SET seq = seq + 1 RETURNING seq INTO next_seq;
+
+    IF TG_OP = 'DELETE' THEN
+        INSERT INTO contact_tombstones (contact_id, change_seq) VALUES (OLD.id, next_seq)
+        ON CONFLICT (contact_id) DO UPDATE SET change_seq = EXCLUDED.change_seq, deleted_at = CURRENT_TIMESTAMP;
+
+        RETURN NULL;
+    END IF;
+
+    UPDATE contacts SET
+        change_seq = next_seq,
+        created_seq = CASE WHEN TG_OP = 'INSERT' THEN next_seq ELSE created_seq END
+    WHERE id = NEW.id;
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE CONSTRAINT TRIGGER contacts_track_insert
+    AFTER INSERT ON contacts
+    DEFERRABLE INITIALLY DEFERRED
+    FOR EACH ROW EXECUTE FUNCTION contacts_track_change();
+
+CREATE CONSTRAINT TRIGGER contacts_track_update
+    AFTER UPDATE ON contacts
+    DEFERRABLE INITIALLY DEFERRED
+    FOR EACH ROW WHEN (OLD.change_seq = NEW.change_seq)
+    EXECUTE FUNCTION contacts_track_change();
+
+CREATE CONSTRAINT TRIGGER contacts_track_delete
+    AFTER DELETE ON contacts
+    DEFERRABLE INITIALLY DEFERRED
+    FOR EACH ROW EXECUTE FUNCTION contacts_track_change();
+
+CREATE TABLE carddav_objects (
+    contact_id INTEGER PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    uid VARCHAR(255) NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (user_id, name),
+    CONSTRAINT fk_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- The time step of the last accepted TOTP code, so a code is accepted once.
+ALTER TABLE user_mfa ADD COLUMN last_used_step BIGINT NOT NULL DEFAULT 0;
+
+-- Erasure drops the deliveries about a contact.
+CREATE INDEX idx_webhook_deliveries_contact ON webhook_deliveries (((payload->>'contact_id')::bigint));
+
+-- The replayed response headers of idempotent requests, content_type is only
+-- read for records completed before.
+ALTER TABLE idempotency_keys ADD COLUMN headers JSONB;
@@ -0,0 +1,286 @@
+package psql
+
+import (
+	"context"
+
+	"github.com/wilfridterry/contact-list/internal/domain"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	oauthClientColumns = "id, client_id, COALESCE(secret_hash, ''), user_id, name, redirect_uris, scopes, confidential, created_at, updated_at"
+	oauthCodeColumns   = "id, code_hash, client_id, user_id, redirect_uri, scopes, code_challenge, expires_at, used_at"
+	oauthTokenColumns  = "id, token_hash, kind, client_id, user_id, scopes, parent_id, expires_at, revoked_at, created_at"
+)
+
+type OAuth struct {
+	Pool *pgxpool.Pool
+}
+
+func NewOAuth(pool *pgxpool.Pool) *OAuth {
+	return &OAuth{pool}
+}
+
+func (repo *OAuth) CreateClient(ctx context.Context, client *domain.OAuthClient) (int64, error) {
+	var lastInsertId int64
+
+	var secretHash *string
+	if client.SecretHash != "" {
+		secretHash = &client.SecretHash
+	}
+
+	err := repo.Pool.QueryRow(
+		ctx,
+		"INSERT INTO oauth_clients (client_id, secret_hash, user_id, name, redirect_uris, scopes, confidential) values ($1, $2, $3, $4, $5, $6, $7) RETURNING id",
+		client.ClientID,
+		secretHash,
+		client.UserID,
+		client.Name,
+		client.RedirectURIs,
+		permissionsToStrings(client.Scopes),
+		client.Confidential,
+	).Scan(&lastInsertId)
+
+	return lastInsertId, translate(err, nil)
+}
+
+func (repo *OAuth) GetClientByClientID(ctx context.Context, clientId string) (*domain.OAuthClient, error) {
+	row := repo.Pool.QueryRow(ctx, "SELECT "+oauthClientColumns+" FROM oauth_clients WHERE client_id = $1", clientId)
+
+	client, err := scanOAuthClient(row)
+	if err != nil {
+		return nil, translate(err, domain.ErrOAuthClientNotFound)
+	}
+
+	return client, nil
+}
+
+func (repo *OAuth) GetClientsByUser(ctx context.Context, userId int64) ([]domain.OAuthClient, error) {
+	rows, err := repo.Pool.Query(ctx, "SELECT "+oauthClientColumns+" FROM oauth_clients WHERE user_id = $1 ORDER BY id", userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	clients := make([]domain.OAuthClient, 0)
+
+	for rows.Next() {
+		client, err := scanOAuthClient(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		clients = append(clients, *client)
+	}
+
+	return clients, rows.Err()
+}
+
+func (repo *OAuth) DeleteClient(ctx context.Context, userId, id int64) error {
+	tag, err := repo.Pool.Exec(ctx, "DELETE FROM oauth_clients WHERE id = $1 AND user_id = $2", id, userId)
+	if err != nil {
+		return translate(err, nil)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return domain.ErrOAuthClientNotFound
+	}
+
+	return nil
+}
+
+func (repo *OAuth) CreateCode(ctx context.Context, code *domain.OAuthCode) error {
+	_, err := repo.Pool.Exec(
+		ctx,
+		"INSERT INTO oauth_codes (code_hash, client_id, user_id, redirect_uri, scopes, code_challenge, expires_at) values ($1, $2, $3, $4, $5, $6, $7)",
+		code.CodeHash,
+		code.ClientID,
+		code.UserID,
+		code.RedirectURI,
+		permissionsToStrings(code.Scopes),
+		code.CodeChallenge,
+		code.ExpiresAt,
+	)
+
+	return translate(err, nil)
+}
+
+// UseCode marks the code as used and returns it. A code can be used only once,
+// so concurrent redemptions of the same code get ErrOAuthCodeNotFound.
+func (repo *OAuth) UseCode(ctx context.Context, codeHash string) (*domain.OAuthCode, error) {
+	var (
+		c      domain.OAuthCode
+		scopes []string
+	)
+
+	err := repo.Pool.QueryRow(
+		ctx,
+		"UPDATE oauth_codes SET used_at = CURRENT_TIMESTAMP WHERE code_hash = $1 AND used_at IS NULL RETURNING "+oauthCodeColumns,
+		codeHash,
+	).Scan(&c.ID, &c.CodeHash, &c.ClientID, &c.UserID, &c.RedirectURI, &scopes, &c.CodeChallenge, &c.ExpiresAt, &c.UsedAt)
+
+	if err != nil {
+		return nil, translate(err, domain.ErrOAuthCodeNotFound)
+	}
+
+	c.Scopes = stringsToPermissions(scopes)
+
+	return &c, nil
+}
+
+func (repo *OAuth) CreateToken(ctx context.Context, token *domain.OAuthToken) (int64, error) {
+	var lastInsertId int64
+
+	err := repo.Pool.QueryRow(
+		ctx,
+		"INSERT INTO oauth_tokens (token_hash, kind, client_id, user_id, scopes, parent_id, expires_at) values ($1, $2, $3, $4, $5, $6, $7) RETURNING id",
+		token.TokenHash,
+		token.Kind,
+		token.ClientID,
+		token.UserID,
+		permissionsToStrings(token.Scopes),
+		token.ParentID,
+		token.ExpiresAt,
+	).Scan(&lastInsertId)
+
+	return lastInsertId, translate(err, nil)
+}
+
+func (repo *OAuth) GetTokenByHash(ctx context.Context, tokenHash string) (*domain.OAuthToken, error) {
+	var (
+		t      domain.OAuthToken
+		scopes []string
+	)
+
+	err := repo.Pool.QueryRow(ctx, "SELECT "+oauthTokenColumns+" FROM oauth_tokens WHERE token_hash = $1", tokenHash).
+		Scan(&t.ID, &t.TokenHash, &t.Kind, &t.ClientID, &t.UserID, &scopes, &t.ParentID, &t.ExpiresAt, &t.RevokedAt, &t.CreatedAt)
+
+	if err != nil {
+		return nil, translate(err, domain.ErrOAuthTokenNotFound)
+	}
+
+	t.Scopes = stringsToPermissions(scopes)
+
+	return &t, nil
+}
+
+// RevokeToken revokes the token and the access tokens issued with it.
+func (repo *OAuth) RevokeToken(ctx context.Context, id int64) error {
+	_, err := repo.Pool.Exec(
+		ctx,
+		"UPDATE oauth_tokens SET revoked_at = CURRENT_TIMESTAMP WHERE (id = $1 OR parent_id = $1) AND revoked_at IS NULL",
+		id,
+	)
+
+	return translate(err, nil)
+}
+
+func (repo *OAuth) RevokeTokensByClient(ctx context.Context, userId, clientId int64) error {
+	_, err := repo.Pool.Exec(
+		ctx,
+		"UPDATE oauth_tokens SET revoked_at = CURRENT_TIMESTAMP WHERE user_id = $1 AND client_id = $2 AND revoked_at IS NULL",
+		userId,
+		clientId,
+	)
+
+	return translate(err, nil)
+}
+
+func (repo *OAuth) GetConsent(ctx context.Context, userId, clientId int64) (*domain.OAuthConsent, error) {
+	row := repo.Pool.QueryRow(
+		ctx,
+		"SELECT oc.user_id, oc.client_id, c.name, c.client_id, oc.scopes, oc.granted_at FROM oauth_consents oc JOIN oauth_clients c ON c.id = oc.client_id WHERE oc.user_id = $1 AND oc.client_id = $2",
+		userId,
+		clientId,
+	)
+
+	consent, err := scanOAuthConsent(row)
+	if err != nil {
+		return nil, translate(err, domain.ErrOAuthConsentNotFound)
+	}
+
+	return consent, nil
+}
+
+func (repo *OAuth) GetConsentsByUser(ctx context.Context, userId int64) ([]domain.OAuthConsent, error) {
+	rows, err := repo.Pool.Query(
+		ctx,
+		"SELECT oc.user_id, oc.client_id, c.name, c.client_id, oc.scopes, oc.granted_at FROM oauth_consents oc JOIN oauth_clients c ON c.id = oc.client_id WHERE oc.user_id = $1 ORDER BY oc.granted_at",
+		userId,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	consents := make([]domain.OAuthConsent, 0)
+
+	for rows.Next() {
+		consent, err := scanOAuthConsent(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		consents = append(consents, *consent)
+	}
+
+	return consents, rows.Err()
+}
+
+func (repo *OAuth) SaveConsent(ctx context.Context, consent *domain.OAuthConsent) error {
+	_, err := repo.Pool.Exec(
+		ctx,
+		`INSERT INTO oauth_consents (user_id, client_id, scopes) values ($1, $2, $3)
+		ON CONFLICT (user_id, client_id) DO UPDATE SET scopes = EXCLUDED.scopes, granted_at = CURRENT_TIMESTAMP`,
+		consent.UserID,
+		consent.ClientID,
+		permissionsToStrings(consent.Scopes),
+	)
+
+	return translate(err, nil)
+}
+
+func (repo *OAuth) DeleteConsent(ctx context.Context, userId, clientId int64) error {
+	tag, err := repo.Pool.Exec(ctx, "DELETE FROM oauth_consents WHERE user_id = $1 AND client_id = $2", userId, clientId)
+	if err != nil {
+		return translate(err, nil)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return domain.ErrOAuthConsentNotFound
+	}
+
+	return nil
+}
+
+func scanOAuthClient(row pgx.Row) (*domain.OAuthClient, error) {
+	var (
+		c      domain.OAuthClient
+		scopes []string
+	)
+
+	if err := row.Scan(&c.ID, &c.ClientID, &c.SecretHash, &c.UserID, &c.Name, &c.RedirectURIs, &scopes, &c.Confidential, &c.CreatedAt, &c.UpdatedAt); err != nil {
+		return nil, err
+	}
+
+	c.Scopes = stringsToPermissions(scopes)
+
+	return &c, nil
+}
+
+func scanOAuthConsent(row pgx.Row) (*domain.OAuthConsent, error) {
+	var (
+		c      domain.OAuthConsent
+		scopes []string
+	)
+
+	if err := row.Scan(&c.UserID, &c.ClientID, &c.ClientName, &c.PublicID, &scopes, &c.GrantedAt); err != nil {
+		return nil, err
+	}
+
+	c.Scopes = stringsToPermissions(scopes)
+
+	return &c, nil
+}
@@ -5,19 +5,19 @@ import (
 
 	"github.com/wilfridterry/contact-list/internal/domain"
 
-	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 type Tokens struct {
-	Conn *pgx.Conn
+	Pool *pgxpool.Pool
 }
 
-func NewTokens(conn *pgx.Conn) *Tokens {
-	return &Tokens{conn}
+func NewTokens(pool *pgxpool.Pool) *Tokens {
+	return &Tokens{pool}
 }
 
 func (r *Tokens) Create(ctx context.Context, session *domain.RefreshSession) error {
-	_, err := r.Conn.Exec(
+	_, err := r.Pool.Exec(
 		ctx,
 		"INSERT INTO refresh_tokens (user_id, token, expires_at) values ($1, $2, $3)",
 		session.UserId,
@@ -25,18 +25,45 @@ func (r *Tokens) Create(ctx context.Context, session *domain.RefreshSession) err
 		session.ExpiresAt,
 	)
 
-	return err
+	return translate(err, nil)
 }
 
 func (r *Tokens) GetByToken(ctx context.Context, token string) (*domain.RefreshSession, error) {
 	s := domain.RefreshSession{}
-	row := r.Conn.QueryRow(ctx, "SELECT * from refresh_tokens WHERE token = $1", token)
+	row := r.Pool.QueryRow(ctx, "SELECT * from refresh_tokens WHERE token = $1", token)
 
-	if err := row.Scan(&s.ID, &s.Token, &s.ExpiresAt, &s.CreatedAt, &s.UpdatedAt); err != nil {
-		return &domain.RefreshSession{}, err
+	if err := row.Scan(&s.ID, &s.UserId, &s.Token, &s.ExpiresAt, &s.CreatedAt, &s.UpdatedAt); err != nil {
+		return nil, translate(err, domain.ErrRefreshTokenNotFound)
 	}
 
-	_, err := r.Conn.Exec(ctx, "DELETE FROM refresh_tokens WHERE token = $1", token)
+	_, err := r.Pool.Exec(ctx, "DELETE FROM refresh_tokens WHERE token = $1", token)
 
-	return &s, err
+	return &s, translate(err, nil)
+}
+
+func (r *Tokens) DeleteAllByUser(ctx context.Context, userId int64) error {
+	_, err := r.Pool.Exec(ctx, "DELETE FROM refresh_tokens WHERE user_id = $1", userId)
+
+	return translate(err, nil)
+}
+
+func (r *Tokens) GetAllByUser(ctx context.Context, userId int64) ([]domain.RefreshSession, error) {
+	rows, err := r.Pool.Query(ctx, "SELECT id, user_id, token, expires_at, created_at, updated_at FROM refresh_tokens WHERE user_id = $1 ORDER BY id", userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := make([]domain.RefreshSession, 0)
+
+	for rows.Next() {
+		var s domain.RefreshSession
+		if err := rows.Scan(&s.ID, &s.UserId, &s.Token, &s.ExpiresAt, &s.CreatedAt, &s.UpdatedAt); err != nil {
+			return nil, err
+		}
+
+		sessions = append(sessions, s)
+	}
+
+	return sessions, rows.Err()
 }
@@ -2,48 +2,253 @@ package psql
 
 import (
 	"context"
-	"errors"
+	"strings"
 
 	"github.com/wilfridterry/contact-list/internal/domain"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+const userColumns = "id, name, email, password, role, email_verified_at, disabled_at, password_reset_required, registered_at, created_at, updated_at"
+
 type Users struct {
-	Conn *pgx.Conn
+	Pool *pgxpool.Pool
 }
 
-func NewUsers(conn *pgx.Conn) *Users {
-	return &Users{conn}
+func NewUsers(pool *pgxpool.Pool) *Users {
+	return &Users{pool}
 }
 
 func (repo *Users) Create(ctx context.Context, user *domain.User) (int64, error) {
 	var lastInsertId int64
 
-	err := repo.Conn.QueryRow(
+	err := repo.Pool.QueryRow(
 		ctx,
-		"INSERT INTO users (name, email, password, registered_at) values ($1, $2, $3, $4) RETURNING id",
+		"INSERT INTO users (name, email, password, role, registered_at) values ($1, $2, $3, $4, $5) RETURNING id",
 		user.Name,
 		user.Email,
 		user.Password,
+		user.Role,
 		user.RegisteredAt,
 	).Scan(&lastInsertId)
 
-	return lastInsertId, err
+	return lastInsertId, translate(err, nil)
 }
 
 func (repo *Users) GetByEmailAndPassword(ctx context.Context, email string, password string) (*domain.User, error) {
-	var u domain.User
-	err := repo.Conn.QueryRow(ctx, "SELECT * FROM users WHERE email=$1 AND password=$2", email, password).
-		Scan(&u.ID, &u.Name, &u.Email, &u.Password, &u.RegisteredAt, &u.CreatedAt, &u.UpdatedAt)
+	return repo.getOne(ctx, "SELECT "+userColumns+" FROM users WHERE email=$1 AND password=$2", email, password)
+}
 
+func (repo *Users) GetById(ctx context.Context, id int64) (*domain.User, error) {
+	return repo.getOne(ctx, "SELECT "+userColumns+" FROM users WHERE id=$1", id)
+}
+
+// GetByIds returns the users found, in no particular order.
+func (repo *Users) GetByIds(ctx context.Context, ids []int64) ([]domain.User, error) {
+	rows, err := repo.Pool.Query(ctx, "SELECT "+userColumns+" FROM users WHERE id = ANY($1)", ids)
 	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
-			return nil, domain.ErrNotFoundUser
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := make([]domain.User, 0, len(ids))
+
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, err
 		}
 
-		return nil, err
+		users = append(users, *user)
+	}
+
+	return users, rows.Err()
+}
+
+func (repo *Users) GetByEmail(ctx context.Context, email string) (*domain.User, error) {
+	return repo.getOne(ctx, "SELECT "+userColumns+" FROM users WHERE email=$1", email)
+}
+
+func (repo *Users) UpdateRole(ctx context.Context, id int64, role domain.Role) error {
+	return repo.exec(ctx, "UPDATE users SET role=$1, updated_at=CURRENT_TIMESTAMP WHERE id=$2", role, id)
+}
+
+func (repo *Users) UpdatePassword(ctx context.Context, id int64, password string) error {
+	return repo.exec(ctx, "UPDATE users SET password=$1, password_reset_required=FALSE, updated_at=CURRENT_TIMESTAMP WHERE id=$2", password, id)
+}
+
+// SetDisabled disables the user or enables it again when disabled is false.
+func (repo *Users) SetDisabled(ctx context.Context, id int64, disabled bool) error {
+	return repo.exec(
+		ctx,
+		"UPDATE users SET disabled_at=CASE WHEN $1 THEN COALESCE(disabled_at, CURRENT_TIMESTAMP) END, updated_at=CURRENT_TIMESTAMP WHERE id=$2",
+		disabled,
+		id,
+	)
+}
+
+func (repo *Users) SetPasswordResetRequired(ctx context.Context, id int64, required bool) error {
+	return repo.exec(ctx, "UPDATE users SET password_reset_required=$1, updated_at=CURRENT_TIMESTAMP WHERE id=$2", required, id)
+}
+
+// List returns a page of users whose name or email contains the search term,
+// together with the total number of matching users.
+func (repo *Users) List(ctx context.Context, filter *domain.UserFilter) ([]domain.User, int64, error) {
+	search := "%" + escapeLike(filter.Search) + "%"
+
+	var total int64
+	if err := repo.Pool.QueryRow(ctx, "SELECT COUNT(*) FROM users WHERE name ILIKE $1 OR email ILIKE $1", search).Scan(&total); err != nil {
+		return nil, 0, translate(err, nil)
+	}
+
+	rows, err := repo.Pool.Query(
+		ctx,
+		"SELECT "+userColumns+" FROM users WHERE name ILIKE $1 OR email ILIKE $1 ORDER BY id LIMIT $2 OFFSET $3",
+		search,
+		filter.PerPage,
+		(filter.Page-1)*filter.PerPage,
+	)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	users := make([]domain.User, 0)
+
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		users = append(users, *user)
 	}
 
-	return &u, nil
+	return users, total, rows.Err()
+}
+
+func (repo *Users) MarkEmailVerified(ctx context.Context, id int64) error {
+	return repo.exec(ctx, "UPDATE users SET email_verified_at=CURRENT_TIMESTAMP, updated_at=CURRENT_TIMESTAMP WHERE id=$1", id)
+}
+
+// UpdateProfile saves the name and email, a changed email has to be verified
+// again.
+func (repo *Users) UpdateProfile(ctx context.Context, user *domain.User) error {
+	return repo.exec(
+		ctx,
+		"UPDATE users SET name=$1, email=$2, email_verified_at=$3, updated_at=CURRENT_TIMESTAMP WHERE id=$4",
+		user.Name,
+		user.Email,
+		user.EmailVerifiedAt,
+		user.ID,
+	)
+}
+
+// Delete removes the user with everything that cascades from it. Owned
+// contacts are either deleted or kept without an owner and author.
+func (repo *Users) Delete(ctx context.Context, id int64, contacts domain.OwnedContactsPolicy) error {
+	tx, err := repo.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	query := "UPDATE contacts SET user_id=NULL, author='', updated_at=CURRENT_TIMESTAMP WHERE user_id=$1"
+	if contacts == domain.OwnedContactsDelete {
+		query = "DELETE FROM contacts WHERE user_id=$1"
+	}
+
+	if _, err := tx.Exec(ctx, query, id); err != nil {
+		return translate(err, nil)
+	}
+
+	tag, err := tx.Exec(ctx, "DELETE FROM users WHERE id=$1", id)
+	if err != nil {
+		return translate(err, nil)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return domain.ErrNotFoundUser
+	}
+
+	return translate(tx.Commit(ctx), nil)
+}
+
+// Pseudonymize replaces the personal data of the user and removes every
+// credential and session, so the account is kept only as an anonymous owner
+// of its contacts.
+func (repo *Users) Pseudonymize(ctx context.Context, user *domain.User) error {
+	tx, err := repo.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(
+		ctx,
+		"UPDATE users SET name=$1, email=$2, password=$3, email_verified_at=NULL, updated_at=CURRENT_TIMESTAMP WHERE id=$4",
+		user.Name,
+		user.Email,
+		user.Password,
+		user.ID,
+	)
+	if err != nil {
+		return translate(err, nil)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return domain.ErrNotFoundUser
+	}
+
+	for _, query := range []string{
+		"DELETE FROM refresh_tokens WHERE user_id=$1",
+		"DELETE FROM api_keys WHERE user_id=$1",
+		"DELETE FROM user_mfa WHERE user_id=$1",
+		"DELETE FROM mfa_recovery_codes WHERE user_id=$1",
+		"DELETE FROM user_identities WHERE user_id=$1",
+		"DELETE FROM oauth_clients WHERE user_id=$1",
+		"DELETE FROM oauth_consents WHERE user_id=$1",
+		"DELETE FROM oauth_tokens WHERE user_id=$1",
+		"UPDATE contacts SET author='' WHERE user_id=$1",
+	} {
+		if _, err := tx.Exec(ctx, query, user.ID); err != nil {
+			return translate(err, nil)
+		}
+	}
+
+	return translate(tx.Commit(ctx), nil)
+}
+
+func (repo *Users) getOne(ctx context.Context, query string, args ...any) (*domain.User, error) {
+	u, err := scanUser(repo.Pool.QueryRow(ctx, query, args...))
+	if err != nil {
+		return nil, translate(err, domain.ErrNotFoundUser)
+	}
+
+	return u, nil
+}
+
+func (repo *Users) exec(ctx context.Context, query string, args ...any) error {
+	tag, err := repo.Pool.Exec(ctx, query, args...)
+	if err != nil {
+		return translate(err, nil)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return domain.ErrNotFoundUser
+	}
+
+	return nil
+}
+
+func scanUser(row pgx.Row) (*domain.User, error) {
+	var u domain.User
+	err := row.Scan(&u.ID, &u.Name, &u.Email, &u.Password, &u.Role, &u.EmailVerifiedAt, &u.DisabledAt, &u.PasswordResetRequired, &u.RegisteredAt, &u.CreatedAt, &u.UpdatedAt)
+
+	return &u, err
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
 }
@@ -0,0 +1,312 @@
+package psql
+
+import (
+	"context"
+	"time"
+
+	"github.com/wilfridterry/contact-list/internal/domain"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	webhookColumns  = "id, user_id, url, events, secret, created_at"
+	deliveryColumns = "id, webhook_id, event, payload, status, attempts, next_attempt_at, delivered_at, created_at"
+
+	maxListedDeliveries = 100
+)
+
+type Webhooks struct {
+	Pool *pgxpool.Pool
+}
+
+func NewWebhooks(pool *pgxpool.Pool) *Webhooks {
+	return &Webhooks{pool}
+}
+
+func (repo *Webhooks) Create(ctx context.Context, webhook *domain.Webhook) (int64, error) {
+	var lastInsertId int64
+
+	err := repo.Pool.QueryRow(
+		ctx,
+		"INSERT INTO webhooks (user_id, url, events, secret) values ($1, $2, $3, $4) RETURNING id",
+		webhook.UserID,
+		webhook.URL,
+		webhook.Events,
+		webhook.Secret,
+	).Scan(&lastInsertId)
+
+	return lastInsertId, translate(err, nil)
+}
+
+func (repo *Webhooks) GetById(ctx context.Context, id int64) (*domain.Webhook, error) {
+	row := repo.Pool.QueryRow(ctx, "SELECT "+webhookColumns+" FROM webhooks WHERE id = $1", id)
+
+	webhook, err := scanWebhook(row)
+	if err != nil {
+		return nil, translate(err, domain.ErrWebhookNotFound)
+	}
+
+	return webhook, nil
+}
+
+func (repo *Webhooks) GetByUser(ctx context.Context, userId, id int64) (*domain.Webhook, error) {
+	row := repo.Pool.QueryRow(ctx, "SELECT "+webhookColumns+" FROM webhooks WHERE id = $1 AND user_id = $2", id, userId)
+
+	webhook, err := scanWebhook(row)
+	if err != nil {
+		return nil, translate(err, domain.ErrWebhookNotFound)
+	}
+
+	return webhook, nil
+}
+
+func (repo *Webhooks) GetAllByUser(ctx context.Context, userId int64) ([]domain.Webhook, error) {
+	return repo.query(ctx, "SELECT "+webhookColumns+" FROM webhooks WHERE user_id = $1 ORDER BY id", userId)
+}
+
+// GetSubscribed returns the webhooks of every user subscribed to the event.
+func (repo *Webhooks) GetSubscribed(ctx context.Context, event string) ([]domain.Webhook, error) {
+	return repo.query(ctx, "SELECT "+webhookColumns+" FROM webhooks WHERE $1 = ANY(events) ORDER BY id", event)
+}
+
+func (repo *Webhooks) Delete(ctx context.Context, userId, id int64) error {
+	tag, err := repo.Pool.Exec(ctx, "DELETE FROM webhooks WHERE id = $1 AND user_id = $2", id, userId)
+	if err != nil {
+		return translate(err, nil)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return domain.ErrWebhookNotFound
+	}
+
+	return nil
+}
+
+// DeleteAllByUser drops the webhooks of the user with their deliveries.
+func (repo *Webhooks) DeleteAllByUser(ctx context.Context, userId int64) error {
+	_, err := repo.Pool.Exec(ctx, "DELETE FROM webhooks WHERE user_id = $1", userId)
+
+	return translate(err, nil)
+}
+
+// DeleteDeliveriesByContact drops the deliveries about the contacts, whichever
+// webhook they were queued for, with their attempts.
+func (repo *Webhooks) DeleteDeliveriesByContact(ctx context.Context, contactIds []int64) error {
+	if len(contactIds) == 0 {
+		return nil
+	}
+
+	_, err := repo.Pool.Exec(ctx, "DELETE FROM webhook_deliveries WHERE (payload->>'contact_id')::bigint = ANY($1)", contactIds)
+
+	return translate(err, nil)
+}
+
+func (repo *Webhooks) CreateDelivery(ctx context.Context, delivery *domain.WebhookDelivery) (int64, error) {
+	var lastInsertId int64
+
+	err := repo.Pool.QueryRow(
+		ctx,
+		"INSERT INTO webhook_deliveries (webhook_id, event, payload, status, next_attempt_at) values ($1, $2, $3, $4, $5) RETURNING id",
+		delivery.WebhookID,
+		delivery.Event,
+		delivery.Payload,
+		delivery.Status,
+		delivery.NextAttemptAt,
+	).Scan(&lastInsertId)
+
+	return lastInsertId, translate(err, nil)
+}
+
+// GetDeliveries returns the latest deliveries of the webhook, newest first.
+func (repo *Webhooks) GetDeliveries(ctx context.Context, webhookId int64) ([]domain.WebhookDelivery, error) {
+	rows, err := repo.Pool.Query(ctx, "SELECT "+deliveryColumns+" FROM webhook_deliveries WHERE webhook_id = $1 ORDER BY id DESC LIMIT $2", webhookId, maxListedDeliveries)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := make([]domain.WebhookDelivery, 0)
+
+	for rows.Next() {
+		delivery, err := scanDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		deliveries = append(deliveries, *delivery)
+	}
+
+	return deliveries, rows.Err()
+}
+
+// GetDelivery returns the delivery of the webhook with its attempts log.
+func (repo *Webhooks) GetDelivery(ctx context.Context, webhookId, id int64) (*domain.WebhookDelivery, error) {
+	row := repo.Pool.QueryRow(ctx, "SELECT "+deliveryColumns+" FROM webhook_deliveries WHERE id = $1 AND webhook_id = $2", id, webhookId)
+
+	delivery, err := scanDelivery(row)
+	if err != nil {
+		return nil, translate(err, domain.ErrWebhookDeliveryNotFound)
+	}
+
+	rows, err := repo.Pool.Query(ctx, "SELECT status_code, error, duration_ms, attempted_at FROM webhook_attempts WHERE delivery_id = $1 ORDER BY id", id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	delivery.Log = make([]domain.WebhookAttempt, 0)
+
+	for rows.Next() {
+		var a domain.WebhookAttempt
+		if err := rows.Scan(&a.StatusCode, &a.Error, &a.Duration, &a.AttemptedAt); err != nil {
+			return nil, err
+		}
+
+		delivery.Log = append(delivery.Log, a)
+	}
+
+	return delivery, rows.Err()
+}
+
+// ClaimDue returns up to limit pending deliveries due at now and postpones
+// them until leaseUntil, so another worker does not pick them up meanwhile.
+func (repo *Webhooks) ClaimDue(ctx context.Context, now, leaseUntil time.Time, limit int) ([]domain.WebhookDelivery, error) {
+	rows, err := repo.Pool.Query(
+		ctx,
+		`UPDATE webhook_deliveries SET next_attempt_at = $2
+		WHERE id IN (
+			SELECT id FROM webhook_deliveries
+			WHERE status = 'pending' AND next_attempt_at <= $1
+			ORDER BY next_attempt_at
+			LIMIT $3
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING `+deliveryColumns,
+		now,
+		leaseUntil,
+		limit,
+	)
+	if err != nil {
+		return nil, translate(err, nil)
+	}
+	defer rows.Close()
+
+	deliveries := make([]domain.WebhookDelivery, 0)
+
+	for rows.Next() {
+		delivery, err := scanDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		deliveries = append(deliveries, *delivery)
+	}
+
+	return deliveries, rows.Err()
+}
+
+// RecordAttempt logs the attempt and moves the delivery to status, a pending
+// delivery is tried again at nextAttemptAt.
+func (repo *Webhooks) RecordAttempt(ctx context.Context, deliveryId int64, attempt *domain.WebhookAttempt, status string, nextAttemptAt *time.Time) error {
+	tx, err := repo.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(
+		ctx,
+		"INSERT INTO webhook_attempts (delivery_id, status_code, error, duration_ms, attempted_at) values ($1, $2, $3, $4, $5)",
+		deliveryId,
+		attempt.StatusCode,
+		attempt.Error,
+		attempt.Duration,
+		attempt.AttemptedAt,
+	); err != nil {
+		return translate(err, nil)
+	}
+
+	tag, err := tx.Exec(
+		ctx,
+		`UPDATE webhook_deliveries SET
+			status = $1,
+			attempts = attempts + 1,
+			next_attempt_at = $2,
+			delivered_at = CASE WHEN $1 = 'succeeded' THEN $3 ELSE delivered_at END
+		WHERE id = $4`,
+		status,
+		nextAttemptAt,
+		attempt.AttemptedAt,
+		deliveryId,
+	)
+	if err != nil {
+		return translate(err, nil)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return domain.ErrWebhookDeliveryNotFound
+	}
+
+	return translate(tx.Commit(ctx), nil)
+}
+
+// Redeliver queues the delivery again with a fresh set of attempts.
+func (repo *Webhooks) Redeliver(ctx context.Context, webhookId, id int64, at time.Time) error {
+	tag, err := repo.Pool.Exec(
+		ctx,
+		"UPDATE webhook_deliveries SET status = 'pending', attempts = 0, next_attempt_at = $1 WHERE id = $2 AND webhook_id = $3",
+		at,
+		id,
+		webhookId,
+	)
+	if err != nil {
+		return translate(err, nil)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return domain.ErrWebhookDeliveryNotFound
+	}
+
+	return nil
+}
+
+func (repo *Webhooks) query(ctx context.Context, sql string, args ...any) ([]domain.Webhook, error) {
+	rows, err := repo.Pool.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	webhooks := make([]domain.Webhook, 0)
+
+	for rows.Next() {
+		webhook, err := scanWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		webhooks = append(webhooks, *webhook)
+	}
+
+	return webhooks, rows.Err()
+}
+
+func scanWebhook(row pgx.Row) (*domain.Webhook, error) {
+	var w domain.Webhook
+	if err := row.Scan(&w.ID, &w.UserID, &w.URL, &w.Events, &w.Secret, &w.CreatedAt); err != nil {
+		return nil, err
+	}
+
+	return &w, nil
+}
+
+func scanDelivery(row pgx.Row) (*domain.WebhookDelivery, error) {
+	var d domain.WebhookDelivery
+	if err := row.Scan(&d.ID, &d.WebhookID, &d.Event, &d.Payload, &d.Status, &d.Attempts, &d.NextAttemptAt, &d.DeliveredAt, &d.CreatedAt); err != nil {
+		return nil, err
+	}
+
+	return &d, nil
+}
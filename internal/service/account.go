@@ -0,0 +1,204 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/wilfridterry/contact-list/internal/domain"
+	"github.com/wilfridterry/contact-list/pkg/logger"
+	"github.com/wilfridterry/contact-list/pkg/mailer"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	verifyEmailTTL   = 48 * time.Hour
+	resetPasswordTTL = time.Hour
+)
+
+// VerifyEmail marks the email of the user as verified. The token stops
+// working once the email of the user changes.
+func (service *Auth) VerifyEmail(ctx context.Context, token string) error {
+	ctx, span := tracer.Start(ctx, "Auth.VerifyEmail")
+	defer span.End()
+
+	userId, fingerprint, err := service.parsePurposeToken(purposeVerifyEmail, token)
+	if err != nil {
+		return domain.ErrInvalidEmailToken
+	}
+
+	user, err := service.userRepo.GetById(ctx, userId)
+	if err != nil {
+		return err
+	}
+
+	if fingerprint != tokenFingerprint(user.Email) {
+		return domain.ErrInvalidEmailToken
+	}
+
+	if user.EmailVerifiedAt != nil {
+		return nil
+	}
+
+	if err := service.userRepo.MarkEmailVerified(ctx, userId); err != nil {
+		return err
+	}
+
+	if err := service.auditLog.Log(ctx, LogMessage{
+		Action:    ACTION_VERIFY_EMAIL,
+		Entity:    ENTITY_USER,
+		EntityID:  userId,
+		Timestamp: time.Now(),
+	}); err != nil {
+		logger.FromContext(ctx).WithFields(logrus.Fields{
+			"method": "Users.VerifyEmail",
+		}).Error("failed to send log request:", err)
+	}
+
+	return nil
+}
+
+// ResendVerification sends a new verification email. Unknown and already
+// verified emails are ignored, so the caller can not probe for accounts.
+func (service *Auth) ResendVerification(ctx context.Context, email string) error {
+	ctx, span := tracer.Start(ctx, "Auth.ResendVerification")
+	defer span.End()
+
+	user, err := service.userRepo.GetByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFoundUser) {
+			return nil
+		}
+
+		return err
+	}
+
+	if user.EmailVerifiedAt != nil {
+		return nil
+	}
+
+	return service.sendVerificationEmail(ctx, user)
+}
+
+// ForgotPassword emails a password reset link. Unknown emails are ignored, so
+// the caller can not probe for accounts.
+func (service *Auth) ForgotPassword(ctx context.Context, email string) error {
+	ctx, span := tracer.Start(ctx, "Auth.ForgotPassword")
+	defer span.End()
+
+	user, err := service.userRepo.GetByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFoundUser) {
+			return nil
+		}
+
+		return err
+	}
+
+	return service.sendResetPasswordEmail(ctx, user)
+}
+
+// ResetPassword sets a new password and signs the user out everywhere. The
+// token is bound to the last update of the user, which the new password
+// changes, so it can be used only once even to set the same password again.
+func (service *Auth) ResetPassword(ctx context.Context, inp *domain.ResetPasswordInput) error {
+	ctx, span := tracer.Start(ctx, "Auth.ResetPassword")
+	defer span.End()
+
+	userId, fingerprint, err := service.parsePurposeToken(purposeResetPassword, inp.Token)
+	if err != nil {
+		return domain.ErrInvalidEmailToken
+	}
+
+	user, err := service.userRepo.GetById(ctx, userId)
+	if err != nil {
+		return err
+	}
+
+	if fingerprint != resetFingerprint(user) {
+		return domain.ErrInvalidEmailToken
+	}
+
+	password, err := service.hashier.Hash(inp.Password)
+	if err != nil {
+		return err
+	}
+
+	if err := service.userRepo.UpdatePassword(ctx, userId, password); err != nil {
+		return err
+	}
+
+	if err := service.sessionRepo.DeleteAllByUser(ctx, userId); err != nil {
+		return err
+	}
+
+	if err := service.auditLog.Log(ctx, LogMessage{
+		Action:    ACTION_RESET_PASSWORD,
+		Entity:    ENTITY_USER,
+		EntityID:  userId,
+		Timestamp: time.Now(),
+	}); err != nil {
+		logger.FromContext(ctx).WithFields(logrus.Fields{
+			"method": "Users.ResetPassword",
+		}).Error("failed to send log request:", err)
+	}
+
+	return nil
+}
+
+func (service *Auth) sendVerificationEmail(ctx context.Context, user *domain.User) error {
+	token, _, err := service.newPurposeToken(purposeVerifyEmail, user.ID, tokenFingerprint(user.Email), verifyEmailTTL)
+	if err != nil {
+		return err
+	}
+
+	return service.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Verify your email",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nplease confirm your email address by opening the link below:\n\n%s\n\nThe link expires in %s.\n",
+			user.Name,
+			service.link("/verify-email", token),
+			verifyEmailTTL,
+		),
+	})
+}
+
+func (service *Auth) sendResetPasswordEmail(ctx context.Context, user *domain.User) error {
+	token, _, err := service.newPurposeToken(purposeResetPassword, user.ID, resetFingerprint(user), resetPasswordTTL)
+	if err != nil {
+		return err
+	}
+
+	return service.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nyou can set a new password by opening the link below:\n\n%s\n\nThe link expires in %s. If you did not ask for it, ignore this email.\n",
+			user.Name,
+			service.link("/reset-password", token),
+			resetPasswordTTL,
+		),
+	})
+}
+
+func (service *Auth) link(path, token string) string {
+	return service.appURL + path + "?token=" + url.QueryEscape(token)
+}
+
+// resetFingerprint changes with every update of the user, a password
+// change included.
+func resetFingerprint(user *domain.User) string {
+	return tokenFingerprint(user.UpdatedAt.UTC().Format(time.RFC3339Nano))
+}
+
+func tokenFingerprint(value string) string {
+	sum := sha256.Sum256([]byte(value))
+
+	return hex.EncodeToString(sum[:8])
+}
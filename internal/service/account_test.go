@@ -0,0 +1,278 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/wilfridterry/contact-list/internal/domain"
+	"github.com/wilfridterry/contact-list/pkg/mailer"
+)
+
+// accountUsers keeps users in memory, every write bumps their updated time
+// as the database does.
+type accountUsers struct {
+	memoryUsers
+}
+
+func (m accountUsers) UpdatePassword(_ context.Context, id int64, password string) error {
+	m.users[id].Password, m.users[id].UpdatedAt = password, time.Now()
+
+	return nil
+}
+
+func (m accountUsers) MarkEmailVerified(_ context.Context, id int64) error {
+	now := time.Now()
+	m.users[id].EmailVerifiedAt, m.users[id].UpdatedAt = &now, now
+
+	return nil
+}
+
+// revokedSessions records whose sessions were revoked.
+type revokedSessions struct {
+	SessionRepository
+
+	users []int64
+}
+
+func (m *revokedSessions) DeleteAllByUser(_ context.Context, userId int64) error {
+	m.users = append(m.users, userId)
+
+	return nil
+}
+
+// outbox keeps the messages sent.
+type outbox struct {
+	messages []mailer.Message
+}
+
+func (m *outbox) Send(_ context.Context, msg mailer.Message) error {
+	m.messages = append(m.messages, msg)
+
+	return nil
+}
+
+// token returns the token of the link in the last message.
+func (m *outbox) token(t *testing.T) string {
+	t.Helper()
+
+	if len(m.messages) == 0 {
+		t.Fatal("no message was sent")
+	}
+
+	for _, field := range strings.Fields(m.messages[len(m.messages)-1].Body) {
+		if link, err := url.Parse(field); err == nil && link.Query().Has("token") {
+			return link.Query().Get("token")
+		}
+	}
+
+	t.Fatal("the message has no link with a token")
+
+	return ""
+}
+
+func newAccountAuth(verified bool) (*Auth, accountUsers, *revokedSessions, *outbox) {
+	user := &domain.User{
+		ID:        1,
+		Name:      "Test",
+		Email:     "test@test.com",
+		Password:  "qwerty",
+		UpdatedAt: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+	if verified {
+		user.EmailVerifiedAt = &user.UpdatedAt
+	}
+
+	users := accountUsers{memoryUsers{users: map[int64]*domain.User{1: user}}}
+	sessions := &revokedSessions{}
+	mails := &outbox{}
+
+	auth := New(users, sessions, nil, nil, nil, discardAuditLog{}, plainHashier{}, mails, AuthConfig{
+		Secret: []byte("secret"),
+		AppURL: "https://app.test",
+	})
+
+	return auth, users, sessions, mails
+}
+
+func TestAuth_VerifyEmail(t *testing.T) {
+	testTable := []struct {
+		name        string
+		token       func(t *testing.T, auth *Auth, mails *outbox) string
+		expectedErr error
+	}{
+		{
+			name: "OK",
+			token: func(t *testing.T, auth *Auth, mails *outbox) string {
+				if err := auth.ResendVerification(context.Background(), "test@test.com"); err != nil {
+					t.Fatal(err)
+				}
+
+				return mails.token(t)
+			},
+		},
+		{
+			name: "Expired",
+			token: func(t *testing.T, auth *Auth, _ *outbox) string {
+				token, _, _ := auth.newPurposeToken(purposeVerifyEmail, 1, tokenFingerprint("test@test.com"), -time.Minute)
+				return token
+			},
+			expectedErr: domain.ErrInvalidEmailToken,
+		},
+		{
+			name: "Wrong purpose",
+			token: func(t *testing.T, auth *Auth, _ *outbox) string {
+				token, _, _ := auth.newPurposeToken(purposeResetPassword, 1, tokenFingerprint("test@test.com"), time.Hour)
+				return token
+			},
+			expectedErr: domain.ErrInvalidEmailToken,
+		},
+		{
+			name: "Email changed",
+			token: func(t *testing.T, auth *Auth, _ *outbox) string {
+				token, _, _ := auth.newPurposeToken(purposeVerifyEmail, 1, tokenFingerprint("old@test.com"), time.Hour)
+				return token
+			},
+			expectedErr: domain.ErrInvalidEmailToken,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			auth, users, _, mails := newAccountAuth(false)
+
+			err := auth.VerifyEmail(context.Background(), testCase.token(t, auth, mails))
+			if !errors.Is(err, testCase.expectedErr) {
+				t.Fatalf("VerifyEmail() error = %v, want %v", err, testCase.expectedErr)
+			}
+
+			if verified := users.users[1].EmailVerifiedAt != nil; verified != (testCase.expectedErr == nil) {
+				t.Errorf("VerifyEmail() verified = %t", verified)
+			}
+		})
+	}
+}
+
+func TestAuth_ResetPassword(t *testing.T) {
+	testTable := []struct {
+		name        string
+		token       func(t *testing.T, auth *Auth, mails *outbox) string
+		password    string
+		expectedErr error
+	}{
+		{
+			name: "OK",
+			token: func(t *testing.T, auth *Auth, mails *outbox) string {
+				if err := auth.ForgotPassword(context.Background(), "test@test.com"); err != nil {
+					t.Fatal(err)
+				}
+
+				return mails.token(t)
+			},
+			password: "new password",
+		},
+		{
+			name: "Expired",
+			token: func(t *testing.T, auth *Auth, _ *outbox) string {
+				user, _ := auth.userRepo.GetById(context.Background(), 1)
+				token, _, _ := auth.newPurposeToken(purposeResetPassword, 1, resetFingerprint(user), -time.Minute)
+				return token
+			},
+			password:    "new password",
+			expectedErr: domain.ErrInvalidEmailToken,
+		},
+		{
+			name: "Wrong purpose",
+			token: func(t *testing.T, auth *Auth, _ *outbox) string {
+				user, _ := auth.userRepo.GetById(context.Background(), 1)
+				token, _, _ := auth.newPurposeToken(purposeVerifyEmail, 1, resetFingerprint(user), time.Hour)
+				return token
+			},
+			password:    "new password",
+			expectedErr: domain.ErrInvalidEmailToken,
+		},
+		{
+			name: "Reused with the same password",
+			token: func(t *testing.T, auth *Auth, mails *outbox) string {
+				if err := auth.ForgotPassword(context.Background(), "test@test.com"); err != nil {
+					t.Fatal(err)
+				}
+
+				token := mails.token(t)
+				if err := auth.ResetPassword(context.Background(), &domain.ResetPasswordInput{Token: token, Password: "qwerty"}); err != nil {
+					t.Fatal(err)
+				}
+
+				return token
+			},
+			password:    "qwerty",
+			expectedErr: domain.ErrInvalidEmailToken,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			auth, users, sessions, mails := newAccountAuth(true)
+			token := testCase.token(t, auth, mails)
+			revoked := len(sessions.users)
+
+			err := auth.ResetPassword(context.Background(), &domain.ResetPasswordInput{Token: token, Password: testCase.password})
+			if !errors.Is(err, testCase.expectedErr) {
+				t.Fatalf("ResetPassword() error = %v, want %v", err, testCase.expectedErr)
+			}
+
+			if testCase.expectedErr != nil {
+				if len(sessions.users) != revoked {
+					t.Error("ResetPassword() revoked the sessions")
+				}
+
+				return
+			}
+
+			if users.users[1].Password != testCase.password || len(sessions.users) != revoked+1 {
+				t.Errorf("ResetPassword() password = %q, revoked %v", users.users[1].Password, sessions.users)
+			}
+		})
+	}
+}
+
+func TestAuth_accountEmails(t *testing.T) {
+	testTable := []struct {
+		name     string
+		send     func(*Auth, context.Context, string) error
+		email    string
+		verified bool
+		subject  string
+	}{
+		{name: "Resend", send: (*Auth).ResendVerification, email: "test@test.com", subject: "Verify your email"},
+		{name: "Resend verified", send: (*Auth).ResendVerification, email: "test@test.com", verified: true},
+		{name: "Resend unknown", send: (*Auth).ResendVerification, email: "nobody@test.com"},
+		{name: "Forgot", send: (*Auth).ForgotPassword, email: "test@test.com", verified: true, subject: "Reset your password"},
+		{name: "Forgot unknown", send: (*Auth).ForgotPassword, email: "nobody@test.com"},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			auth, _, _, mails := newAccountAuth(testCase.verified)
+
+			if err := testCase.send(auth, context.Background(), testCase.email); err != nil {
+				t.Fatalf("error = %v", err)
+			}
+
+			if testCase.subject == "" {
+				if len(mails.messages) != 0 {
+					t.Errorf("sent %d messages, want none", len(mails.messages))
+				}
+
+				return
+			}
+
+			if len(mails.messages) != 1 || mails.messages[0].To != testCase.email || mails.messages[0].Subject != testCase.subject {
+				t.Errorf("sent %v, want %q to %s", mails.messages, testCase.subject, testCase.email)
+			}
+		})
+	}
+}
@@ -0,0 +1,214 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/wilfridterry/contact-list/internal/domain"
+	"github.com/wilfridterry/contact-list/pkg/logger"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	defaultUsersPerPage = 20
+	impersonationTTL    = 15 * time.Minute
+)
+
+func (service *Auth) ListUsers(ctx context.Context, filter *domain.UserFilter) (*domain.UserPage, error) {
+	ctx, span := tracer.Start(ctx, "Auth.ListUsers")
+	defer span.End()
+
+	if filter.Page < 1 {
+		filter.Page = 1
+	}
+
+	if filter.PerPage < 1 {
+		filter.PerPage = defaultUsersPerPage
+	}
+
+	users, total, err := service.userRepo.List(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.UserPage{
+		Users:   users,
+		Total:   total,
+		Page:    filter.Page,
+		PerPage: filter.PerPage,
+	}, nil
+}
+
+func (service *Auth) UserDetails(ctx context.Context, userId int64) (*domain.UserDetails, error) {
+	ctx, span := tracer.Start(ctx, "Auth.UserDetails")
+	defer span.End()
+
+	user, err := service.userRepo.GetById(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	mfa, err := service.mfaRepo.GetByUser(ctx, userId)
+	if err != nil && !errors.Is(err, domain.ErrMFANotEnrolled) {
+		return nil, err
+	}
+
+	sessions, err := service.sessionRepo.GetAllByUser(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	active := 0
+	for _, session := range sessions {
+		if session.ExpiresAt.After(time.Now()) {
+			active++
+		}
+	}
+
+	return &domain.UserDetails{
+		User:       user,
+		MFAEnabled: mfa != nil && mfa.EnabledAt != nil,
+		Sessions:   active,
+	}, nil
+}
+
+// DisableUser blocks sign in and token refresh of the user and ends all of its
+// sessions. Access tokens already issued are refused from then on.
+func (service *Auth) DisableUser(ctx context.Context, adminId, userId int64) error {
+	ctx, span := tracer.Start(ctx, "Auth.DisableUser")
+	defer span.End()
+
+	if adminId == userId {
+		return domain.ErrSelfAdminAction
+	}
+
+	if err := service.userRepo.SetDisabled(ctx, userId, true); err != nil {
+		return err
+	}
+
+	if err := service.sessionRepo.DeleteAllByUser(ctx, userId); err != nil {
+		return err
+	}
+
+	service.logAdminAction(ctx, ACTION_DISABLE, adminId, userId, "Users.DisableUser")
+
+	return nil
+}
+
+func (service *Auth) EnableUser(ctx context.Context, adminId, userId int64) error {
+	ctx, span := tracer.Start(ctx, "Auth.EnableUser")
+	defer span.End()
+
+	if err := service.userRepo.SetDisabled(ctx, userId, false); err != nil {
+		return err
+	}
+
+	service.logAdminAction(ctx, ACTION_ENABLE, adminId, userId, "Users.EnableUser")
+
+	return nil
+}
+
+// ForcePasswordReset signs the user out everywhere and blocks password sign
+// in until the password is reset with the emailed link.
+func (service *Auth) ForcePasswordReset(ctx context.Context, adminId, userId int64) error {
+	ctx, span := tracer.Start(ctx, "Auth.ForcePasswordReset")
+	defer span.End()
+
+	user, err := service.userRepo.GetById(ctx, userId)
+	if err != nil {
+		return err
+	}
+
+	if err := service.userRepo.SetPasswordResetRequired(ctx, userId, true); err != nil {
+		return err
+	}
+
+	if err := service.sessionRepo.DeleteAllByUser(ctx, userId); err != nil {
+		return err
+	}
+
+	service.logAdminAction(ctx, ACTION_FORCE_PASSWORD_RESET, adminId, userId, "Users.ForcePasswordReset")
+
+	return service.sendResetPasswordEmail(ctx, user)
+}
+
+func (service *Auth) RevokeSessions(ctx context.Context, adminId, userId int64) error {
+	ctx, span := tracer.Start(ctx, "Auth.RevokeSessions")
+	defer span.End()
+
+	if _, err := service.userRepo.GetById(ctx, userId); err != nil {
+		return err
+	}
+
+	if err := service.sessionRepo.DeleteAllByUser(ctx, userId); err != nil {
+		return err
+	}
+
+	service.logAdminAction(ctx, ACTION_REVOKE_SESSIONS, adminId, userId, "Users.RevokeSessions")
+
+	return nil
+}
+
+// Impersonate issues a short-lived access token acting as the user. There is
+// no refresh token, the token carries the administrator in the "imp" claim and
+// every audited action made with it names the administrator as actor.
+func (service *Auth) Impersonate(ctx context.Context, adminId, userId int64) (*domain.ImpersonationToken, error) {
+	ctx, span := tracer.Start(ctx, "Auth.Impersonate")
+	defer span.End()
+
+	if adminId == userId {
+		return nil, domain.ErrSelfAdminAction
+	}
+
+	user, err := service.userRepo.GetById(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.DisabledAt != nil {
+		return nil, domain.ErrUserDisabled
+	}
+
+	if user.Role == domain.RoleAdmin {
+		return nil, domain.ErrImpersonationForbidden
+	}
+
+	expiresAt := time.Now().Add(impersonationTTL)
+	if service.ttlToken > 0 && service.ttlToken < impersonationTTL {
+		expiresAt = time.Now().Add(service.ttlToken)
+	}
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, UserClaim{
+		RegisteredClaims: jwt.RegisteredClaims{ExpiresAt: jwt.NewNumericDate(expiresAt)},
+		ID:               user.ID,
+		IssuedAt:         time.Now().Unix(),
+		ExpiresAt:        expiresAt.Unix(),
+		Role:             user.Role,
+		Permissions:      user.Role.Permissions(),
+		Impersonator:     adminId,
+	}).SignedString(service.hmacSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	service.logAdminAction(ctx, ACTION_IMPERSONATE, adminId, userId, "Users.Impersonate")
+
+	return &domain.ImpersonationToken{Token: token, ExpiresAt: expiresAt}, nil
+}
+
+func (service *Auth) logAdminAction(ctx context.Context, act action, adminId, userId int64, method string) {
+	if err := service.auditLog.Log(ctx, LogMessage{
+		Action:    act,
+		Entity:    ENTITY_USER,
+		EntityID:  userId,
+		ActorID:   adminId,
+		Timestamp: time.Now(),
+	}); err != nil {
+		logger.FromContext(ctx).WithFields(logrus.Fields{
+			"method": method,
+		}).Error("failed to send log request:", err)
+	}
+}
@@ -0,0 +1,217 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"github.com/wilfridterry/contact-list/internal/domain"
+	"github.com/wilfridterry/contact-list/pkg/logger"
+
+	"github.com/sirupsen/logrus"
+)
+
+// API keys look like "ck_<prefix>_<secret>". The prefix is stored in plain
+// text to find the key, the whole key is stored only as a sha256 hash.
+const (
+	apiKeyScheme       = "ck"
+	apiKeyPrefixBytes  = 6
+	apiKeySecretBytes  = 32
+	apiKeyPartsCount   = 3
+	apiKeyPartsDivider = "_"
+)
+
+type APIKeyRepository interface {
+	Create(context.Context, *domain.APIKey) (int64, error)
+	GetByPrefix(context.Context, string) (*domain.APIKey, error)
+	GetAllByUser(context.Context, int64) ([]domain.APIKey, error)
+	Revoke(context.Context, int64, int64) error
+	TouchLastUsed(context.Context, int64, time.Time) error
+}
+
+type APIKeys struct {
+	repository APIKeyRepository
+	userRepo   UserRepository
+	auditLog   AuditLog
+}
+
+func NewAPIKeys(repository APIKeyRepository, userRepo UserRepository, auditLog AuditLog) *APIKeys {
+	return &APIKeys{
+		repository: repository,
+		userRepo:   userRepo,
+		auditLog:   auditLog,
+	}
+}
+
+// Create stores a new key for the user and returns it with the plain key,
+// which is not retrievable afterwards.
+func (service *APIKeys) Create(ctx context.Context, userId int64, inp *domain.CreateAPIKeyInput) (*domain.APIKey, string, error) {
+	ctx, span := tracer.Start(ctx, "APIKeys.Create")
+	defer span.End()
+
+	user, err := service.userRepo.GetById(ctx, userId)
+	if err != nil {
+		return nil, "", err
+	}
+
+	identity := domain.Identity{UserID: user.ID, Role: user.Role, Permissions: user.Role.Permissions()}
+	for _, scope := range inp.Scopes {
+		if !identity.HasPermission(scope) {
+			return nil, "", domain.ErrInvalidScope
+		}
+	}
+
+	if inp.ExpiresAt != nil && inp.ExpiresAt.Before(time.Now()) {
+		return nil, "", domain.ErrAPIKeyExpired
+	}
+
+	prefix, err := randomHex(apiKeyPrefixBytes)
+	if err != nil {
+		return nil, "", err
+	}
+
+	secret, err := randomHex(apiKeySecretBytes)
+	if err != nil {
+		return nil, "", err
+	}
+
+	plain := strings.Join([]string{apiKeyScheme, prefix, secret}, apiKeyPartsDivider)
+
+	key := domain.APIKey{
+		UserID:    userId,
+		Name:      inp.Name,
+		Prefix:    prefix,
+		Hash:      hashSecret(plain),
+		Scopes:    inp.Scopes,
+		ExpiresAt: inp.ExpiresAt,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+
+	id, err := service.repository.Create(ctx, &key)
+	if err != nil {
+		return nil, "", err
+	}
+
+	key.ID = id
+
+	if err := service.auditLog.Log(ctx, LogMessage{
+		Action:    ACTION_CREATE,
+		Entity:    ENTITY_API_KEY,
+		EntityID:  key.ID,
+		Timestamp: time.Now(),
+	}); err != nil {
+		logger.FromContext(ctx).WithFields(logrus.Fields{
+			"method": "APIKeys.Create",
+		}).Error("failed to send log request:", err)
+	}
+
+	return &key, plain, nil
+}
+
+func (service *APIKeys) All(ctx context.Context, userId int64) ([]domain.APIKey, error) {
+	ctx, span := tracer.Start(ctx, "APIKeys.All")
+	defer span.End()
+
+	return service.repository.GetAllByUser(ctx, userId)
+}
+
+func (service *APIKeys) Revoke(ctx context.Context, userId, id int64) error {
+	ctx, span := tracer.Start(ctx, "APIKeys.Revoke")
+	defer span.End()
+
+	if err := service.repository.Revoke(ctx, userId, id); err != nil {
+		return err
+	}
+
+	if err := service.auditLog.Log(ctx, LogMessage{
+		Action:    ACTION_DELETE,
+		Entity:    ENTITY_API_KEY,
+		EntityID:  id,
+		Timestamp: time.Now(),
+	}); err != nil {
+		logger.FromContext(ctx).WithFields(logrus.Fields{
+			"method": "APIKeys.Revoke",
+		}).Error("failed to send log request:", err)
+	}
+
+	return nil
+}
+
+// Authenticate resolves the plain key to the identity of its owner. The
+// permissions are the key scopes narrowed down to the current role of the user.
+func (service *APIKeys) Authenticate(ctx context.Context, plain string) (*domain.Identity, error) {
+	ctx, span := tracer.Start(ctx, "APIKeys.Authenticate")
+	defer span.End()
+
+	parts := strings.Split(plain, apiKeyPartsDivider)
+	if len(parts) != apiKeyPartsCount || parts[0] != apiKeyScheme {
+		return nil, domain.ErrAPIKeyInvalid
+	}
+
+	key, err := service.repository.GetByPrefix(ctx, parts[1])
+	if err != nil {
+		return nil, err
+	}
+
+	if subtle.ConstantTimeCompare([]byte(key.Hash), []byte(hashSecret(plain))) != 1 {
+		return nil, domain.ErrAPIKeyInvalid
+	}
+
+	if key.RevokedAt != nil {
+		return nil, domain.ErrAPIKeyRevoked
+	}
+
+	if key.ExpiresAt != nil && key.ExpiresAt.Before(time.Now()) {
+		return nil, domain.ErrAPIKeyExpired
+	}
+
+	user, err := service.userRepo.GetById(ctx, key.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.DisabledAt != nil {
+		return nil, domain.ErrUserDisabled
+	}
+
+	owner := domain.Identity{Permissions: user.Role.Permissions()}
+	permissions := make([]domain.Permission, 0, len(key.Scopes))
+	for _, scope := range key.Scopes {
+		if owner.HasPermission(scope) {
+			permissions = append(permissions, scope)
+		}
+	}
+
+	if err := service.repository.TouchLastUsed(ctx, key.ID, time.Now()); err != nil {
+		logger.FromContext(ctx).WithFields(logrus.Fields{
+			"method": "APIKeys.Authenticate",
+		}).Error("failed to update last used time:", err)
+	}
+
+	return &domain.Identity{
+		UserID:      user.ID,
+		Role:        user.Role,
+		Permissions: permissions,
+		APIKeyID:    key.ID,
+	}, nil
+}
+
+func hashSecret(plain string) string {
+	sum := sha256.Sum256([]byte(plain))
+
+	return hex.EncodeToString(sum[:])
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
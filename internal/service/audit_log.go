@@ -1,11 +1,15 @@
 package service
 
 import (
+	"context"
+	"errors"
 	"time"
+
+	"github.com/wilfridterry/contact-list/internal/domain"
 )
 
 type AuditLog interface {
-	Log(logMsg LogMessage) error 
+	Log(ctx context.Context, logMsg LogMessage) error
 }
 
 type action string
@@ -14,39 +18,135 @@ type entity string
 const (
 	ACTION_REGISTER action = "REGISTER"
 	ACTION_LOGIN    action = "LOGIN"
+
+	ACTION_LOGIN_FAILED action = "LOGIN_FAILED"
+	ACTION_UNLOCK       action = "UNLOCK"
+
+	ACTION_LINK_IDENTITY action = "LINK_IDENTITY"
 	ACTION_CREATE   action = "CREATE"
 	ACTION_GET      action = "GET"
 	ACTION_UPDATE   action = "UPDATE"
 	ACTION_DELETE   action = "DELETE"
+	ACTION_BATCH    action = "BATCH"
+
+	ACTION_ASSIGN_ROLE action = "ASSIGN_ROLE"
+	ACTION_MFA_ENROLL  action = "MFA_ENROLL"
+	ACTION_MFA_DISABLE action = "MFA_DISABLE"
+	ACTION_MFA_SUCCESS action = "MFA_SUCCESS"
+	ACTION_MFA_FAILURE action = "MFA_FAILURE"
+
+	ACTION_VERIFY_EMAIL   action = "VERIFY_EMAIL"
+	ACTION_RESET_PASSWORD action = "RESET_PASSWORD"
+
+	ACTION_CHANGE_EMAIL    action = "CHANGE_EMAIL"
+	ACTION_CHANGE_PASSWORD action = "CHANGE_PASSWORD"
+
+	ACTION_EXPORT action = "EXPORT"
+	ACTION_ERASE  action = "ERASE"
+
+	ACTION_DISABLE              action = "DISABLE"
+	ACTION_ENABLE               action = "ENABLE"
+	ACTION_FORCE_PASSWORD_RESET action = "FORCE_PASSWORD_RESET"
+	ACTION_REVOKE_SESSIONS      action = "REVOKE_SESSIONS"
+	ACTION_IMPERSONATE          action = "IMPERSONATE"
+
+	ACTION_GRANT_CONSENT  action = "GRANT_CONSENT"
+	ACTION_REVOKE_CONSENT action = "REVOKE_CONSENT"
+	ACTION_ISSUE_TOKEN    action = "ISSUE_TOKEN"
+	ACTION_REVOKE_TOKEN   action = "REVOKE_TOKEN"
 
 	ENTITY_CONTACT entity = "CONTACT"
 	ENTITY_USER    entity = "USER"
+	ENTITY_API_KEY entity = "API_KEY"
+
+	ENTITY_OAUTH_CLIENT entity = "OAUTH_CLIENT"
+	ENTITY_WEBHOOK      entity = "WEBHOOK"
 )
 
+// LogMessage describes an audited action. ActorID is set when somebody other
+// than the subject performed it, e.g. an administrator. Details are only sent
+// to the audit queue, the local journal keeps the identifiers.
 type LogMessage struct {
 	Action    action
 	Entity    entity
 	EntityID  int64
+	ActorID   int64
+	Details   map[string]any
 	Timestamp time.Time
 }
 
 type AMQPClient interface {
-	Log(msg map[string]any) error
+	Log(ctx context.Context, msg map[string]any) error
+}
+
+// AuditJournal keeps a local copy of the audit messages, so they can be
+// exported and erased for data subject requests.
+type AuditJournal interface {
+	Create(context.Context, *domain.AuditEntry) error
+	GetByEntity(context.Context, string, []int64) ([]domain.AuditEntry, error)
+	DeleteByEntity(context.Context, string, []int64) error
 }
 
 type AuditLogService struct {
-	client AMQPClient
+	client  AMQPClient
+	journal AuditJournal
 }
 
-func NewAuditLog(client AMQPClient) *AuditLogService {
-	return &AuditLogService{client}
+func NewAuditLog(client AMQPClient, journal AuditJournal) *AuditLogService {
+	return &AuditLogService{client, journal}
 }
 
-func (s *AuditLogService) Log(logMsg LogMessage) error {
-	return s.client.Log(map[string]any{
-		"action":     logMsg.Action,
+// Log journals and publishes the message. It is kept even when the request
+// which triggered it is canceled.
+func (s *AuditLogService) Log(ctx context.Context, logMsg LogMessage) error {
+	ctx, span := tracer.Start(ctx, "AuditLogService.Log")
+	defer span.End()
+
+	ctx = context.WithoutCancel(ctx)
+
+	journalErr := s.journal.Create(ctx, &domain.AuditEntry{
+		Action:    string(logMsg.Action),
+		Entity:    string(logMsg.Entity),
+		EntityID:  logMsg.EntityID,
+		ActorID:   logMsg.ActorID,
+		Timestamp: logMsg.Timestamp,
+	})
+
+	msg := map[string]any{
+		"action":    logMsg.Action,
 		"entity":    logMsg.Entity,
 		"entity_id": logMsg.EntityID,
 		"timestamp": logMsg.Timestamp,
-	})
+	}
+
+	if logMsg.ActorID != 0 {
+		msg["actor_id"] = logMsg.ActorID
+	}
+
+	if len(logMsg.Details) > 0 {
+		msg["details"] = logMsg.Details
+	}
+
+	return errors.Join(journalErr, s.client.Log(ctx, msg))
+}
+
+// ContactHistory returns the changes made to the contacts, oldest first. Reads
+// are audited too but left out.
+func (s *AuditLogService) ContactHistory(ctx context.Context, contactIds []int64) ([]domain.AuditEntry, error) {
+	ctx, span := tracer.Start(ctx, "AuditLogService.ContactHistory")
+	defer span.End()
+
+	entries, err := s.journal.GetByEntity(ctx, string(ENTITY_CONTACT), contactIds)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := make([]domain.AuditEntry, 0, len(entries))
+	for _, e := range entries {
+		if e.Action != string(ACTION_GET) {
+			changes = append(changes, e)
+		}
+	}
+
+	return changes, nil
 }
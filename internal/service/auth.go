@@ -2,11 +2,15 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"math/rand"
 	"time"
 
 	"github.com/wilfridterry/contact-list/internal/domain"
+	"github.com/wilfridterry/contact-list/internal/metrics"
+	"github.com/wilfridterry/contact-list/pkg/logger"
+	"github.com/wilfridterry/contact-list/pkg/mailer"
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/sirupsen/logrus"
@@ -16,47 +20,103 @@ import (
 type UserRepository interface {
 	Create(context.Context, *domain.User) (int64, error)
 	GetByEmailAndPassword(context.Context, string, string) (*domain.User, error)
+	GetById(context.Context, int64) (*domain.User, error)
+	GetByIds(context.Context, []int64) ([]domain.User, error)
+	GetByEmail(context.Context, string) (*domain.User, error)
+	UpdateRole(context.Context, int64, domain.Role) error
+	UpdatePassword(context.Context, int64, string) error
+	MarkEmailVerified(context.Context, int64) error
+	UpdateProfile(context.Context, *domain.User) error
+	Delete(context.Context, int64, domain.OwnedContactsPolicy) error
+	Pseudonymize(context.Context, *domain.User) error
+	SetDisabled(context.Context, int64, bool) error
+	SetPasswordResetRequired(context.Context, int64, bool) error
+	List(context.Context, *domain.UserFilter) ([]domain.User, int64, error)
 }
 
 type SessionRepository interface {
 	Create(context.Context, *domain.RefreshSession) error
 	GetByToken(context.Context, string) (*domain.RefreshSession, error)
+	DeleteAllByUser(context.Context, int64) error
+	GetAllByUser(context.Context, int64) ([]domain.RefreshSession, error)
+}
+
+type MFARepository interface {
+	SaveSecret(context.Context, int64, string) error
+	GetByUser(context.Context, int64) (*domain.UserMFA, error)
+	Enable(context.Context, int64, []string) error
+	Disable(context.Context, int64) error
+	UseRecoveryCode(context.Context, int64, string) (bool, error)
+	UseStep(context.Context, int64, int64) (bool, error)
 }
 
 type Hashier interface {
 	Hash(string) (string, error)
 }
 
+type Mailer interface {
+	Send(context.Context, mailer.Message) error
+}
+
+type AuthConfig struct {
+	Secret   []byte
+	TokenTTL time.Duration
+	// RequireVerifiedEmail blocks sign in until the email is verified.
+	RequireVerifiedEmail bool
+	// AppURL is the client application used to build links sent by email.
+	AppURL  string
+	Lockout LockoutConfig
+}
+
 type Auth struct {
-	userRepo    UserRepository
-	sessionRepo SessionRepository
-	auditClient AuditClient
-	auditLog    AuditLog
-	hashier     Hashier
-	hmacSecret  []byte
-	ttlToken    time.Duration
+	userRepo             UserRepository
+	sessionRepo          SessionRepository
+	mfaRepo              MFARepository
+	attemptsRepo         LoginAttemptRepository
+	auditClient          AuditClient
+	auditLog             AuditLog
+	hashier              Hashier
+	mailer               Mailer
+	hmacSecret           []byte
+	ttlToken             time.Duration
+	requireVerifiedEmail bool
+	appURL               string
+	lockout              LockoutConfig
 }
 
 type UserClaim struct {
 	jwt.RegisteredClaims
-	ID        int64
-	IssuedAt  int64
-	ExpiresAt int64
+	ID          int64
+	IssuedAt    int64
+	ExpiresAt   int64
+	Role        domain.Role         `json:"role"`
+	Permissions []domain.Permission `json:"permissions"`
+	// Impersonator is the administrator the token was issued to, see Impersonate.
+	Impersonator int64 `json:"imp,omitempty"`
 }
 
-func New(userRepo UserRepository, sessionRepo SessionRepository, auditClient AuditClient, auditLog AuditLog, hashier Hashier, secret []byte, ttlToken time.Duration) *Auth {
+func New(userRepo UserRepository, sessionRepo SessionRepository, mfaRepo MFARepository, attemptsRepo LoginAttemptRepository, auditClient AuditClient, auditLog AuditLog, hashier Hashier, mailer Mailer, cf AuthConfig) *Auth {
 	return &Auth{
-		userRepo:    userRepo,
-		sessionRepo: sessionRepo,
-		auditClient: auditClient,
-		auditLog:    auditLog,
-		hashier:     hashier,
-		hmacSecret:  secret,
-		ttlToken:    ttlToken,
+		userRepo:             userRepo,
+		sessionRepo:          sessionRepo,
+		mfaRepo:              mfaRepo,
+		attemptsRepo:         attemptsRepo,
+		auditClient:          auditClient,
+		auditLog:             auditLog,
+		hashier:              hashier,
+		mailer:               mailer,
+		hmacSecret:           cf.Secret,
+		ttlToken:             cf.TokenTTL,
+		requireVerifiedEmail: cf.RequireVerifiedEmail,
+		appURL:               cf.AppURL,
+		lockout:              cf.Lockout,
 	}
 }
 
 func (service *Auth) SignUp(ctx context.Context, inp *domain.SignUpInput) (*domain.User, error) {
+	ctx, span := tracer.Start(ctx, "Auth.SignUp")
+	defer span.End()
+
 	password, err := service.hashier.Hash(inp.Password)
 	if err != nil {
 		return nil, err
@@ -66,6 +126,7 @@ func (service *Auth) SignUp(ctx context.Context, inp *domain.SignUpInput) (*doma
 		Name:         inp.Name,
 		Email:        inp.Email,
 		Password:     password,
+		Role:         domain.RoleUser,
 		RegisteredAt: time.Now(),
 	}
 
@@ -76,6 +137,12 @@ func (service *Auth) SignUp(ctx context.Context, inp *domain.SignUpInput) (*doma
 
 	user.ID = id
 
+	if err := service.sendVerificationEmail(ctx, &user); err != nil {
+		logger.FromContext(ctx).WithFields(logrus.Fields{
+			"method": "Users.SignUp",
+		}).Error("failed to send verification email:", err)
+	}
+
 	// if err := service.auditClient.SendLogRequest(ctx, audit.LogItem{
 	// 	Action:    audit.ACTION_REGISTER,
 	// 	Entity:    audit.ENTITY_USER,
@@ -87,13 +154,13 @@ func (service *Auth) SignUp(ctx context.Context, inp *domain.SignUpInput) (*doma
 	// 	}).Error("failed to send log request:", err)
 	// }
 
-	if err := service.auditLog.Log(LogMessage{
+	if err := service.auditLog.Log(ctx, LogMessage{
 		Action:    ACTION_REGISTER,
 		Entity:    ENTITY_USER,
 		EntityID:  user.ID,
 		Timestamp: time.Now(),
 	}); err != nil {
-		logrus.WithFields(logrus.Fields{
+		logger.FromContext(ctx).WithFields(logrus.Fields{
 			"method": "Users.SignUp",
 		}).Error("failed to send log request:", err)
 	}
@@ -102,6 +169,20 @@ func (service *Auth) SignUp(ctx context.Context, inp *domain.SignUpInput) (*doma
 }
 
 func (service *Auth) SingIn(ctx context.Context, inp *domain.SignInInput) (string, string, error) {
+	ctx, span := tracer.Start(ctx, "Auth.SingIn")
+	defer span.End()
+
+	accessToken, refreshToken, err := service.signIn(ctx, inp)
+	metrics.ObserveSignIn(metrics.SignInPassword, err)
+
+	return accessToken, refreshToken, err
+}
+
+func (service *Auth) signIn(ctx context.Context, inp *domain.SignInInput) (string, string, error) {
+	if err := service.checkLoginLock(ctx, inp.Email, inp.IP); err != nil {
+		return "", "", err
+	}
+
 	password, err := service.hashier.Hash(inp.Password)
 	if err != nil {
 		return "", "", err
@@ -110,9 +191,68 @@ func (service *Auth) SingIn(ctx context.Context, inp *domain.SignInInput) (strin
 	user, err := service.userRepo.GetByEmailAndPassword(ctx, inp.Email, password)
 
 	if err != nil {
+		if errors.Is(err, domain.ErrNotFoundUser) {
+			service.registerLoginFailure(ctx, inp.Email, inp.IP)
+		}
+
 		return "", "", err
 	}
 
+	return service.admit(ctx, user)
+}
+
+// SignInExternal signs in a user authenticated by an external identity
+// provider. The same checks as for a password sign in apply, so the provider
+// never skips the lockout or the second factor.
+func (service *Auth) SignInExternal(ctx context.Context, user *domain.User, ip string) (string, string, error) {
+	ctx, span := tracer.Start(ctx, "Auth.SignInExternal")
+	defer span.End()
+
+	accessToken, refreshToken, err := service.signInExternal(ctx, user, ip)
+	metrics.ObserveSignIn(metrics.SignInOIDC, err)
+
+	return accessToken, refreshToken, err
+}
+
+func (service *Auth) signInExternal(ctx context.Context, user *domain.User, ip string) (string, string, error) {
+	if err := service.checkLoginLock(ctx, user.Email, ip); err != nil {
+		return "", "", err
+	}
+
+	return service.admit(ctx, user)
+}
+
+// admit issues tokens to an authenticated user unless the account may not sign
+// in, or asks for the second factor when MFA is enabled.
+func (service *Auth) admit(ctx context.Context, user *domain.User) (string, string, error) {
+	if user.DisabledAt != nil {
+		return "", "", domain.ErrUserDisabled
+	}
+
+	if user.PasswordResetRequired {
+		return "", "", domain.ErrPasswordResetRequired
+	}
+
+	if service.requireVerifiedEmail && user.EmailVerifiedAt == nil {
+		return "", "", domain.ErrEmailNotVerified
+	}
+
+	mfa, err := service.mfaRepo.GetByUser(ctx, user.ID)
+	if err != nil && !errors.Is(err, domain.ErrMFANotEnrolled) {
+		return "", "", err
+	}
+
+	if mfa != nil && mfa.EnabledAt != nil {
+		challenge, err := service.newMFAChallenge(mfa)
+		if err != nil {
+			return "", "", err
+		}
+
+		return "", "", challenge
+	}
+
+	service.resetLoginFailures(ctx, user.Email)
+
 	// if err := service.auditClient.SendLogRequest(ctx, audit.LogItem{
 	// 	Action:    audit.ACTION_LOGIN,
 	// 	Entity:    audit.ENTITY_USER,
@@ -124,44 +264,120 @@ func (service *Auth) SingIn(ctx context.Context, inp *domain.SignInInput) (strin
 	// 	}).Error("failed to send log request:", err)
 	// }
 
-	if err := service.auditLog.Log(LogMessage{
+	if err := service.auditLog.Log(ctx, LogMessage{
 		Action:    ACTION_LOGIN,
 		Entity:    ENTITY_USER,
 		EntityID:  user.ID,
 		Timestamp: time.Now(),
 	}); err != nil {
-		logrus.WithFields(logrus.Fields{
+		logger.FromContext(ctx).WithFields(logrus.Fields{
 			"method": "Users.SignIn",
 		}).Error("failed to send log request:", err)
 	}
 
-	return service.generateTokens(ctx, user.ID)
+	return service.generateTokens(ctx, user)
 }
 
-func (service *Auth) ParseJWTToken(ctx context.Context, tokenString string) (int64, error) {
+func (service *Auth) ParseJWTToken(ctx context.Context, tokenString string) (*domain.Identity, error) {
+	ctx, span := tracer.Start(ctx, "Auth.ParseJWTToken")
+	defer span.End()
+
 	userClaim := &UserClaim{}
+	// Tokens without an expiry are refused, they would be valid forever.
 	token, err := jwt.ParseWithClaims(tokenString, userClaim, func(token *jwt.Token) (interface{}, error) {
 		return service.hmacSecret, nil
-	})
+	}, jwt.WithExpirationRequired())
 
 	if err != nil {
-		return 0, err
+		return nil, fmt.Errorf("%w: %v", domain.ErrInvalidAccessToken, err)
 	}
 
 	if !token.Valid {
-		return 0, err
+		return nil, domain.ErrInvalidAccessToken
 	}
 
 	userClaim, ok := token.Claims.(*UserClaim)
 
 	if !ok {
-		return 0, err
+		return nil, domain.ErrInvalidAccessToken
 	}
 
-	return userClaim.ID, nil
+	// Disabling a user, or the administrator impersonating it, and changing
+	// the role take effect right away rather than once the token expires, so
+	// the role comes from the user and not from the claims.
+	user, err := service.enabledUser(ctx, userClaim.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if userClaim.Impersonator != 0 {
+		if _, err := service.enabledUser(ctx, userClaim.Impersonator); err != nil {
+			return nil, err
+		}
+
+		// Administrators can not be impersonated, nor once they become one.
+		if user.Role == domain.RoleAdmin {
+			return nil, domain.ErrInvalidAccessToken
+		}
+	}
+
+	return &domain.Identity{
+		UserID:         user.ID,
+		Role:           user.Role,
+		Permissions:    user.Role.Permissions(),
+		ImpersonatorID: userClaim.Impersonator,
+	}, nil
+}
+
+func (service *Auth) enabledUser(ctx context.Context, userId int64) (*domain.User, error) {
+	user, err := service.userRepo.GetById(ctx, userId)
+	if errors.Is(err, domain.ErrNotFoundUser) {
+		return nil, domain.ErrInvalidAccessToken
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if user.DisabledAt != nil {
+		return nil, domain.ErrUserDisabled
+	}
+
+	return user, nil
+}
+
+// AssignRole changes the role of the user. Access tokens already issued get
+// the permissions of the new role on their next use.
+func (service *Auth) AssignRole(ctx context.Context, userId int64, role domain.Role) error {
+	ctx, span := tracer.Start(ctx, "Auth.AssignRole")
+	defer span.End()
+
+	if !role.Valid() {
+		return domain.ErrUnknownRole
+	}
+
+	if err := service.userRepo.UpdateRole(ctx, userId, role); err != nil {
+		return err
+	}
+
+	if err := service.auditLog.Log(ctx, LogMessage{
+		Action:    ACTION_ASSIGN_ROLE,
+		Entity:    ENTITY_USER,
+		EntityID:  userId,
+		Timestamp: time.Now(),
+	}); err != nil {
+		logger.FromContext(ctx).WithFields(logrus.Fields{
+			"method": "Users.AssignRole",
+		}).Error("failed to send log request:", err)
+	}
+
+	return nil
 }
 
 func (service *Auth) RefreshTokens(ctx context.Context, token string) (string, string, error) {
+	ctx, span := tracer.Start(ctx, "Auth.RefreshTokens")
+	defer span.End()
+
 	session, err := service.sessionRepo.GetByToken(ctx, token)
 	if err != nil {
 		return "", "", err
@@ -171,15 +387,32 @@ func (service *Auth) RefreshTokens(ctx context.Context, token string) (string, s
 		return "", "", domain.ErrRefreshTokenExpired
 	}
 
-	return service.generateTokens(ctx, session.UserId)
+	user, err := service.userRepo.GetById(ctx, session.UserId)
+	if err != nil {
+		return "", "", err
+	}
+
+	return service.generateTokens(ctx, user)
 }
 
-func (service *Auth) generateTokens(ctx context.Context, userId int64) (string, string, error) {
+func (service *Auth) generateTokens(ctx context.Context, user *domain.User) (string, string, error) {
+	if user.DisabledAt != nil {
+		return "", "", domain.ErrUserDisabled
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(service.ttlToken)
+
 	t := jwt.NewWithClaims(jwt.SigningMethodHS256, UserClaim{
-		RegisteredClaims: jwt.RegisteredClaims{},
-		ID:               int64(userId),
-		IssuedAt:         time.Now().Unix(),
-		ExpiresAt:        time.Now().Add(service.ttlToken).Unix(),
+		RegisteredClaims: jwt.RegisteredClaims{
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+		ID:          user.ID,
+		IssuedAt:    now.Unix(),
+		ExpiresAt:   expiresAt.Unix(),
+		Role:        user.Role,
+		Permissions: user.Role.Permissions(),
 	})
 
 	accessToken, err := t.SignedString(service.hmacSecret)
@@ -193,7 +426,7 @@ func (service *Auth) generateTokens(ctx context.Context, userId int64) (string,
 	}
 
 	if err := service.sessionRepo.Create(ctx, &domain.RefreshSession{
-		UserId:    userId,
+		UserId:    user.ID,
 		Token:     refreshToken,
 		ExpiresAt: time.Now().Add(time.Hour * 24 * 30),
 	}); err != nil {
@@ -0,0 +1,175 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/wilfridterry/contact-list/internal/domain"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// memorySessions accepts refresh sessions without keeping them.
+type memorySessions struct {
+	SessionRepository
+}
+
+func (memorySessions) Create(context.Context, *domain.RefreshSession) error {
+	return nil
+}
+
+// memoryUsers looks users up by ID.
+type memoryUsers struct {
+	UserRepository
+
+	users map[int64]*domain.User
+}
+
+func (m memoryUsers) GetById(_ context.Context, id int64) (*domain.User, error) {
+	user, ok := m.users[id]
+	if !ok {
+		return nil, domain.ErrNotFoundUser
+	}
+
+	return user, nil
+}
+
+func (m memoryUsers) GetByEmail(_ context.Context, email string) (*domain.User, error) {
+	for _, user := range m.users {
+		if user.Email == email {
+			return user, nil
+		}
+	}
+
+	return nil, domain.ErrNotFoundUser
+}
+
+func TestAuth_ParseJWTToken(t *testing.T) {
+	secret := []byte("secret")
+	disabledAt := time.Now()
+
+	user := &domain.User{ID: 1, Role: domain.RoleUser}
+	users := memoryUsers{users: map[int64]*domain.User{
+		1: user,
+		2: {ID: 2, Role: domain.RoleUser, DisabledAt: &disabledAt},
+		3: {ID: 3, Role: domain.RoleAdmin, DisabledAt: &disabledAt},
+		5: {ID: 5, Role: domain.RoleAdmin},
+		6: {ID: 6, Role: domain.RoleAdmin},
+	}}
+
+	issue := func(ttl time.Duration) string {
+		auth := New(nil, memorySessions{}, nil, nil, nil, nil, nil, nil, AuthConfig{Secret: secret, TokenTTL: ttl})
+
+		token, _, err := auth.generateTokens(context.Background(), user)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return token
+	}
+
+	sign := func(claim UserClaim) string {
+		claim.RegisteredClaims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(time.Minute))
+
+		token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claim).SignedString(secret)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return token
+	}
+
+	withoutExpiry, err := jwt.NewWithClaims(jwt.SigningMethodHS256, UserClaim{ID: 1, Role: domain.RoleUser}).SignedString(secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testTable := []struct {
+		name         string
+		token        string
+		expectedUser int64
+		expectedRole domain.Role
+		expectedErr  error
+	}{
+		{
+			name:         "Valid",
+			token:        issue(time.Minute),
+			expectedUser: 1,
+			expectedRole: domain.RoleUser,
+		},
+		{
+			name:         "Demoted",
+			token:        sign(UserClaim{ID: 1, Role: domain.RoleAdmin, Permissions: domain.RoleAdmin.Permissions()}),
+			expectedUser: 1,
+			expectedRole: domain.RoleUser,
+		},
+		{
+			name:         "Promoted",
+			token:        sign(UserClaim{ID: 5, Role: domain.RoleUser, Permissions: domain.RoleUser.Permissions()}),
+			expectedUser: 5,
+			expectedRole: domain.RoleAdmin,
+		},
+		{
+			name:         "Impersonated",
+			token:        sign(UserClaim{ID: 1, Role: domain.RoleUser, Impersonator: 6}),
+			expectedUser: 1,
+			expectedRole: domain.RoleUser,
+		},
+		{
+			name:        "Impersonated admin",
+			token:       sign(UserClaim{ID: 5, Role: domain.RoleUser, Impersonator: 6}),
+			expectedErr: domain.ErrInvalidAccessToken,
+		},
+		{
+			name:        "Expired",
+			token:       issue(-time.Minute),
+			expectedErr: domain.ErrInvalidAccessToken,
+		},
+		{
+			name:        "Without expiry",
+			token:       withoutExpiry,
+			expectedErr: domain.ErrInvalidAccessToken,
+		},
+		{
+			name:        "Disabled user",
+			token:       sign(UserClaim{ID: 2, Role: domain.RoleUser}),
+			expectedErr: domain.ErrUserDisabled,
+		},
+		{
+			name:        "Disabled impersonator",
+			token:       sign(UserClaim{ID: 1, Role: domain.RoleUser, Impersonator: 3}),
+			expectedErr: domain.ErrUserDisabled,
+		},
+		{
+			name:        "Deleted user",
+			token:       sign(UserClaim{ID: 4, Role: domain.RoleUser}),
+			expectedErr: domain.ErrInvalidAccessToken,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			auth := New(users, nil, nil, nil, nil, nil, nil, nil, AuthConfig{Secret: secret})
+
+			identity, err := auth.ParseJWTToken(context.Background(), testCase.token)
+			if !errors.Is(err, testCase.expectedErr) {
+				t.Fatalf("got %v, want %v", err, testCase.expectedErr)
+			}
+
+			if err != nil {
+				return
+			}
+
+			if identity.UserID != testCase.expectedUser || identity.Role != testCase.expectedRole {
+				t.Errorf("got user %d as %s, want %d as %s", identity.UserID, identity.Role, testCase.expectedUser, testCase.expectedRole)
+			}
+
+			if !reflect.DeepEqual(identity.Permissions, testCase.expectedRole.Permissions()) {
+				t.Errorf("got permissions %v, want %v", identity.Permissions, testCase.expectedRole.Permissions())
+			}
+		})
+	}
+}
@@ -0,0 +1,302 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/wilfridterry/contact-list/internal/domain"
+)
+
+// AddressBookContacts is the part of Contacts address books are built on.
+type AddressBookContacts interface {
+	AllByUser(context.Context, int64) ([]domain.Contact, error)
+	GetOne(context.Context, int64) (*domain.Contact, error)
+	Create(context.Context, *domain.SaveInputContact) (int64, error)
+	UpdateIfUnmodified(context.Context, int64, time.Time, *domain.SaveInputContact) error
+	Delete(context.Context, int64) error
+	DeleteIfUnmodified(context.Context, int64, time.Time) error
+	Changes(context.Context, *domain.ContactChangesFilter) (*domain.ContactChanges, error)
+	SyncToken(context.Context) (string, error)
+}
+
+type CardDAVRepository interface {
+	GetByName(context.Context, int64, string) (*domain.CardDAVObject, error)
+	GetAllByUser(context.Context, int64) ([]domain.CardDAVObject, error)
+	CreateMissing(context.Context, []domain.CardDAVObject) error
+	Save(context.Context, *domain.CardDAVObject, int64) error
+}
+
+// CardDAV serves the contacts owned by a user as an address book. Cards keep
+// the names clients gave them, contacts added through the API get a name when
+// they are listed first, so every card a client has seen can be reported once
+// it is gone.
+type CardDAV struct {
+	repository CardDAVRepository
+	contacts   AddressBookContacts
+}
+
+func NewCardDAV(repository CardDAVRepository, contacts AddressBookContacts) *CardDAV {
+	return &CardDAV{
+		repository: repository,
+		contacts:   contacts,
+	}
+}
+
+// SyncToken returns the token of the current state of the address books.
+func (service *CardDAV) SyncToken(ctx context.Context) (string, error) {
+	ctx, span := tracer.Start(ctx, "CardDAV.SyncToken")
+	defer span.End()
+
+	return service.contacts.SyncToken(ctx)
+}
+
+func (service *CardDAV) Cards(ctx context.Context, userId int64) ([]domain.Card, error) {
+	ctx, span := tracer.Start(ctx, "CardDAV.Cards")
+	defer span.End()
+
+	contacts, err := service.contacts.AllByUser(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	objects, err := service.objects(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	return service.cards(ctx, userId, contacts, objects)
+}
+
+func (service *CardDAV) Card(ctx context.Context, userId int64, name string) (*domain.Card, error) {
+	ctx, span := tracer.Start(ctx, "CardDAV.Card")
+	defer span.End()
+
+	object, err := service.repository.GetByName(ctx, userId, name)
+	if err != nil {
+		return nil, err
+	}
+
+	contact, err := service.owned(ctx, userId, object.ContactID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.Card{Name: object.Name, UID: object.UID, Contact: *contact}, nil
+}
+
+// SaveCard updates the contact behind the current card or creates the card,
+// in which case it returns true. The current card is the one the request was
+// checked against, nil when there was none. If the card changed since it was
+// read, or one was created meanwhile, ErrCardModified is returned and nothing
+// is written. The name of a deleted card is given to the new one.
+func (service *CardDAV) SaveCard(ctx context.Context, userId int64, name, uid string, inp *domain.SaveInputContact, current *domain.Card) (bool, error) {
+	ctx, span := tracer.Start(ctx, "CardDAV.SaveCard")
+	defer span.End()
+
+	inp.UserID = userId
+
+	if current != nil {
+		err := service.contacts.UpdateIfUnmodified(ctx, current.Contact.ID, current.Contact.UpdatedAt, inp)
+		if errors.Is(err, domain.ErrContactModified) {
+			return false, domain.ErrCardModified
+		}
+
+		return false, err
+	}
+
+	object, err := service.repository.GetByName(ctx, userId, name)
+	if err != nil && !errors.Is(err, domain.ErrCardNotFound) {
+		return false, err
+	}
+
+	var replaced int64
+	if object != nil {
+		_, err := service.owned(ctx, userId, object.ContactID)
+		if err == nil {
+			return false, domain.ErrCardModified
+		}
+
+		if !errors.Is(err, domain.ErrCardNotFound) {
+			return false, err
+		}
+		replaced = object.ContactID
+	}
+
+	id, err := service.contacts.Create(ctx, inp)
+	if err != nil {
+		return false, err
+	}
+
+	if uid == "" {
+		uid = name
+	}
+
+	err = service.repository.Save(ctx, &domain.CardDAVObject{
+		ContactID: id,
+		UserID:    userId,
+		Name:      name,
+		UID:       uid,
+	}, replaced)
+	if errors.Is(err, domain.ErrCardModified) {
+		// Another request took the name first, its card stays.
+		if err := service.contacts.Delete(ctx, id); err != nil {
+			return false, err
+		}
+
+		return false, domain.ErrCardModified
+	}
+
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// DeleteCard deletes the contact behind the card unless it changed since the
+// card was read, then ErrCardModified is returned.
+func (service *CardDAV) DeleteCard(ctx context.Context, card *domain.Card) error {
+	ctx, span := tracer.Start(ctx, "CardDAV.DeleteCard")
+	defer span.End()
+
+	err := service.contacts.DeleteIfUnmodified(ctx, card.Contact.ID, card.Contact.UpdatedAt)
+	if errors.Is(err, domain.ErrContactModified) {
+		return domain.ErrCardModified
+	}
+
+	return err
+}
+
+// Changes lists the cards changed and removed since the sync token, or every
+// card without one. Contacts which left the user, deleted or no longer owned,
+// are reported by the name the user knows them under.
+func (service *CardDAV) Changes(ctx context.Context, userId int64, token string) (*domain.CardChanges, error) {
+	ctx, span := tracer.Start(ctx, "CardDAV.Changes")
+	defer span.End()
+
+	if token == "" {
+		// The token comes first, changes made while listing are sent again
+		// next time rather than missed.
+		syncToken, err := service.contacts.SyncToken(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		cards, err := service.Cards(ctx, userId)
+		if err != nil {
+			return nil, err
+		}
+
+		return &domain.CardChanges{Cards: cards, Deleted: make([]string, 0), SyncToken: syncToken}, nil
+	}
+
+	objects, err := service.objects(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	changed := make([]domain.Contact, 0)
+	deleted := make([]string, 0)
+
+	filter := domain.ContactChangesFilter{Since: token}
+	for {
+		changes, err := service.contacts.Changes(ctx, &filter)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, contact := range append(changes.Created, changes.Updated...) {
+			if owns(userId, &contact) {
+				changed = append(changed, contact)
+			} else if object, ok := objects[contact.ID]; ok {
+				deleted = append(deleted, object.Name)
+			}
+		}
+
+		for _, id := range changes.Deleted {
+			if object, ok := objects[id]; ok {
+				deleted = append(deleted, object.Name)
+			}
+		}
+
+		filter.Since = changes.SyncToken
+		if !changes.HasMore {
+			break
+		}
+	}
+
+	cards, err := service.cards(ctx, userId, changed, objects)
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.CardChanges{Cards: cards, Deleted: deleted, SyncToken: filter.Since}, nil
+}
+
+// cards names the contacts, contacts seen for the first time are named after
+// their ID.
+func (service *CardDAV) cards(ctx context.Context, userId int64, contacts []domain.Contact, objects map[int64]domain.CardDAVObject) ([]domain.Card, error) {
+	cards := make([]domain.Card, 0, len(contacts))
+	missing := make([]domain.CardDAVObject, 0)
+
+	for _, contact := range contacts {
+		object, ok := objects[contact.ID]
+		if !ok {
+			object = domain.CardDAVObject{
+				ContactID: contact.ID,
+				UserID:    userId,
+				Name:      fmt.Sprintf("contact-%d.vcf", contact.ID),
+				UID:       fmt.Sprintf("contact-list-%d", contact.ID),
+			}
+			missing = append(missing, object)
+		}
+
+		cards = append(cards, domain.Card{Name: object.Name, UID: object.UID, Contact: contact})
+	}
+
+	if len(missing) > 0 {
+		if err := service.repository.CreateMissing(ctx, missing); err != nil {
+			return nil, err
+		}
+	}
+
+	return cards, nil
+}
+
+func (service *CardDAV) objects(ctx context.Context, userId int64) (map[int64]domain.CardDAVObject, error) {
+	objects, err := service.repository.GetAllByUser(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	byContact := make(map[int64]domain.CardDAVObject, len(objects))
+	for _, o := range objects {
+		byContact[o.ContactID] = o
+	}
+
+	return byContact, nil
+}
+
+// owned returns the contact unless it is gone or belongs to someone else.
+func (service *CardDAV) owned(ctx context.Context, userId, contactId int64) (*domain.Contact, error) {
+	contact, err := service.contacts.GetOne(ctx, contactId)
+	if errors.Is(err, domain.ErrContactNotFound) {
+		return nil, domain.ErrCardNotFound
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !owns(userId, contact) {
+		return nil, domain.ErrCardNotFound
+	}
+
+	return contact, nil
+}
+
+func owns(userId int64, contact *domain.Contact) bool {
+	return contact.UserID != nil && *contact.UserID == userId
+}
@@ -0,0 +1,188 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/wilfridterry/contact-list/internal/domain"
+)
+
+// memoryCards keeps card objects by name. A hidden object is not found by
+// GetByName, as if another request stored it meanwhile.
+type memoryCards struct {
+	CardDAVRepository
+
+	objects map[string]domain.CardDAVObject
+	hidden  bool
+}
+
+func (m *memoryCards) GetByName(_ context.Context, _ int64, name string) (*domain.CardDAVObject, error) {
+	o, ok := m.objects[name]
+	if !ok || m.hidden {
+		return nil, domain.ErrCardNotFound
+	}
+
+	return &o, nil
+}
+
+func (m *memoryCards) Save(_ context.Context, o *domain.CardDAVObject, replaced int64) error {
+	if existing, ok := m.objects[o.Name]; ok && existing.ContactID != replaced {
+		return domain.ErrCardModified
+	}
+	m.objects[o.Name] = *o
+
+	return nil
+}
+
+// addressBook keeps contacts in memory, writes bump their updated time.
+type addressBook struct {
+	AddressBookContacts
+
+	contacts map[int64]*domain.Contact
+}
+
+func (m *addressBook) GetOne(_ context.Context, id int64) (*domain.Contact, error) {
+	c, ok := m.contacts[id]
+	if !ok {
+		return nil, domain.ErrContactNotFound
+	}
+
+	return c, nil
+}
+
+func (m *addressBook) Create(_ context.Context, inp *domain.SaveInputContact) (int64, error) {
+	id := int64(len(m.contacts) + 1)
+	m.contacts[id] = &domain.Contact{ID: id, Name: inp.Name, UserID: &inp.UserID, UpdatedAt: time.Now()}
+
+	return id, nil
+}
+
+func (m *addressBook) UpdateIfUnmodified(_ context.Context, id int64, updatedAt time.Time, inp *domain.SaveInputContact) error {
+	c, ok := m.contacts[id]
+	if !ok || !c.UpdatedAt.Equal(updatedAt) {
+		return domain.ErrContactModified
+	}
+	c.Name, c.UpdatedAt = inp.Name, time.Now()
+
+	return nil
+}
+
+func (m *addressBook) Delete(_ context.Context, id int64) error {
+	delete(m.contacts, id)
+
+	return nil
+}
+
+func (m *addressBook) DeleteIfUnmodified(_ context.Context, id int64, updatedAt time.Time) error {
+	c, ok := m.contacts[id]
+	if !ok || !c.UpdatedAt.Equal(updatedAt) {
+		return domain.ErrContactModified
+	}
+	delete(m.contacts, id)
+
+	return nil
+}
+
+func TestCardDAV_SaveCard(t *testing.T) {
+	read := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	userId := int64(7)
+
+	tests := []struct {
+		name         string
+		updatedAt    time.Time
+		current      bool
+		hidden       bool
+		wantErr      error
+		wantCreated  bool
+		wantContacts int
+	}{
+		{
+			name:         "update",
+			updatedAt:    read,
+			current:      true,
+			wantContacts: 1,
+		},
+		{
+			name:         "updated meanwhile",
+			updatedAt:    read.Add(time.Second),
+			current:      true,
+			wantErr:      domain.ErrCardModified,
+			wantContacts: 1,
+		},
+		{
+			name:         "created meanwhile",
+			updatedAt:    read,
+			wantErr:      domain.ErrCardModified,
+			wantContacts: 1,
+		},
+		{
+			name:         "created while creating",
+			updatedAt:    read,
+			hidden:       true,
+			wantErr:      domain.ErrCardModified,
+			wantContacts: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			contact := domain.Contact{ID: 1, Name: "John", UserID: &userId, UpdatedAt: tt.updatedAt}
+			contacts := &addressBook{contacts: map[int64]*domain.Contact{1: &contact}}
+			cards := &memoryCards{
+				objects: map[string]domain.CardDAVObject{"a1.vcf": {ContactID: 1, UserID: userId, Name: "a1.vcf", UID: "a1"}},
+				hidden:  tt.hidden,
+			}
+			service := NewCardDAV(cards, contacts)
+
+			var current *domain.Card
+			if tt.current {
+				current = &domain.Card{Name: "a1.vcf", UID: "a1", Contact: domain.Contact{ID: 1, UpdatedAt: read}}
+			}
+
+			created, err := service.SaveCard(context.Background(), userId, "a1.vcf", "a1", &domain.SaveInputContact{Name: "Johnny"}, current)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("SaveCard() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if created != tt.wantCreated || len(contacts.contacts) != tt.wantContacts {
+				t.Errorf("SaveCard() created = %t, contacts = %d, want %t and %d", created, len(contacts.contacts), tt.wantCreated, tt.wantContacts)
+			}
+
+			if cards.objects["a1.vcf"].ContactID != 1 {
+				t.Errorf("SaveCard() gave the card to contact %d", cards.objects["a1.vcf"].ContactID)
+			}
+		})
+	}
+}
+
+func TestCardDAV_DeleteCard(t *testing.T) {
+	read := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	card := &domain.Card{Name: "a1.vcf", Contact: domain.Contact{ID: 1, UpdatedAt: read}}
+
+	tests := []struct {
+		name      string
+		updatedAt time.Time
+		wantErr   error
+		wantGone  bool
+	}{
+		{name: "unchanged", updatedAt: read, wantGone: true},
+		{name: "updated meanwhile", updatedAt: read.Add(time.Second), wantErr: domain.ErrCardModified},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			contacts := &addressBook{contacts: map[int64]*domain.Contact{1: {ID: 1, UpdatedAt: tt.updatedAt}}}
+			service := NewCardDAV(&memoryCards{}, contacts)
+
+			if err := service.DeleteCard(context.Background(), card); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("DeleteCard() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if _, ok := contacts.contacts[1]; ok == tt.wantGone {
+				t.Errorf("DeleteCard() left the contact = %t, want %t", ok, !tt.wantGone)
+			}
+		})
+	}
+}
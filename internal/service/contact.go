@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/wilfridterry/contact-list/internal/domain"
+	"github.com/wilfridterry/contact-list/pkg/logger"
 
 	"github.com/sirupsen/logrus"
 	// audit "github.com/wilfridterry/audit-log/pkg/domain"
@@ -14,6 +15,12 @@ type Contacts struct {
 	repository  ContactRepository
 	auditClient AuditClient
 	auditLog    AuditLog
+	events      ContactEventBus
+}
+
+type ContactEventBus interface {
+	Publish(domain.ContactEvent)
+	Subscribe(int64) *domain.ContactSubscription
 }
 
 type ContactRepository interface {
@@ -22,13 +29,48 @@ type ContactRepository interface {
 	Create(context.Context, *domain.SaveInputContact) (int64, error)
 	Delete(context.Context, int64) error
 	Update(context.Context, int64, *domain.SaveInputContact) error
+	UpdateIfUnmodified(context.Context, int64, time.Time, *domain.SaveInputContact) error
+	DeleteIfUnmodified(context.Context, int64, time.Time) error
+	GetAllByUser(context.Context, int64) ([]domain.Contact, error)
+	Search(context.Context, string, int) ([]domain.Contact, error)
+	Pseudonymize(context.Context, *domain.Contact) error
+	Batch(context.Context, []domain.BatchOperation, bool) ([]domain.BatchResult, error)
+	Changes(context.Context, int64, int) ([]domain.ContactChange, int64, error)
+	DeleteTombstones(context.Context, time.Time) (int64, error)
+	LastChangeSeq(context.Context) (int64, error)
 }
 
 func (c *Contacts) All(ctx context.Context) ([]domain.Contact, error) {
+	ctx, span := tracer.Start(ctx, "Contacts.All")
+	defer span.End()
+
 	return c.repository.GetAll(ctx)
 }
 
+// AllByUser returns the contacts owned by the user.
+func (c *Contacts) AllByUser(ctx context.Context, userId int64) ([]domain.Contact, error) {
+	ctx, span := tracer.Start(ctx, "Contacts.AllByUser")
+	defer span.End()
+
+	return c.repository.GetAllByUser(ctx, userId)
+}
+
+func (service *Contacts) Search(ctx context.Context, search *domain.ContactSearch) ([]domain.Contact, error) {
+	ctx, span := tracer.Start(ctx, "Contacts.Search")
+	defer span.End()
+
+	limit := search.Limit
+	if limit == 0 {
+		limit = domain.DefaultSearchLimit
+	}
+
+	return service.repository.Search(ctx, search.Query, limit)
+}
+
 func (service *Contacts) GetOne(ctx context.Context, id int64) (*domain.Contact, error) {
+	ctx, span := tracer.Start(ctx, "Contacts.GetOne")
+	defer span.End()
+
 	contact, err := service.repository.GetById(ctx, id)
 	if err != nil {
 		return nil, err
@@ -45,13 +87,14 @@ func (service *Contacts) GetOne(ctx context.Context, id int64) (*domain.Contact,
 	// 	}).Error("failed to send log request:", err)
 	// }
 
-	if err := service.auditLog.Log(LogMessage{
+	if err := service.auditLog.Log(ctx, LogMessage{
 		Action:    ACTION_GET,
 		Entity:    ENTITY_CONTACT,
 		EntityID:  contact.ID,
+		ActorID:   domain.ImpersonatorFromContext(ctx),
 		Timestamp: time.Now(),
 	}); err != nil {
-		logrus.WithFields(logrus.Fields{
+		logger.FromContext(ctx).WithFields(logrus.Fields{
 			"method": "Contacts.Get",
 		}).Error("failed to send log request:", err)
 	}
@@ -59,12 +102,17 @@ func (service *Contacts) GetOne(ctx context.Context, id int64) (*domain.Contact,
 	return contact, nil
 }
 
-func (service *Contacts) Create(ctx context.Context, inp *domain.SaveInputContact) error {
+func (service *Contacts) Create(ctx context.Context, inp *domain.SaveInputContact) (int64, error) {
+	ctx, span := tracer.Start(ctx, "Contacts.Create")
+	defer span.End()
+
 	id, err := service.repository.Create(ctx, inp)
 	if err != nil {
-		return err
+		return 0, err
 	}
 
+	service.publish(domain.ContactCreated, id)
+
 	// if err := service.auditClient.SendLogRequest(ctx, audit.LogItem{
 	// 	Action: audit.ACTION_CREATE,
 	// 	Entity: audit.ENTITY_CONTACT,
@@ -76,26 +124,32 @@ func (service *Contacts) Create(ctx context.Context, inp *domain.SaveInputContac
 	// 	}).Error("failed to send log request:", err)
 	// }
 
-	if err := service.auditLog.Log(LogMessage{
+	if err := service.auditLog.Log(ctx, LogMessage{
 		Action:    ACTION_CREATE,
 		Entity:    ENTITY_CONTACT,
 		EntityID:  id,
+		ActorID:   domain.ImpersonatorFromContext(ctx),
 		Timestamp: time.Now(),
 	}); err != nil {
-		logrus.WithFields(logrus.Fields{
+		logger.FromContext(ctx).WithFields(logrus.Fields{
 			"method": "Contacts.Create",
 		}).Error("failed to send log request:", err)
 	}
 
-	return nil
+	return id, nil
 }
 
 func (service *Contacts) Update(ctx context.Context, id int64, inp *domain.SaveInputContact) error {
+	ctx, span := tracer.Start(ctx, "Contacts.Update")
+	defer span.End()
+
 	err := service.repository.Update(ctx, id, inp)
 	if err != nil {
 		return err
 	}
 
+	service.publish(domain.ContactUpdated, id)
+
 	// if err := service.auditClient.SendLogRequest(ctx, audit.LogItem{
 	// 	Action: audit.ACTION_UPDATE,
 	// 	Entity: audit.ENTITY_CONTACT,
@@ -107,13 +161,14 @@ func (service *Contacts) Update(ctx context.Context, id int64, inp *domain.SaveI
 	// 	}).Error("failed to send log request:", err)
 	// }
 
-	if err := service.auditLog.Log(LogMessage{
+	if err := service.auditLog.Log(ctx, LogMessage{
 		Action:    ACTION_UPDATE,
 		Entity:    ENTITY_CONTACT,
 		EntityID:  id,
+		ActorID:   domain.ImpersonatorFromContext(ctx),
 		Timestamp: time.Now(),
 	}); err != nil {
-		logrus.WithFields(logrus.Fields{
+		logger.FromContext(ctx).WithFields(logrus.Fields{
 			"method": "Contacts.Update",
 		}).Error("failed to send log request:", err)
 	}
@@ -122,11 +177,16 @@ func (service *Contacts) Update(ctx context.Context, id int64, inp *domain.SaveI
 }
 
 func (service *Contacts) Delete(ctx context.Context, id int64) error {
+	ctx, span := tracer.Start(ctx, "Contacts.Delete")
+	defer span.End()
+
 	err := service.repository.Delete(ctx, id)
 	if err != nil {
 		return err
 	}
 
+	service.publish(domain.ContactDeleted, id)
+
 	// if err := service.auditClient.SendLogRequest(ctx, audit.LogItem{
 	// 	Action: audit.ACTION_DELETE,
 	// 	Entity: audit.ENTITY_CONTACT,
@@ -138,13 +198,14 @@ func (service *Contacts) Delete(ctx context.Context, id int64) error {
 	// 	}).Error("failed to send log request:", err)
 	// }
 
-	if err := service.auditLog.Log(LogMessage{
+	if err := service.auditLog.Log(ctx, LogMessage{
 		Action:    ACTION_DELETE,
 		Entity:    ENTITY_CONTACT,
 		EntityID:  id,
+		ActorID:   domain.ImpersonatorFromContext(ctx),
 		Timestamp: time.Now(),
 	}); err != nil {
-		logrus.WithFields(logrus.Fields{
+		logger.FromContext(ctx).WithFields(logrus.Fields{
 			"method": "Contacts.Delete",
 		}).Error("failed to send log request:", err)
 	}
@@ -152,10 +213,180 @@ func (service *Contacts) Delete(ctx context.Context, id int64) error {
 	return nil
 }
 
-func NewContacts(repository ContactRepository, auditClient AuditClient, auditLog AuditLog) *Contacts {
+// UpdateIfUnmodified updates the contact unless it was written after the
+// updatedAt it was read with, which fails with ErrContactModified.
+func (service *Contacts) UpdateIfUnmodified(ctx context.Context, id int64, updatedAt time.Time, inp *domain.SaveInputContact) error {
+	ctx, span := tracer.Start(ctx, "Contacts.UpdateIfUnmodified")
+	defer span.End()
+
+	if err := service.repository.UpdateIfUnmodified(ctx, id, updatedAt, inp); err != nil {
+		return err
+	}
+
+	service.publish(domain.ContactUpdated, id)
+	service.log(ctx, ACTION_UPDATE, id, "Contacts.UpdateIfUnmodified")
+
+	return nil
+}
+
+// DeleteIfUnmodified deletes the contact unless it was written after the
+// updatedAt it was read with, which fails with ErrContactModified.
+func (service *Contacts) DeleteIfUnmodified(ctx context.Context, id int64, updatedAt time.Time) error {
+	ctx, span := tracer.Start(ctx, "Contacts.DeleteIfUnmodified")
+	defer span.End()
+
+	if err := service.repository.DeleteIfUnmodified(ctx, id, updatedAt); err != nil {
+		return err
+	}
+
+	service.publish(domain.ContactDeleted, id)
+	service.log(ctx, ACTION_DELETE, id, "Contacts.DeleteIfUnmodified")
+
+	return nil
+}
+
+func (service *Contacts) log(ctx context.Context, act action, id int64, method string) {
+	if err := service.auditLog.Log(ctx, LogMessage{
+		Action:    act,
+		Entity:    ENTITY_CONTACT,
+		EntityID:  id,
+		ActorID:   domain.ImpersonatorFromContext(ctx),
+		Timestamp: time.Now(),
+	}); err != nil {
+		logger.FromContext(ctx).WithFields(logrus.Fields{
+			"method": method,
+		}).Error("failed to send log request:", err)
+	}
+}
+
+var batchActions = map[string]action{
+	domain.BatchCreate: ACTION_CREATE,
+	domain.BatchUpdate: ACTION_UPDATE,
+	domain.BatchDelete: ACTION_DELETE,
+}
+
+var batchEvents = map[string]string{
+	domain.BatchCreate: domain.ContactCreated,
+	domain.BatchUpdate: domain.ContactUpdated,
+	domain.BatchDelete: domain.ContactDeleted,
+}
+
+// Batch applies the operations of inp, atomically unless the partial mode is
+// requested. Every applied operation is audited as if it was a single request,
+// and the batch itself is audited once for the user.
+func (service *Contacts) Batch(ctx context.Context, userId int64, inp *domain.BatchInput) ([]domain.BatchResult, error) {
+	ctx, span := tracer.Start(ctx, "Contacts.Batch")
+	defer span.End()
+
+	if len(inp.Operations) > domain.MaxBatchOperations {
+		return nil, domain.ErrBatchTooLarge
+	}
+
+	if inp.Mode == "" {
+		inp.Mode = domain.BatchAtomic
+	}
+
+	for i, op := range inp.Operations {
+		if err := validateBatchOperation(op); err != nil {
+			return nil, &domain.BatchOperationError{Index: i, Err: err}
+		}
+
+		if op.Op == domain.BatchCreate {
+			op.Contact.UserID = userId
+		}
+	}
+
+	results, err := service.repository.Batch(ctx, inp.Operations, inp.Mode == domain.BatchAtomic)
+	if err != nil {
+		return nil, err
+	}
+
+	applied := map[string]int{}
+	failed := 0
+
+	for _, result := range results {
+		if result.Err != nil {
+			failed++
+			continue
+		}
+
+		applied[result.Op]++
+		service.publish(batchEvents[result.Op], result.ID)
+
+		if err := service.auditLog.Log(ctx, LogMessage{
+			Action:    batchActions[result.Op],
+			Entity:    ENTITY_CONTACT,
+			EntityID:  result.ID,
+			ActorID:   domain.ImpersonatorFromContext(ctx),
+			Timestamp: time.Now(),
+		}); err != nil {
+			logger.FromContext(ctx).WithFields(logrus.Fields{
+				"method": "Contacts.Batch",
+			}).Error("failed to send log request:", err)
+		}
+	}
+
+	if err := service.auditLog.Log(ctx, LogMessage{
+		Action:   ACTION_BATCH,
+		Entity:   ENTITY_USER,
+		EntityID: userId,
+		ActorID:  domain.ImpersonatorFromContext(ctx),
+		Details: map[string]any{
+			"mode":    inp.Mode,
+			"created": applied[domain.BatchCreate],
+			"updated": applied[domain.BatchUpdate],
+			"deleted": applied[domain.BatchDelete],
+			"failed":  failed,
+		},
+		Timestamp: time.Now(),
+	}); err != nil {
+		logger.FromContext(ctx).WithFields(logrus.Fields{
+			"method": "Contacts.Batch",
+		}).Error("failed to send log request:", err)
+	}
+
+	return results, nil
+}
+
+func validateBatchOperation(op domain.BatchOperation) error {
+	switch op.Op {
+	case domain.BatchCreate:
+		if op.Contact == nil {
+			return domain.ErrInvalidBatchOperation
+		}
+	case domain.BatchUpdate:
+		if op.ID == 0 || op.Contact == nil {
+			return domain.ErrInvalidBatchOperation
+		}
+	case domain.BatchDelete:
+		if op.ID == 0 {
+			return domain.ErrInvalidBatchOperation
+		}
+	default:
+		return domain.ErrInvalidBatchOperation
+	}
+
+	return nil
+}
+
+// Subscribe streams changes of the contacts, see ContactEvents.Subscribe.
+func (service *Contacts) Subscribe(lastEventID int64) *domain.ContactSubscription {
+	return service.events.Subscribe(lastEventID)
+}
+
+func (service *Contacts) publish(eventType string, contactId int64) {
+	service.events.Publish(domain.ContactEvent{
+		Type:      eventType,
+		ContactID: contactId,
+		Time:      time.Now(),
+	})
+}
+
+func NewContacts(repository ContactRepository, auditClient AuditClient, auditLog AuditLog, events ContactEventBus) *Contacts {
 	return &Contacts{
 		repository:  repository,
 		auditClient: auditClient,
 		auditLog:    auditLog,
+		events:      events,
 	}
 }
@@ -0,0 +1,114 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"time"
+
+	"github.com/wilfridterry/contact-list/internal/domain"
+)
+
+const defaultTombstoneTTL = 30 * 24 * time.Hour
+
+// Changes lists the contacts created, updated and deleted after the sync token
+// of the filter, or every contact when there is none. A contact changed more
+// than once is listed once with its latest state.
+func (service *Contacts) Changes(ctx context.Context, filter *domain.ContactChangesFilter) (*domain.ContactChanges, error) {
+	ctx, span := tracer.Start(ctx, "Contacts.Changes")
+	defer span.End()
+
+	var since int64
+	if filter.Since != "" {
+		var err error
+		if since, err = decodeSyncToken(filter.Since); err != nil {
+			return nil, err
+		}
+	}
+
+	limit := filter.Limit
+	if limit <= 0 || limit > domain.MaxContactChanges {
+		limit = domain.MaxContactChanges
+	}
+
+	changes, seq, err := service.repository.Changes(ctx, since, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &domain.ContactChanges{
+		Created: make([]domain.Contact, 0),
+		Updated: make([]domain.Contact, 0),
+		Deleted: make([]int64, 0),
+	}
+
+	for _, change := range changes {
+		switch {
+		case change.Contact == nil:
+			result.Deleted = append(result.Deleted, change.ContactID)
+		case change.Created:
+			result.Created = append(result.Created, *change.Contact)
+		default:
+			result.Updated = append(result.Updated, *change.Contact)
+		}
+	}
+
+	// A full page may be followed by more changes, the client resumes right
+	// after the last one.
+	if len(changes) == limit && changes[len(changes)-1].Seq < seq {
+		seq = changes[len(changes)-1].Seq
+		result.HasMore = true
+	}
+
+	result.SyncToken = encodeSyncToken(seq)
+
+	return result, nil
+}
+
+// SyncToken returns the sync token of the latest change to the contacts.
+func (service *Contacts) SyncToken(ctx context.Context) (string, error) {
+	ctx, span := tracer.Start(ctx, "Contacts.SyncToken")
+	defer span.End()
+
+	seq, err := service.repository.LastChangeSeq(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	return encodeSyncToken(seq), nil
+}
+
+// PurgeTombstones forgets the contacts deleted longer than ttl ago, clients
+// holding an older sync token have to sync in full.
+func (service *Contacts) PurgeTombstones(ctx context.Context, ttl time.Duration) (int64, error) {
+	ctx, span := tracer.Start(ctx, "Contacts.PurgeTombstones")
+	defer span.End()
+
+	if ttl <= 0 {
+		ttl = defaultTombstoneTTL
+	}
+
+	return service.repository.DeleteTombstones(ctx, time.Now().Add(-ttl))
+}
+
+// Sync tokens are the change sequence, encoded so clients don't read into it.
+func encodeSyncToken(seq int64) string {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, uint64(seq))
+
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeSyncToken(token string) (int64, error) {
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil || len(b) != 8 {
+		return 0, domain.ErrInvalidSyncToken
+	}
+
+	seq := int64(binary.BigEndian.Uint64(b))
+	if seq < 0 {
+		return 0, domain.ErrInvalidSyncToken
+	}
+
+	return seq, nil
+}
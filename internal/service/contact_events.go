@@ -0,0 +1,94 @@
+package service
+
+import (
+	"sync"
+
+	"github.com/wilfridterry/contact-list/internal/domain"
+)
+
+const (
+	contactEventsHistory = 1000
+	contactEventsBuffer  = 64
+)
+
+// ContactEvents is the in-process bus of contact changes. It keeps the latest
+// events in memory, so a client reconnecting to the same instance can resume
+// where it stopped. A subscriber that does not keep up is dropped rather than
+// slowing down the writers, it can resume as well.
+type ContactEvents struct {
+	mu          sync.Mutex
+	lastID      int64
+	history     []domain.ContactEvent
+	subscribers map[chan domain.ContactEvent]struct{}
+}
+
+func NewContactEvents() *ContactEvents {
+	return &ContactEvents{
+		history:     make([]domain.ContactEvent, 0, contactEventsHistory),
+		subscribers: make(map[chan domain.ContactEvent]struct{}),
+	}
+}
+
+func (bus *ContactEvents) Publish(event domain.ContactEvent) {
+	bus.mu.Lock()
+	defer bus.mu.Unlock()
+
+	bus.lastID++
+	event.ID = bus.lastID
+
+	if len(bus.history) == contactEventsHistory {
+		copy(bus.history, bus.history[1:])
+		bus.history = bus.history[:contactEventsHistory-1]
+	}
+	bus.history = append(bus.history, event)
+
+	for ch := range bus.subscribers {
+		select {
+		case ch <- event:
+		default:
+			delete(bus.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// Subscribe starts delivering events published after lastEventID, zero
+// subscribes to new events only.
+func (bus *ContactEvents) Subscribe(lastEventID int64) *domain.ContactSubscription {
+	bus.mu.Lock()
+	defer bus.mu.Unlock()
+
+	ch := make(chan domain.ContactEvent, contactEventsBuffer)
+	bus.subscribers[ch] = struct{}{}
+
+	sub := &domain.ContactSubscription{
+		Events: ch,
+		Close: func() {
+			bus.mu.Lock()
+			defer bus.mu.Unlock()
+
+			if _, ok := bus.subscribers[ch]; ok {
+				delete(bus.subscribers, ch)
+				close(ch)
+			}
+		},
+	}
+
+	if lastEventID <= 0 || lastEventID == bus.lastID {
+		return sub
+	}
+
+	// The id is from before a restart or its successors were already dropped.
+	if lastEventID > bus.lastID || len(bus.history) == 0 || lastEventID < bus.history[0].ID-1 {
+		sub.Reset = true
+		return sub
+	}
+
+	for _, event := range bus.history {
+		if event.ID > lastEventID {
+			sub.Missed = append(sub.Missed, event)
+		}
+	}
+
+	return sub
+}
@@ -0,0 +1,110 @@
+package service
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/wilfridterry/contact-list/internal/domain"
+)
+
+const defaultHealthTimeout = 2 * time.Second
+
+// HealthCheck reports an error when a dependency cannot be used.
+type HealthCheck func(context.Context) error
+
+// Health checks the dependencies of the app, each one at a time limited by
+// the timeout and all of them at once.
+type Health struct {
+	checks    map[string]HealthCheck
+	timeout   time.Duration
+	startedAt time.Time
+	draining  atomic.Bool
+}
+
+func NewHealth(checks map[string]HealthCheck, timeout time.Duration) *Health {
+	if timeout <= 0 {
+		timeout = defaultHealthTimeout
+	}
+
+	return &Health{
+		checks:    checks,
+		timeout:   timeout,
+		startedAt: time.Now(),
+	}
+}
+
+// Drain marks the app as shutting down, it is not ready from then on.
+func (service *Health) Drain() {
+	service.draining.Store(true)
+}
+
+func (service *Health) Check(ctx context.Context) *domain.HealthReport {
+	report := &domain.HealthReport{
+		Status:       domain.HealthUp,
+		StartedAt:    service.startedAt,
+		Dependencies: make([]domain.DependencyHealth, len(service.checks)),
+	}
+
+	names := make([]string, 0, len(service.checks))
+	for name := range service.checks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var wg sync.WaitGroup
+	for i, name := range names {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			report.Dependencies[i] = service.check(ctx, name)
+		}()
+	}
+	wg.Wait()
+
+	for _, dependency := range report.Dependencies {
+		if dependency.Status != domain.HealthUp {
+			report.Status = domain.HealthDown
+		}
+	}
+
+	if service.draining.Load() {
+		report.Status = domain.HealthDraining
+	}
+
+	report.CheckedAt = time.Now()
+
+	return report
+}
+
+func (service *Health) check(ctx context.Context, name string) domain.DependencyHealth {
+	ctx, cancel := context.WithTimeout(ctx, service.timeout)
+	defer cancel()
+
+	// A check stuck past the deadline is left behind rather than waited for.
+	result := make(chan error, 1)
+	start := time.Now()
+	go func() { result <- service.checks[name](ctx) }()
+
+	var err error
+	select {
+	case err = <-result:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	dependency := domain.DependencyHealth{
+		Name:      name,
+		Status:    domain.HealthUp,
+		LatencyMs: time.Since(start).Milliseconds(),
+	}
+
+	if err != nil {
+		dependency.Status = domain.HealthDown
+		dependency.Error = err.Error()
+	}
+
+	return dependency
+}
@@ -0,0 +1,73 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/magiconair/properties/assert"
+	"github.com/wilfridterry/contact-list/internal/domain"
+)
+
+func TestHealth_Check(t *testing.T) {
+	up := func(context.Context) error { return nil }
+	down := func(context.Context) error { return errors.New("connection refused") }
+	stuck := func(ctx context.Context) error {
+		time.Sleep(time.Second)
+		return nil
+	}
+
+	testTable := []struct {
+		name           string
+		checks         map[string]HealthCheck
+		draining       bool
+		expectedStatus string
+		expectedErrors map[string]string
+	}{
+		{
+			name:           "Up",
+			checks:         map[string]HealthCheck{"postgres": up, "amqp": up},
+			expectedStatus: domain.HealthUp,
+			expectedErrors: map[string]string{"amqp": "", "postgres": ""},
+		},
+		{
+			name:           "Dependency down",
+			checks:         map[string]HealthCheck{"postgres": up, "amqp": down},
+			expectedStatus: domain.HealthDown,
+			expectedErrors: map[string]string{"amqp": "connection refused", "postgres": ""},
+		},
+		{
+			name:           "Timeout",
+			checks:         map[string]HealthCheck{"audit": stuck},
+			expectedStatus: domain.HealthDown,
+			expectedErrors: map[string]string{"audit": context.DeadlineExceeded.Error()},
+		},
+		{
+			name:           "Draining",
+			checks:         map[string]HealthCheck{"postgres": up},
+			draining:       true,
+			expectedStatus: domain.HealthDraining,
+			expectedErrors: map[string]string{"postgres": ""},
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			health := NewHealth(testCase.checks, 50*time.Millisecond)
+			if testCase.draining {
+				health.Drain()
+			}
+
+			report := health.Check(context.Background())
+
+			errs := make(map[string]string)
+			for _, dependency := range report.Dependencies {
+				errs[dependency.Name] = dependency.Error
+			}
+
+			assert.Equal(t, report.Status, testCase.expectedStatus)
+			assert.Equal(t, errs, testCase.expectedErrors)
+		})
+	}
+}
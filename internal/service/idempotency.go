@@ -0,0 +1,99 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/wilfridterry/contact-list/internal/domain"
+)
+
+const defaultIdempotencyTTL = 24 * time.Hour
+
+type IdempotencyRepository interface {
+	Reserve(context.Context, *domain.IdempotencyRecord) (*domain.IdempotencyRecord, bool, error)
+	Complete(context.Context, *domain.IdempotencyRecord) error
+	Release(context.Context, int64, string) error
+	DeleteByUser(context.Context, int64) error
+	DeleteByContacts(context.Context, []int64) error
+	DeleteExpired(context.Context, time.Time) (int64, error)
+}
+
+// Idempotency makes retries of a request with the same Idempotency-Key run it
+// once. Keys are scoped to the user and forgotten after the ttl.
+type Idempotency struct {
+	repository IdempotencyRepository
+	ttl        time.Duration
+}
+
+func NewIdempotency(repository IdempotencyRepository, ttl time.Duration) *Idempotency {
+	if ttl <= 0 {
+		ttl = defaultIdempotencyTTL
+	}
+
+	return &Idempotency{
+		repository: repository,
+		ttl:        ttl,
+	}
+}
+
+// Begin reserves the key for the request identified by fingerprint. It returns
+// nil when the request should run and the completed record when its stored
+// response should be replayed instead.
+func (service *Idempotency) Begin(ctx context.Context, userId int64, key, fingerprint string) (*domain.IdempotencyRecord, error) {
+	ctx, span := tracer.Start(ctx, "Idempotency.Begin")
+	defer span.End()
+
+	record, reserved, err := service.repository.Reserve(ctx, &domain.IdempotencyRecord{
+		UserID:      userId,
+		Key:         key,
+		Fingerprint: fingerprint,
+		ExpiresAt:   time.Now().Add(service.ttl),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if reserved {
+		return nil, nil
+	}
+
+	if record.Fingerprint != fingerprint {
+		return nil, domain.ErrIdempotencyKeyReused
+	}
+
+	if !record.Completed() {
+		return nil, domain.ErrIdempotencyInProgress
+	}
+
+	return record, nil
+}
+
+// Complete stores the response of a request started with Begin.
+func (service *Idempotency) Complete(ctx context.Context, userId int64, key string, status int, header map[string][]string, body []byte) error {
+	ctx, span := tracer.Start(ctx, "Idempotency.Complete")
+	defer span.End()
+
+	return service.repository.Complete(ctx, &domain.IdempotencyRecord{
+		UserID: userId,
+		Key:    key,
+		Status: status,
+		Header: header,
+		Body:   body,
+	})
+}
+
+// Release forgets a key whose request failed, so a retry runs it again.
+func (service *Idempotency) Release(ctx context.Context, userId int64, key string) error {
+	ctx, span := tracer.Start(ctx, "Idempotency.Release")
+	defer span.End()
+
+	return service.repository.Release(ctx, userId, key)
+}
+
+// Purge deletes expired keys and returns how many were deleted.
+func (service *Idempotency) Purge(ctx context.Context) (int64, error) {
+	ctx, span := tracer.Start(ctx, "Idempotency.Purge")
+	defer span.End()
+
+	return service.repository.DeleteExpired(ctx, time.Now())
+}
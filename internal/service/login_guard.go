@@ -0,0 +1,185 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/wilfridterry/contact-list/internal/domain"
+	"github.com/wilfridterry/contact-list/pkg/logger"
+
+	"github.com/sirupsen/logrus"
+)
+
+const maxBackoffShift = 30
+
+type LoginAttemptRepository interface {
+	Get(context.Context, string) (*domain.LoginAttempt, error)
+	RegisterFailure(context.Context, string, time.Time, time.Duration) (*domain.LoginAttempt, error)
+	Lock(context.Context, string, time.Time) error
+	Reset(context.Context, string) error
+}
+
+// LockoutConfig controls the brute-force protection of sign in. Every failure
+// after FreeAttempts within Window makes the key wait exponentially longer,
+// starting from BackoffBase up to BackoffMax. After MaxFailures (per account)
+// or IPMaxFailures (per client ip) the key is locked for Duration. Zero limits
+// disable the respective check.
+type LockoutConfig struct {
+	MaxFailures   int
+	IPMaxFailures int
+	FreeAttempts  int
+	Window        time.Duration
+	Duration      time.Duration
+	BackoffBase   time.Duration
+	BackoffMax    time.Duration
+}
+
+func (cf LockoutConfig) lockFor(failures, maxFailures int) time.Duration {
+	if maxFailures > 0 && failures >= maxFailures {
+		return cf.Duration
+	}
+
+	if failures <= cf.FreeAttempts || cf.BackoffBase <= 0 {
+		return 0
+	}
+
+	shift := failures - cf.FreeAttempts - 1
+	if shift > maxBackoffShift {
+		shift = maxBackoffShift
+	}
+
+	delay := cf.BackoffBase << shift
+	if cf.BackoffMax > 0 && delay > cf.BackoffMax {
+		delay = cf.BackoffMax
+	}
+
+	return delay
+}
+
+// Unlock forgets the failed sign in attempts of the user account.
+func (service *Auth) Unlock(ctx context.Context, userId int64) error {
+	ctx, span := tracer.Start(ctx, "Auth.Unlock")
+	defer span.End()
+
+	user, err := service.userRepo.GetById(ctx, userId)
+	if err != nil {
+		return err
+	}
+
+	if err := service.attemptsRepo.Reset(ctx, accountAttemptKey(user.Email)); err != nil {
+		return err
+	}
+
+	if err := service.auditLog.Log(ctx, LogMessage{
+		Action:    ACTION_UNLOCK,
+		Entity:    ENTITY_USER,
+		EntityID:  userId,
+		Timestamp: time.Now(),
+	}); err != nil {
+		logger.FromContext(ctx).WithFields(logrus.Fields{
+			"method": "Users.Unlock",
+		}).Error("failed to send log request:", err)
+	}
+
+	return nil
+}
+
+func (service *Auth) checkLoginLock(ctx context.Context, email, ip string) error {
+	for _, key := range attemptKeys(email, ip) {
+		attempt, err := service.attemptsRepo.Get(ctx, key)
+		if err != nil {
+			return err
+		}
+
+		if attempt == nil || attempt.LockedUntil == nil {
+			continue
+		}
+
+		if retryAfter := time.Until(*attempt.LockedUntil); retryAfter > 0 {
+			return &domain.LoginLockedError{RetryAfter: retryAfter}
+		}
+	}
+
+	return nil
+}
+
+// registerLoginFailure counts the failure for the account and the client ip
+// and emits ACTION_LOGIN_FAILED. Errors are logged only, the caller returns
+// the original sign in error anyway.
+func (service *Auth) registerLoginFailure(ctx context.Context, email, ip string) {
+	now := time.Now()
+
+	for _, key := range attemptKeys(email, ip) {
+		attempt, err := service.attemptsRepo.RegisterFailure(ctx, key, now, service.lockout.Window)
+		if err != nil {
+			logger.FromContext(ctx).WithFields(logrus.Fields{
+				"method": "Users.SignIn",
+			}).Error("failed to register sign in failure:", err)
+			continue
+		}
+
+		maxFailures := service.lockout.MaxFailures
+		if strings.HasPrefix(key, ipAttemptKeyPrefix) {
+			maxFailures = service.lockout.IPMaxFailures
+		}
+
+		if lockFor := service.lockout.lockFor(attempt.Failures, maxFailures); lockFor > 0 {
+			if err := service.attemptsRepo.Lock(ctx, key, now.Add(lockFor)); err != nil {
+				logger.FromContext(ctx).WithFields(logrus.Fields{
+					"method": "Users.SignIn",
+				}).Error("failed to lock sign in:", err)
+			}
+		}
+	}
+
+	var userId int64
+	user, err := service.userRepo.GetByEmail(ctx, email)
+	if err != nil && !errors.Is(err, domain.ErrNotFoundUser) {
+		logger.FromContext(ctx).WithFields(logrus.Fields{
+			"method": "Users.SignIn",
+		}).Error("failed to find user:", err)
+	}
+
+	if user != nil {
+		userId = user.ID
+	}
+
+	if err := service.auditLog.Log(ctx, LogMessage{
+		Action:    ACTION_LOGIN_FAILED,
+		Entity:    ENTITY_USER,
+		EntityID:  userId,
+		Timestamp: now,
+	}); err != nil {
+		logger.FromContext(ctx).WithFields(logrus.Fields{
+			"method": "Users.SignIn",
+		}).Error("failed to send log request:", err)
+	}
+}
+
+func (service *Auth) resetLoginFailures(ctx context.Context, email string) {
+	if err := service.attemptsRepo.Reset(ctx, accountAttemptKey(email)); err != nil {
+		logger.FromContext(ctx).WithFields(logrus.Fields{
+			"method": "Users.SignIn",
+		}).Error("failed to reset sign in failures:", err)
+	}
+}
+
+const (
+	accountAttemptKeyPrefix = "email:"
+	ipAttemptKeyPrefix      = "ip:"
+)
+
+func accountAttemptKey(email string) string {
+	return accountAttemptKeyPrefix + strings.ToLower(email)
+}
+
+func attemptKeys(email, ip string) []string {
+	keys := []string{accountAttemptKey(email)}
+	if ip != "" {
+		keys = append(keys, ipAttemptKeyPrefix+ip)
+	}
+
+	return keys
+}
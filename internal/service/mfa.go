@@ -0,0 +1,282 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/wilfridterry/contact-list/internal/domain"
+	"github.com/wilfridterry/contact-list/internal/metrics"
+	"github.com/wilfridterry/contact-list/pkg/logger"
+	"github.com/wilfridterry/contact-list/pkg/totp"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	mfaIssuer             = "Contacts API"
+	mfaChallengeTTL       = 5 * time.Minute
+	mfaAllowedSkew        = 1
+	mfaRecoveryCodesCount = 10
+	mfaRecoveryCodeBytes  = 5
+)
+
+// EnrollMFA generates a new TOTP secret for the user. The second factor is not
+// required on sign in until the enrollment is confirmed with a valid code.
+func (service *Auth) EnrollMFA(ctx context.Context, userId int64) (*domain.MFAEnrollment, error) {
+	ctx, span := tracer.Start(ctx, "Auth.EnrollMFA")
+	defer span.End()
+
+	user, err := service.userRepo.GetById(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	mfa, err := service.mfaRepo.GetByUser(ctx, userId)
+	if err != nil && !errors.Is(err, domain.ErrMFANotEnrolled) {
+		return nil, err
+	}
+
+	if mfa != nil && mfa.EnabledAt != nil {
+		return nil, domain.ErrMFAAlreadyEnabled
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := service.mfaRepo.SaveSecret(ctx, userId, secret); err != nil {
+		return nil, err
+	}
+
+	return &domain.MFAEnrollment{
+		Secret:          secret,
+		ProvisioningURI: totp.ProvisioningURI(mfaIssuer, user.Email, secret),
+	}, nil
+}
+
+// ConfirmMFA enables the pending enrollment and returns one-time recovery
+// codes, which are stored hashed only.
+func (service *Auth) ConfirmMFA(ctx context.Context, userId int64, code string) ([]string, error) {
+	ctx, span := tracer.Start(ctx, "Auth.ConfirmMFA")
+	defer span.End()
+
+	mfa, err := service.mfaRepo.GetByUser(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	if mfa.EnabledAt != nil {
+		return nil, domain.ErrMFAAlreadyEnabled
+	}
+
+	step, ok := totp.Match(mfa.Secret, code, time.Now(), mfaAllowedSkew)
+	if !ok {
+		return nil, domain.ErrInvalidMFACode
+	}
+
+	// The confirming code can not be replayed to pass the second factor.
+	used, err := service.mfaRepo.UseStep(ctx, userId, step)
+	if err != nil {
+		return nil, err
+	}
+
+	if !used {
+		return nil, domain.ErrInvalidMFACode
+	}
+
+	codes := make([]string, 0, mfaRecoveryCodesCount)
+	hashes := make([]string, 0, mfaRecoveryCodesCount)
+	for i := 0; i < mfaRecoveryCodesCount; i++ {
+		code, err := randomHex(mfaRecoveryCodeBytes)
+		if err != nil {
+			return nil, err
+		}
+
+		codes = append(codes, code)
+		hashes = append(hashes, hashRecoveryCode(code))
+	}
+
+	if err := service.mfaRepo.Enable(ctx, userId, hashes); err != nil {
+		return nil, err
+	}
+
+	if err := service.auditLog.Log(ctx, LogMessage{
+		Action:    ACTION_MFA_ENROLL,
+		Entity:    ENTITY_USER,
+		EntityID:  userId,
+		Timestamp: time.Now(),
+	}); err != nil {
+		logger.FromContext(ctx).WithFields(logrus.Fields{
+			"method": "Users.ConfirmMFA",
+		}).Error("failed to send log request:", err)
+	}
+
+	return codes, nil
+}
+
+func (service *Auth) DisableMFA(ctx context.Context, userId int64, code string) error {
+	ctx, span := tracer.Start(ctx, "Auth.DisableMFA")
+	defer span.End()
+
+	mfa, err := service.mfaRepo.GetByUser(ctx, userId)
+	if err != nil {
+		return err
+	}
+
+	if mfa.EnabledAt != nil {
+		ok, err := service.checkMFACode(ctx, mfa, code)
+		if err != nil {
+			return err
+		}
+
+		if !ok {
+			return domain.ErrInvalidMFACode
+		}
+	}
+
+	if err := service.mfaRepo.Disable(ctx, userId); err != nil {
+		return err
+	}
+
+	if err := service.auditLog.Log(ctx, LogMessage{
+		Action:    ACTION_MFA_DISABLE,
+		Entity:    ENTITY_USER,
+		EntityID:  userId,
+		Timestamp: time.Now(),
+	}); err != nil {
+		logger.FromContext(ctx).WithFields(logrus.Fields{
+			"method": "Users.DisableMFA",
+		}).Error("failed to send log request:", err)
+	}
+
+	return nil
+}
+
+// VerifyMFA completes the sign in started by SingIn. The code is either a TOTP
+// code or one of the unused recovery codes.
+func (service *Auth) VerifyMFA(ctx context.Context, inp *domain.MFAVerifyInput) (string, string, error) {
+	ctx, span := tracer.Start(ctx, "Auth.VerifyMFA")
+	defer span.End()
+
+	accessToken, refreshToken, err := service.verifyMFA(ctx, inp)
+	metrics.ObserveSignIn(metrics.SignInMFA, err)
+
+	return accessToken, refreshToken, err
+}
+
+func (service *Auth) verifyMFA(ctx context.Context, inp *domain.MFAVerifyInput) (string, string, error) {
+	userId, fingerprint, err := service.parseMFAChallenge(inp.Challenge)
+	if err != nil {
+		return "", "", err
+	}
+
+	user, err := service.userRepo.GetById(ctx, userId)
+	if err != nil {
+		return "", "", err
+	}
+
+	if err := service.checkLoginLock(ctx, user.Email, inp.IP); err != nil {
+		return "", "", err
+	}
+
+	mfa, err := service.mfaRepo.GetByUser(ctx, userId)
+	if err != nil {
+		return "", "", err
+	}
+
+	// Every accepted code changes the enrollment, so a challenge already
+	// completed can not be used again.
+	if fingerprint != mfaFingerprint(mfa) {
+		return "", "", domain.ErrInvalidMFAChallenge
+	}
+
+	ok, err := service.checkMFACode(ctx, mfa, inp.Code)
+	if err != nil {
+		return "", "", err
+	}
+
+	action := ACTION_MFA_SUCCESS
+	if !ok {
+		action = ACTION_MFA_FAILURE
+	}
+
+	if err := service.auditLog.Log(ctx, LogMessage{
+		Action:    action,
+		Entity:    ENTITY_USER,
+		EntityID:  userId,
+		Timestamp: time.Now(),
+	}); err != nil {
+		logger.FromContext(ctx).WithFields(logrus.Fields{
+			"method": "Users.VerifyMFA",
+		}).Error("failed to send log request:", err)
+	}
+
+	if !ok {
+		service.registerLoginFailure(ctx, user.Email, inp.IP)
+
+		return "", "", domain.ErrInvalidMFACode
+	}
+
+	service.resetLoginFailures(ctx, user.Email)
+
+	if err := service.auditLog.Log(ctx, LogMessage{
+		Action:    ACTION_LOGIN,
+		Entity:    ENTITY_USER,
+		EntityID:  user.ID,
+		Timestamp: time.Now(),
+	}); err != nil {
+		logger.FromContext(ctx).WithFields(logrus.Fields{
+			"method": "Users.VerifyMFA",
+		}).Error("failed to send log request:", err)
+	}
+
+	return service.generateTokens(ctx, user)
+}
+
+func (service *Auth) checkMFACode(ctx context.Context, mfa *domain.UserMFA, code string) (bool, error) {
+	if mfa.EnabledAt == nil {
+		return false, domain.ErrMFANotEnrolled
+	}
+
+	// A TOTP code is accepted once, only codes of a later time step than the
+	// last accepted one pass.
+	if step, ok := totp.Match(mfa.Secret, code, time.Now(), mfaAllowedSkew); ok {
+		return service.mfaRepo.UseStep(ctx, mfa.UserID, step)
+	}
+
+	return service.mfaRepo.UseRecoveryCode(ctx, mfa.UserID, hashRecoveryCode(code))
+}
+
+func (service *Auth) newMFAChallenge(mfa *domain.UserMFA) (*domain.MFARequiredError, error) {
+	challenge, expiresAt, err := service.newPurposeToken(purposeMFAChallenge, mfa.UserID, mfaFingerprint(mfa), mfaChallengeTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.MFARequiredError{Challenge: challenge, ExpiresAt: expiresAt}, nil
+}
+
+func (service *Auth) parseMFAChallenge(challenge string) (int64, string, error) {
+	userId, fingerprint, err := service.parsePurposeToken(purposeMFAChallenge, challenge)
+	if err != nil {
+		return 0, "", domain.ErrInvalidMFAChallenge
+	}
+
+	return userId, fingerprint, nil
+}
+
+// mfaFingerprint binds a challenge to the enrollment as it was when issued,
+// updated_at changes whenever a code is accepted.
+func mfaFingerprint(mfa *domain.UserMFA) string {
+	return tokenFingerprint(mfa.UpdatedAt.UTC().Format(time.RFC3339Nano))
+}
+
+func hashRecoveryCode(code string) string {
+	sum := sha256.Sum256([]byte(code))
+
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,112 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/wilfridterry/contact-list/internal/domain"
+	"github.com/wilfridterry/contact-list/pkg/totp"
+)
+
+// enrolledMFA is a single enabled enrollment, without recovery codes.
+type enrolledMFA struct {
+	MFARepository
+
+	mfa      domain.UserMFA
+	lastStep int64
+}
+
+func (m *enrolledMFA) GetByUser(context.Context, int64) (*domain.UserMFA, error) {
+	mfa := m.mfa
+
+	return &mfa, nil
+}
+
+func (m *enrolledMFA) UseStep(_ context.Context, _ int64, step int64) (bool, error) {
+	if step <= m.lastStep {
+		return false, nil
+	}
+
+	m.lastStep = step
+	m.mfa.UpdatedAt = m.mfa.UpdatedAt.Add(time.Microsecond)
+
+	return true, nil
+}
+
+func (m *enrolledMFA) UseRecoveryCode(context.Context, int64, string) (bool, error) {
+	return false, nil
+}
+
+func TestAuth_VerifyMFA(t *testing.T) {
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	code, err := totp.Code(secret, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	enabledAt := time.Now()
+	user := &domain.User{ID: 1, Email: "user@test.com", Role: domain.RoleUser}
+	users := memoryUsers{users: map[int64]*domain.User{1: user}}
+
+	testTable := []struct {
+		name        string
+		reuse       bool
+		replay      bool
+		expectedErr error
+	}{
+		{
+			name: "OK",
+		},
+		{
+			name:        "Code replayed",
+			replay:      true,
+			expectedErr: domain.ErrInvalidMFACode,
+		},
+		{
+			name:        "Challenge reused",
+			reuse:       true,
+			expectedErr: domain.ErrInvalidMFAChallenge,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			mfa := &enrolledMFA{mfa: domain.UserMFA{UserID: 1, Secret: secret, EnabledAt: &enabledAt, UpdatedAt: enabledAt}}
+			auth := New(users, memorySessions{}, mfa, memoryAttempts{}, nil, discardAuditLog{}, nil, nil, AuthConfig{Secret: []byte("secret"), TokenTTL: time.Minute})
+
+			challenge := func() string {
+				challenge, err := auth.newMFAChallenge(&mfa.mfa)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				return challenge.Challenge
+			}
+
+			first := challenge()
+			if _, _, err := auth.VerifyMFA(context.Background(), &domain.MFAVerifyInput{Challenge: first, Code: code}); err != nil {
+				t.Fatalf("first verification failed: %v", err)
+			}
+
+			if testCase.expectedErr == nil {
+				return
+			}
+
+			second := challenge()
+			if testCase.reuse {
+				second = first
+			}
+
+			_, _, err := auth.VerifyMFA(context.Background(), &domain.MFAVerifyInput{Challenge: second, Code: code})
+			if !errors.Is(err, testCase.expectedErr) {
+				t.Fatalf("got %v, want %v", err, testCase.expectedErr)
+			}
+		})
+	}
+}
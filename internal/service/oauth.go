@@ -0,0 +1,830 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/wilfridterry/contact-list/internal/domain"
+	"github.com/wilfridterry/contact-list/pkg/logger"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Access and refresh tokens issued to third-party applications are opaque
+// random strings, only their sha256 hash is stored, the same way as API keys.
+const (
+	oauthRefreshTokenPrefix = "ort_"
+	oauthClientSecretPrefix = "ocs_"
+	oauthClientIdBytes      = 12
+	oauthSecretBytes        = 32
+	oauthCodeTTL            = 5 * time.Minute
+	oauthAccessTokenTTL     = time.Hour
+	oauthRefreshTokenTTL    = 30 * 24 * time.Hour
+	oauthTokenType          = "Bearer"
+	oauthResponseTypeCode   = "code"
+	pkceMethodS256          = "S256"
+	pkceVerifierMinLength   = 43
+	pkceVerifierMaxLength   = 128
+)
+
+type OAuthRepository interface {
+	CreateClient(context.Context, *domain.OAuthClient) (int64, error)
+	GetClientByClientID(context.Context, string) (*domain.OAuthClient, error)
+	GetClientsByUser(context.Context, int64) ([]domain.OAuthClient, error)
+	DeleteClient(context.Context, int64, int64) error
+	CreateCode(context.Context, *domain.OAuthCode) error
+	UseCode(context.Context, string) (*domain.OAuthCode, error)
+	CreateToken(context.Context, *domain.OAuthToken) (int64, error)
+	GetTokenByHash(context.Context, string) (*domain.OAuthToken, error)
+	RevokeToken(context.Context, int64) error
+	RevokeTokensByClient(context.Context, int64, int64) error
+	GetConsent(context.Context, int64, int64) (*domain.OAuthConsent, error)
+	GetConsentsByUser(context.Context, int64) ([]domain.OAuthConsent, error)
+	SaveConsent(context.Context, *domain.OAuthConsent) error
+	DeleteConsent(context.Context, int64, int64) error
+}
+
+// OAuth is the authorization server for third-party applications. It supports
+// the authorization code grant with PKCE, the client credentials grant (the
+// client acts on behalf of the user who registered it) and refresh tokens.
+type OAuth struct {
+	repository OAuthRepository
+	userRepo   UserRepository
+	auditLog   AuditLog
+}
+
+func NewOAuth(repository OAuthRepository, userRepo UserRepository, auditLog AuditLog) *OAuth {
+	return &OAuth{
+		repository: repository,
+		userRepo:   userRepo,
+		auditLog:   auditLog,
+	}
+}
+
+// RegisterClient stores a new client of the user. The secret of confidential
+// clients is returned only once.
+func (service *OAuth) RegisterClient(ctx context.Context, userId int64, inp *domain.RegisterOAuthClientInput) (*domain.OAuthClient, string, error) {
+	ctx, span := tracer.Start(ctx, "OAuth.RegisterClient")
+	defer span.End()
+
+	user, err := service.userRepo.GetById(ctx, userId)
+	if err != nil {
+		return nil, "", err
+	}
+
+	for _, scope := range inp.Scopes {
+		if !scope.OAuthScope() || !containsScopes(user.Role.Permissions(), []domain.Permission{scope}) {
+			return nil, "", domain.ErrInvalidOAuthScope
+		}
+	}
+
+	if !inp.Confidential && len(inp.RedirectURIs) == 0 {
+		return nil, "", domain.ErrInvalidRedirectURI
+	}
+
+	for _, uri := range inp.RedirectURIs {
+		if err := validateRedirectURI(uri); err != nil {
+			return nil, "", err
+		}
+	}
+
+	clientId, err := randomHex(oauthClientIdBytes)
+	if err != nil {
+		return nil, "", err
+	}
+
+	client := domain.OAuthClient{
+		ClientID:     clientId,
+		UserID:       userId,
+		Name:         inp.Name,
+		RedirectURIs: inp.RedirectURIs,
+		Scopes:       inp.Scopes,
+		Confidential: inp.Confidential,
+		CreatedAt:    time.Now(),
+		UpdatedAt:    time.Now(),
+	}
+
+	var secret string
+	if inp.Confidential {
+		secret, err = randomHex(oauthSecretBytes)
+		if err != nil {
+			return nil, "", err
+		}
+
+		secret = oauthClientSecretPrefix + secret
+		client.SecretHash = hashSecret(secret)
+	}
+
+	id, err := service.repository.CreateClient(ctx, &client)
+	if err != nil {
+		return nil, "", err
+	}
+
+	client.ID = id
+
+	if err := service.auditLog.Log(ctx, LogMessage{
+		Action:    ACTION_CREATE,
+		Entity:    ENTITY_OAUTH_CLIENT,
+		EntityID:  client.ID,
+		Timestamp: time.Now(),
+	}); err != nil {
+		logger.FromContext(ctx).WithFields(logrus.Fields{
+			"method": "OAuth.RegisterClient",
+		}).Error("failed to send log request:", err)
+	}
+
+	return &client, secret, nil
+}
+
+func (service *OAuth) Clients(ctx context.Context, userId int64) ([]domain.OAuthClient, error) {
+	ctx, span := tracer.Start(ctx, "OAuth.Clients")
+	defer span.End()
+
+	return service.repository.GetClientsByUser(ctx, userId)
+}
+
+func (service *OAuth) DeleteClient(ctx context.Context, userId, id int64) error {
+	ctx, span := tracer.Start(ctx, "OAuth.DeleteClient")
+	defer span.End()
+
+	if err := service.repository.DeleteClient(ctx, userId, id); err != nil {
+		return err
+	}
+
+	if err := service.auditLog.Log(ctx, LogMessage{
+		Action:    ACTION_DELETE,
+		Entity:    ENTITY_OAUTH_CLIENT,
+		EntityID:  id,
+		Timestamp: time.Now(),
+	}); err != nil {
+		logger.FromContext(ctx).WithFields(logrus.Fields{
+			"method": "OAuth.DeleteClient",
+		}).Error("failed to send log request:", err)
+	}
+
+	return nil
+}
+
+// Authorize validates the authorization request of the signed in user. When
+// the user already consented to the requested scopes a code is issued right
+// away, otherwise the prompt asks for consent.
+func (service *OAuth) Authorize(ctx context.Context, userId int64, inp *domain.AuthorizeInput) (*domain.AuthorizePrompt, error) {
+	ctx, span := tracer.Start(ctx, "OAuth.Authorize")
+	defer span.End()
+
+	client, redirectURI, scopes, err := service.validateAuthorize(ctx, userId, inp)
+	if err != nil {
+		return nil, err
+	}
+
+	prompt := domain.AuthorizePrompt{
+		ClientID:        client.ClientID,
+		ClientName:      client.Name,
+		Scopes:          scopes,
+		ConsentRequired: true,
+	}
+
+	consent, err := service.repository.GetConsent(ctx, userId, client.ID)
+	if err != nil && !errors.Is(err, domain.ErrOAuthConsentNotFound) {
+		return nil, err
+	}
+
+	if consent != nil && containsScopes(consent.Scopes, scopes) {
+		prompt.ConsentRequired = false
+		prompt.RedirectTo, err = service.issueCode(ctx, client, userId, redirectURI, scopes, inp)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &prompt, nil
+}
+
+// Consent records the decision of the user and returns the url to send the
+// user back to the client, with either a code or an access_denied error.
+func (service *OAuth) Consent(ctx context.Context, userId int64, inp *domain.ConsentInput) (string, error) {
+	ctx, span := tracer.Start(ctx, "OAuth.Consent")
+	defer span.End()
+
+	client, redirectURI, scopes, err := service.validateAuthorize(ctx, userId, &inp.AuthorizeInput)
+	if err != nil {
+		return "", err
+	}
+
+	if !inp.Approve {
+		return redirectWith(redirectURI, url.Values{"error": {"access_denied"}, "state": {inp.State}})
+	}
+
+	granted := scopes
+	consent, err := service.repository.GetConsent(ctx, userId, client.ID)
+	if err != nil && !errors.Is(err, domain.ErrOAuthConsentNotFound) {
+		return "", err
+	}
+
+	if consent != nil {
+		granted = mergeScopes(consent.Scopes, scopes)
+	}
+
+	if err := service.repository.SaveConsent(ctx, &domain.OAuthConsent{
+		UserID:   userId,
+		ClientID: client.ID,
+		Scopes:   granted,
+	}); err != nil {
+		return "", err
+	}
+
+	if err := service.auditLog.Log(ctx, LogMessage{
+		Action:    ACTION_GRANT_CONSENT,
+		Entity:    ENTITY_OAUTH_CLIENT,
+		EntityID:  client.ID,
+		Timestamp: time.Now(),
+	}); err != nil {
+		logger.FromContext(ctx).WithFields(logrus.Fields{
+			"method": "OAuth.Consent",
+		}).Error("failed to send log request:", err)
+	}
+
+	return service.issueCode(ctx, client, userId, redirectURI, scopes, &inp.AuthorizeInput)
+}
+
+func (service *OAuth) Consents(ctx context.Context, userId int64) ([]domain.OAuthConsent, error) {
+	ctx, span := tracer.Start(ctx, "OAuth.Consents")
+	defer span.End()
+
+	return service.repository.GetConsentsByUser(ctx, userId)
+}
+
+// RevokeConsent removes the consent and revokes every token the client holds
+// for the user.
+func (service *OAuth) RevokeConsent(ctx context.Context, userId int64, clientId string) error {
+	ctx, span := tracer.Start(ctx, "OAuth.RevokeConsent")
+	defer span.End()
+
+	client, err := service.repository.GetClientByClientID(ctx, clientId)
+	if err != nil {
+		return err
+	}
+
+	if err := service.repository.DeleteConsent(ctx, userId, client.ID); err != nil {
+		return err
+	}
+
+	if err := service.repository.RevokeTokensByClient(ctx, userId, client.ID); err != nil {
+		return err
+	}
+
+	if err := service.auditLog.Log(ctx, LogMessage{
+		Action:    ACTION_REVOKE_CONSENT,
+		Entity:    ENTITY_OAUTH_CLIENT,
+		EntityID:  client.ID,
+		Timestamp: time.Now(),
+	}); err != nil {
+		logger.FromContext(ctx).WithFields(logrus.Fields{
+			"method": "OAuth.RevokeConsent",
+		}).Error("failed to send log request:", err)
+	}
+
+	return nil
+}
+
+// Token implements the token endpoint for every supported grant type.
+func (service *OAuth) Token(ctx context.Context, inp *domain.TokenInput) (*domain.TokenResponse, error) {
+	ctx, span := tracer.Start(ctx, "OAuth.Token")
+	defer span.End()
+
+	client, err := service.authenticateClient(ctx, inp.ClientID, inp.ClientSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	switch inp.GrantType {
+	case domain.GrantTypeAuthorizationCode:
+		return service.exchangeCode(ctx, client, inp)
+	case domain.GrantTypeClientCredentials:
+		return service.clientCredentials(ctx, client, inp)
+	case domain.GrantTypeRefreshToken:
+		return service.refresh(ctx, client, inp)
+	default:
+		return nil, domain.ErrUnsupportedGrantType
+	}
+}
+
+// Introspect describes a token issued to the calling client (RFC 7662).
+func (service *OAuth) Introspect(ctx context.Context, inp *domain.OAuthTokenInput) (*domain.TokenIntrospection, error) {
+	ctx, span := tracer.Start(ctx, "OAuth.Introspect")
+	defer span.End()
+
+	client, err := service.authenticateClient(ctx, inp.ClientID, inp.ClientSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := service.repository.GetTokenByHash(ctx, hashSecret(inp.Token))
+	if err != nil {
+		if errors.Is(err, domain.ErrOAuthTokenNotFound) {
+			return &domain.TokenIntrospection{}, nil
+		}
+
+		return nil, err
+	}
+
+	if token.ClientID != client.ID || !tokenActive(token) {
+		return &domain.TokenIntrospection{}, nil
+	}
+
+	tokenType := oauthTokenType
+	if token.Kind == domain.OAuthTokenRefresh {
+		tokenType = domain.GrantTypeRefreshToken
+	}
+
+	return &domain.TokenIntrospection{
+		Active:    true,
+		Scope:     joinScopes(token.Scopes),
+		ClientID:  client.ClientID,
+		Subject:   strconv.FormatInt(token.UserID, 10),
+		TokenType: tokenType,
+		ExpiresAt: token.ExpiresAt.Unix(),
+		IssuedAt:  token.CreatedAt.Unix(),
+	}, nil
+}
+
+// Revoke revokes a token issued to the calling client (RFC 7009). Unknown
+// tokens are ignored, as the client can not do anything about them anyway.
+func (service *OAuth) Revoke(ctx context.Context, inp *domain.OAuthTokenInput) error {
+	ctx, span := tracer.Start(ctx, "OAuth.Revoke")
+	defer span.End()
+
+	client, err := service.authenticateClient(ctx, inp.ClientID, inp.ClientSecret)
+	if err != nil {
+		return err
+	}
+
+	token, err := service.repository.GetTokenByHash(ctx, hashSecret(inp.Token))
+	if err != nil {
+		if errors.Is(err, domain.ErrOAuthTokenNotFound) {
+			return nil
+		}
+
+		return err
+	}
+
+	if token.ClientID != client.ID || token.RevokedAt != nil {
+		return nil
+	}
+
+	if err := service.repository.RevokeToken(ctx, token.ID); err != nil {
+		return err
+	}
+
+	if err := service.auditLog.Log(ctx, LogMessage{
+		Action:    ACTION_REVOKE_TOKEN,
+		Entity:    ENTITY_OAUTH_CLIENT,
+		EntityID:  client.ID,
+		Timestamp: time.Now(),
+	}); err != nil {
+		logger.FromContext(ctx).WithFields(logrus.Fields{
+			"method": "OAuth.Revoke",
+		}).Error("failed to send log request:", err)
+	}
+
+	return nil
+}
+
+// Authenticate resolves an access token to the identity of the user who
+// granted it. The permissions are the token scopes narrowed down to the
+// current role of the user.
+func (service *OAuth) Authenticate(ctx context.Context, plain string) (*domain.Identity, error) {
+	ctx, span := tracer.Start(ctx, "OAuth.Authenticate")
+	defer span.End()
+
+	if !strings.HasPrefix(plain, domain.OAuthAccessTokenPrefix) {
+		return nil, domain.ErrInvalidOAuthToken
+	}
+
+	token, err := service.repository.GetTokenByHash(ctx, hashSecret(plain))
+	if err != nil {
+		if errors.Is(err, domain.ErrOAuthTokenNotFound) {
+			return nil, domain.ErrInvalidOAuthToken
+		}
+
+		return nil, err
+	}
+
+	if token.Kind != domain.OAuthTokenAccess || !tokenActive(token) {
+		return nil, domain.ErrInvalidOAuthToken
+	}
+
+	user, err := service.userRepo.GetById(ctx, token.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.DisabledAt != nil {
+		return nil, domain.ErrInvalidOAuthToken
+	}
+
+	return &domain.Identity{
+		UserID:        user.ID,
+		Role:          user.Role,
+		Permissions:   narrowScopes(token.Scopes, user.Role.Permissions()),
+		OAuthClientID: token.ClientID,
+	}, nil
+}
+
+func (service *OAuth) validateAuthorize(ctx context.Context, userId int64, inp *domain.AuthorizeInput) (*domain.OAuthClient, string, []domain.Permission, error) {
+	client, err := service.repository.GetClientByClientID(ctx, inp.ClientID)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	redirectURI := inp.RedirectURI
+	if redirectURI == "" && len(client.RedirectURIs) == 1 {
+		redirectURI = client.RedirectURIs[0]
+	}
+
+	if !containsString(client.RedirectURIs, redirectURI) {
+		return nil, "", nil, domain.ErrInvalidRedirectURI
+	}
+
+	if inp.ResponseType != oauthResponseTypeCode {
+		return nil, "", nil, domain.ErrUnsupportedResponseType
+	}
+
+	if inp.CodeChallenge == "" || inp.CodeChallengeMethod != pkceMethodS256 {
+		return nil, "", nil, domain.ErrInvalidOAuthRequest
+	}
+
+	user, err := service.userRepo.GetById(ctx, userId)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	scopes, err := requestedScopes(inp.Scope, client.Scopes)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	if !containsScopes(user.Role.Permissions(), scopes) {
+		return nil, "", nil, domain.ErrInvalidOAuthScope
+	}
+
+	return client, redirectURI, scopes, nil
+}
+
+func (service *OAuth) issueCode(ctx context.Context, client *domain.OAuthClient, userId int64, redirectURI string, scopes []domain.Permission, inp *domain.AuthorizeInput) (string, error) {
+	code, err := randomHex(oauthSecretBytes)
+	if err != nil {
+		return "", err
+	}
+
+	if err := service.repository.CreateCode(ctx, &domain.OAuthCode{
+		CodeHash:      hashSecret(code),
+		ClientID:      client.ID,
+		UserID:        userId,
+		RedirectURI:   inp.RedirectURI,
+		Scopes:        scopes,
+		CodeChallenge: inp.CodeChallenge,
+		ExpiresAt:     time.Now().Add(oauthCodeTTL),
+	}); err != nil {
+		return "", err
+	}
+
+	return redirectWith(redirectURI, url.Values{"code": {code}, "state": {inp.State}})
+}
+
+// authenticateClient checks the client secret of confidential clients. Public
+// clients only identify themselves, PKCE protects their codes instead.
+func (service *OAuth) authenticateClient(ctx context.Context, clientId, secret string) (*domain.OAuthClient, error) {
+	if clientId == "" {
+		return nil, domain.ErrInvalidOAuthClient
+	}
+
+	client, err := service.repository.GetClientByClientID(ctx, clientId)
+	if err != nil {
+		if errors.Is(err, domain.ErrOAuthClientNotFound) {
+			return nil, domain.ErrInvalidOAuthClient
+		}
+
+		return nil, err
+	}
+
+	if client.Confidential && subtle.ConstantTimeCompare([]byte(client.SecretHash), []byte(hashSecret(secret))) != 1 {
+		return nil, domain.ErrInvalidOAuthClient
+	}
+
+	return client, nil
+}
+
+func (service *OAuth) exchangeCode(ctx context.Context, client *domain.OAuthClient, inp *domain.TokenInput) (*domain.TokenResponse, error) {
+	code, err := service.repository.UseCode(ctx, hashSecret(inp.Code))
+	if err != nil {
+		if errors.Is(err, domain.ErrOAuthCodeNotFound) {
+			return nil, domain.ErrInvalidOAuthGrant
+		}
+
+		return nil, err
+	}
+
+	if code.ClientID != client.ID || code.ExpiresAt.Before(time.Now()) {
+		return nil, domain.ErrInvalidOAuthGrant
+	}
+
+	// The redirect uri must be repeated when the authorization request
+	// included it (RFC 6749 section 4.1.3).
+	if code.RedirectURI != "" && inp.RedirectURI != code.RedirectURI {
+		return nil, domain.ErrInvalidOAuthGrant
+	}
+
+	if !verifyPKCE(inp.CodeVerifier, code.CodeChallenge) {
+		return nil, domain.ErrInvalidOAuthGrant
+	}
+
+	user, err := service.userRepo.GetById(ctx, code.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.DisabledAt != nil {
+		return nil, domain.ErrInvalidOAuthGrant
+	}
+
+	scopes := narrowScopes(code.Scopes, user.Role.Permissions())
+	if len(scopes) == 0 {
+		return nil, domain.ErrInvalidOAuthScope
+	}
+
+	return service.issueTokens(ctx, client, user.ID, scopes, true)
+}
+
+func (service *OAuth) clientCredentials(ctx context.Context, client *domain.OAuthClient, inp *domain.TokenInput) (*domain.TokenResponse, error) {
+	if !client.Confidential {
+		return nil, domain.ErrUnauthorizedOAuthClient
+	}
+
+	scopes, err := requestedScopes(inp.Scope, client.Scopes)
+	if err != nil {
+		return nil, err
+	}
+
+	owner, err := service.userRepo.GetById(ctx, client.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	if owner.DisabledAt != nil {
+		return nil, domain.ErrInvalidOAuthGrant
+	}
+
+	scopes = narrowScopes(scopes, owner.Role.Permissions())
+	if len(scopes) == 0 {
+		return nil, domain.ErrInvalidOAuthScope
+	}
+
+	return service.issueTokens(ctx, client, owner.ID, scopes, false)
+}
+
+// refresh rotates the refresh token: the used one is revoked together with
+// the access tokens issued with it.
+func (service *OAuth) refresh(ctx context.Context, client *domain.OAuthClient, inp *domain.TokenInput) (*domain.TokenResponse, error) {
+	token, err := service.repository.GetTokenByHash(ctx, hashSecret(inp.RefreshToken))
+	if err != nil {
+		if errors.Is(err, domain.ErrOAuthTokenNotFound) {
+			return nil, domain.ErrInvalidOAuthGrant
+		}
+
+		return nil, err
+	}
+
+	if token.Kind != domain.OAuthTokenRefresh || token.ClientID != client.ID || !tokenActive(token) {
+		return nil, domain.ErrInvalidOAuthGrant
+	}
+
+	scopes, err := requestedScopes(inp.Scope, token.Scopes)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := service.userRepo.GetById(ctx, token.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.DisabledAt != nil {
+		return nil, domain.ErrInvalidOAuthGrant
+	}
+
+	scopes = narrowScopes(scopes, user.Role.Permissions())
+	if len(scopes) == 0 {
+		return nil, domain.ErrInvalidOAuthScope
+	}
+
+	if err := service.repository.RevokeToken(ctx, token.ID); err != nil {
+		return nil, err
+	}
+
+	return service.issueTokens(ctx, client, user.ID, scopes, true)
+}
+
+func (service *OAuth) issueTokens(ctx context.Context, client *domain.OAuthClient, userId int64, scopes []domain.Permission, withRefresh bool) (*domain.TokenResponse, error) {
+	res := domain.TokenResponse{
+		TokenType: oauthTokenType,
+		ExpiresIn: int64(oauthAccessTokenTTL.Seconds()),
+		Scope:     joinScopes(scopes),
+	}
+
+	var parentId *int64
+	if withRefresh {
+		secret, err := randomHex(oauthSecretBytes)
+		if err != nil {
+			return nil, err
+		}
+
+		res.RefreshToken = oauthRefreshTokenPrefix + secret
+
+		id, err := service.repository.CreateToken(ctx, &domain.OAuthToken{
+			TokenHash: hashSecret(res.RefreshToken),
+			Kind:      domain.OAuthTokenRefresh,
+			ClientID:  client.ID,
+			UserID:    userId,
+			Scopes:    scopes,
+			ExpiresAt: time.Now().Add(oauthRefreshTokenTTL),
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		parentId = &id
+	}
+
+	secret, err := randomHex(oauthSecretBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	res.AccessToken = domain.OAuthAccessTokenPrefix + secret
+
+	if _, err := service.repository.CreateToken(ctx, &domain.OAuthToken{
+		TokenHash: hashSecret(res.AccessToken),
+		Kind:      domain.OAuthTokenAccess,
+		ClientID:  client.ID,
+		UserID:    userId,
+		Scopes:    scopes,
+		ParentID:  parentId,
+		ExpiresAt: time.Now().Add(oauthAccessTokenTTL),
+	}); err != nil {
+		return nil, err
+	}
+
+	if err := service.auditLog.Log(ctx, LogMessage{
+		Action:    ACTION_ISSUE_TOKEN,
+		Entity:    ENTITY_OAUTH_CLIENT,
+		EntityID:  client.ID,
+		Timestamp: time.Now(),
+	}); err != nil {
+		logger.FromContext(ctx).WithFields(logrus.Fields{
+			"method": "OAuth.Token",
+		}).Error("failed to send log request:", err)
+	}
+
+	return &res, nil
+}
+
+// validateRedirectURI accepts https urls, http only on the loopback interface
+// and private-use schemes of native apps (RFC 8252), never with a fragment.
+func validateRedirectURI(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || !u.IsAbs() || strings.Contains(raw, "#") {
+		return domain.ErrInvalidRedirectURI
+	}
+
+	switch u.Scheme {
+	case "https":
+		if u.Host == "" {
+			return domain.ErrInvalidRedirectURI
+		}
+	case "http":
+		host := u.Hostname()
+		if host != "localhost" && host != "127.0.0.1" && host != "::1" {
+			return domain.ErrInvalidRedirectURI
+		}
+	default:
+		if !strings.Contains(u.Scheme, ".") {
+			return domain.ErrInvalidRedirectURI
+		}
+	}
+
+	return nil
+}
+
+func redirectWith(redirectURI string, params url.Values) (string, error) {
+	u, err := url.Parse(redirectURI)
+	if err != nil {
+		return "", domain.ErrInvalidRedirectURI
+	}
+
+	query := u.Query()
+	for key, values := range params {
+		if len(values) > 0 && values[0] != "" {
+			query.Set(key, values[0])
+		}
+	}
+
+	u.RawQuery = query.Encode()
+
+	return u.String(), nil
+}
+
+func verifyPKCE(verifier, challenge string) bool {
+	if len(verifier) < pkceVerifierMinLength || len(verifier) > pkceVerifierMaxLength {
+		return false
+	}
+
+	sum := sha256.Sum256([]byte(verifier))
+	expected := base64.RawURLEncoding.EncodeToString(sum[:])
+
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(challenge)) == 1
+}
+
+func tokenActive(token *domain.OAuthToken) bool {
+	return token.RevokedAt == nil && token.ExpiresAt.After(time.Now())
+}
+
+// requestedScopes parses the space separated scope parameter, an empty one
+// means every allowed scope.
+func requestedScopes(scope string, allowed []domain.Permission) ([]domain.Permission, error) {
+	fields := strings.Fields(scope)
+	if len(fields) == 0 {
+		return append([]domain.Permission(nil), allowed...), nil
+	}
+
+	scopes := make([]domain.Permission, 0, len(fields))
+	for _, field := range fields {
+		scopes = append(scopes, domain.Permission(field))
+	}
+
+	if !containsScopes(allowed, scopes) {
+		return nil, domain.ErrInvalidOAuthScope
+	}
+
+	return scopes, nil
+}
+
+func containsScopes(have, want []domain.Permission) bool {
+	identity := domain.Identity{Permissions: have}
+	for _, scope := range want {
+		if !identity.HasPermission(scope) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func narrowScopes(scopes, permissions []domain.Permission) []domain.Permission {
+	identity := domain.Identity{Permissions: permissions}
+	res := make([]domain.Permission, 0, len(scopes))
+	for _, scope := range scopes {
+		if identity.HasPermission(scope) {
+			res = append(res, scope)
+		}
+	}
+
+	return res
+}
+
+func mergeScopes(a, b []domain.Permission) []domain.Permission {
+	res := append([]domain.Permission(nil), a...)
+	for _, scope := range b {
+		if !containsScopes(res, []domain.Permission{scope}) {
+			res = append(res, scope)
+		}
+	}
+
+	return res
+}
+
+func joinScopes(scopes []domain.Permission) string {
+	values := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		values = append(values, string(scope))
+	}
+
+	return strings.Join(values, " ")
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
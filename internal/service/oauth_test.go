@@ -0,0 +1,125 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/wilfridterry/contact-list/internal/domain"
+)
+
+// memoryOAuth knows a single client and hands out a single code.
+type memoryOAuth struct {
+	OAuthRepository
+
+	client *domain.OAuthClient
+	code   *domain.OAuthCode
+}
+
+func (m *memoryOAuth) GetClientByClientID(_ context.Context, clientId string) (*domain.OAuthClient, error) {
+	if clientId != m.client.ClientID {
+		return nil, domain.ErrOAuthClientNotFound
+	}
+
+	return m.client, nil
+}
+
+func (m *memoryOAuth) UseCode(_ context.Context, hash string) (*domain.OAuthCode, error) {
+	if m.code == nil || hash != m.code.CodeHash {
+		return nil, domain.ErrOAuthCodeNotFound
+	}
+
+	code := m.code
+	m.code = nil
+
+	return code, nil
+}
+
+func (m *memoryOAuth) CreateToken(context.Context, *domain.OAuthToken) (int64, error) {
+	return 1, nil
+}
+
+// discardAuditLog drops the audit log messages.
+type discardAuditLog struct{}
+
+func (discardAuditLog) Log(context.Context, LogMessage) error {
+	return nil
+}
+
+func TestOAuth_Token_authorizationCode(t *testing.T) {
+	const redirectURI = "https://app.example.com/callback"
+
+	verifier := strings.Repeat("v", pkceVerifierMinLength)
+	sum := sha256.Sum256([]byte(verifier))
+	challenge := base64.RawURLEncoding.EncodeToString(sum[:])
+
+	users := memoryUsers{users: map[int64]*domain.User{
+		1: {ID: 1, Role: domain.RoleUser},
+	}}
+
+	testTable := []struct {
+		name             string
+		codeRedirectURI  string
+		tokenRedirectURI string
+		expectedErr      error
+	}{
+		{
+			name:             "OK",
+			codeRedirectURI:  redirectURI,
+			tokenRedirectURI: redirectURI,
+		},
+		{
+			name: "OK without redirect uri",
+		},
+		{
+			name:             "Redirect uri missing",
+			codeRedirectURI:  redirectURI,
+			tokenRedirectURI: "",
+			expectedErr:      domain.ErrInvalidOAuthGrant,
+		},
+		{
+			name:             "Redirect uri different",
+			codeRedirectURI:  redirectURI,
+			tokenRedirectURI: "https://app.example.com/other",
+			expectedErr:      domain.ErrInvalidOAuthGrant,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			repo := &memoryOAuth{
+				client: &domain.OAuthClient{ID: 1, ClientID: "client", RedirectURIs: []string{redirectURI}},
+				code: &domain.OAuthCode{
+					CodeHash:      hashSecret("code"),
+					ClientID:      1,
+					UserID:        1,
+					RedirectURI:   testCase.codeRedirectURI,
+					Scopes:        []domain.Permission{domain.PermissionContactsRead},
+					CodeChallenge: challenge,
+					ExpiresAt:     time.Now().Add(time.Minute),
+				},
+			}
+
+			oauth := NewOAuth(repo, users, discardAuditLog{})
+
+			res, err := oauth.Token(context.Background(), &domain.TokenInput{
+				GrantType:    domain.GrantTypeAuthorizationCode,
+				ClientID:     "client",
+				Code:         "code",
+				RedirectURI:  testCase.tokenRedirectURI,
+				CodeVerifier: verifier,
+			})
+			if !errors.Is(err, testCase.expectedErr) {
+				t.Fatalf("got %v, want %v", err, testCase.expectedErr)
+			}
+
+			if err == nil && res.AccessToken == "" {
+				t.Error("got no access token")
+			}
+		})
+	}
+}
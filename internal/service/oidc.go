@@ -0,0 +1,276 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/wilfridterry/contact-list/internal/domain"
+	"github.com/wilfridterry/contact-list/pkg/logger"
+	"github.com/wilfridterry/contact-list/pkg/oidc"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	oidcStateTTL         = 10 * time.Minute
+	oidcStateSecretLabel = ":oidc-state"
+	oidcRandomBytes      = 16
+	oidcPasswordBytes    = 32
+)
+
+type OIDCProvider interface {
+	AuthCodeURL(ctx context.Context, state, nonce, verifier string) (string, error)
+	Exchange(ctx context.Context, code, verifier, nonce string) (*oidc.Claims, error)
+}
+
+type IdentityRepository interface {
+	Create(context.Context, *domain.ExternalIdentity) (int64, error)
+	GetByProviderSubject(context.Context, string, string) (*domain.ExternalIdentity, error)
+}
+
+// TokenIssuer signs in a user who was authenticated by other means than the
+// password, with the same checks as a password sign in.
+type TokenIssuer interface {
+	SignInExternal(context.Context, *domain.User, string) (string, string, error)
+}
+
+// OIDC signs users in with external OpenID Connect providers. The flow state
+// (state, nonce and PKCE verifier) is kept on the client in a signed token,
+// so no server side storage is needed between the redirect and the callback.
+type OIDC struct {
+	providers    map[string]OIDCProvider
+	identityRepo IdentityRepository
+	userRepo     UserRepository
+	hashier      Hashier
+	tokens       TokenIssuer
+	auditLog     AuditLog
+	secret       []byte
+}
+
+type oidcStateClaims struct {
+	jwt.RegisteredClaims
+	Provider string `json:"provider"`
+	State    string `json:"state"`
+	Nonce    string `json:"nonce"`
+	Verifier string `json:"verifier"`
+	// LinkUserID is the signed in user who asked to link the provider.
+	LinkUserID int64 `json:"link_user_id,omitempty"`
+}
+
+func NewOIDC(providers map[string]OIDCProvider, identityRepo IdentityRepository, userRepo UserRepository, hashier Hashier, tokens TokenIssuer, auditLog AuditLog, secret []byte) *OIDC {
+	return &OIDC{
+		providers:    providers,
+		identityRepo: identityRepo,
+		userRepo:     userRepo,
+		hashier:      hashier,
+		tokens:       tokens,
+		auditLog:     auditLog,
+		secret:       append(append([]byte{}, secret...), []byte(oidcStateSecretLabel)...),
+	}
+}
+
+// Begin returns the url of the provider to redirect the user to and the state
+// token the client has to bring back to the callback. A signed in user passes
+// its ID to link the provider account to its own, 0 otherwise.
+func (service *OIDC) Begin(ctx context.Context, providerName string, linkUserId int64) (string, string, error) {
+	ctx, span := tracer.Start(ctx, "OIDC.Begin")
+	defer span.End()
+
+	provider, ok := service.providers[providerName]
+	if !ok {
+		return "", "", domain.ErrUnknownProvider
+	}
+
+	state, err := randomHex(oidcRandomBytes)
+	if err != nil {
+		return "", "", err
+	}
+
+	nonce, err := randomHex(oidcRandomBytes)
+	if err != nil {
+		return "", "", err
+	}
+
+	verifier := oidc.GenerateVerifier()
+
+	redirectURL, err := provider.AuthCodeURL(ctx, state, nonce, verifier)
+	if err != nil {
+		return "", "", err
+	}
+
+	t := jwt.NewWithClaims(jwt.SigningMethodHS256, oidcStateClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(oidcStateTTL)),
+		},
+		Provider:   providerName,
+		State:      state,
+		Nonce:      nonce,
+		Verifier:   verifier,
+		LinkUserID: linkUserId,
+	})
+
+	stateToken, err := t.SignedString(service.secret)
+	if err != nil {
+		return "", "", err
+	}
+
+	return redirectURL, stateToken, nil
+}
+
+// Complete finishes the flow on the callback: it redeems the code, finds,
+// links or provisions the user and signs it in.
+func (service *OIDC) Complete(ctx context.Context, providerName, stateToken, state, code, ip string) (string, string, error) {
+	ctx, span := tracer.Start(ctx, "OIDC.Complete")
+	defer span.End()
+
+	provider, ok := service.providers[providerName]
+	if !ok {
+		return "", "", domain.ErrUnknownProvider
+	}
+
+	claims := &oidcStateClaims{}
+	_, err := jwt.ParseWithClaims(stateToken, claims, func(token *jwt.Token) (interface{}, error) {
+		return service.secret, nil
+	}, jwt.WithExpirationRequired(), jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Name}))
+
+	if err != nil || claims.Provider != providerName || claims.State != state {
+		return "", "", domain.ErrInvalidOIDCState
+	}
+
+	external, err := provider.Exchange(ctx, code, claims.Verifier, claims.Nonce)
+	if err != nil {
+		return "", "", err
+	}
+
+	user, err := service.findOrProvision(ctx, providerName, external, claims.LinkUserID)
+	if err != nil {
+		return "", "", err
+	}
+
+	return service.tokens.SignInExternal(ctx, user, ip)
+}
+
+// findOrProvision resolves the user linked to the external identity. An
+// unknown identity is linked to the signed in user who started the flow, or
+// gets a new user. It is never linked to an existing account by email alone,
+// the provider account would then be enough to take the account over.
+func (service *OIDC) findOrProvision(ctx context.Context, providerName string, external *oidc.Claims, linkUserId int64) (*domain.User, error) {
+	identity, err := service.identityRepo.GetByProviderSubject(ctx, providerName, external.Subject)
+	if err == nil {
+		if linkUserId != 0 && identity.UserID != linkUserId {
+			return nil, domain.ErrIdentityAlreadyLinked
+		}
+
+		return service.userRepo.GetById(ctx, identity.UserID)
+	}
+
+	if !errors.Is(err, domain.ErrExternalIdentityNotFound) {
+		return nil, err
+	}
+
+	var user *domain.User
+	if linkUserId != 0 {
+		user, err = service.userRepo.GetById(ctx, linkUserId)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		if external.Email != "" {
+			_, err = service.userRepo.GetByEmail(ctx, external.Email)
+			if err == nil {
+				return nil, domain.ErrIdentityLinkRequired
+			}
+
+			if !errors.Is(err, domain.ErrNotFoundUser) {
+				return nil, err
+			}
+		}
+
+		user, err = service.provision(ctx, external)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	id, err := service.identityRepo.Create(ctx, &domain.ExternalIdentity{
+		UserID:   user.ID,
+		Provider: providerName,
+		Subject:  external.Subject,
+		Email:    external.Email,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := service.auditLog.Log(ctx, LogMessage{
+		Action:    ACTION_LINK_IDENTITY,
+		Entity:    ENTITY_USER,
+		EntityID:  user.ID,
+		Timestamp: time.Now(),
+	}); err != nil {
+		logger.FromContext(ctx).WithFields(logrus.Fields{
+			"method":      "OIDC.Complete",
+			"identity_id": id,
+		}).Error("failed to send log request:", err)
+	}
+
+	return user, nil
+}
+
+// provision creates a user for the external identity. The password is random
+// and unknown to anybody, it can be set later with the password reset flow.
+func (service *OIDC) provision(ctx context.Context, external *oidc.Claims) (*domain.User, error) {
+	plain, err := randomHex(oidcPasswordBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	password, err := service.hashier.Hash(plain)
+	if err != nil {
+		return nil, err
+	}
+
+	name := external.Name
+	if name == "" {
+		name = external.Email
+	}
+
+	user := domain.User{
+		Name:         name,
+		Email:        external.Email,
+		Password:     password,
+		Role:         domain.RoleUser,
+		RegisteredAt: time.Now(),
+	}
+
+	id, err := service.userRepo.Create(ctx, &user)
+	if err != nil {
+		return nil, err
+	}
+
+	user.ID = id
+
+	if external.EmailVerified {
+		if err := service.userRepo.MarkEmailVerified(ctx, user.ID); err != nil {
+			return nil, err
+		}
+
+		now := time.Now()
+		user.EmailVerifiedAt = &now
+	}
+
+	if err := service.auditLog.Log(ctx, LogMessage{
+		Action:    ACTION_REGISTER,
+		Entity:    ENTITY_USER,
+		EntityID:  user.ID,
+		Timestamp: time.Now(),
+	}); err != nil {
+		logger.FromContext(ctx).WithFields(logrus.Fields{
+			"method": "OIDC.Complete",
+		}).Error("failed to send log request:", err)
+	}
+
+	return &user, nil
+}
@@ -0,0 +1,211 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/wilfridterry/contact-list/internal/domain"
+	"github.com/wilfridterry/contact-list/pkg/oidc"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// staticProvider authenticates everybody as the same external account.
+type staticProvider struct {
+	claims *oidc.Claims
+}
+
+func (p staticProvider) AuthCodeURL(context.Context, string, string, string) (string, error) {
+	return "https://idp.example.com/authorize", nil
+}
+
+func (p staticProvider) Exchange(context.Context, string, string, string) (*oidc.Claims, error) {
+	return p.claims, nil
+}
+
+// memoryIdentities keeps the linked external identities.
+type memoryIdentities struct {
+	IdentityRepository
+
+	identities []domain.ExternalIdentity
+}
+
+func (m *memoryIdentities) Create(_ context.Context, identity *domain.ExternalIdentity) (int64, error) {
+	m.identities = append(m.identities, *identity)
+
+	return int64(len(m.identities)), nil
+}
+
+func (m *memoryIdentities) GetByProviderSubject(_ context.Context, provider, subject string) (*domain.ExternalIdentity, error) {
+	for _, identity := range m.identities {
+		if identity.Provider == provider && identity.Subject == subject {
+			return &identity, nil
+		}
+	}
+
+	return nil, domain.ErrExternalIdentityNotFound
+}
+
+// signedInUsers records who was signed in.
+type signedInUsers struct {
+	userIds []int64
+}
+
+func (s *signedInUsers) SignInExternal(_ context.Context, user *domain.User, _ string) (string, string, error) {
+	s.userIds = append(s.userIds, user.ID)
+
+	return "access", "refresh", nil
+}
+
+func TestOIDC_Complete(t *testing.T) {
+	users := memoryUsers{users: map[int64]*domain.User{
+		1: {ID: 1, Email: "user@test.com", Role: domain.RoleUser},
+		2: {ID: 2, Email: "other@test.com", Role: domain.RoleUser},
+	}}
+
+	claims := &oidc.Claims{Subject: "sub", Email: "user@test.com", EmailVerified: true}
+
+	testTable := []struct {
+		name             string
+		linked           []domain.ExternalIdentity
+		linkUserId       int64
+		expectedUserId   int64
+		expectedErr      error
+		expectedLinkedTo int64
+	}{
+		{
+			name:        "Existing email without a session",
+			expectedErr: domain.ErrIdentityLinkRequired,
+		},
+		{
+			name:             "Link from the session",
+			linkUserId:       1,
+			expectedUserId:   1,
+			expectedLinkedTo: 1,
+		},
+		{
+			name:             "Linked identity",
+			linked:           []domain.ExternalIdentity{{UserID: 1, Provider: "idp", Subject: "sub"}},
+			expectedUserId:   1,
+			expectedLinkedTo: 1,
+		},
+		{
+			name:             "Linked to another user",
+			linked:           []domain.ExternalIdentity{{UserID: 2, Provider: "idp", Subject: "sub"}},
+			linkUserId:       1,
+			expectedErr:      domain.ErrIdentityAlreadyLinked,
+			expectedLinkedTo: 2,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			identities := &memoryIdentities{identities: testCase.linked}
+			tokens := &signedInUsers{}
+
+			service := NewOIDC(map[string]OIDCProvider{"idp": staticProvider{claims: claims}}, identities, users, nil, tokens, discardAuditLog{}, []byte("secret"))
+
+			_, stateToken, err := service.Begin(context.Background(), "idp", testCase.linkUserId)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			state := &oidcStateClaims{}
+			if _, err := jwt.ParseWithClaims(stateToken, state, func(*jwt.Token) (interface{}, error) {
+				return service.secret, nil
+			}); err != nil {
+				t.Fatal(err)
+			}
+
+			_, _, err = service.Complete(context.Background(), "idp", stateToken, state.State, "code", "")
+			if !errors.Is(err, testCase.expectedErr) {
+				t.Fatalf("got %v, want %v", err, testCase.expectedErr)
+			}
+
+			if err == nil && (len(tokens.userIds) != 1 || tokens.userIds[0] != testCase.expectedUserId) {
+				t.Errorf("signed in %v, want user %d", tokens.userIds, testCase.expectedUserId)
+			}
+
+			var linkedTo int64
+			if identity, err := identities.GetByProviderSubject(context.Background(), "idp", "sub"); err == nil {
+				linkedTo = identity.UserID
+			}
+
+			if linkedTo != testCase.expectedLinkedTo {
+				t.Errorf("identity linked to %d, want %d", linkedTo, testCase.expectedLinkedTo)
+			}
+		})
+	}
+}
+
+// memoryMFA has MFA enabled for every user.
+type memoryMFA struct {
+	MFARepository
+}
+
+func (memoryMFA) GetByUser(_ context.Context, userId int64) (*domain.UserMFA, error) {
+	enabledAt := time.Now()
+
+	return &domain.UserMFA{UserID: userId, EnabledAt: &enabledAt}, nil
+}
+
+// memoryAttempts locks the keys it was given.
+type memoryAttempts struct {
+	LoginAttemptRepository
+
+	locked map[string]bool
+}
+
+func (m memoryAttempts) Get(_ context.Context, key string) (*domain.LoginAttempt, error) {
+	if !m.locked[key] {
+		return nil, nil
+	}
+
+	lockedUntil := time.Now().Add(time.Minute)
+
+	return &domain.LoginAttempt{Key: key, LockedUntil: &lockedUntil}, nil
+}
+
+func (memoryAttempts) RegisterFailure(_ context.Context, key string, at time.Time, _ time.Duration) (*domain.LoginAttempt, error) {
+	return &domain.LoginAttempt{Key: key, Failures: 1, FirstFailedAt: at, LastFailedAt: at}, nil
+}
+
+func (memoryAttempts) Reset(context.Context, string) error {
+	return nil
+}
+
+func TestAuth_SignInExternal(t *testing.T) {
+	user := &domain.User{ID: 1, Email: "user@test.com", Role: domain.RoleUser}
+
+	testTable := []struct {
+		name   string
+		locked map[string]bool
+		check  func(error) bool
+	}{
+		{
+			name:  "MFA enabled",
+			check: func(err error) bool { return errors.Is(err, domain.ErrMFARequired) },
+		},
+		{
+			name:   "Locked account",
+			locked: map[string]bool{accountAttemptKey(user.Email): true},
+			check: func(err error) bool {
+				var lockErr *domain.LoginLockedError
+				return errors.As(err, &lockErr)
+			},
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			auth := New(nil, memorySessions{}, memoryMFA{}, memoryAttempts{locked: testCase.locked}, nil, discardAuditLog{}, nil, nil, AuthConfig{Secret: []byte("secret"), TokenTTL: time.Minute})
+
+			accessToken, _, err := auth.SignInExternal(context.Background(), user, "")
+			if !testCase.check(err) || accessToken != "" {
+				t.Fatalf("got token %q and error %v", accessToken, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,326 @@
+package service
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/wilfridterry/contact-list/internal/domain"
+	"github.com/wilfridterry/contact-list/pkg/logger"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	erasedName        = "erased"
+	erasedEmailFormat = "erased-%s-%d@erased.invalid"
+	erasedSecretBytes = 32
+)
+
+// Privacy answers data subject requests: it exports everything held about a
+// user and erases users and contacts. Erasure removes the local audit entries
+// of the subject and leaves a single ERASE tombstone. Webhook deliveries and
+// stored idempotent responses holding erased contacts are dropped, as they
+// keep the contact as it was. Messages already handed
+// over to the audit queue carry only identifiers, which point to nothing once
+// the subject is erased.
+type Privacy struct {
+	userRepo     UserRepository
+	sessionRepo  SessionRepository
+	contactsRepo ContactRepository
+	apiKeyRepo   APIKeyRepository
+	attemptsRepo LoginAttemptRepository
+	webhookRepo  WebhookRepository
+	idempotency  IdempotencyRepository
+	journal      AuditJournal
+	auditLog     AuditLog
+	hashier      Hashier
+}
+
+// exportedSession leaves the refresh token itself out of the export.
+type exportedSession struct {
+	ID        int64     `json:"id"`
+	ExpiresAt time.Time `json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type exportFile struct {
+	name string
+	data any
+}
+
+func NewPrivacy(userRepo UserRepository, sessionRepo SessionRepository, contactsRepo ContactRepository, apiKeyRepo APIKeyRepository, attemptsRepo LoginAttemptRepository, webhookRepo WebhookRepository, idempotency IdempotencyRepository, journal AuditJournal, auditLog AuditLog, hashier Hashier) *Privacy {
+	return &Privacy{
+		userRepo:     userRepo,
+		sessionRepo:  sessionRepo,
+		contactsRepo: contactsRepo,
+		apiKeyRepo:   apiKeyRepo,
+		attemptsRepo: attemptsRepo,
+		webhookRepo:  webhookRepo,
+		idempotency:  idempotency,
+		journal:      journal,
+		auditLog:     auditLog,
+		hashier:      hashier,
+	}
+}
+
+// Export writes a ZIP archive with one JSON file per kind of data held about
+// the user: profile, sessions, contacts, API keys and audit entries.
+func (service *Privacy) Export(ctx context.Context, userId int64, w io.Writer) error {
+	ctx, span := tracer.Start(ctx, "Privacy.Export")
+	defer span.End()
+
+	user, err := service.userRepo.GetById(ctx, userId)
+	if err != nil {
+		return err
+	}
+
+	sessions, err := service.sessionRepo.GetAllByUser(ctx, userId)
+	if err != nil {
+		return err
+	}
+
+	exportedSessions := make([]exportedSession, 0, len(sessions))
+	for _, s := range sessions {
+		exportedSessions = append(exportedSessions, exportedSession{ID: s.ID, ExpiresAt: s.ExpiresAt, CreatedAt: s.CreatedAt})
+	}
+
+	contacts, err := service.contactsRepo.GetAllByUser(ctx, userId)
+	if err != nil {
+		return err
+	}
+
+	keys, err := service.apiKeyRepo.GetAllByUser(ctx, userId)
+	if err != nil {
+		return err
+	}
+
+	audit, err := service.auditEntries(ctx, userId, contactIds(contacts), apiKeyIds(keys))
+	if err != nil {
+		return err
+	}
+
+	archive := zip.NewWriter(w)
+	for _, file := range []exportFile{
+		{"profile.json", user},
+		{"sessions.json", exportedSessions},
+		{"contacts.json", contacts},
+		{"api_keys.json", keys},
+		{"audit_log.json", audit},
+	} {
+		f, err := archive.Create(file.name)
+		if err != nil {
+			return err
+		}
+
+		encoder := json.NewEncoder(f)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(file.data); err != nil {
+			return err
+		}
+	}
+
+	if err := archive.Close(); err != nil {
+		return err
+	}
+
+	if err := service.auditLog.Log(ctx, LogMessage{
+		Action:    ACTION_EXPORT,
+		Entity:    ENTITY_USER,
+		EntityID:  userId,
+		Timestamp: time.Now(),
+	}); err != nil {
+		logger.FromContext(ctx).WithFields(logrus.Fields{
+			"method": "Privacy.Export",
+		}).Error("failed to send log request:", err)
+	}
+
+	return nil
+}
+
+// EraseUser deletes the user with the owned contacts, or pseudonymizes the
+// account and drops its sessions, credentials and webhooks while keeping the
+// contacts. Either way the webhook deliveries and idempotent responses of the
+// user or about the contacts are dropped.
+func (service *Privacy) EraseUser(ctx context.Context, userId int64, mode domain.ErasureMode) error {
+	ctx, span := tracer.Start(ctx, "Privacy.EraseUser")
+	defer span.End()
+
+	user, err := service.userRepo.GetById(ctx, userId)
+	if err != nil {
+		return err
+	}
+
+	contacts, err := service.contactsRepo.GetAllByUser(ctx, userId)
+	if err != nil {
+		return err
+	}
+
+	keys, err := service.apiKeyRepo.GetAllByUser(ctx, userId)
+	if err != nil {
+		return err
+	}
+
+	switch mode {
+	case domain.ErasureDelete:
+		if err := service.userRepo.Delete(ctx, userId, domain.OwnedContactsDelete); err != nil {
+			return err
+		}
+
+		if err := service.journal.DeleteByEntity(ctx, string(ENTITY_CONTACT), contactIds(contacts)); err != nil {
+			return err
+		}
+	case domain.ErasurePseudonymize:
+		password, err := service.randomPassword()
+		if err != nil {
+			return err
+		}
+
+		if err := service.userRepo.Pseudonymize(ctx, &domain.User{
+			ID:       userId,
+			Name:     erasedName,
+			Email:    fmt.Sprintf(erasedEmailFormat, "user", userId),
+			Password: password,
+		}); err != nil {
+			return err
+		}
+
+		if err := service.webhookRepo.DeleteAllByUser(ctx, userId); err != nil {
+			return err
+		}
+	default:
+		return domain.ErrUnknownErasureMode
+	}
+
+	if err := service.attemptsRepo.Reset(ctx, accountAttemptKey(user.Email)); err != nil {
+		return err
+	}
+
+	if err := service.journal.DeleteByEntity(ctx, string(ENTITY_API_KEY), apiKeyIds(keys)); err != nil {
+		return err
+	}
+
+	if err := service.webhookRepo.DeleteDeliveriesByContact(ctx, contactIds(contacts)); err != nil {
+		return err
+	}
+
+	if err := service.idempotency.DeleteByUser(ctx, userId); err != nil {
+		return err
+	}
+
+	if err := service.idempotency.DeleteByContacts(ctx, contactIds(contacts)); err != nil {
+		return err
+	}
+
+	return service.tombstone(ctx, ENTITY_USER, userId)
+}
+
+// EraseContact deletes the contact or replaces its personal data, and drops
+// the webhook deliveries and idempotent responses holding it.
+func (service *Privacy) EraseContact(ctx context.Context, contactId int64, mode domain.ErasureMode) error {
+	ctx, span := tracer.Start(ctx, "Privacy.EraseContact")
+	defer span.End()
+
+	switch mode {
+	case domain.ErasureDelete:
+		if err := service.contactsRepo.Delete(ctx, contactId); err != nil {
+			return err
+		}
+	case domain.ErasurePseudonymize:
+		if err := service.contactsRepo.Pseudonymize(ctx, &domain.Contact{
+			ID:       contactId,
+			Name:     erasedName,
+			LastName: erasedName,
+			Email:    fmt.Sprintf(erasedEmailFormat, "contact", contactId),
+		}); err != nil {
+			return err
+		}
+	default:
+		return domain.ErrUnknownErasureMode
+	}
+
+	if err := service.webhookRepo.DeleteDeliveriesByContact(ctx, []int64{contactId}); err != nil {
+		return err
+	}
+
+	if err := service.idempotency.DeleteByContacts(ctx, []int64{contactId}); err != nil {
+		return err
+	}
+
+	return service.tombstone(ctx, ENTITY_CONTACT, contactId)
+}
+
+// tombstone replaces the audit entries of the subject with a single ERASE
+// entry, which is also sent to the audit queue. Unlike other audit messages
+// the erasure fails without it, the subject being erased already.
+func (service *Privacy) tombstone(ctx context.Context, subject entity, id int64) error {
+	if err := service.journal.DeleteByEntity(ctx, string(subject), []int64{id}); err != nil {
+		return err
+	}
+
+	if err := service.auditLog.Log(ctx, LogMessage{
+		Action:    ACTION_ERASE,
+		Entity:    subject,
+		EntityID:  id,
+		Timestamp: time.Now(),
+	}); err != nil {
+		return fmt.Errorf("erased %s %d without an audit tombstone: %w", strings.ToLower(string(subject)), id, err)
+	}
+
+	return nil
+}
+
+func (service *Privacy) auditEntries(ctx context.Context, userId int64, contactIds, keyIds []int64) ([]domain.AuditEntry, error) {
+	entries := make([]domain.AuditEntry, 0)
+
+	for subject, ids := range map[entity][]int64{
+		ENTITY_USER:    {userId},
+		ENTITY_CONTACT: contactIds,
+		ENTITY_API_KEY: keyIds,
+	} {
+		found, err := service.journal.GetByEntity(ctx, string(subject), ids)
+		if err != nil {
+			return nil, err
+		}
+
+		entries = append(entries, found...)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].ID < entries[j].ID
+	})
+
+	return entries, nil
+}
+
+func (service *Privacy) randomPassword() (string, error) {
+	plain, err := randomHex(erasedSecretBytes)
+	if err != nil {
+		return "", err
+	}
+
+	return service.hashier.Hash(plain)
+}
+
+func contactIds(contacts []domain.Contact) []int64 {
+	ids := make([]int64, 0, len(contacts))
+	for _, c := range contacts {
+		ids = append(ids, c.ID)
+	}
+
+	return ids
+}
+
+func apiKeyIds(keys []domain.APIKey) []int64 {
+	ids := make([]int64, 0, len(keys))
+	for _, k := range keys {
+		ids = append(ids, k.ID)
+	}
+
+	return ids
+}
@@ -0,0 +1,195 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/wilfridterry/contact-list/internal/domain"
+)
+
+// erasableUsers accepts any erasure of the users it knows.
+type erasableUsers struct {
+	memoryUsers
+}
+
+func (erasableUsers) Delete(context.Context, int64, domain.OwnedContactsPolicy) error {
+	return nil
+}
+
+func (erasableUsers) Pseudonymize(context.Context, *domain.User) error {
+	return nil
+}
+
+// ownedContacts are the contacts of every user.
+type ownedContacts struct {
+	ContactRepository
+
+	contacts []domain.Contact
+}
+
+func (m ownedContacts) GetAllByUser(context.Context, int64) ([]domain.Contact, error) {
+	return m.contacts, nil
+}
+
+func (ownedContacts) Delete(context.Context, int64) error {
+	return nil
+}
+
+func (ownedContacts) Pseudonymize(context.Context, *domain.Contact) error {
+	return nil
+}
+
+type noAPIKeys struct {
+	APIKeyRepository
+}
+
+func (noAPIKeys) GetAllByUser(context.Context, int64) ([]domain.APIKey, error) {
+	return nil, nil
+}
+
+type discardJournal struct {
+	AuditJournal
+}
+
+func (discardJournal) DeleteByEntity(context.Context, string, []int64) error {
+	return nil
+}
+
+// plainHashier leaves passwords as they are.
+type plainHashier struct{}
+
+func (plainHashier) Hash(password string) (string, error) {
+	return password, nil
+}
+
+// erasedWebhooks records whose webhooks and deliveries were dropped.
+type erasedWebhooks struct {
+	WebhookRepository
+
+	users    []int64
+	contacts []int64
+}
+
+func (m *erasedWebhooks) DeleteAllByUser(_ context.Context, userId int64) error {
+	m.users = append(m.users, userId)
+
+	return nil
+}
+
+func (m *erasedWebhooks) DeleteDeliveriesByContact(_ context.Context, contactIds []int64) error {
+	m.contacts = append(m.contacts, contactIds...)
+
+	return nil
+}
+
+// erasedIdempotency records whose idempotent responses were dropped.
+type erasedIdempotency struct {
+	IdempotencyRepository
+
+	users    []int64
+	contacts []int64
+}
+
+func (m *erasedIdempotency) DeleteByUser(_ context.Context, userId int64) error {
+	m.users = append(m.users, userId)
+
+	return nil
+}
+
+func (m *erasedIdempotency) DeleteByContacts(_ context.Context, contactIds []int64) error {
+	m.contacts = append(m.contacts, contactIds...)
+
+	return nil
+}
+
+func TestPrivacy_Erase_storedCopies(t *testing.T) {
+	users := erasableUsers{memoryUsers{users: map[int64]*domain.User{
+		1: {ID: 1, Email: "user@test.com", Role: domain.RoleUser},
+	}}}
+	contacts := ownedContacts{contacts: []domain.Contact{{ID: 10}, {ID: 11}}}
+
+	testTable := []struct {
+		name             string
+		erase            func(*Privacy) error
+		expectedWebhooks []int64
+		expectedUsers    []int64
+		expectedContacts []int64
+	}{
+		{
+			name: "User deleted",
+			erase: func(p *Privacy) error {
+				return p.EraseUser(context.Background(), 1, domain.ErasureDelete)
+			},
+			expectedUsers:    []int64{1},
+			expectedContacts: []int64{10, 11},
+		},
+		{
+			name: "User pseudonymized",
+			erase: func(p *Privacy) error {
+				return p.EraseUser(context.Background(), 1, domain.ErasurePseudonymize)
+			},
+			expectedWebhooks: []int64{1},
+			expectedUsers:    []int64{1},
+			expectedContacts: []int64{10, 11},
+		},
+		{
+			name: "Contact deleted",
+			erase: func(p *Privacy) error {
+				return p.EraseContact(context.Background(), 10, domain.ErasureDelete)
+			},
+			expectedContacts: []int64{10},
+		},
+		{
+			name: "Contact pseudonymized",
+			erase: func(p *Privacy) error {
+				return p.EraseContact(context.Background(), 10, domain.ErasurePseudonymize)
+			},
+			expectedContacts: []int64{10},
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			webhooks := &erasedWebhooks{}
+			idempotency := &erasedIdempotency{}
+			privacy := NewPrivacy(users, memorySessions{}, contacts, noAPIKeys{}, memoryAttempts{}, webhooks, idempotency, discardJournal{}, discardAuditLog{}, plainHashier{})
+
+			if err := testCase.erase(privacy); err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(webhooks.users, testCase.expectedWebhooks) {
+				t.Errorf("dropped webhooks of %v, want %v", webhooks.users, testCase.expectedWebhooks)
+			}
+
+			if !reflect.DeepEqual(webhooks.contacts, testCase.expectedContacts) {
+				t.Errorf("dropped deliveries about %v, want %v", webhooks.contacts, testCase.expectedContacts)
+			}
+
+			if !reflect.DeepEqual(idempotency.users, testCase.expectedUsers) {
+				t.Errorf("dropped idempotent responses of %v, want %v", idempotency.users, testCase.expectedUsers)
+			}
+
+			if !reflect.DeepEqual(idempotency.contacts, testCase.expectedContacts) {
+				t.Errorf("dropped idempotent responses about %v, want %v", idempotency.contacts, testCase.expectedContacts)
+			}
+		})
+	}
+}
+
+// failingAuditLog can not publish audit messages.
+type failingAuditLog struct{}
+
+func (failingAuditLog) Log(context.Context, LogMessage) error {
+	return errors.New("broker unreachable")
+}
+
+func TestPrivacy_EraseContact_tombstoneFailed(t *testing.T) {
+	privacy := NewPrivacy(erasableUsers{}, memorySessions{}, ownedContacts{}, noAPIKeys{}, memoryAttempts{}, &erasedWebhooks{}, &erasedIdempotency{}, discardJournal{}, failingAuditLog{}, plainHashier{})
+
+	if err := privacy.EraseContact(context.Background(), 10, domain.ErasureDelete); err == nil {
+		t.Fatal("erasure succeeded without a tombstone")
+	}
+}
@@ -0,0 +1,200 @@
+package service
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/wilfridterry/contact-list/internal/domain"
+	"github.com/wilfridterry/contact-list/pkg/logger"
+	"github.com/wilfridterry/contact-list/pkg/mailer"
+
+	"github.com/sirupsen/logrus"
+)
+
+func (service *Auth) Profile(ctx context.Context, userId int64) (*domain.User, error) {
+	ctx, span := tracer.Start(ctx, "Auth.Profile")
+	defer span.End()
+
+	return service.userRepo.GetById(ctx, userId)
+}
+
+// Users returns the users found among the IDs, unknown ones are left out.
+func (service *Auth) Users(ctx context.Context, ids []int64) ([]domain.User, error) {
+	ctx, span := tracer.Start(ctx, "Auth.Users")
+	defer span.End()
+
+	return service.userRepo.GetByIds(ctx, ids)
+}
+
+// UpdateProfile changes the name and the email of the user. A new email is
+// unverified until the user follows the link sent to it, the previous address
+// is told about the change.
+func (service *Auth) UpdateProfile(ctx context.Context, userId int64, inp *domain.UpdateProfileInput) (*domain.User, error) {
+	ctx, span := tracer.Start(ctx, "Auth.UpdateProfile")
+	defer span.End()
+
+	user, err := service.userRepo.GetById(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	if inp.Name != nil {
+		user.Name = *inp.Name
+	}
+
+	previousEmail := user.Email
+	emailChanged := inp.Email != nil && *inp.Email != user.Email
+
+	if emailChanged {
+		_, err := service.userRepo.GetByEmail(ctx, *inp.Email)
+		if err == nil {
+			return nil, domain.ErrEmailTaken
+		}
+
+		if !errors.Is(err, domain.ErrNotFoundUser) {
+			return nil, err
+		}
+
+		user.Email = *inp.Email
+		user.EmailVerifiedAt = nil
+	}
+
+	if err := service.userRepo.UpdateProfile(ctx, user); err != nil {
+		return nil, err
+	}
+
+	action := ACTION_UPDATE
+	if emailChanged {
+		action = ACTION_CHANGE_EMAIL
+
+		if err := service.sendVerificationEmail(ctx, user); err != nil {
+			logger.FromContext(ctx).WithFields(logrus.Fields{
+				"method": "Users.UpdateProfile",
+			}).Error("failed to send verification email:", err)
+		}
+
+		if err := service.sendEmailChangedNotice(ctx, user, previousEmail); err != nil {
+			logger.FromContext(ctx).WithFields(logrus.Fields{
+				"method": "Users.UpdateProfile",
+			}).Error("failed to send email change notice:", err)
+		}
+	}
+
+	if err := service.auditLog.Log(ctx, LogMessage{
+		Action:    action,
+		Entity:    ENTITY_USER,
+		EntityID:  user.ID,
+		Timestamp: time.Now(),
+	}); err != nil {
+		logger.FromContext(ctx).WithFields(logrus.Fields{
+			"method": "Users.UpdateProfile",
+		}).Error("failed to send log request:", err)
+	}
+
+	return user, nil
+}
+
+// ChangePassword sets a new password when the current one is right. Every
+// session is revoked and the caller gets a fresh pair of tokens, so only the
+// session that changed the password stays signed in.
+func (service *Auth) ChangePassword(ctx context.Context, userId int64, inp *domain.ChangePasswordInput) (string, string, error) {
+	ctx, span := tracer.Start(ctx, "Auth.ChangePassword")
+	defer span.End()
+
+	user, err := service.userRepo.GetById(ctx, userId)
+	if err != nil {
+		return "", "", err
+	}
+
+	if err := service.checkPassword(user, inp.CurrentPassword); err != nil {
+		return "", "", err
+	}
+
+	password, err := service.hashier.Hash(inp.NewPassword)
+	if err != nil {
+		return "", "", err
+	}
+
+	if err := service.userRepo.UpdatePassword(ctx, userId, password); err != nil {
+		return "", "", err
+	}
+
+	if err := service.sessionRepo.DeleteAllByUser(ctx, userId); err != nil {
+		return "", "", err
+	}
+
+	if err := service.auditLog.Log(ctx, LogMessage{
+		Action:    ACTION_CHANGE_PASSWORD,
+		Entity:    ENTITY_USER,
+		EntityID:  userId,
+		Timestamp: time.Now(),
+	}); err != nil {
+		logger.FromContext(ctx).WithFields(logrus.Fields{
+			"method": "Users.ChangePassword",
+		}).Error("failed to send log request:", err)
+	}
+
+	return service.generateTokens(ctx, user)
+}
+
+// DeleteAccount removes the user after the password is confirmed. Sessions,
+// API keys, MFA and linked identities are removed with the user, the owned
+// contacts are deleted or anonymized as asked.
+func (service *Auth) DeleteAccount(ctx context.Context, userId int64, inp *domain.DeleteAccountInput) error {
+	ctx, span := tracer.Start(ctx, "Auth.DeleteAccount")
+	defer span.End()
+
+	user, err := service.userRepo.GetById(ctx, userId)
+	if err != nil {
+		return err
+	}
+
+	if err := service.checkPassword(user, inp.Password); err != nil {
+		return err
+	}
+
+	if err := service.userRepo.Delete(ctx, userId, inp.Contacts); err != nil {
+		return err
+	}
+
+	if err := service.auditLog.Log(ctx, LogMessage{
+		Action:    ACTION_DELETE,
+		Entity:    ENTITY_USER,
+		EntityID:  userId,
+		Timestamp: time.Now(),
+	}); err != nil {
+		logger.FromContext(ctx).WithFields(logrus.Fields{
+			"method": "Users.DeleteAccount",
+		}).Error("failed to send log request:", err)
+	}
+
+	return nil
+}
+
+func (service *Auth) checkPassword(user *domain.User, plain string) error {
+	password, err := service.hashier.Hash(plain)
+	if err != nil {
+		return err
+	}
+
+	if subtle.ConstantTimeCompare([]byte(password), []byte(user.Password)) != 1 {
+		return domain.ErrInvalidPassword
+	}
+
+	return nil
+}
+
+func (service *Auth) sendEmailChangedNotice(ctx context.Context, user *domain.User, previousEmail string) error {
+	return service.mailer.Send(ctx, mailer.Message{
+		To:      previousEmail,
+		Subject: "Your email address was changed",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nthe email address of your account was changed to %s. If you did not do it, reset your password right away.\n",
+			user.Name,
+			user.Email,
+		),
+	})
+}
@@ -0,0 +1,72 @@
+package service
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Purpose tokens are short-lived signed tokens for a single flow (mfa
+// challenge, email verification, password reset, contact stream). Each purpose is signed with
+// its own key derived from the secret, so they can never be accepted as
+// access tokens by ParseJWTToken or used for another flow.
+const (
+	purposeMFAChallenge  = "mfa"
+	purposeVerifyEmail   = "verify-email"
+	purposeResetPassword = "reset-password"
+	purposeStream        = "stream"
+)
+
+var errInvalidPurposeToken = errors.New("invalid purpose token")
+
+type purposeClaims struct {
+	jwt.RegisteredClaims
+	// Fingerprint binds the token to the state it was issued for, e.g. the
+	// last update of the user, so it stops working once that state changes.
+	Fingerprint string `json:"fp,omitempty"`
+}
+
+func (service *Auth) purposeSecret(purpose string) []byte {
+	return append(append([]byte{}, service.hmacSecret...), []byte(":"+purpose)...)
+}
+
+func (service *Auth) newPurposeToken(purpose string, userId int64, fingerprint string, ttl time.Duration) (string, time.Time, error) {
+	expiresAt := time.Now().Add(ttl)
+
+	t := jwt.NewWithClaims(jwt.SigningMethodHS256, purposeClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   strconv.FormatInt(userId, 10),
+			Audience:  jwt.ClaimStrings{purpose},
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+		Fingerprint: fingerprint,
+	})
+
+	token, err := t.SignedString(service.purposeSecret(purpose))
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	return token, expiresAt, nil
+}
+
+func (service *Auth) parsePurposeToken(purpose, token string) (int64, string, error) {
+	claims := &purposeClaims{}
+	_, err := jwt.ParseWithClaims(token, claims, func(token *jwt.Token) (interface{}, error) {
+		return service.purposeSecret(purpose), nil
+	}, jwt.WithAudience(purpose), jwt.WithExpirationRequired(), jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Name}))
+
+	if err != nil {
+		return 0, "", errInvalidPurposeToken
+	}
+
+	userId, err := strconv.ParseInt(claims.Subject, 10, 64)
+	if err != nil {
+		return 0, "", errInvalidPurposeToken
+	}
+
+	return userId, claims.Fingerprint, nil
+}
@@ -0,0 +1,76 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"time"
+
+	"github.com/wilfridterry/contact-list/internal/domain"
+)
+
+const streamTokenTTL = time.Minute
+
+// IssueStreamToken issues the token a browser passes in the query string to
+// open a contact stream or a GraphQL subscription, since EventSource and
+// WebSocket requests can not carry the Authorization header. It only reads
+// contacts and expires quickly as urls end up in browser history and proxy
+// logs.
+func (service *Auth) IssueStreamToken(ctx context.Context, userId int64) (*domain.StreamToken, error) {
+	_, span := tracer.Start(ctx, "Auth.IssueStreamToken")
+	defer span.End()
+
+	token, expiresAt, err := service.newPurposeToken(purposeStream, userId, "", streamTokenTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.StreamToken{Token: token, ExpiresAt: expiresAt}, nil
+}
+
+// ParseStreamToken authenticates a contact stream opened with a stream token.
+// Browsers send the Origin of the page, which must be the client application,
+// so other sites can not open a stream with a token they got hold of.
+func (service *Auth) ParseStreamToken(ctx context.Context, token, origin string) (*domain.Identity, error) {
+	ctx, span := tracer.Start(ctx, "Auth.ParseStreamToken")
+	defer span.End()
+
+	if origin != "" && origin != service.appOrigin() {
+		return nil, domain.ErrStreamOriginForbidden
+	}
+
+	userId, _, err := service.parsePurposeToken(purposeStream, token)
+	if err != nil {
+		return nil, domain.ErrInvalidAccessToken
+	}
+
+	user, err := service.userRepo.GetById(ctx, userId)
+	if errors.Is(err, domain.ErrNotFoundUser) {
+		return nil, domain.ErrInvalidAccessToken
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if user.DisabledAt != nil {
+		return nil, domain.ErrUserDisabled
+	}
+
+	return &domain.Identity{
+		UserID:      user.ID,
+		Role:        user.Role,
+		Permissions: narrowScopes([]domain.Permission{domain.PermissionContactsRead}, user.Role.Permissions()),
+	}, nil
+}
+
+// appOrigin is the origin of the client application, empty when none is
+// configured.
+func (service *Auth) appOrigin() string {
+	u, err := url.Parse(service.appURL)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return ""
+	}
+
+	return u.Scheme + "://" + u.Host
+}
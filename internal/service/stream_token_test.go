@@ -0,0 +1,92 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/wilfridterry/contact-list/internal/domain"
+)
+
+func TestAuth_ParseStreamToken(t *testing.T) {
+	const appURL = "https://app.example.com/contacts"
+
+	disabledAt := time.Now()
+	users := memoryUsers{users: map[int64]*domain.User{
+		1: {ID: 1, Role: domain.RoleUser},
+		2: {ID: 2, Role: domain.RoleUser, DisabledAt: &disabledAt},
+	}}
+
+	auth := New(users, memorySessions{}, nil, nil, nil, nil, nil, nil, AuthConfig{Secret: []byte("secret"), TokenTTL: time.Minute, AppURL: appURL})
+
+	issue := func(userId int64) string {
+		token, err := auth.IssueStreamToken(context.Background(), userId)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return token.Token
+	}
+
+	accessToken, _, err := auth.generateTokens(context.Background(), users.users[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testTable := []struct {
+		name        string
+		token       string
+		origin      string
+		expectedErr error
+	}{
+		{
+			name:   "Same origin",
+			token:  issue(1),
+			origin: "https://app.example.com",
+		},
+		{
+			name:  "Without origin",
+			token: issue(1),
+		},
+		{
+			name:        "Foreign origin",
+			token:       issue(1),
+			origin:      "https://evil.example.com",
+			expectedErr: domain.ErrStreamOriginForbidden,
+		},
+		{
+			name:        "Access token",
+			token:       accessToken,
+			expectedErr: domain.ErrInvalidAccessToken,
+		},
+		{
+			name:        "Disabled user",
+			token:       issue(2),
+			expectedErr: domain.ErrUserDisabled,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			identity, err := auth.ParseStreamToken(context.Background(), testCase.token, testCase.origin)
+			if !errors.Is(err, testCase.expectedErr) {
+				t.Fatalf("got %v, want %v", err, testCase.expectedErr)
+			}
+
+			if err != nil {
+				return
+			}
+
+			if identity.UserID != 1 || !identity.HasPermission(domain.PermissionContactsRead) || identity.HasPermission(domain.PermissionContactsWrite) {
+				t.Errorf("got identity %+v, want user 1 reading contacts only", identity)
+			}
+		})
+	}
+
+	t.Run("Refused as access token", func(t *testing.T) {
+		if _, err := auth.ParseJWTToken(context.Background(), issue(1)); !errors.Is(err, domain.ErrInvalidAccessToken) {
+			t.Fatalf("got %v, want %v", err, domain.ErrInvalidAccessToken)
+		}
+	})
+}
@@ -0,0 +1,7 @@
+package service
+
+import "go.opentelemetry.io/otel"
+
+// tracer starts a span for every service method, below the one of the request
+// and above those of the queries and calls the method makes.
+var tracer = otel.Tracer("github.com/wilfridterry/contact-list/internal/service")
@@ -0,0 +1,428 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/wilfridterry/contact-list/internal/domain"
+	"github.com/wilfridterry/contact-list/pkg/logger"
+	"github.com/wilfridterry/contact-list/pkg/webhook"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	webhookSecretBytes = 32
+	webhookClaimLimit  = 20
+	// webhookLeaseMargin leaves time to record the attempts after the
+	// deliveries of a claim took their longest.
+	webhookLeaseMargin = time.Minute
+
+	defaultWebhookMaxAttempts  = 8
+	defaultWebhookBackoffBase  = 30 * time.Second
+	defaultWebhookBackoffMax   = 6 * time.Hour
+	defaultWebhookPollInterval = 5 * time.Second
+	defaultWebhookTimeout      = 10 * time.Second
+)
+
+type WebhookRepository interface {
+	Create(context.Context, *domain.Webhook) (int64, error)
+	GetById(context.Context, int64) (*domain.Webhook, error)
+	GetByUser(context.Context, int64, int64) (*domain.Webhook, error)
+	GetAllByUser(context.Context, int64) ([]domain.Webhook, error)
+	GetSubscribed(context.Context, string) ([]domain.Webhook, error)
+	Delete(context.Context, int64, int64) error
+	DeleteAllByUser(context.Context, int64) error
+	DeleteDeliveriesByContact(context.Context, []int64) error
+	CreateDelivery(context.Context, *domain.WebhookDelivery) (int64, error)
+	GetDeliveries(context.Context, int64) ([]domain.WebhookDelivery, error)
+	GetDelivery(context.Context, int64, int64) (*domain.WebhookDelivery, error)
+	ClaimDue(context.Context, time.Time, time.Time, int) ([]domain.WebhookDelivery, error)
+	RecordAttempt(context.Context, int64, *domain.WebhookAttempt, string, *time.Time) error
+	Redeliver(context.Context, int64, int64, time.Time) error
+}
+
+type WebhookSender interface {
+	Send(context.Context, webhook.Request) (int, error)
+}
+
+// WebhookConfig controls retries of failed deliveries. The n-th retry waits
+// BackoffBase * 2^(n-1), at most BackoffMax. A delivery is dead after
+// MaxAttempts attempts. Due deliveries are looked up every PollInterval, a
+// single delivery gives up after Timeout.
+type WebhookConfig struct {
+	MaxAttempts  int
+	BackoffBase  time.Duration
+	BackoffMax   time.Duration
+	PollInterval time.Duration
+	Timeout      time.Duration
+}
+
+// lease is how long claimed deliveries are kept from other instances. The
+// deliveries of a claim are sent one after another, so it has to outlast all
+// of them timing out.
+func (cf WebhookConfig) lease() time.Duration {
+	return webhookClaimLimit*cf.Timeout + webhookLeaseMargin
+}
+
+func (cf WebhookConfig) backoff(attempts int) time.Duration {
+	shift := attempts - 1
+	if shift > maxBackoffShift {
+		shift = maxBackoffShift
+	}
+
+	delay := cf.BackoffBase << shift
+	if delay > cf.BackoffMax || delay <= 0 {
+		delay = cf.BackoffMax
+	}
+
+	return delay
+}
+
+// Webhooks manages webhook subscriptions of users and delivers contact events
+// to them. Deliveries are stored before they are sent, so they survive
+// restarts and failing receivers.
+type Webhooks struct {
+	repository   WebhookRepository
+	contactsRepo ContactRepository
+	sender       WebhookSender
+	auditLog     AuditLog
+	cf           WebhookConfig
+}
+
+func NewWebhooks(repository WebhookRepository, contactsRepo ContactRepository, sender WebhookSender, auditLog AuditLog, cf WebhookConfig) *Webhooks {
+	if cf.MaxAttempts <= 0 {
+		cf.MaxAttempts = defaultWebhookMaxAttempts
+	}
+
+	if cf.BackoffBase <= 0 {
+		cf.BackoffBase = defaultWebhookBackoffBase
+	}
+
+	if cf.BackoffMax <= 0 {
+		cf.BackoffMax = defaultWebhookBackoffMax
+	}
+
+	if cf.PollInterval <= 0 {
+		cf.PollInterval = defaultWebhookPollInterval
+	}
+
+	if cf.Timeout <= 0 {
+		cf.Timeout = defaultWebhookTimeout
+	}
+
+	return &Webhooks{
+		repository:   repository,
+		contactsRepo: contactsRepo,
+		sender:       sender,
+		auditLog:     auditLog,
+		cf:           cf,
+	}
+}
+
+// Create registers a webhook of the user and returns it with its secret, a
+// secret is generated unless the input has one.
+func (service *Webhooks) Create(ctx context.Context, userId int64, inp *domain.CreateWebhookInput) (*domain.Webhook, string, error) {
+	ctx, span := tracer.Start(ctx, "Webhooks.Create")
+	defer span.End()
+
+	secret := inp.Secret
+	if secret == "" {
+		var err error
+		if secret, err = randomHex(webhookSecretBytes); err != nil {
+			return nil, "", err
+		}
+	}
+
+	hook := &domain.Webhook{
+		UserID: userId,
+		URL:    inp.URL,
+		Events: inp.Events,
+		Secret: secret,
+	}
+
+	id, err := service.repository.Create(ctx, hook)
+	if err != nil {
+		return nil, "", err
+	}
+
+	hook, err = service.repository.GetById(ctx, id)
+	if err != nil {
+		return nil, "", err
+	}
+
+	service.log(ctx, ACTION_CREATE, id, "Webhooks.Create")
+
+	return hook, secret, nil
+}
+
+func (service *Webhooks) All(ctx context.Context, userId int64) ([]domain.Webhook, error) {
+	ctx, span := tracer.Start(ctx, "Webhooks.All")
+	defer span.End()
+
+	return service.repository.GetAllByUser(ctx, userId)
+}
+
+func (service *Webhooks) Delete(ctx context.Context, userId, id int64) error {
+	ctx, span := tracer.Start(ctx, "Webhooks.Delete")
+	defer span.End()
+
+	if err := service.repository.Delete(ctx, userId, id); err != nil {
+		return err
+	}
+
+	service.log(ctx, ACTION_DELETE, id, "Webhooks.Delete")
+
+	return nil
+}
+
+func (service *Webhooks) Deliveries(ctx context.Context, userId, webhookId int64) ([]domain.WebhookDelivery, error) {
+	ctx, span := tracer.Start(ctx, "Webhooks.Deliveries")
+	defer span.End()
+
+	if _, err := service.repository.GetByUser(ctx, userId, webhookId); err != nil {
+		return nil, err
+	}
+
+	return service.repository.GetDeliveries(ctx, webhookId)
+}
+
+func (service *Webhooks) Delivery(ctx context.Context, userId, webhookId, id int64) (*domain.WebhookDelivery, error) {
+	ctx, span := tracer.Start(ctx, "Webhooks.Delivery")
+	defer span.End()
+
+	if _, err := service.repository.GetByUser(ctx, userId, webhookId); err != nil {
+		return nil, err
+	}
+
+	return service.repository.GetDelivery(ctx, webhookId, id)
+}
+
+// Redeliver sends a delivery again on the next poll, whatever its state.
+func (service *Webhooks) Redeliver(ctx context.Context, userId, webhookId, id int64) error {
+	ctx, span := tracer.Start(ctx, "Webhooks.Redeliver")
+	defer span.End()
+
+	if _, err := service.repository.GetByUser(ctx, userId, webhookId); err != nil {
+		return err
+	}
+
+	return service.repository.Redeliver(ctx, webhookId, id, time.Now())
+}
+
+// Enqueue stores a delivery of the event for every webhook subscribed to it.
+func (service *Webhooks) Enqueue(ctx context.Context, event domain.ContactEvent) error {
+	ctx, span := tracer.Start(ctx, "Webhooks.Enqueue")
+	defer span.End()
+
+	hooks, err := service.repository.GetSubscribed(ctx, event.Type)
+	if err != nil || len(hooks) == 0 {
+		return err
+	}
+
+	payload := domain.WebhookPayload{
+		EventID:   event.ID,
+		Type:      event.Type,
+		ContactID: event.ContactID,
+		Time:      event.Time,
+	}
+
+	if event.Type != domain.ContactDeleted {
+		// The contact may be gone already, the event is delivered anyway.
+		if contact, err := service.contactsRepo.GetById(ctx, event.ContactID); err == nil {
+			payload.Contact = contact
+		}
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, hook := range hooks {
+		if _, err := service.repository.CreateDelivery(ctx, &domain.WebhookDelivery{
+			WebhookID:     hook.ID,
+			Event:         event.Type,
+			Payload:       body,
+			Status:        domain.DeliveryPending,
+			NextAttemptAt: &now,
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Run enqueues deliveries of the events published on the bus and sends due
+// deliveries until ctx is done. Events published while the process is down
+// are not delivered. It returns once it stopped using the repository.
+func (service *Webhooks) Run(ctx context.Context, events ContactEventBus) {
+	consumed := make(chan struct{})
+	go func() {
+		defer close(consumed)
+		service.consume(ctx, events)
+	}()
+	defer func() { <-consumed }()
+
+	ticker := time.NewTicker(service.cf.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := service.DeliverDue(ctx); err != nil {
+				logger.FromContext(ctx).WithFields(logrus.Fields{
+					"method": "Webhooks.Run",
+				}).Error("failed to deliver webhooks:", err)
+			}
+		}
+	}
+}
+
+// consume subscribes again after being dropped for falling behind and goes
+// on after the last enqueued event.
+func (service *Webhooks) consume(ctx context.Context, events ContactEventBus) {
+	var lastID int64
+
+	for ctx.Err() == nil {
+		sub := events.Subscribe(lastID)
+		if sub.Reset {
+			logger.FromContext(ctx).WithFields(logrus.Fields{
+				"method":        "Webhooks.consume",
+				"last_event_id": lastID,
+			}).Warn("contact events were lost before webhook deliveries were stored")
+		}
+
+		for _, event := range sub.Missed {
+			service.enqueue(ctx, event)
+			lastID = event.ID
+		}
+
+		lastID = service.consumeSubscription(ctx, sub, lastID)
+		sub.Close()
+	}
+}
+
+func (service *Webhooks) consumeSubscription(ctx context.Context, sub *domain.ContactSubscription, lastID int64) int64 {
+	for {
+		select {
+		case <-ctx.Done():
+			return lastID
+		case event, ok := <-sub.Events:
+			if !ok {
+				return lastID
+			}
+
+			service.enqueue(ctx, event)
+			lastID = event.ID
+		}
+	}
+}
+
+func (service *Webhooks) enqueue(ctx context.Context, event domain.ContactEvent) {
+	if err := service.Enqueue(ctx, event); err != nil {
+		logger.FromContext(ctx).WithFields(logrus.Fields{
+			"method":   "Webhooks.Enqueue",
+			"event_id": event.ID,
+		}).Error("failed to enqueue webhook deliveries:", err)
+	}
+}
+
+// DeliverDue sends the deliveries which are due and returns how many were
+// attempted.
+func (service *Webhooks) DeliverDue(ctx context.Context) (int, error) {
+	ctx, span := tracer.Start(ctx, "Webhooks.DeliverDue")
+	defer span.End()
+
+	now := time.Now()
+
+	deliveries, err := service.repository.ClaimDue(ctx, now, now.Add(service.cf.lease()), webhookClaimLimit)
+	if err != nil {
+		return 0, err
+	}
+
+	hooks := make(map[int64]*domain.Webhook)
+	for _, delivery := range deliveries {
+		hook, ok := hooks[delivery.WebhookID]
+		if !ok {
+			hook, err = service.repository.GetById(ctx, delivery.WebhookID)
+			if errors.Is(err, domain.ErrWebhookNotFound) {
+				// Deleted meanwhile, its deliveries are gone with it.
+				continue
+			}
+
+			if err != nil {
+				return 0, err
+			}
+			hooks[delivery.WebhookID] = hook
+		}
+
+		if err := service.deliver(ctx, hook, &delivery); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(deliveries), nil
+}
+
+func (service *Webhooks) deliver(ctx context.Context, hook *domain.Webhook, delivery *domain.WebhookDelivery) error {
+	started := time.Now()
+
+	// The sender may not time out by itself, the lease counts on it.
+	sendCtx, cancel := context.WithTimeout(ctx, service.cf.Timeout)
+	defer cancel()
+
+	statusCode, err := service.sender.Send(sendCtx, webhook.Request{
+		URL:        hook.URL,
+		Secret:     hook.Secret,
+		DeliveryID: delivery.ID,
+		Event:      delivery.Event,
+		Payload:    delivery.Payload,
+	})
+
+	attempt := &domain.WebhookAttempt{
+		StatusCode:  statusCode,
+		Duration:    time.Since(started).Milliseconds(),
+		AttemptedAt: started,
+	}
+
+	switch {
+	case err != nil:
+		attempt.Error = err.Error()
+	case statusCode < http.StatusOK || statusCode >= http.StatusMultipleChoices:
+		attempt.Error = fmt.Sprintf("unexpected status %d", statusCode)
+	default:
+		return service.repository.RecordAttempt(ctx, delivery.ID, attempt, domain.DeliverySucceeded, nil)
+	}
+
+	attempts := delivery.Attempts + 1
+	if attempts >= service.cf.MaxAttempts {
+		return service.repository.RecordAttempt(ctx, delivery.ID, attempt, domain.DeliveryDead, nil)
+	}
+
+	next := time.Now().Add(service.cf.backoff(attempts))
+
+	return service.repository.RecordAttempt(ctx, delivery.ID, attempt, domain.DeliveryPending, &next)
+}
+
+func (service *Webhooks) log(ctx context.Context, act action, id int64, method string) {
+	if err := service.auditLog.Log(ctx, LogMessage{
+		Action:    act,
+		Entity:    ENTITY_WEBHOOK,
+		EntityID:  id,
+		ActorID:   domain.ImpersonatorFromContext(ctx),
+		Timestamp: time.Now(),
+	}); err != nil {
+		logger.FromContext(ctx).WithFields(logrus.Fields{
+			"method": method,
+		}).Error("failed to send log request:", err)
+	}
+}
@@ -0,0 +1,242 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/wilfridterry/contact-list/internal/domain"
+	"github.com/wilfridterry/contact-list/pkg/webhook"
+)
+
+// memoryWebhooks keeps a single webhook and its deliveries in memory.
+type memoryWebhooks struct {
+	WebhookRepository
+
+	hook       domain.Webhook
+	deliveries map[int64]*domain.WebhookDelivery
+	attempts   map[int64][]domain.WebhookAttempt
+}
+
+func (m *memoryWebhooks) GetById(_ context.Context, id int64) (*domain.Webhook, error) {
+	if id != m.hook.ID {
+		return nil, domain.ErrWebhookNotFound
+	}
+
+	return &m.hook, nil
+}
+
+func (m *memoryWebhooks) GetSubscribed(_ context.Context, event string) ([]domain.Webhook, error) {
+	if !m.hook.Subscribed(event) {
+		return nil, nil
+	}
+
+	return []domain.Webhook{m.hook}, nil
+}
+
+func (m *memoryWebhooks) CreateDelivery(_ context.Context, delivery *domain.WebhookDelivery) (int64, error) {
+	d := *delivery
+	d.ID = int64(len(m.deliveries) + 1)
+	m.deliveries[d.ID] = &d
+
+	return d.ID, nil
+}
+
+func (m *memoryWebhooks) ClaimDue(_ context.Context, now, leaseUntil time.Time, limit int) ([]domain.WebhookDelivery, error) {
+	due := make([]domain.WebhookDelivery, 0)
+	for _, d := range m.deliveries {
+		if d.Status == domain.DeliveryPending && !d.NextAttemptAt.After(now) && len(due) < limit {
+			d.NextAttemptAt = &leaseUntil
+			due = append(due, *d)
+		}
+	}
+
+	return due, nil
+}
+
+func (m *memoryWebhooks) RecordAttempt(_ context.Context, id int64, attempt *domain.WebhookAttempt, status string, next *time.Time) error {
+	d := m.deliveries[id]
+	d.Status = status
+	d.Attempts++
+	d.NextAttemptAt = next
+	m.attempts[id] = append(m.attempts[id], *attempt)
+
+	return nil
+}
+
+type memoryContacts struct {
+	ContactRepository
+}
+
+func (memoryContacts) GetById(_ context.Context, id int64) (*domain.Contact, error) {
+	return &domain.Contact{ID: id, Name: "John"}, nil
+}
+
+func TestWebhooks_DeliverDue(t *testing.T) {
+	tests := []struct {
+		name         string
+		statuses     []int
+		polls        int
+		wantStatus   string
+		wantAttempts int
+		wantNext     bool
+	}{
+		{
+			name:         "delivered",
+			statuses:     []int{http.StatusOK},
+			polls:        1,
+			wantStatus:   domain.DeliverySucceeded,
+			wantAttempts: 1,
+		},
+		{
+			name:         "retried after failure",
+			statuses:     []int{http.StatusInternalServerError, http.StatusNoContent},
+			polls:        2,
+			wantStatus:   domain.DeliverySucceeded,
+			wantAttempts: 2,
+		},
+		{
+			name:         "waits for the next attempt",
+			statuses:     []int{http.StatusBadGateway},
+			polls:        1,
+			wantStatus:   domain.DeliveryPending,
+			wantAttempts: 1,
+			wantNext:     true,
+		},
+		{
+			name:         "dead after max attempts",
+			statuses:     []int{http.StatusInternalServerError, http.StatusInternalServerError, http.StatusInternalServerError},
+			polls:        4,
+			wantStatus:   domain.DeliveryDead,
+			wantAttempts: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var received []*http.Request
+			var bodies [][]byte
+			receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var payload json.RawMessage
+				_ = json.NewDecoder(r.Body).Decode(&payload)
+
+				received = append(received, r)
+				bodies = append(bodies, payload)
+				w.WriteHeader(tt.statuses[len(received)-1])
+			}))
+			defer receiver.Close()
+
+			repo := &memoryWebhooks{
+				hook:       domain.Webhook{ID: 1, URL: receiver.URL, Events: []string{domain.ContactCreated}, Secret: "secret"},
+				deliveries: map[int64]*domain.WebhookDelivery{},
+				attempts:   map[int64][]domain.WebhookAttempt{},
+			}
+
+			service := NewWebhooks(repo, memoryContacts{}, webhook.NewClient(webhook.Config{AllowPrivateNetworks: true}), nil, WebhookConfig{
+				MaxAttempts: 3,
+				BackoffBase: time.Hour,
+			})
+
+			ctx := context.Background()
+			if err := service.Enqueue(ctx, domain.ContactEvent{ID: 5, Type: domain.ContactCreated, ContactID: 9}); err != nil {
+				t.Fatalf("Enqueue() error = %v", err)
+			}
+
+			if err := service.Enqueue(ctx, domain.ContactEvent{ID: 6, Type: domain.ContactDeleted, ContactID: 9}); err != nil {
+				t.Fatalf("Enqueue() error = %v", err)
+			}
+
+			if len(repo.deliveries) != 1 {
+				t.Fatalf("Enqueue() stored %d deliveries, want 1", len(repo.deliveries))
+			}
+
+			delivery := repo.deliveries[1]
+			for i := 0; i < tt.polls; i++ {
+				if _, err := service.DeliverDue(ctx); err != nil {
+					t.Fatalf("DeliverDue() error = %v", err)
+				}
+
+				// Skip the backoff.
+				if delivery.Status == domain.DeliveryPending && !tt.wantNext {
+					now := time.Now()
+					delivery.NextAttemptAt = &now
+				}
+			}
+
+			if delivery.Status != tt.wantStatus {
+				t.Errorf("DeliverDue() status = %s, want %s", delivery.Status, tt.wantStatus)
+			}
+
+			if delivery.Attempts != tt.wantAttempts || len(received) != tt.wantAttempts || len(repo.attempts[1]) != tt.wantAttempts {
+				t.Errorf("DeliverDue() attempts = %d, sent %d, logged %d, want %d", delivery.Attempts, len(received), len(repo.attempts[1]), tt.wantAttempts)
+			}
+
+			if tt.wantNext && (delivery.NextAttemptAt == nil || time.Until(*delivery.NextAttemptAt) < 59*time.Minute) {
+				t.Errorf("DeliverDue() next attempt = %v, want in an hour", delivery.NextAttemptAt)
+			}
+
+			last := received[len(received)-1]
+			timestamp, _ := strconv.ParseInt(last.Header.Get(webhook.TimestampHeader), 10, 64)
+			if !webhook.Verify("secret", timestamp, bodies[len(bodies)-1], last.Header.Get(webhook.SignatureHeader)) {
+				t.Error("DeliverDue() sent a payload with an invalid signature")
+			}
+
+			var payload domain.WebhookPayload
+			_ = json.Unmarshal(bodies[0], &payload)
+			if payload.EventID != 5 || payload.Type != domain.ContactCreated || payload.Contact == nil || payload.Contact.Name != "John" {
+				t.Errorf("DeliverDue() payload = %s", bodies[0])
+			}
+		})
+	}
+}
+
+// stalledSender never gets an answer, it waits until the send is given up.
+type stalledSender struct {
+	leases []time.Time
+	repo   *memoryWebhooks
+}
+
+func (s *stalledSender) Send(ctx context.Context, req webhook.Request) (int, error) {
+	s.leases = append(s.leases, *s.repo.deliveries[req.DeliveryID].NextAttemptAt)
+	<-ctx.Done()
+
+	return 0, ctx.Err()
+}
+
+func TestWebhooks_DeliverDue_lease(t *testing.T) {
+	repo := &memoryWebhooks{
+		hook:       domain.Webhook{ID: 1, URL: "https://example.com/hook", Events: []string{domain.ContactCreated}},
+		deliveries: map[int64]*domain.WebhookDelivery{},
+		attempts:   map[int64][]domain.WebhookAttempt{},
+	}
+	sender := &stalledSender{repo: repo}
+
+	timeout := 10 * time.Millisecond
+	service := NewWebhooks(repo, memoryContacts{}, sender, nil, WebhookConfig{Timeout: timeout})
+
+	ctx := context.Background()
+	if err := service.Enqueue(ctx, domain.ContactEvent{ID: 5, Type: domain.ContactCreated, ContactID: 9}); err != nil {
+		t.Fatalf("Enqueue() error = %v", err)
+	}
+
+	started := time.Now()
+	if _, err := service.DeliverDue(ctx); err != nil {
+		t.Fatalf("DeliverDue() error = %v", err)
+	}
+
+	if len(sender.leases) != 1 {
+		t.Fatalf("DeliverDue() sent %d deliveries, want 1", len(sender.leases))
+	}
+
+	if sender.leases[0].Sub(started) < webhookClaimLimit*timeout {
+		t.Errorf("DeliverDue() leased until %v, shorter than %d timeouts", sender.leases[0].Sub(started), webhookClaimLimit)
+	}
+
+	if attempts := repo.attempts[1]; len(attempts) != 1 || attempts[0].Error == "" {
+		t.Errorf("DeliverDue() attempts = %v, want a timed out attempt", attempts)
+	}
+}
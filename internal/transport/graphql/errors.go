@@ -0,0 +1,37 @@
+package graphql_api
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/wilfridterry/contact-list/internal/apperror"
+	"github.com/wilfridterry/contact-list/pkg/logger"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+)
+
+// reportErrors replaces the errors of the resolvers with the application
+// errors they map to, so the message is safe to show and the extensions carry
+// the same code a REST problem has. Query errors are left as they are.
+func reportErrors(ctx context.Context, result *graphql.Result) {
+	for i, formatted := range result.Errors {
+		located, ok := formatted.OriginalError().(*gqlerrors.Error)
+		if !ok || located.OriginalError == nil {
+			continue
+		}
+
+		appErr := apperror.From(located.OriginalError)
+		if appErr.Status >= http.StatusInternalServerError {
+			logger.FromContext(ctx).WithField("path", formatted.Path).Error("graphql resolver failed:", located.OriginalError)
+		}
+
+		extensions := map[string]any{"code": appErr.Code}
+		if len(appErr.Fields) > 0 {
+			extensions["fields"] = appErr.Fields
+		}
+
+		result.Errors[i].Message = appErr.Message
+		result.Errors[i].Extensions = extensions
+	}
+}
@@ -0,0 +1,149 @@
+package graphql_api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/wilfridterry/contact-list/internal/domain"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+//go:generate mockgen -source=handler.go -destination=mocks/mock.go
+
+type Contacts interface {
+	All(context.Context) ([]domain.Contact, error)
+	GetOne(context.Context, int64) (*domain.Contact, error)
+	Create(context.Context, *domain.SaveInputContact) (int64, error)
+	Update(context.Context, int64, *domain.SaveInputContact) error
+	Delete(context.Context, int64) error
+	Search(context.Context, *domain.ContactSearch) ([]domain.Contact, error)
+	Subscribe(int64) *domain.ContactSubscription
+}
+
+type Users interface {
+	Profile(context.Context, int64) (*domain.User, error)
+	Users(context.Context, []int64) ([]domain.User, error)
+}
+
+type History interface {
+	ContactHistory(context.Context, []int64) ([]domain.AuditEntry, error)
+}
+
+// Handler serves GraphQL queries and mutations over HTTP and subscriptions
+// over WebSocket. It expects the caller to be authenticated already, see
+// domain.WithIdentity.
+type Handler struct {
+	schema  graphql.Schema
+	users   Users
+	history History
+}
+
+func NewHandler(contacts Contacts, users Users, history History) (*Handler, error) {
+	schema, err := newSchema(&resolver{contactService: contacts, userService: users})
+	if err != nil {
+		return nil, err
+	}
+
+	return &Handler{schema: schema, users: users, history: history}, nil
+}
+
+type request struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName"`
+	Variables     map[string]any `json:"variables"`
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+		h.serveWebSocket(w, r)
+		return
+	}
+
+	var req request
+
+	switch r.Method {
+	case http.MethodGet:
+		req.Query = r.URL.Query().Get("query")
+		req.OperationName = r.URL.Query().Get("operationName")
+		if variables := r.URL.Query().Get("variables"); variables != "" {
+			if err := json.Unmarshal([]byte(variables), &req.Variables); err != nil {
+				writeErrors(w, http.StatusBadRequest, gqlerrors.NewFormattedError("variables must be a JSON object"))
+				return
+			}
+		}
+	case http.MethodPost:
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeErrors(w, http.StatusBadRequest, gqlerrors.NewFormattedError("malformed request"))
+			return
+		}
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		writeErrors(w, http.StatusMethodNotAllowed, gqlerrors.NewFormattedError("method not allowed"))
+		return
+	}
+
+	op, err := h.prepare(&req)
+	if err != nil {
+		writeErrors(w, http.StatusBadRequest, gqlerrors.FormatError(err))
+		return
+	}
+
+	// A GET request may be sent by a link on another site, it must not change
+	// anything.
+	if r.Method == http.MethodGet && op.Operation != ast.OperationTypeQuery {
+		w.Header().Set("Allow", "POST")
+		writeErrors(w, http.StatusMethodNotAllowed, gqlerrors.NewFormattedError(op.Operation+" operations must be sent with POST"))
+		return
+	}
+
+	if op.Operation == ast.OperationTypeSubscription {
+		writeErrors(w, http.StatusBadRequest, gqlerrors.NewFormattedError("subscriptions are served over WebSocket"))
+		return
+	}
+
+	result := graphql.Do(graphql.Params{
+		Schema:         h.schema,
+		RequestString:  req.Query,
+		VariableValues: req.Variables,
+		OperationName:  req.OperationName,
+		Context:        withLoaders(r.Context(), h.users, h.history),
+	})
+	reportErrors(r.Context(), result)
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+// prepare returns the operation of the request to run, unless the query does
+// not parse or goes over the limits.
+func (h *Handler) prepare(req *request) (*ast.OperationDefinition, error) {
+	doc, err := parse(req.Query)
+	if err != nil {
+		return nil, err
+	}
+
+	op, err := operation(doc, req.OperationName)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkLimits(h.schema, doc, op, req.Variables); err != nil {
+		return nil, err
+	}
+
+	return op, nil
+}
+
+func writeErrors(w http.ResponseWriter, status int, errs ...gqlerrors.FormattedError) {
+	writeJSON(w, status, &graphql.Result{Errors: errs})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
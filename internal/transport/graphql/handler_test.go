@@ -0,0 +1,244 @@
+package graphql_api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/magiconair/properties/assert"
+	"github.com/wilfridterry/contact-list/internal/domain"
+	mock_graphql_api "github.com/wilfridterry/contact-list/internal/transport/graphql/mocks"
+	"go.uber.org/mock/gomock"
+	"golang.org/x/net/websocket"
+)
+
+var (
+	testUser     = &domain.Identity{UserID: 1, Permissions: domain.RoleUser.Permissions()}
+	testReadOnly = &domain.Identity{UserID: 1, Permissions: []domain.Permission{domain.PermissionContactsRead}}
+	testTime     = time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+)
+
+type mocks struct {
+	contacts *mock_graphql_api.MockContacts
+	users    *mock_graphql_api.MockUsers
+	history  *mock_graphql_api.MockHistory
+}
+
+func newTestHandler(t *testing.T, c *gomock.Controller) (*Handler, *mocks) {
+	m := &mocks{
+		contacts: mock_graphql_api.NewMockContacts(c),
+		users:    mock_graphql_api.NewMockUsers(c),
+		history:  mock_graphql_api.NewMockHistory(c),
+	}
+
+	h, err := NewHandler(m.contacts, m.users, m.history)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return h, m
+}
+
+func TestHandler_ServeHTTP(t *testing.T) {
+	type mockBehavior func(m *mocks)
+
+	ownerId, otherId := int64(1), int64(2)
+
+	testTable := []struct {
+		name                 string
+		method               string
+		identity             *domain.Identity
+		query                string
+		variables            map[string]any
+		mockBehavior         mockBehavior
+		expectedStatusCode   int
+		expectedResponseBody string
+	}{
+		{
+			name:     "Owners and history in one batch each",
+			method:   http.MethodPost,
+			identity: testUser,
+			query:    `{ contacts { id lastName owner { name email } history { action actorId } } }`,
+			mockBehavior: func(m *mocks) {
+				m.contacts.EXPECT().All(gomock.Any()).Return([]domain.Contact{
+					{ID: 10, LastName: "Doe", UserID: &ownerId},
+					{ID: 11, LastName: "Roe", UserID: &otherId},
+					{ID: 12, LastName: "Poe", UserID: &ownerId},
+				}, nil)
+				m.users.EXPECT().Users(gomock.Any(), []int64{1, 2}).Return([]domain.User{
+					{ID: 1, Name: "Jane", Email: "jane@example.com"},
+					{ID: 2, Name: "John", Email: "john@example.com"},
+				}, nil)
+				m.history.EXPECT().ContactHistory(gomock.Any(), []int64{10, 11, 12}).Return([]domain.AuditEntry{
+					{EntityID: 10, Action: "CREATE"},
+					{EntityID: 10, Action: "UPDATE", ActorID: 5},
+				}, nil)
+			},
+			expectedStatusCode:   200,
+			expectedResponseBody: `{"data":{"contacts":[{"history":[{"action":"CREATE","actorId":null},{"action":"UPDATE","actorId":"5"}],"id":"10","lastName":"Doe","owner":{"email":"jane@example.com","name":"Jane"}},{"history":[],"id":"11","lastName":"Roe","owner":{"email":null,"name":"John"}},{"history":[],"id":"12","lastName":"Poe","owner":{"email":"jane@example.com","name":"Jane"}}]}}`,
+		},
+		{
+			name:     "Missing contact",
+			method:   http.MethodGet,
+			identity: testUser,
+			query:    `{ contact(id: 5) { id } }`,
+			mockBehavior: func(m *mocks) {
+				m.contacts.EXPECT().GetOne(gomock.Any(), int64(5)).Return(nil, domain.ErrContactNotFound)
+			},
+			expectedStatusCode:   200,
+			expectedResponseBody: `{"data":{"contact":null}}`,
+		},
+		{
+			name:                 "Unauthenticated",
+			method:               http.MethodPost,
+			query:                `{ contacts { id } }`,
+			mockBehavior:         func(m *mocks) {},
+			expectedStatusCode:   200,
+			expectedResponseBody: `{"data":null,"errors":[{"message":"unauthenticated","locations":[{"line":1,"column":3}],"path":["contacts"],"extensions":{"code":"unauthenticated"}}]}`,
+		},
+		{
+			name:                 "Missing permission",
+			method:               http.MethodPost,
+			identity:             testReadOnly,
+			query:                `mutation { deleteContact(id: "3") }`,
+			mockBehavior:         func(m *mocks) {},
+			expectedStatusCode:   200,
+			expectedResponseBody: `{"data":null,"errors":[{"message":"permission denied: contacts:write","locations":[{"line":1,"column":12}],"path":["deleteContact"],"extensions":{"code":"missing_permission"}}]}`,
+		},
+		{
+			name:                 "Invalid input",
+			method:               http.MethodPost,
+			identity:             testUser,
+			query:                `mutation($input: ContactInput!) { createContact(input: $input) { id } }`,
+			variables:            map[string]any{"input": map[string]any{"name": "Jane", "lastName": "Doe", "phone": "123", "email": "jane@example.com", "address": "Kyiv", "author": "John"}},
+			mockBehavior:         func(m *mocks) {},
+			expectedStatusCode:   200,
+			expectedResponseBody: `{"data":null,"errors":[{"message":"request validation failed","locations":[{"line":1,"column":35}],"path":["createContact"],"extensions":{"code":"validation_failed","fields":[{"field":"Phone","code":"e164","message":"is invalid"}]}}]}`,
+		},
+		{
+			name:                 "Mutation over GET",
+			method:               http.MethodGet,
+			identity:             testUser,
+			query:                `mutation { deleteContact(id: "3") }`,
+			mockBehavior:         func(m *mocks) {},
+			expectedStatusCode:   405,
+			expectedResponseBody: `{"data":null,"errors":[{"message":"mutation operations must be sent with POST","locations":[]}]}`,
+		},
+		{
+			name:                 "Too deep",
+			method:               http.MethodPost,
+			identity:             testUser,
+			query:                `{ a { b { c { d { e { f { g { h { i } } } } } } } } }`,
+			mockBehavior:         func(m *mocks) {},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"data":null,"errors":[{"message":"query is nested 9 levels deep, at most 8 are allowed","locations":[]}]}`,
+		},
+		{
+			name:                 "Too complex",
+			method:               http.MethodPost,
+			identity:             testUser,
+			query:                `query($n: Int) { a: searchContacts(query: "a", limit: $n) { ...c } b: searchContacts(query: "b", limit: 100) { ...c } } fragment c on Contact { id history { action timestamp } }`,
+			variables:            map[string]any{"n": 100},
+			mockBehavior:         func(m *mocks) {},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"data":null,"errors":[{"message":"query complexity 8402 exceeds the limit of 5000","locations":[]}]}`,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			handler, m := newTestHandler(t, c)
+			testCase.mockBehavior(m)
+
+			var req *http.Request
+			if testCase.method == http.MethodGet {
+				req = httptest.NewRequest(http.MethodGet, "/graphql?query="+url.QueryEscape(testCase.query), nil)
+			} else {
+				body, _ := json.Marshal(request{Query: testCase.query, Variables: testCase.variables})
+				req = httptest.NewRequest(http.MethodPost, "/graphql", bytes.NewReader(body))
+			}
+
+			if testCase.identity != nil {
+				req = req.WithContext(domain.WithIdentity(req.Context(), testCase.identity))
+			}
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			assert.Equal(t, w.Code, testCase.expectedStatusCode)
+			assert.Equal(t, strings.TrimSpace(w.Body.String()), testCase.expectedResponseBody)
+		})
+	}
+}
+
+func TestHandler_subscription(t *testing.T) {
+	c := gomock.NewController(t)
+	defer c.Finish()
+
+	handler, m := newTestHandler(t, c)
+
+	events := make(chan domain.ContactEvent, 1)
+	closed := make(chan struct{})
+
+	m.contacts.EXPECT().Subscribe(int64(3)).Return(&domain.ContactSubscription{
+		Missed: []domain.ContactEvent{{ID: 4, Type: domain.ContactUpdated, ContactID: 7, Time: testTime}},
+		Reset:  true,
+		Events: events,
+		Close:  func() { close(closed) },
+	})
+	m.contacts.EXPECT().GetOne(gomock.Any(), int64(7)).Return(&domain.Contact{ID: 7, Name: "Jane"}, nil)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler.ServeHTTP(w, r.WithContext(domain.WithIdentity(r.Context(), testUser)))
+	}))
+	defer server.Close()
+
+	config, _ := websocket.NewConfig("ws"+strings.TrimPrefix(server.URL, "http")+"/graphql", server.URL)
+	config.Protocol = []string{transportWSProtocol}
+
+	ws, err := websocket.DialConfig(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ws.Close()
+
+	send := func(msg string) {
+		if err := websocket.Message.Send(ws, msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	receive := func() string {
+		var msg string
+		if err := websocket.Message.Receive(ws, &msg); err != nil {
+			t.Fatal(err)
+		}
+		return msg
+	}
+
+	send(`{"type":"connection_init"}`)
+	assert.Equal(t, receive(), `{"type":"connection_ack"}`)
+
+	send(`{"id":"1","type":"subscribe","payload":{"query":"subscription { contactChanged(lastEventId: \"3\") { id type contactId contact { name } } }"}}`)
+	assert.Equal(t, receive(), `{"id":"1","type":"next","payload":{"data":{"contactChanged":{"contact":null,"contactId":null,"id":"0","type":"RESET"}}}}`)
+	assert.Equal(t, receive(), `{"id":"1","type":"next","payload":{"data":{"contactChanged":{"contact":{"name":"Jane"},"contactId":"7","id":"4","type":"UPDATED"}}}}`)
+
+	events <- domain.ContactEvent{ID: 5, Type: domain.ContactDeleted, ContactID: 7, Time: testTime}
+	assert.Equal(t, receive(), `{"id":"1","type":"next","payload":{"data":{"contactChanged":{"contact":null,"contactId":"7","id":"5","type":"DELETED"}}}}`)
+
+	send(`{"id":"1","type":"complete"}`)
+
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		t.Fatal("subscription was not closed")
+	}
+}
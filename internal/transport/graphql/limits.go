@@ -0,0 +1,191 @@
+package graphql_api
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/graphql/language/source"
+)
+
+const (
+	maxDepth      = 8
+	maxComplexity = 5000
+
+	// defaultListSize is the number of items assumed for a list field without
+	// a limit argument.
+	defaultListSize = 20
+)
+
+func parse(query string) (*ast.Document, error) {
+	return parser.Parse(parser.ParseParams{
+		Source: source.NewSource(&source.Source{Body: []byte(query), Name: "GraphQL request"}),
+	})
+}
+
+// operation picks the operation to run, the only one the document has unless
+// a name is given.
+func operation(doc *ast.Document, name string) (*ast.OperationDefinition, error) {
+	var found *ast.OperationDefinition
+
+	for _, def := range doc.Definitions {
+		op, ok := def.(*ast.OperationDefinition)
+		if !ok {
+			continue
+		}
+
+		if name == "" {
+			if found != nil {
+				return nil, gqlerrors.NewFormattedError("operationName is required when the document has several operations")
+			}
+			found = op
+		} else if op.Name != nil && op.Name.Value == name {
+			found = op
+		}
+	}
+
+	if found == nil {
+		return nil, gqlerrors.NewFormattedError("no operation to run")
+	}
+
+	return found, nil
+}
+
+// checkLimits refuses operations nested deeper than maxDepth or costing more
+// than maxComplexity. Every field costs one, the fields below a list cost as
+// much times as the list is long. Introspection is free.
+func checkLimits(schema graphql.Schema, doc *ast.Document, op *ast.OperationDefinition, variables map[string]any) error {
+	m := measurer{
+		fragments: make(map[string]*ast.FragmentDefinition),
+		visiting:  make(map[string]bool),
+		schema:    schema,
+		variables: variables,
+	}
+
+	for _, def := range doc.Definitions {
+		if f, ok := def.(*ast.FragmentDefinition); ok {
+			m.fragments[f.Name.Value] = f
+		}
+	}
+
+	var root *graphql.Object
+	switch op.Operation {
+	case ast.OperationTypeQuery:
+		root = schema.QueryType()
+	case ast.OperationTypeMutation:
+		root = schema.MutationType()
+	case ast.OperationTypeSubscription:
+		root = schema.SubscriptionType()
+	}
+
+	depth, complexity := m.measure(op.SelectionSet, root, 1)
+
+	if depth > maxDepth {
+		return gqlerrors.NewFormattedError(fmt.Sprintf("query is nested %d levels deep, at most %d are allowed", depth, maxDepth))
+	}
+
+	if complexity > maxComplexity {
+		return gqlerrors.NewFormattedError(fmt.Sprintf("query complexity %d exceeds the limit of %d", complexity, maxComplexity))
+	}
+
+	return nil
+}
+
+type measurer struct {
+	fragments map[string]*ast.FragmentDefinition
+	visiting  map[string]bool
+	schema    graphql.Schema
+	variables map[string]any
+}
+
+// measure returns the depth and the complexity of the selection set of a
+// field of the type at the depth.
+func (m *measurer) measure(set *ast.SelectionSet, parent graphql.Type, depth int) (int, int) {
+	if set == nil {
+		return depth - 1, 0
+	}
+
+	maxDepth, complexity := depth, 0
+
+	for _, selection := range set.Selections {
+		switch s := selection.(type) {
+		case *ast.Field:
+			if strings.HasPrefix(s.Name.Value, "__") {
+				continue
+			}
+
+			items, fieldType := 1, graphql.Type(nil)
+			if obj, ok := parent.(*graphql.Object); ok {
+				if def, ok := obj.Fields()[s.Name.Value]; ok {
+					fieldType = unwrapNonNull(def.Type)
+					if list, ok := fieldType.(*graphql.List); ok {
+						items, fieldType = m.listSize(s), unwrapNonNull(list.OfType)
+					}
+				}
+			}
+
+			d, c := m.measure(s.SelectionSet, fieldType, depth+1)
+			maxDepth = max(maxDepth, d)
+			complexity += 1 + items*c
+		case *ast.InlineFragment:
+			fragmentType := parent
+			if s.TypeCondition != nil {
+				fragmentType = m.schema.Type(s.TypeCondition.Name.Value)
+			}
+
+			d, c := m.measure(s.SelectionSet, fragmentType, depth)
+			maxDepth = max(maxDepth, d)
+			complexity += c
+		case *ast.FragmentSpread:
+			name := s.Name.Value
+			fragment, ok := m.fragments[name]
+			// Cycles are reported by the validation later on.
+			if !ok || m.visiting[name] {
+				continue
+			}
+
+			m.visiting[name] = true
+			d, c := m.measure(fragment.SelectionSet, m.schema.Type(fragment.TypeCondition.Name.Value), depth)
+			m.visiting[name] = false
+
+			maxDepth = max(maxDepth, d)
+			complexity += c
+		}
+	}
+
+	return maxDepth, complexity
+}
+
+// listSize reads the limit argument of a list field.
+func (m *measurer) listSize(field *ast.Field) int {
+	for _, arg := range field.Arguments {
+		if arg.Name.Value != "limit" {
+			continue
+		}
+
+		switch v := arg.Value.(type) {
+		case *ast.IntValue:
+			if n, err := strconv.Atoi(v.Value); err == nil && n > 0 {
+				return n
+			}
+		case *ast.Variable:
+			if n, ok := m.variables[v.Name.Value].(float64); ok && n > 0 {
+				return int(n)
+			}
+		}
+	}
+
+	return defaultListSize
+}
+
+func unwrapNonNull(t graphql.Type) graphql.Type {
+	if nonNull, ok := t.(*graphql.NonNull); ok {
+		return nonNull.OfType
+	}
+
+	return t
+}
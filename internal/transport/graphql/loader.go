@@ -0,0 +1,104 @@
+package graphql_api
+
+import (
+	"context"
+	"sync"
+
+	"github.com/wilfridterry/contact-list/internal/domain"
+)
+
+// loader batches the keys of the fields resolved at one level of a query into
+// a single fetch. load only registers the key, the fetch runs when the first
+// of the returned thunks is called, which the executor does once every field
+// of the level has been resolved.
+type loader[K comparable, V any] struct {
+	fetch func(context.Context, []K) (map[K]V, error)
+
+	mu   sync.Mutex
+	open *batch[K, V]
+}
+
+type batch[K comparable, V any] struct {
+	keys   []K
+	seen   map[K]bool
+	once   sync.Once
+	values map[K]V
+	err    error
+}
+
+func newLoader[K comparable, V any](fetch func(context.Context, []K) (map[K]V, error)) *loader[K, V] {
+	return &loader[K, V]{fetch: fetch}
+}
+
+func (l *loader[K, V]) load(ctx context.Context, key K) func() (V, error) {
+	l.mu.Lock()
+	if l.open == nil {
+		l.open = &batch[K, V]{seen: make(map[K]bool)}
+	}
+
+	b := l.open
+	if !b.seen[key] {
+		b.seen[key] = true
+		b.keys = append(b.keys, key)
+	}
+	l.mu.Unlock()
+
+	return func() (V, error) {
+		// Keys loaded from now on go to the next batch.
+		l.mu.Lock()
+		if l.open == b {
+			l.open = nil
+		}
+		l.mu.Unlock()
+
+		b.once.Do(func() {
+			b.values, b.err = l.fetch(ctx, b.keys)
+		})
+
+		return b.values[key], b.err
+	}
+}
+
+// loaders are the loaders of a single request, results are not shared between
+// requests so nobody sees data fetched for someone else.
+type loaders struct {
+	owners  *loader[int64, *domain.User]
+	history *loader[int64, []domain.AuditEntry]
+}
+
+type loadersKey struct{}
+
+func withLoaders(ctx context.Context, users Users, history History) context.Context {
+	return context.WithValue(ctx, loadersKey{}, &loaders{
+		owners: newLoader(func(ctx context.Context, ids []int64) (map[int64]*domain.User, error) {
+			found, err := users.Users(ctx, ids)
+			if err != nil {
+				return nil, err
+			}
+
+			byId := make(map[int64]*domain.User, len(found))
+			for i := range found {
+				byId[found[i].ID] = &found[i]
+			}
+
+			return byId, nil
+		}),
+		history: newLoader(func(ctx context.Context, ids []int64) (map[int64][]domain.AuditEntry, error) {
+			entries, err := history.ContactHistory(ctx, ids)
+			if err != nil {
+				return nil, err
+			}
+
+			byContact := make(map[int64][]domain.AuditEntry, len(ids))
+			for _, e := range entries {
+				byContact[e.EntityID] = append(byContact[e.EntityID], e)
+			}
+
+			return byContact, nil
+		}),
+	})
+}
+
+func loadersFrom(ctx context.Context) *loaders {
+	return ctx.Value(loadersKey{}).(*loaders)
+}
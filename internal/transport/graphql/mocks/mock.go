@@ -0,0 +1,234 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: handler.go
+//
+// Generated by this command:
+//
+//	mockgen -source=handler.go -destination=mocks/mock.go
+//
+
+// Package mock_graphql_api is a generated GoMock package.
+package mock_graphql_api
+
+import (
+	context "context"
+	reflect "reflect"
+
+	domain "github.com/wilfridterry/contact-list/internal/domain"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockContacts is a mock of Contacts interface.
+type MockContacts struct {
+	ctrl     *gomock.Controller
+	recorder *MockContactsMockRecorder
+}
+
+// MockContactsMockRecorder is the mock recorder for MockContacts.
+type MockContactsMockRecorder struct {
+	mock *MockContacts
+}
+
+// NewMockContacts creates a new mock instance.
+func NewMockContacts(ctrl *gomock.Controller) *MockContacts {
+	mock := &MockContacts{ctrl: ctrl}
+	mock.recorder = &MockContactsMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockContacts) EXPECT() *MockContactsMockRecorder {
+	return m.recorder
+}
+
+// All mocks base method.
+func (m *MockContacts) All(arg0 context.Context) ([]domain.Contact, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "All", arg0)
+	ret0, _ := ret[0].([]domain.Contact)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// All indicates an expected call of All.
+func (mr *MockContactsMockRecorder) All(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "All", reflect.TypeOf((*MockContacts)(nil).All), arg0)
+}
+
+// Create mocks base method.
+func (m *MockContacts) Create(arg0 context.Context, arg1 *domain.SaveInputContact) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", arg0, arg1)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Create indicates an expected call of Create.
+func (mr *MockContactsMockRecorder) Create(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockContacts)(nil).Create), arg0, arg1)
+}
+
+// Delete mocks base method.
+func (m *MockContacts) Delete(arg0 context.Context, arg1 int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Delete indicates an expected call of Delete.
+func (mr *MockContactsMockRecorder) Delete(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockContacts)(nil).Delete), arg0, arg1)
+}
+
+// GetOne mocks base method.
+func (m *MockContacts) GetOne(arg0 context.Context, arg1 int64) (*domain.Contact, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetOne", arg0, arg1)
+	ret0, _ := ret[0].(*domain.Contact)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetOne indicates an expected call of GetOne.
+func (mr *MockContactsMockRecorder) GetOne(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOne", reflect.TypeOf((*MockContacts)(nil).GetOne), arg0, arg1)
+}
+
+// Search mocks base method.
+func (m *MockContacts) Search(arg0 context.Context, arg1 *domain.ContactSearch) ([]domain.Contact, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Search", arg0, arg1)
+	ret0, _ := ret[0].([]domain.Contact)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Search indicates an expected call of Search.
+func (mr *MockContactsMockRecorder) Search(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Search", reflect.TypeOf((*MockContacts)(nil).Search), arg0, arg1)
+}
+
+// Subscribe mocks base method.
+func (m *MockContacts) Subscribe(arg0 int64) *domain.ContactSubscription {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Subscribe", arg0)
+	ret0, _ := ret[0].(*domain.ContactSubscription)
+	return ret0
+}
+
+// Subscribe indicates an expected call of Subscribe.
+func (mr *MockContactsMockRecorder) Subscribe(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Subscribe", reflect.TypeOf((*MockContacts)(nil).Subscribe), arg0)
+}
+
+// Update mocks base method.
+func (m *MockContacts) Update(arg0 context.Context, arg1 int64, arg2 *domain.SaveInputContact) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Update", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Update indicates an expected call of Update.
+func (mr *MockContactsMockRecorder) Update(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockContacts)(nil).Update), arg0, arg1, arg2)
+}
+
+// MockUsers is a mock of Users interface.
+type MockUsers struct {
+	ctrl     *gomock.Controller
+	recorder *MockUsersMockRecorder
+}
+
+// MockUsersMockRecorder is the mock recorder for MockUsers.
+type MockUsersMockRecorder struct {
+	mock *MockUsers
+}
+
+// NewMockUsers creates a new mock instance.
+func NewMockUsers(ctrl *gomock.Controller) *MockUsers {
+	mock := &MockUsers{ctrl: ctrl}
+	mock.recorder = &MockUsersMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockUsers) EXPECT() *MockUsersMockRecorder {
+	return m.recorder
+}
+
+// Profile mocks base method.
+func (m *MockUsers) Profile(arg0 context.Context, arg1 int64) (*domain.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Profile", arg0, arg1)
+	ret0, _ := ret[0].(*domain.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Profile indicates an expected call of Profile.
+func (mr *MockUsersMockRecorder) Profile(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Profile", reflect.TypeOf((*MockUsers)(nil).Profile), arg0, arg1)
+}
+
+// Users mocks base method.
+func (m *MockUsers) Users(arg0 context.Context, arg1 []int64) ([]domain.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Users", arg0, arg1)
+	ret0, _ := ret[0].([]domain.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Users indicates an expected call of Users.
+func (mr *MockUsersMockRecorder) Users(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Users", reflect.TypeOf((*MockUsers)(nil).Users), arg0, arg1)
+}
+
+// MockHistory is a mock of History interface.
+type MockHistory struct {
+	ctrl     *gomock.Controller
+	recorder *MockHistoryMockRecorder
+}
+
+// MockHistoryMockRecorder is the mock recorder for MockHistory.
+type MockHistoryMockRecorder struct {
+	mock *MockHistory
+}
+
+// NewMockHistory creates a new mock instance.
+func NewMockHistory(ctrl *gomock.Controller) *MockHistory {
+	mock := &MockHistory{ctrl: ctrl}
+	mock.recorder = &MockHistoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockHistory) EXPECT() *MockHistoryMockRecorder {
+	return m.recorder
+}
+
+// ContactHistory mocks base method.
+func (m *MockHistory) ContactHistory(arg0 context.Context, arg1 []int64) ([]domain.AuditEntry, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ContactHistory", arg0, arg1)
+	ret0, _ := ret[0].([]domain.AuditEntry)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ContactHistory indicates an expected call of ContactHistory.
+func (mr *MockHistoryMockRecorder) ContactHistory(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ContactHistory", reflect.TypeOf((*MockHistory)(nil).ContactHistory), arg0, arg1)
+}
@@ -0,0 +1,338 @@
+package graphql_api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/wilfridterry/contact-list/internal/apperror"
+	"github.com/wilfridterry/contact-list/internal/domain"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/graphql-go/graphql"
+)
+
+var (
+	errUnauthenticated = apperror.New(http.StatusUnauthorized, apperror.CodeUnauthenticated, "unauthenticated")
+	errInvalidID       = apperror.New(http.StatusBadRequest, apperror.CodeMalformedRequest, "invalid id")
+)
+
+type resolver struct {
+	contactService Contacts
+	userService    Users
+}
+
+func (r *resolver) contact(p graphql.ResolveParams) (any, error) {
+	if _, err := authorize(p.Context, domain.PermissionContactsRead); err != nil {
+		return nil, err
+	}
+
+	id, err := idArg(p.Args["id"])
+	if err != nil {
+		return nil, err
+	}
+
+	contact, err := r.contactService.GetOne(p.Context, id)
+	if errors.Is(err, domain.ErrContactNotFound) {
+		return nil, nil
+	}
+
+	return contact, err
+}
+
+func (r *resolver) contacts(p graphql.ResolveParams) (any, error) {
+	if _, err := authorize(p.Context, domain.PermissionContactsRead); err != nil {
+		return nil, err
+	}
+
+	return r.contactService.All(p.Context)
+}
+
+func (r *resolver) searchContacts(p graphql.ResolveParams) (any, error) {
+	if _, err := authorize(p.Context, domain.PermissionContactsRead); err != nil {
+		return nil, err
+	}
+
+	search := domain.ContactSearch{Query: p.Args["query"].(string)}
+	if limit, ok := p.Args["limit"].(int); ok {
+		search.Limit = limit
+	}
+
+	if err := binding.Validator.ValidateStruct(&search); err != nil {
+		return nil, err
+	}
+
+	return r.contactService.Search(p.Context, &search)
+}
+
+func (r *resolver) me(p graphql.ResolveParams) (any, error) {
+	identity, err := authorize(p.Context)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.userService.Profile(p.Context, identity.UserID)
+}
+
+// userEmail hides the email of other users from everyone but administrators.
+func (r *resolver) userEmail(p graphql.ResolveParams) (any, error) {
+	user := p.Source.(*domain.User)
+
+	identity, _ := domain.IdentityFromContext(p.Context)
+	if identity == nil || (identity.UserID != user.ID && !identity.HasPermission(domain.PermissionUsersAdmin)) {
+		return nil, nil
+	}
+
+	return user.Email, nil
+}
+
+func (r *resolver) contactOwner(p graphql.ResolveParams) (any, error) {
+	contact := sourceContact(p)
+	if contact.UserID == nil {
+		return nil, nil
+	}
+
+	owner := loadersFrom(p.Context).owners.load(p.Context, *contact.UserID)
+
+	return func() (any, error) {
+		user, err := owner()
+		if err != nil || user == nil {
+			return nil, err
+		}
+
+		return user, nil
+	}, nil
+}
+
+func (r *resolver) contactHistory(p graphql.ResolveParams) (any, error) {
+	history := loadersFrom(p.Context).history.load(p.Context, sourceContact(p).ID)
+
+	return func() (any, error) {
+		entries, err := history()
+		if err != nil {
+			return nil, err
+		}
+
+		if entries == nil {
+			entries = make([]domain.AuditEntry, 0)
+		}
+
+		return entries, nil
+	}, nil
+}
+
+func historyActor(p graphql.ResolveParams) (any, error) {
+	if actor := p.Source.(domain.AuditEntry).ActorID; actor != 0 {
+		return actor, nil
+	}
+
+	return nil, nil
+}
+
+func (r *resolver) eventContact(p graphql.ResolveParams) (any, error) {
+	event := p.Source.(domain.ContactEvent)
+	if event.Type == domain.ContactDeleted || event.Type == contactReset {
+		return nil, nil
+	}
+
+	contact, err := r.contactService.GetOne(p.Context, event.ContactID)
+	if errors.Is(err, domain.ErrContactNotFound) {
+		return nil, nil
+	}
+
+	return contact, err
+}
+
+func eventContactID(p graphql.ResolveParams) (any, error) {
+	if id := p.Source.(domain.ContactEvent).ContactID; id != 0 {
+		return id, nil
+	}
+
+	return nil, nil
+}
+
+func eventTime(p graphql.ResolveParams) (any, error) {
+	if t := p.Source.(domain.ContactEvent).Time; !t.IsZero() {
+		return t, nil
+	}
+
+	return nil, nil
+}
+
+func (r *resolver) createContact(p graphql.ResolveParams) (any, error) {
+	identity, err := authorize(p.Context, domain.PermissionContactsWrite)
+	if err != nil {
+		return nil, err
+	}
+
+	inp, err := saveInput(p.Args["input"])
+	if err != nil {
+		return nil, err
+	}
+	inp.UserID = identity.UserID
+
+	id, err := r.contactService.Create(p.Context, inp)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.contactService.GetOne(p.Context, id)
+}
+
+func (r *resolver) updateContact(p graphql.ResolveParams) (any, error) {
+	if _, err := authorize(p.Context, domain.PermissionContactsWrite); err != nil {
+		return nil, err
+	}
+
+	id, err := idArg(p.Args["id"])
+	if err != nil {
+		return nil, err
+	}
+
+	inp, err := saveInput(p.Args["input"])
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.contactService.Update(p.Context, id, inp); err != nil {
+		return nil, err
+	}
+
+	return r.contactService.GetOne(p.Context, id)
+}
+
+func (r *resolver) deleteContact(p graphql.ResolveParams) (any, error) {
+	if _, err := authorize(p.Context, domain.PermissionContactsWrite); err != nil {
+		return nil, err
+	}
+
+	id, err := idArg(p.Args["id"])
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.contactService.Delete(p.Context, id); err != nil {
+		return nil, err
+	}
+
+	return id, nil
+}
+
+// subscribeContactChanged sends the missed events, then the events as they
+// happen. The subscription completes when the subscriber falls behind, it
+// resubscribes after the last event it has received.
+func (r *resolver) subscribeContactChanged(p graphql.ResolveParams) (any, error) {
+	if _, err := authorize(p.Context, domain.PermissionContactsRead); err != nil {
+		return nil, err
+	}
+
+	var lastEventID int64
+	if p.Args["lastEventId"] != nil {
+		id, err := idArg(p.Args["lastEventId"])
+		if err != nil {
+			return nil, err
+		}
+		lastEventID = id
+	}
+
+	sub := r.contactService.Subscribe(lastEventID)
+	events := make(chan any)
+
+	go func() {
+		defer sub.Close()
+		defer close(events)
+
+		send := func(event domain.ContactEvent) bool {
+			select {
+			case events <- event:
+				return true
+			case <-p.Context.Done():
+				return false
+			}
+		}
+
+		if sub.Reset && !send(domain.ContactEvent{Type: contactReset}) {
+			return
+		}
+
+		for _, event := range sub.Missed {
+			if !send(event) {
+				return
+			}
+		}
+
+		for {
+			select {
+			case <-p.Context.Done():
+				return
+			case event, ok := <-sub.Events:
+				if !ok || !send(event) {
+					return
+				}
+			}
+		}
+	}()
+
+	return events, nil
+}
+
+// authorize returns the caller if it has the permissions.
+func authorize(ctx context.Context, permissions ...domain.Permission) (*domain.Identity, error) {
+	identity, ok := domain.IdentityFromContext(ctx)
+	if !ok {
+		return nil, errUnauthenticated
+	}
+
+	for _, permission := range permissions {
+		if !identity.HasPermission(permission) {
+			return nil, apperror.New(http.StatusForbidden, "missing_permission", "permission denied: "+string(permission))
+		}
+	}
+
+	return identity, nil
+}
+
+func idArg(value any) (int64, error) {
+	s, _ := value.(string)
+
+	id, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || id <= 0 {
+		return 0, errInvalidID
+	}
+
+	return id, nil
+}
+
+func saveInput(value any) (*domain.SaveInputContact, error) {
+	fields, _ := value.(map[string]any)
+	field := func(name string) string {
+		s, _ := fields[name].(string)
+		return s
+	}
+
+	inp := domain.SaveInputContact{
+		Name:     field("name"),
+		LastName: field("lastName"),
+		Phone:    field("phone"),
+		Email:    field("email"),
+		Address:  field("address"),
+		Author:   field("author"),
+	}
+
+	if err := binding.Validator.ValidateStruct(&inp); err != nil {
+		return nil, err
+	}
+
+	return &inp, nil
+}
+
+// sourceContact returns the contact a field belongs to, lists hold them by
+// value and single contacts come as pointers.
+func sourceContact(p graphql.ResolveParams) *domain.Contact {
+	if contact, ok := p.Source.(domain.Contact); ok {
+		return &contact
+	}
+
+	return p.Source.(*domain.Contact)
+}
@@ -0,0 +1,162 @@
+package graphql_api
+
+import (
+	"github.com/wilfridterry/contact-list/internal/domain"
+
+	"github.com/graphql-go/graphql"
+)
+
+// contactReset is the type of the event telling a subscriber to reload the
+// contacts, the events it missed are gone.
+const contactReset = "contact.reset"
+
+func newSchema(r *resolver) (graphql.Schema, error) {
+	contactEventTypeEnum := graphql.NewEnum(graphql.EnumConfig{
+		Name: "ContactEventType",
+		Values: graphql.EnumValueConfigMap{
+			"CREATED": &graphql.EnumValueConfig{Value: domain.ContactCreated},
+			"UPDATED": &graphql.EnumValueConfig{Value: domain.ContactUpdated},
+			"DELETED": &graphql.EnumValueConfig{Value: domain.ContactDeleted},
+			"RESET": &graphql.EnumValueConfig{
+				Value:       contactReset,
+				Description: "The missed events are gone, the contacts have to be reloaded.",
+			},
+		},
+	})
+
+	contactInputType := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: "ContactInput",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"name":     &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+			"lastName": &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+			"phone":    &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+			"email":    &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+			"address":  &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+			"author":   &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+		},
+	})
+
+	userType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "User",
+		Fields: graphql.Fields{
+			"id":   &graphql.Field{Type: graphql.NewNonNull(graphql.ID)},
+			"name": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"email": &graphql.Field{
+				Type:        graphql.String,
+				Description: "Only shown to the user and to administrators.",
+				Resolve:     r.userEmail,
+			},
+		},
+	})
+
+	historyEntryType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "HistoryEntry",
+		Fields: graphql.Fields{
+			"action":    &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"actorId":   &graphql.Field{Type: graphql.ID, Resolve: historyActor},
+			"timestamp": &graphql.Field{Type: graphql.NewNonNull(graphql.DateTime)},
+		},
+	})
+
+	contactType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Contact",
+		Fields: graphql.Fields{
+			"id":        &graphql.Field{Type: graphql.NewNonNull(graphql.ID)},
+			"name":      &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"lastName":  &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"phone":     &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"email":     &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"address":   &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"author":    &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"createdAt": &graphql.Field{Type: graphql.NewNonNull(graphql.DateTime)},
+			"updatedAt": &graphql.Field{Type: graphql.NewNonNull(graphql.DateTime)},
+			"owner":     &graphql.Field{Type: userType, Resolve: r.contactOwner},
+			"history": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(historyEntryType))),
+				Description: "Changes made to the contact, oldest first.",
+				Resolve:     r.contactHistory,
+			},
+		},
+	})
+
+	contactEventType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "ContactEvent",
+		Fields: graphql.Fields{
+			"id":        &graphql.Field{Type: graphql.NewNonNull(graphql.ID)},
+			"type":      &graphql.Field{Type: graphql.NewNonNull(contactEventTypeEnum)},
+			"contactId": &graphql.Field{Type: graphql.ID, Resolve: eventContactID},
+			"time":      &graphql.Field{Type: graphql.DateTime, Resolve: eventTime},
+			"contact": &graphql.Field{
+				Type:        contactType,
+				Description: "The contact as it is now, null once it is deleted.",
+				Resolve:     r.eventContact,
+			},
+		},
+	})
+
+	contactList := graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(contactType)))
+
+	query := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"contact": &graphql.Field{
+				Type:    contactType,
+				Args:    graphql.FieldConfigArgument{"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)}},
+				Resolve: r.contact,
+			},
+			"contacts": &graphql.Field{Type: contactList, Resolve: r.contacts},
+			"searchContacts": &graphql.Field{
+				Type: contactList,
+				Args: graphql.FieldConfigArgument{
+					"query": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+					"limit": &graphql.ArgumentConfig{Type: graphql.Int, Description: "Up to 100, 20 when not set."},
+				},
+				Resolve: r.searchContacts,
+			},
+			"me": &graphql.Field{Type: graphql.NewNonNull(userType), Resolve: r.me},
+		},
+	})
+
+	mutation := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Mutation",
+		Fields: graphql.Fields{
+			"createContact": &graphql.Field{
+				Type:    graphql.NewNonNull(contactType),
+				Args:    graphql.FieldConfigArgument{"input": &graphql.ArgumentConfig{Type: graphql.NewNonNull(contactInputType)}},
+				Resolve: r.createContact,
+			},
+			"updateContact": &graphql.Field{
+				Type: graphql.NewNonNull(contactType),
+				Args: graphql.FieldConfigArgument{
+					"id":    &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+					"input": &graphql.ArgumentConfig{Type: graphql.NewNonNull(contactInputType)},
+				},
+				Resolve: r.updateContact,
+			},
+			"deleteContact": &graphql.Field{
+				Type:    graphql.NewNonNull(graphql.ID),
+				Args:    graphql.FieldConfigArgument{"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)}},
+				Resolve: r.deleteContact,
+			},
+		},
+	})
+
+	subscription := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Subscription",
+		Fields: graphql.Fields{
+			"contactChanged": &graphql.Field{
+				Type:        graphql.NewNonNull(contactEventType),
+				Description: "Changes of the contacts, a reconnecting client passes the last event it has seen.",
+				Args:        graphql.FieldConfigArgument{"lastEventId": &graphql.ArgumentConfig{Type: graphql.ID}},
+				Subscribe:   r.subscribeContactChanged,
+				Resolve:     func(p graphql.ResolveParams) (any, error) { return p.Source, nil },
+			},
+		},
+	})
+
+	return graphql.NewSchema(graphql.SchemaConfig{
+		Query:        query,
+		Mutation:     mutation,
+		Subscription: subscription,
+	})
+}
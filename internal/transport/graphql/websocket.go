@@ -0,0 +1,206 @@
+package graphql_api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+
+	"github.com/wilfridterry/contact-list/pkg/logger"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/ast"
+	"golang.org/x/net/websocket"
+)
+
+// transportWSProtocol is the graphql-transport-ws subprotocol of the
+// graphql-ws library, which most clients speak.
+const transportWSProtocol = "graphql-transport-ws"
+
+// Messages of the graphql-transport-ws protocol.
+const (
+	messageConnectionInit = "connection_init"
+	messageConnectionAck  = "connection_ack"
+	messagePing           = "ping"
+	messagePong           = "pong"
+	messageSubscribe      = "subscribe"
+	messageNext           = "next"
+	messageError          = "error"
+	messageComplete       = "complete"
+)
+
+type wsMessage struct {
+	ID      string          `json:"id,omitempty"`
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// wsConn runs the operations of a connection, each one until it completes or
+// the client stops it.
+type wsConn struct {
+	handler *Handler
+	ws      *websocket.Conn
+	ctx     context.Context
+
+	sendMu sync.Mutex
+
+	mu         sync.Mutex
+	operations map[string]context.CancelFunc
+}
+
+func (h *Handler) serveWebSocket(w http.ResponseWriter, r *http.Request) {
+	server := websocket.Server{
+		// Credentials come in the Authorization header or in a stream token
+		// checked against the origin, never in cookies, so connections from
+		// other origins can not act on behalf of the user.
+		Handshake: func(config *websocket.Config, _ *http.Request) error {
+			for _, protocol := range config.Protocol {
+				if protocol == transportWSProtocol {
+					config.Protocol = []string{transportWSProtocol}
+					return nil
+				}
+			}
+
+			config.Protocol = nil
+			return nil
+		},
+		Handler: func(ws *websocket.Conn) {
+			defer ws.Close()
+
+			ctx, cancel := context.WithCancel(r.Context())
+			defer cancel()
+
+			// The connection is closed once the request context is done,
+			// as the connection only waits for client messages.
+			stop := context.AfterFunc(ctx, func() { ws.Close() })
+			defer stop()
+
+			conn := &wsConn{handler: h, ws: ws, ctx: ctx, operations: make(map[string]context.CancelFunc)}
+			conn.serve()
+		},
+	}
+
+	server.ServeHTTP(w, r)
+}
+
+func (c *wsConn) serve() {
+	acknowledged := false
+
+	for {
+		var msg wsMessage
+		if err := websocket.JSON.Receive(c.ws, &msg); err != nil {
+			return
+		}
+
+		switch msg.Type {
+		case messageConnectionInit:
+			// The caller is authenticated with the upgrade request already.
+			acknowledged = true
+			c.send(wsMessage{Type: messageConnectionAck})
+		case messagePing:
+			c.send(wsMessage{Type: messagePong})
+		case messagePong:
+		case messageSubscribe:
+			if !acknowledged {
+				return
+			}
+			c.start(msg)
+		case messageComplete:
+			c.stop(msg.ID)
+		default:
+			return
+		}
+	}
+}
+
+func (c *wsConn) start(msg wsMessage) {
+	var req request
+	if err := json.Unmarshal(msg.Payload, &req); err != nil {
+		c.sendErrors(msg.ID, gqlerrors.NewFormattedError("malformed request"))
+		return
+	}
+
+	op, err := c.handler.prepare(&req)
+	if err != nil {
+		c.sendErrors(msg.ID, gqlerrors.FormatError(err))
+		return
+	}
+
+	c.mu.Lock()
+	if _, ok := c.operations[msg.ID]; ok {
+		c.mu.Unlock()
+		c.sendErrors(msg.ID, gqlerrors.NewFormattedError("operation "+msg.ID+" is running already"))
+		return
+	}
+
+	ctx, cancel := context.WithCancel(c.ctx)
+	c.operations[msg.ID] = cancel
+	c.mu.Unlock()
+
+	params := graphql.Params{
+		Schema:         c.handler.schema,
+		RequestString:  req.Query,
+		VariableValues: req.Variables,
+		OperationName:  req.OperationName,
+		Context:        withLoaders(ctx, c.handler.users, c.handler.history),
+	}
+
+	go func() {
+		defer c.finish(msg.ID)
+
+		if op.Operation != ast.OperationTypeSubscription {
+			c.sendResult(msg.ID, graphql.Do(params))
+			return
+		}
+
+		for result := range graphql.Subscribe(params) {
+			c.sendResult(msg.ID, result)
+		}
+	}()
+}
+
+// stop cancels an operation the client is not interested in anymore.
+func (c *wsConn) stop(id string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if cancel, ok := c.operations[id]; ok {
+		cancel()
+		delete(c.operations, id)
+	}
+}
+
+// finish tells the client an operation is over, unless the client stopped it.
+func (c *wsConn) finish(id string) {
+	c.mu.Lock()
+	cancel, running := c.operations[id]
+	delete(c.operations, id)
+	c.mu.Unlock()
+
+	if running {
+		cancel()
+		c.send(wsMessage{ID: id, Type: messageComplete})
+	}
+}
+
+func (c *wsConn) sendResult(id string, result *graphql.Result) {
+	reportErrors(c.ctx, result)
+
+	payload, _ := json.Marshal(result)
+	c.send(wsMessage{ID: id, Type: messageNext, Payload: payload})
+}
+
+func (c *wsConn) sendErrors(id string, errs ...gqlerrors.FormattedError) {
+	payload, _ := json.Marshal(errs)
+	c.send(wsMessage{ID: id, Type: messageError, Payload: payload})
+}
+
+func (c *wsConn) send(msg wsMessage) {
+	c.sendMu.Lock()
+	defer c.sendMu.Unlock()
+
+	if err := websocket.JSON.Send(c.ws, msg); err != nil {
+		logger.FromContext(c.ctx).WithField("handler", "graphql").Debug("websocket send failed:", err)
+	}
+}
@@ -2,13 +2,18 @@ package grpc_client
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	audit "github.com/wilfridterry/audit-log/pkg/domain"
+	"github.com/wilfridterry/contact-list/pkg/tracing"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
 	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
+var errNotConnected = errors.New("audit: not connected")
+
 type Client struct {
 	conn *grpc.ClientConn
 	auditClient audit.AuditServiceClient
@@ -17,7 +22,7 @@ type Client struct {
 func NewClient(port int) (*Client, error) {
 	addr := fmt.Sprintf(":%d", port)
 
-	conn, err := grpc.NewClient(addr, grpc.WithInsecure())
+	conn, err := grpc.NewClient(addr, grpc.WithInsecure(), grpc.WithUnaryInterceptor(tracing.UnaryClientInterceptor()))
 	if err != nil {
 		return nil, err
 	}
@@ -32,6 +37,30 @@ func (c *Client) CloseConnection() error {
 	return c.conn.Close()
 }
 
+// Ping connects to the audit service unless connected and waits until the
+// connection is ready or fails. A client which failed to connect may be nil.
+func (c *Client) Ping(ctx context.Context) error {
+	if c == nil || c.conn == nil {
+		return errNotConnected
+	}
+
+	c.conn.Connect()
+
+	for {
+		state := c.conn.GetState()
+		switch state {
+		case connectivity.Ready:
+			return nil
+		case connectivity.TransientFailure, connectivity.Shutdown:
+			return fmt.Errorf("audit: connection is %s", state)
+		}
+
+		if !c.conn.WaitForStateChange(ctx, state) {
+			return ctx.Err()
+		}
+	}
+}
+
 func (c *Client) SendLogRequest(ctx context.Context, req audit.LogItem) error {
 	action, err := audit.ToPbAction(req.Action)
 	if err != nil {
@@ -0,0 +1,92 @@
+package grpc_server
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+
+	authv1 "github.com/wilfridterry/contact-list/api/auth/v1"
+	"github.com/wilfridterry/contact-list/internal/apperror"
+	"github.com/wilfridterry/contact-list/internal/domain"
+
+	"github.com/gin-gonic/gin/binding"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// errInvalidCredentials does not tell unknown emails from wrong passwords.
+var errInvalidCredentials = apperror.New(http.StatusBadRequest, "invalid_credentials", "invalid email or password")
+
+type authServer struct {
+	authv1.UnimplementedAuthServiceServer
+
+	auth Auth
+}
+
+func (s *authServer) SignIn(ctx context.Context, req *authv1.SignInRequest) (*authv1.SignInResponse, error) {
+	inp := domain.SignInInput{Email: req.GetEmail(), Password: req.GetPassword(), IP: peerIP(ctx)}
+	if err := binding.Validator.ValidateStruct(&inp); err != nil {
+		return nil, err
+	}
+
+	accessToken, refreshToken, err := s.auth.SingIn(ctx, &inp)
+	if err != nil {
+		var mfaErr *domain.MFARequiredError
+		if errors.As(err, &mfaErr) {
+			return &authv1.SignInResponse{Result: &authv1.SignInResponse_MfaChallenge{MfaChallenge: &authv1.MFAChallenge{
+				Challenge: mfaErr.Challenge,
+				ExpiresAt: timestamppb.New(mfaErr.ExpiresAt),
+			}}}, nil
+		}
+
+		if errors.Is(err, domain.ErrNotFoundUser) {
+			return nil, errInvalidCredentials
+		}
+
+		return nil, err
+	}
+
+	return &authv1.SignInResponse{Result: &authv1.SignInResponse_Tokens{Tokens: &authv1.Tokens{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+	}}}, nil
+}
+
+func (s *authServer) VerifyMFA(ctx context.Context, req *authv1.VerifyMFARequest) (*authv1.Tokens, error) {
+	inp := domain.MFAVerifyInput{Challenge: req.GetChallenge(), Code: req.GetCode(), IP: peerIP(ctx)}
+	if err := binding.Validator.ValidateStruct(&inp); err != nil {
+		return nil, err
+	}
+
+	accessToken, refreshToken, err := s.auth.VerifyMFA(ctx, &inp)
+	if err != nil {
+		return nil, err
+	}
+
+	return &authv1.Tokens{AccessToken: accessToken, RefreshToken: refreshToken}, nil
+}
+
+func (s *authServer) Refresh(ctx context.Context, req *authv1.RefreshRequest) (*authv1.Tokens, error) {
+	accessToken, refreshToken, err := s.auth.RefreshTokens(ctx, req.GetRefreshToken())
+	if err != nil {
+		return nil, err
+	}
+
+	return &authv1.Tokens{AccessToken: accessToken, RefreshToken: refreshToken}, nil
+}
+
+// peerIP is the address login attempts are counted against.
+func peerIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return ""
+	}
+
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+
+	return host
+}
@@ -0,0 +1,182 @@
+package grpc_server
+
+import (
+	"context"
+
+	contactsv1 "github.com/wilfridterry/contact-list/api/contacts/v1"
+	"github.com/wilfridterry/contact-list/internal/domain"
+
+	"github.com/gin-gonic/gin/binding"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+var eventTypes = map[string]contactsv1.ContactEvent_Type{
+	domain.ContactCreated: contactsv1.ContactEvent_TYPE_CREATED,
+	domain.ContactUpdated: contactsv1.ContactEvent_TYPE_UPDATED,
+	domain.ContactDeleted: contactsv1.ContactEvent_TYPE_DELETED,
+}
+
+type contactsServer struct {
+	contactsv1.UnimplementedContactsServiceServer
+
+	contacts Contacts
+}
+
+func (s *contactsServer) List(ctx context.Context, _ *contactsv1.ListRequest) (*contactsv1.ListResponse, error) {
+	contacts, err := s.contacts.All(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &contactsv1.ListResponse{Contacts: toContacts(contacts)}, nil
+}
+
+func (s *contactsServer) Get(ctx context.Context, req *contactsv1.GetRequest) (*contactsv1.Contact, error) {
+	contact, err := s.contacts.GetOne(ctx, req.GetId())
+	if err != nil {
+		return nil, err
+	}
+
+	return toContact(contact), nil
+}
+
+func (s *contactsServer) Create(ctx context.Context, req *contactsv1.CreateRequest) (*contactsv1.Contact, error) {
+	inp, err := saveInput(req.GetContact())
+	if err != nil {
+		return nil, err
+	}
+
+	identity, _ := getIdentity(ctx)
+	inp.UserID = identity.UserID
+
+	id, err := s.contacts.Create(ctx, inp)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.Get(ctx, &contactsv1.GetRequest{Id: id})
+}
+
+func (s *contactsServer) Update(ctx context.Context, req *contactsv1.UpdateRequest) (*contactsv1.Contact, error) {
+	inp, err := saveInput(req.GetContact())
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.contacts.Update(ctx, req.GetId(), inp); err != nil {
+		return nil, err
+	}
+
+	return s.Get(ctx, &contactsv1.GetRequest{Id: req.GetId()})
+}
+
+func (s *contactsServer) Delete(ctx context.Context, req *contactsv1.DeleteRequest) (*emptypb.Empty, error) {
+	if err := s.contacts.Delete(ctx, req.GetId()); err != nil {
+		return nil, err
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+func (s *contactsServer) Search(ctx context.Context, req *contactsv1.SearchRequest) (*contactsv1.SearchResponse, error) {
+	search := domain.ContactSearch{Query: req.GetQuery(), Limit: int(req.GetLimit())}
+	if err := binding.Validator.ValidateStruct(&search); err != nil {
+		return nil, err
+	}
+
+	contacts, err := s.contacts.Search(ctx, &search)
+	if err != nil {
+		return nil, err
+	}
+
+	return &contactsv1.SearchResponse{Contacts: toContacts(contacts)}, nil
+}
+
+// Watch sends the missed events first, then the events as they happen. A
+// client which falls behind gets Unavailable and resumes after the last event
+// it has received.
+func (s *contactsServer) Watch(req *contactsv1.WatchRequest, stream grpc.ServerStreamingServer[contactsv1.ContactEvent]) error {
+	sub := s.contacts.Subscribe(req.GetLastEventId())
+	defer sub.Close()
+
+	if sub.Reset {
+		if err := stream.Send(&contactsv1.ContactEvent{Type: contactsv1.ContactEvent_TYPE_RESET}); err != nil {
+			return err
+		}
+	}
+
+	for _, event := range sub.Missed {
+		if err := stream.Send(toEvent(event)); err != nil {
+			return err
+		}
+	}
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case event, ok := <-sub.Events:
+			if !ok {
+				return status.Error(codes.Unavailable, "subscriber fell behind")
+			}
+
+			if err := stream.Send(toEvent(event)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func saveInput(contact *contactsv1.ContactInput) (*domain.SaveInputContact, error) {
+	inp := domain.SaveInputContact{
+		Name:     contact.GetName(),
+		LastName: contact.GetLastName(),
+		Phone:    contact.GetPhone(),
+		Email:    contact.GetEmail(),
+		Address:  contact.GetAddress(),
+		Author:   contact.GetAuthor(),
+	}
+
+	if err := binding.Validator.ValidateStruct(&inp); err != nil {
+		return nil, err
+	}
+
+	return &inp, nil
+}
+
+func toContacts(contacts []domain.Contact) []*contactsv1.Contact {
+	out := make([]*contactsv1.Contact, 0, len(contacts))
+	for i := range contacts {
+		out = append(out, toContact(&contacts[i]))
+	}
+
+	return out
+}
+
+func toContact(c *domain.Contact) *contactsv1.Contact {
+	return &contactsv1.Contact{
+		Id:        c.ID,
+		Name:      c.Name,
+		LastName:  c.LastName,
+		Phone:     c.Phone,
+		Email:     c.Email,
+		Address:   c.Address,
+		Author:    c.Author,
+		UserId:    c.UserID,
+		CreatedAt: timestamppb.New(c.CreatedAt),
+		UpdatedAt: timestamppb.New(c.UpdatedAt),
+	}
+}
+
+func toEvent(event domain.ContactEvent) *contactsv1.ContactEvent {
+	return &contactsv1.ContactEvent{
+		Id:        event.ID,
+		Type:      eventTypes[event.Type],
+		ContactId: event.ContactID,
+		Time:      timestamppb.New(event.Time),
+	}
+}
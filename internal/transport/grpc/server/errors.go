@@ -0,0 +1,90 @@
+package grpc_server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/wilfridterry/contact-list/internal/apperror"
+	"github.com/wilfridterry/contact-list/internal/domain"
+	"github.com/wilfridterry/contact-list/pkg/logger"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/protoadapt"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// errorDomain names the service in the ErrorInfo of failed calls.
+const errorDomain = "contact-list"
+
+var statusCodes = map[int]codes.Code{
+	http.StatusBadRequest:          codes.InvalidArgument,
+	http.StatusUnprocessableEntity: codes.InvalidArgument,
+	http.StatusUnauthorized:        codes.Unauthenticated,
+	http.StatusForbidden:           codes.PermissionDenied,
+	http.StatusNotFound:            codes.NotFound,
+	http.StatusConflict:            codes.Aborted,
+	http.StatusGone:                codes.FailedPrecondition,
+	http.StatusTooManyRequests:     codes.ResourceExhausted,
+}
+
+// unaryErrors and streamErrors report the errors of the services and the
+// other interceptors with gRPC status codes.
+func unaryErrors(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	resp, err := handler(ctx, req)
+	if err != nil {
+		return nil, toStatus(ctx, err)
+	}
+
+	return resp, nil
+}
+
+func streamErrors(srv any, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if err := handler(srv, ss); err != nil {
+		return toStatus(ss.Context(), err)
+	}
+
+	return nil
+}
+
+// toStatus turns err into the status the call fails with. The application
+// error code travels as the reason of an ErrorInfo, invalid fields as a
+// BadRequest, the same details a REST problem has.
+func toStatus(ctx context.Context, err error) error {
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+
+	appErr := apperror.From(err)
+
+	code, ok := statusCodes[appErr.Status]
+	if !ok {
+		code = codes.Internal
+		logger.FromContext(ctx).WithField("error", err).Error("grpc call failed")
+	}
+
+	details := []protoadapt.MessageV1{&errdetails.ErrorInfo{Reason: appErr.Code, Domain: errorDomain}}
+
+	if len(appErr.Fields) > 0 {
+		violations := make([]*errdetails.BadRequest_FieldViolation, 0, len(appErr.Fields))
+		for _, f := range appErr.Fields {
+			violations = append(violations, &errdetails.BadRequest_FieldViolation{Field: f.Field, Description: f.Message})
+		}
+		details = append(details, &errdetails.BadRequest{FieldViolations: violations})
+	}
+
+	var lockErr *domain.LoginLockedError
+	if errors.As(err, &lockErr) {
+		details = append(details, &errdetails.RetryInfo{RetryDelay: durationpb.New(lockErr.RetryAfter)})
+	}
+
+	st, detailsErr := status.New(code, appErr.Message).WithDetails(details...)
+	if detailsErr != nil {
+		return status.Error(code, appErr.Message)
+	}
+
+	return st.Err()
+}
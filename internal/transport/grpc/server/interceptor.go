@@ -0,0 +1,150 @@
+package grpc_server
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	authv1 "github.com/wilfridterry/contact-list/api/auth/v1"
+	contactsv1 "github.com/wilfridterry/contact-list/api/contacts/v1"
+	"github.com/wilfridterry/contact-list/internal/apperror"
+	"github.com/wilfridterry/contact-list/internal/domain"
+	"github.com/wilfridterry/contact-list/pkg/logger"
+
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+type ctxKey int
+
+const ctxIdentity ctxKey = iota
+
+// publicMethods are called without a token, they hand the tokens out.
+var publicMethods = map[string]bool{
+	authv1.AuthService_SignIn_FullMethodName:    true,
+	authv1.AuthService_VerifyMFA_FullMethodName: true,
+	authv1.AuthService_Refresh_FullMethodName:   true,
+}
+
+// methodPermissions are the permissions a method takes on top of a valid
+// token, the same the REST routes of the method require.
+var methodPermissions = map[string][]domain.Permission{
+	contactsv1.ContactsService_List_FullMethodName:   {domain.PermissionContactsRead},
+	contactsv1.ContactsService_Get_FullMethodName:    {domain.PermissionContactsRead},
+	contactsv1.ContactsService_Search_FullMethodName: {domain.PermissionContactsRead},
+	contactsv1.ContactsService_Watch_FullMethodName:  {domain.PermissionContactsRead},
+	contactsv1.ContactsService_Create_FullMethodName: {domain.PermissionContactsWrite},
+	contactsv1.ContactsService_Update_FullMethodName: {domain.PermissionContactsWrite},
+	contactsv1.ContactsService_Delete_FullMethodName: {domain.PermissionContactsWrite},
+}
+
+var errInvalidAuthMetadata = apperror.New(http.StatusUnauthorized, apperror.CodeUnauthenticated, "invalid authorization metadata")
+
+// authInterceptor checks the access token in the authorization metadata like
+// AuthJWT does for the REST API.
+type authInterceptor struct {
+	auth Auth
+}
+
+func newAuthInterceptor(auth Auth) *authInterceptor {
+	return &authInterceptor{auth: auth}
+}
+
+func (i *authInterceptor) unary(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	ctx, err := i.authorize(ctx, info.FullMethod)
+	if err != nil {
+		return nil, err
+	}
+
+	return handler(ctx, req)
+}
+
+func (i *authInterceptor) stream(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := i.authorize(ss.Context(), info.FullMethod)
+	if err != nil {
+		return err
+	}
+
+	return handler(srv, &identityStream{ServerStream: ss, ctx: ctx})
+}
+
+// authorize returns the context with the identity of the caller, or the
+// context as is for public methods.
+func (i *authInterceptor) authorize(ctx context.Context, method string) (context.Context, error) {
+	if publicMethods[method] {
+		return ctx, nil
+	}
+
+	token, err := bearerToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	identity, err := i.auth.ParseJWTToken(ctx, token)
+	if err != nil {
+		return nil, unauthenticated(err)
+	}
+
+	for _, permission := range methodPermissions[method] {
+		if !identity.HasPermission(permission) {
+			return nil, apperror.New(http.StatusForbidden, "missing_permission", "permission denied: "+string(permission))
+		}
+	}
+
+	ctx = context.WithValue(ctx, ctxIdentity, identity)
+	if identity.ImpersonatorID != 0 {
+		ctx = domain.WithImpersonator(ctx, identity.ImpersonatorID)
+		ctx = logger.WithFields(ctx, logrus.Fields{"impersonator_id": identity.ImpersonatorID})
+	}
+	ctx = logger.WithFields(ctx, logrus.Fields{"user_id": identity.UserID})
+
+	return ctx, nil
+}
+
+func bearerToken(ctx context.Context) (string, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	values := md.Get("authorization")
+	if len(values) != 1 {
+		return "", errInvalidAuthMetadata
+	}
+
+	token, ok := strings.CutPrefix(values[0], "Bearer ")
+	if !ok || token == "" {
+		return "", errInvalidAuthMetadata
+	}
+
+	return token, nil
+}
+
+// unauthenticated reports a rejected token as such, whatever the reason,
+// unless the account is blocked or the check itself failed.
+func unauthenticated(err error) *apperror.Error {
+	appErr := apperror.From(err)
+	if appErr.Status == http.StatusForbidden || appErr.Status >= http.StatusInternalServerError {
+		return appErr
+	}
+
+	if appErr.Status != http.StatusUnauthorized {
+		return apperror.Wrap(err, http.StatusUnauthorized, apperror.CodeUnauthenticated)
+	}
+
+	return appErr
+}
+
+func getIdentity(ctx context.Context) (*domain.Identity, bool) {
+	identity, ok := ctx.Value(ctxIdentity).(*domain.Identity)
+
+	return identity, ok
+}
+
+// identityStream hands the context with the identity to stream handlers.
+type identityStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *identityStream) Context() context.Context {
+	return s.ctx
+}
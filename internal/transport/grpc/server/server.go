@@ -0,0 +1,83 @@
+package grpc_server
+
+import (
+	"context"
+	"fmt"
+	"net"
+
+	authv1 "github.com/wilfridterry/contact-list/api/auth/v1"
+	contactsv1 "github.com/wilfridterry/contact-list/api/contacts/v1"
+	"github.com/wilfridterry/contact-list/internal/domain"
+
+	"google.golang.org/grpc"
+)
+
+//go:generate mockgen -source=server.go -destination=mocks/mock.go
+
+// Contacts is the part of service.Contacts the gRPC API is served from.
+type Contacts interface {
+	All(context.Context) ([]domain.Contact, error)
+	GetOne(context.Context, int64) (*domain.Contact, error)
+	Create(context.Context, *domain.SaveInputContact) (int64, error)
+	Update(context.Context, int64, *domain.SaveInputContact) error
+	Delete(context.Context, int64) error
+	Search(context.Context, *domain.ContactSearch) ([]domain.Contact, error)
+	Subscribe(int64) *domain.ContactSubscription
+}
+
+type Auth interface {
+	SingIn(context.Context, *domain.SignInInput) (string, string, error)
+	VerifyMFA(context.Context, *domain.MFAVerifyInput) (string, string, error)
+	RefreshTokens(context.Context, string) (string, string, error)
+	ParseJWTToken(context.Context, string) (*domain.Identity, error)
+}
+
+// Server serves the contacts and auth APIs over gRPC, next to the REST API
+// and from the same services.
+type Server struct {
+	server *grpc.Server
+}
+
+func NewServer(contacts Contacts, auth Auth) *Server {
+	interceptor := newAuthInterceptor(auth)
+
+	server := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(unaryErrors, interceptor.unary),
+		grpc.ChainStreamInterceptor(streamErrors, interceptor.stream),
+	)
+
+	contactsv1.RegisterContactsServiceServer(server, &contactsServer{contacts: contacts})
+	authv1.RegisterAuthServiceServer(server, &authServer{auth: auth})
+
+	return &Server{server: server}
+}
+
+// ListenAndServe serves on the port until Stop is called.
+func (s *Server) ListenAndServe(port int) error {
+	lis, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+	if err != nil {
+		return err
+	}
+
+	return s.Serve(lis)
+}
+
+func (s *Server) Serve(lis net.Listener) error {
+	return s.server.Serve(lis)
+}
+
+// Stop waits for the pending calls until the context is done, then closes
+// the remaining ones, Watch streams among them.
+func (s *Server) Stop(ctx context.Context) {
+	stopped := make(chan struct{})
+	go func() {
+		s.server.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		s.server.Stop()
+	}
+}
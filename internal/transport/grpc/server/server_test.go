@@ -0,0 +1,344 @@
+package grpc_server
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/magiconair/properties/assert"
+	authv1 "github.com/wilfridterry/contact-list/api/auth/v1"
+	contactsv1 "github.com/wilfridterry/contact-list/api/contacts/v1"
+	"github.com/wilfridterry/contact-list/internal/domain"
+	mock_grpc_server "github.com/wilfridterry/contact-list/internal/transport/grpc/server/mocks"
+	"go.uber.org/mock/gomock"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+const testToken = "token"
+
+var testIdentity = &domain.Identity{UserID: 1, Permissions: domain.RoleUser.Permissions()}
+
+// dial serves the services on an in-memory listener and returns a connection
+// to it.
+func dial(t *testing.T, contacts Contacts, auth Auth) *grpc.ClientConn {
+	lis := bufconn.Listen(1 << 20)
+
+	server := NewServer(contacts, auth)
+	go server.Serve(lis)
+	t.Cleanup(func() { server.Stop(context.Background()) })
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return conn
+}
+
+func withToken(ctx context.Context, token string) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+token)
+}
+
+func errorReason(err error) string {
+	for _, d := range status.Convert(err).Details() {
+		if info, ok := d.(*errdetails.ErrorInfo); ok {
+			return info.Reason
+		}
+	}
+
+	return ""
+}
+
+func TestServer_authorize(t *testing.T) {
+	type mockBehavior func(c *mock_grpc_server.MockContacts, a *mock_grpc_server.MockAuth)
+
+	testTable := []struct {
+		name           string
+		authorization  string
+		mockBehavior   mockBehavior
+		expectedCode   codes.Code
+		expectedReason string
+	}{
+		{
+			name:          "OK",
+			authorization: "Bearer " + testToken,
+			mockBehavior: func(c *mock_grpc_server.MockContacts, a *mock_grpc_server.MockAuth) {
+				a.EXPECT().ParseJWTToken(gomock.Any(), testToken).Return(testIdentity, nil)
+				c.EXPECT().All(gomock.Any()).Return([]domain.Contact{{ID: 1}}, nil)
+			},
+			expectedCode: codes.OK,
+		},
+		{
+			name:           "Missing token",
+			mockBehavior:   func(c *mock_grpc_server.MockContacts, a *mock_grpc_server.MockAuth) {},
+			expectedCode:   codes.Unauthenticated,
+			expectedReason: "unauthenticated",
+		},
+		{
+			name:           "Not a bearer token",
+			authorization:  "ApiKey " + testToken,
+			mockBehavior:   func(c *mock_grpc_server.MockContacts, a *mock_grpc_server.MockAuth) {},
+			expectedCode:   codes.Unauthenticated,
+			expectedReason: "unauthenticated",
+		},
+		{
+			name:          "Invalid token",
+			authorization: "Bearer " + testToken,
+			mockBehavior: func(c *mock_grpc_server.MockContacts, a *mock_grpc_server.MockAuth) {
+				a.EXPECT().ParseJWTToken(gomock.Any(), testToken).Return(nil, domain.ErrInvalidAccessToken)
+			},
+			expectedCode:   codes.Unauthenticated,
+			expectedReason: "invalid_access_token",
+		},
+		{
+			name:          "Missing permission",
+			authorization: "Bearer " + testToken,
+			mockBehavior: func(c *mock_grpc_server.MockContacts, a *mock_grpc_server.MockAuth) {
+				a.EXPECT().ParseJWTToken(gomock.Any(), testToken).Return(&domain.Identity{UserID: 1}, nil)
+			},
+			expectedCode:   codes.PermissionDenied,
+			expectedReason: "missing_permission",
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			contacts := mock_grpc_server.NewMockContacts(c)
+			auth := mock_grpc_server.NewMockAuth(c)
+			testCase.mockBehavior(contacts, auth)
+
+			client := contactsv1.NewContactsServiceClient(dial(t, contacts, auth))
+
+			ctx := context.Background()
+			if testCase.authorization != "" {
+				ctx = metadata.AppendToOutgoingContext(ctx, "authorization", testCase.authorization)
+			}
+
+			_, err := client.List(ctx, &contactsv1.ListRequest{})
+
+			assert.Equal(t, status.Code(err), testCase.expectedCode)
+			assert.Equal(t, errorReason(err), testCase.expectedReason)
+		})
+	}
+}
+
+func TestServer_Create(t *testing.T) {
+	type mockBehavior func(c *mock_grpc_server.MockContacts)
+
+	validInput := &contactsv1.ContactInput{
+		Name:     "Jane",
+		LastName: "Doe",
+		Phone:    "+380501234567",
+		Email:    "jane@example.com",
+		Address:  "Kyiv",
+		Author:   "John",
+	}
+
+	testTable := []struct {
+		name           string
+		input          *contactsv1.ContactInput
+		mockBehavior   mockBehavior
+		expectedCode   codes.Code
+		expectedReason string
+		expectedUserID int64
+	}{
+		{
+			name:  "OK",
+			input: validInput,
+			mockBehavior: func(c *mock_grpc_server.MockContacts) {
+				userId := int64(1)
+				c.EXPECT().Create(gomock.Any(), &domain.SaveInputContact{
+					Name:     "Jane",
+					LastName: "Doe",
+					Phone:    "+380501234567",
+					Email:    "jane@example.com",
+					Address:  "Kyiv",
+					Author:   "John",
+					UserID:   1,
+				}).Return(int64(7), nil)
+				c.EXPECT().GetOne(gomock.Any(), int64(7)).Return(&domain.Contact{ID: 7, Name: "Jane", UserID: &userId}, nil)
+			},
+			expectedCode:   codes.OK,
+			expectedUserID: 1,
+		},
+		{
+			name:           "Invalid input",
+			input:          &contactsv1.ContactInput{Name: "Jane"},
+			mockBehavior:   func(c *mock_grpc_server.MockContacts) {},
+			expectedCode:   codes.InvalidArgument,
+			expectedReason: "validation_failed",
+		},
+		{
+			name:  "Service failure",
+			input: validInput,
+			mockBehavior: func(c *mock_grpc_server.MockContacts) {
+				c.EXPECT().Create(gomock.Any(), gomock.Any()).Return(int64(0), errors.New("connection refused"))
+			},
+			expectedCode:   codes.Internal,
+			expectedReason: "internal_error",
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			contacts := mock_grpc_server.NewMockContacts(c)
+			testCase.mockBehavior(contacts)
+
+			auth := mock_grpc_server.NewMockAuth(c)
+			auth.EXPECT().ParseJWTToken(gomock.Any(), testToken).Return(testIdentity, nil)
+
+			client := contactsv1.NewContactsServiceClient(dial(t, contacts, auth))
+
+			contact, err := client.Create(withToken(context.Background(), testToken), &contactsv1.CreateRequest{Contact: testCase.input})
+
+			assert.Equal(t, status.Code(err), testCase.expectedCode)
+			assert.Equal(t, errorReason(err), testCase.expectedReason)
+			assert.Equal(t, contact.GetUserId(), testCase.expectedUserID)
+		})
+	}
+}
+
+func TestServer_Watch(t *testing.T) {
+	c := gomock.NewController(t)
+	defer c.Finish()
+
+	events := make(chan domain.ContactEvent, 1)
+	closed := make(chan struct{})
+
+	contacts := mock_grpc_server.NewMockContacts(c)
+	contacts.EXPECT().Subscribe(int64(3)).Return(&domain.ContactSubscription{
+		Missed: []domain.ContactEvent{{ID: 4, Type: domain.ContactUpdated, ContactID: 1, Time: time.Now()}},
+		Reset:  true,
+		Events: events,
+		Close:  func() { close(closed) },
+	})
+
+	auth := mock_grpc_server.NewMockAuth(c)
+	auth.EXPECT().ParseJWTToken(gomock.Any(), testToken).Return(testIdentity, nil)
+
+	client := contactsv1.NewContactsServiceClient(dial(t, contacts, auth))
+
+	stream, err := client.Watch(withToken(context.Background(), testToken), &contactsv1.WatchRequest{LastEventId: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	events <- domain.ContactEvent{ID: 5, Type: domain.ContactDeleted, ContactID: 2, Time: time.Now()}
+
+	expected := []struct {
+		id        int64
+		eventType contactsv1.ContactEvent_Type
+		contactId int64
+	}{
+		{0, contactsv1.ContactEvent_TYPE_RESET, 0},
+		{4, contactsv1.ContactEvent_TYPE_UPDATED, 1},
+		{5, contactsv1.ContactEvent_TYPE_DELETED, 2},
+	}
+
+	for _, e := range expected {
+		event, err := stream.Recv()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assert.Equal(t, event.GetId(), e.id)
+		assert.Equal(t, event.GetType(), e.eventType)
+		assert.Equal(t, event.GetContactId(), e.contactId)
+	}
+
+	close(events)
+
+	_, err = stream.Recv()
+	assert.Equal(t, status.Code(err), codes.Unavailable)
+
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		t.Fatal("subscription was not closed")
+	}
+}
+
+func TestServer_SignIn(t *testing.T) {
+	type mockBehavior func(a *mock_grpc_server.MockAuth)
+
+	expiresAt := time.Date(2024, 1, 1, 0, 5, 0, 0, time.UTC)
+
+	testTable := []struct {
+		name              string
+		mockBehavior      mockBehavior
+		expectedCode      codes.Code
+		expectedReason    string
+		expectedToken     string
+		expectedChallenge string
+	}{
+		{
+			name: "OK",
+			mockBehavior: func(a *mock_grpc_server.MockAuth) {
+				a.EXPECT().SingIn(gomock.Any(), gomock.Any()).Return("access", "refresh", nil)
+			},
+			expectedCode:  codes.OK,
+			expectedToken: "access",
+		},
+		{
+			name: "MFA required",
+			mockBehavior: func(a *mock_grpc_server.MockAuth) {
+				a.EXPECT().SingIn(gomock.Any(), gomock.Any()).Return("", "", &domain.MFARequiredError{Challenge: "challenge", ExpiresAt: expiresAt})
+			},
+			expectedCode:      codes.OK,
+			expectedChallenge: "challenge",
+		},
+		{
+			name: "Unknown user",
+			mockBehavior: func(a *mock_grpc_server.MockAuth) {
+				a.EXPECT().SingIn(gomock.Any(), gomock.Any()).Return("", "", domain.ErrNotFoundUser)
+			},
+			expectedCode:   codes.InvalidArgument,
+			expectedReason: "invalid_credentials",
+		},
+		{
+			name: "Locked",
+			mockBehavior: func(a *mock_grpc_server.MockAuth) {
+				a.EXPECT().SingIn(gomock.Any(), gomock.Any()).Return("", "", &domain.LoginLockedError{RetryAfter: time.Minute})
+			},
+			expectedCode:   codes.ResourceExhausted,
+			expectedReason: "too_many_attempts",
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			auth := mock_grpc_server.NewMockAuth(c)
+			testCase.mockBehavior(auth)
+
+			client := authv1.NewAuthServiceClient(dial(t, mock_grpc_server.NewMockContacts(c), auth))
+
+			resp, err := client.SignIn(context.Background(), &authv1.SignInRequest{Email: "jane@example.com", Password: "secret123"})
+
+			assert.Equal(t, status.Code(err), testCase.expectedCode)
+			assert.Equal(t, errorReason(err), testCase.expectedReason)
+			assert.Equal(t, resp.GetTokens().GetAccessToken(), testCase.expectedToken)
+			assert.Equal(t, resp.GetMfaChallenge().GetChallenge(), testCase.expectedChallenge)
+		})
+	}
+}
@@ -0,0 +1,293 @@
+package rest
+
+import (
+	"net/http"
+
+	"github.com/wilfridterry/contact-list/internal/domain"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AssignRole godoc
+// @Summary      Assign a role to a user
+// @Description  change the role (and so the permissions) of a user
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        id   path      int  true  "User ID"
+// @Param role body domain.AssignRoleInput true "Role payload"
+// @Success      200
+// @Failure      400  {object}  Problem
+// @Failure      403  {object}  PermissionProblem
+// @Failure      404  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Router       /admin/users/{id}/role [put]
+func (h *Handler) assignRole(c *gin.Context) {
+	var uri Uri
+	if err := c.ShouldBindUri(&uri); err != nil {
+		newProblem(c, err)
+		return
+	}
+
+	var inp domain.AssignRoleInput
+	if err := c.ShouldBindJSON(&inp); err != nil {
+		newProblem(c, err)
+
+		return
+	}
+
+	if err := h.authServie.AssignRole(c.Request.Context(), uri.ID, inp.Role); err != nil {
+		newProblem(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Updated."})
+}
+
+// UnlockUser godoc
+// @Summary      Unlock a user
+// @Description  forget failed sign in attempts of a locked out user
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        id   path      int  true  "User ID"
+// @Success      204
+// @Failure      400  {object}  Problem
+// @Failure      403  {object}  PermissionProblem
+// @Failure      404  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Router       /admin/users/{id}/lockout [delete]
+func (h *Handler) unlockUser(c *gin.Context) {
+	var uri Uri
+	if err := c.ShouldBindUri(&uri); err != nil {
+		newProblem(c, err)
+		return
+	}
+
+	if err := h.authServie.Unlock(c.Request.Context(), uri.ID); err != nil {
+		newProblem(c, err)
+		return
+	}
+
+	c.JSON(http.StatusNoContent, gin.H{})
+}
+
+// ListUsers godoc
+// @Summary      List users
+// @Description  page through users, optionally searching by name or email
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        search    query     string  false  "Name or email contains"
+// @Param        page      query     int     false  "Page, starting from 1"
+// @Param        per_page  query     int     false  "Users per page, up to 100"
+// @Success      200  {object}  domain.UserPage
+// @Failure      403  {object}  PermissionProblem
+// @Failure      422  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Router       /admin/users [get]
+func (h *Handler) listUsers(c *gin.Context) {
+	var filter domain.UserFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		newProblem(c, err)
+		return
+	}
+
+	page, err := h.authServie.ListUsers(c.Request.Context(), &filter)
+	if err != nil {
+		newProblem(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
+
+// GetUserDetails godoc
+// @Summary      Get a user
+// @Description  get a user with the MFA status and the number of active sessions
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        id   path      int  true  "User ID"
+// @Success      200  {object}  domain.UserDetails
+// @Failure      400  {object}  Problem
+// @Failure      403  {object}  PermissionProblem
+// @Failure      404  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Router       /admin/users/{id} [get]
+func (h *Handler) getUserDetails(c *gin.Context) {
+	var uri Uri
+	if err := c.ShouldBindUri(&uri); err != nil {
+		newProblem(c, err)
+		return
+	}
+
+	details, err := h.authServie.UserDetails(c.Request.Context(), uri.ID)
+	if err != nil {
+		newProblem(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, details)
+}
+
+// DisableUser godoc
+// @Summary      Disable a user
+// @Description  block sign in and token refresh of a user and end all of its sessions
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        id   path      int  true  "User ID"
+// @Param        Idempotency-Key  header  string  false  "retries with the same key run the request once"
+// @Success      204
+// @Failure      400  {object}  Problem
+// @Failure      403  {object}  PermissionProblem
+// @Failure      404  {object}  Problem
+// @Failure      409  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Router       /admin/users/{id}/disable [post]
+func (h *Handler) disableUser(c *gin.Context) {
+	var uri Uri
+	if err := c.ShouldBindUri(&uri); err != nil {
+		newProblem(c, err)
+		return
+	}
+
+	identity, _ := getIdentity(c)
+
+	if err := h.authServie.DisableUser(c.Request.Context(), identity.UserID, uri.ID); err != nil {
+		newProblem(c, err)
+		return
+	}
+
+	c.JSON(http.StatusNoContent, gin.H{})
+}
+
+// EnableUser godoc
+// @Summary      Enable a user
+// @Description  allow a disabled user to sign in again
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        id   path      int  true  "User ID"
+// @Param        Idempotency-Key  header  string  false  "retries with the same key run the request once"
+// @Success      204
+// @Failure      400  {object}  Problem
+// @Failure      403  {object}  PermissionProblem
+// @Failure      404  {object}  Problem
+// @Failure      409  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Router       /admin/users/{id}/enable [post]
+func (h *Handler) enableUser(c *gin.Context) {
+	var uri Uri
+	if err := c.ShouldBindUri(&uri); err != nil {
+		newProblem(c, err)
+		return
+	}
+
+	identity, _ := getIdentity(c)
+
+	if err := h.authServie.EnableUser(c.Request.Context(), identity.UserID, uri.ID); err != nil {
+		newProblem(c, err)
+		return
+	}
+
+	c.JSON(http.StatusNoContent, gin.H{})
+}
+
+// ForcePasswordReset godoc
+// @Summary      Force a password reset
+// @Description  sign the user out everywhere and require a new password, a reset link is emailed
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        id   path      int  true  "User ID"
+// @Param        Idempotency-Key  header  string  false  "retries with the same key run the request once"
+// @Success      204
+// @Failure      400  {object}  Problem
+// @Failure      403  {object}  PermissionProblem
+// @Failure      404  {object}  Problem
+// @Failure      409  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Router       /admin/users/{id}/password-reset [post]
+func (h *Handler) forcePasswordReset(c *gin.Context) {
+	var uri Uri
+	if err := c.ShouldBindUri(&uri); err != nil {
+		newProblem(c, err)
+		return
+	}
+
+	identity, _ := getIdentity(c)
+
+	if err := h.authServie.ForcePasswordReset(c.Request.Context(), identity.UserID, uri.ID); err != nil {
+		newProblem(c, err)
+		return
+	}
+
+	c.JSON(http.StatusNoContent, gin.H{})
+}
+
+// RevokeSessions godoc
+// @Summary      Revoke sessions of a user
+// @Description  delete every refresh token of the user
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        id   path      int  true  "User ID"
+// @Success      204
+// @Failure      400  {object}  Problem
+// @Failure      403  {object}  PermissionProblem
+// @Failure      404  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Router       /admin/users/{id}/sessions [delete]
+func (h *Handler) revokeSessions(c *gin.Context) {
+	var uri Uri
+	if err := c.ShouldBindUri(&uri); err != nil {
+		newProblem(c, err)
+		return
+	}
+
+	identity, _ := getIdentity(c)
+
+	if err := h.authServie.RevokeSessions(c.Request.Context(), identity.UserID, uri.ID); err != nil {
+		newProblem(c, err)
+		return
+	}
+
+	c.JSON(http.StatusNoContent, gin.H{})
+}
+
+// ImpersonateUser godoc
+// @Summary      Impersonate a user
+// @Description  issue a short-lived access token acting as the user, it is marked as impersonated in the audit log
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        id   path      int  true  "User ID"
+// @Success      200  {object}  domain.ImpersonationToken
+// @Failure      400  {object}  Problem
+// @Failure      403  {object}  PermissionProblem
+// @Failure      404  {object}  Problem
+// @Failure      409  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Router       /admin/users/{id}/impersonate [post]
+func (h *Handler) impersonateUser(c *gin.Context) {
+	var uri Uri
+	if err := c.ShouldBindUri(&uri); err != nil {
+		newProblem(c, err)
+		return
+	}
+
+	identity, ok := refuseDelegated(c)
+	if !ok {
+		return
+	}
+
+	token, err := h.authServie.Impersonate(c.Request.Context(), identity.UserID, uri.ID)
+	if err != nil {
+		newProblem(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, token)
+}
@@ -0,0 +1,88 @@
+package rest
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/magiconair/properties/assert"
+	"github.com/wilfridterry/contact-list/internal/domain"
+	mock_rest "github.com/wilfridterry/contact-list/internal/transport/rest/mocks"
+	"go.uber.org/mock/gomock"
+)
+
+func TestHandler_impersonateUser(t *testing.T) {
+	type mockBehavior func(s *mock_rest.MockAuth)
+
+	expiresAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	testTable := []struct {
+		name                 string
+		identity             *domain.Identity
+		mockBehavior         mockBehavior
+		expectedStatusCode   int
+		expectedResponseBody string
+	}{
+		{
+			name:     "OK",
+			identity: &domain.Identity{UserID: 1, Role: domain.RoleAdmin},
+			mockBehavior: func(s *mock_rest.MockAuth) {
+				s.EXPECT().Impersonate(gomock.Any(), int64(1), int64(2)).Return(&domain.ImpersonationToken{Token: "token", ExpiresAt: expiresAt}, nil)
+			},
+			expectedStatusCode:   200,
+			expectedResponseBody: `{"token":"token","expires_at":"2024-01-01T00:00:00Z"}`,
+		},
+		{
+			name:     "Admin target",
+			identity: &domain.Identity{UserID: 1, Role: domain.RoleAdmin},
+			mockBehavior: func(s *mock_rest.MockAuth) {
+				s.EXPECT().Impersonate(gomock.Any(), int64(1), int64(2)).Return(nil, domain.ErrImpersonationForbidden)
+			},
+			expectedStatusCode:   403,
+			expectedResponseBody: `{"type":"urn:contact-list:problem:impersonation_forbidden","title":"Forbidden","status":403,"detail":"administrators can not be impersonated","instance":"/admin/users/2/impersonate","code":"impersonation_forbidden"}`,
+		},
+		{
+			name:     "Not found",
+			identity: &domain.Identity{UserID: 1, Role: domain.RoleAdmin},
+			mockBehavior: func(s *mock_rest.MockAuth) {
+				s.EXPECT().Impersonate(gomock.Any(), int64(1), int64(2)).Return(nil, domain.ErrNotFoundUser)
+			},
+			expectedStatusCode:   404,
+			expectedResponseBody: `{"type":"urn:contact-list:problem:user_not_found","title":"Not Found","status":404,"detail":"Not found user","instance":"/admin/users/2/impersonate","code":"user_not_found"}`,
+		},
+		{
+			name:                 "Impersonation token",
+			identity:             &domain.Identity{UserID: 3, Role: domain.RoleUser, ImpersonatorID: 1},
+			mockBehavior:         func(s *mock_rest.MockAuth) {},
+			expectedStatusCode:   403,
+			expectedResponseBody: `{"type":"urn:contact-list:problem:delegated_credential","title":"Forbidden","status":403,"detail":"not allowed with an api key, oauth or impersonation token","instance":"/admin/users/2/impersonate","code":"delegated_credential"}`,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			auth := mock_rest.NewMockAuth(c)
+			testCase.mockBehavior(auth)
+
+			handler := NewHandler(&mock_rest.MockContacts{}, auth, &mock_rest.MockAPIKeys{}, &mock_rest.MockOIDC{}, &mock_rest.MockOAuth{}, &mock_rest.MockPrivacy{}, &mock_rest.MockIdempotency{}, &mock_rest.MockWebhooks{}, &mock_rest.MockCardDAV{}, &mock_rest.MockGraphQL{}, &mock_rest.MockHealth{})
+
+			r := gin.New()
+			r.POST("/admin/users/:id/impersonate", func(c *gin.Context) {
+				c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), ctxIdentity, testCase.identity))
+			}, handler.impersonateUser)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("POST", "/admin/users/2/impersonate", nil)
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, w.Code, testCase.expectedStatusCode)
+			assert.Equal(t, w.Body.String(), testCase.expectedResponseBody)
+		})
+	}
+}
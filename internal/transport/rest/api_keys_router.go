@@ -0,0 +1,116 @@
+package rest
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/wilfridterry/contact-list/internal/apperror"
+	"github.com/wilfridterry/contact-list/internal/domain"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CreatedAPIKey struct {
+	Key    string         `json:"key"`
+	APIKey *domain.APIKey `json:"api_key"`
+}
+
+// CreateAPIKey godoc
+// @Summary      Create an API key
+// @Description  create a personal API key, the key is shown only once
+// @Tags         api-keys
+// @Accept       json
+// @Produce      json
+// @Param key body domain.CreateAPIKeyInput true "API key payload"
+// @Success      201  {object}  CreatedAPIKey
+// @Failure      400  {object}  Problem
+// @Failure      401  {object}  Problem
+// @Failure      403  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Router       /api-keys [post]
+func (h *Handler) createAPIKey(c *gin.Context) {
+	identity, ok := refuseDelegated(c)
+	if !ok {
+		return
+	}
+
+	var inp domain.CreateAPIKeyInput
+	if err := c.ShouldBindJSON(&inp); err != nil {
+		newProblem(c, err)
+
+		return
+	}
+
+	key, plain, err := h.apiKeyService.Create(c.Request.Context(), identity.UserID, &inp)
+	if err != nil {
+		if errors.Is(err, domain.ErrAPIKeyExpired) {
+			newProblem(c, apperror.Wrap(err, http.StatusUnprocessableEntity, "invalid_expiry"))
+			return
+		}
+
+		newProblem(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, CreatedAPIKey{Key: plain, APIKey: key})
+}
+
+// ListAPIKeys godoc
+// @Summary      List API keys
+// @Description  get API keys of the current user
+// @Tags         api-keys
+// @Accept       json
+// @Produce      json
+// @Success      200  {array}   domain.APIKey
+// @Failure      401  {object}  Problem
+// @Failure      403  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Router       /api-keys [get]
+func (h *Handler) getAPIKeys(c *gin.Context) {
+	identity, ok := refuseDelegated(c)
+	if !ok {
+		return
+	}
+
+	keys, err := h.apiKeyService.All(c.Request.Context(), identity.UserID)
+	if err != nil {
+		newProblem(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, keys)
+}
+
+// RevokeAPIKey godoc
+// @Summary      Revoke an API key
+// @Description  revoke an API key of the current user by ID
+// @Tags         api-keys
+// @Accept       json
+// @Produce      json
+// @Param        id   path      int  true  "API key ID"
+// @Success      204
+// @Failure      400  {object}  Problem
+// @Failure      401  {object}  Problem
+// @Failure      403  {object}  Problem
+// @Failure      404  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Router       /api-keys/{id} [delete]
+func (h *Handler) revokeAPIKey(c *gin.Context) {
+	var uri Uri
+	if err := c.ShouldBindUri(&uri); err != nil {
+		newProblem(c, err)
+		return
+	}
+
+	identity, ok := refuseDelegated(c)
+	if !ok {
+		return
+	}
+
+	if err := h.apiKeyService.Revoke(c.Request.Context(), identity.UserID, uri.ID); err != nil {
+		newProblem(c, err)
+		return
+	}
+
+	c.JSON(http.StatusNoContent, gin.H{})
+}
@@ -2,14 +2,15 @@ package rest
 
 import (
 	"errors"
+	"math"
+	"strconv"
 
+	"github.com/wilfridterry/contact-list/internal/apperror"
 	"github.com/wilfridterry/contact-list/internal/domain"
 
 	"net/http"
 
 	"github.com/gin-gonic/gin"
-	"github.com/sirupsen/logrus"
-	"github.com/swaggo/swag/example/celler/httputil"
 )
 
 // SignUp godoc
@@ -20,15 +21,15 @@ import (
 // @Produce      json
 // @Param user body domain.SignUpInput true "user sign up"
 // @Success      201
-// @Failure      400  {object}  httputil.HTTPError
-// @Failure      404  {object}  httputil.HTTPError
-// @Failure      500  {object}  httputil.HTTPError
+// @Failure      400  {object}  Problem
+// @Failure      404  {object}  Problem
+// @Failure      500  {object}  Problem
 // @Router       /auth/sign-up [post]
 func (h *Handler) signUp(c *gin.Context) {
 	var inp domain.SignUpInput
 
 	if err := c.ShouldBindJSON(&inp); err != nil {
-		httputil.NewError(c, http.StatusUnprocessableEntity, err)
+		newProblem(c, err)
 
 		return
 	}
@@ -36,7 +37,7 @@ func (h *Handler) signUp(c *gin.Context) {
 	user, err := h.authServie.SignUp(c.Request.Context(), &inp)
 
 	if err != nil {
-		httputil.NewError(c, http.StatusInternalServerError, err)
+		newProblem(c, err)
 
 		return
 	}
@@ -55,28 +56,49 @@ func (h *Handler) signUp(c *gin.Context) {
 // @Produce      json
 // @Param user body domain.SignInInput true "user sign in"
 // @Success      200
-// @Failure      400  {object}  httputil.HTTPError
-// @Failure      404  {object}  httputil.HTTPError
-// @Failure      500  {object}  httputil.HTTPError
+// @Success      202  {object}  MFAChallenge
+// @Failure      400  {object}  Problem
+// @Failure      403  {object}  Problem
+// @Failure      404  {object}  Problem
+// @Failure      429  {object}  Problem
+// @Failure      500  {object}  Problem
 // @Router       /auth/sign-in [get]
 func (h *Handler) signIn(c *gin.Context) {
 	var inp domain.SignInInput
 
 	if err := c.ShouldBindJSON(&inp); err != nil {
-		httputil.NewError(c, http.StatusUnprocessableEntity, err)
+		newProblem(c, err)
 
 		return
 	}
 
+	inp.IP = c.ClientIP()
+
 	accessToken, refreshToken, err := h.authServie.SingIn(c.Request.Context(), &inp)
 
 	if err != nil {
+		var mfaErr *domain.MFARequiredError
+		if errors.As(err, &mfaErr) {
+			c.JSON(http.StatusAccepted, MFAChallenge{
+				MFARequired: true,
+				Challenge:   mfaErr.Challenge,
+				ExpiresAt:   mfaErr.ExpiresAt,
+			})
+			return
+		}
+
 		if errors.Is(err, domain.ErrNotFoundUser) {
-			httputil.NewError(c, http.StatusBadRequest, err)
+			newProblem(c, errInvalidCredentials)
+			return
+		}
+
+		var lockErr *domain.LoginLockedError
+		if errors.As(err, &lockErr) {
+			tooManyAttempts(c, lockErr)
 			return
 		}
 
-		httputil.NewError(c, http.StatusInternalServerError, err)
+		newProblem(c, err)
 		return
 	}
 
@@ -91,25 +113,151 @@ func (h *Handler) signIn(c *gin.Context) {
 // @Accept       json
 // @Produce      json
 // @Success      200
-// @Failure      400  {object}  httputil.HTTPError
-// @Failure      404  {object}  httputil.HTTPError
-// @Failure      500  {object}  httputil.HTTPError
+// @Failure      400  {object}  Problem
+// @Failure      403  {object}  Problem
+// @Failure      404  {object}  Problem
+// @Failure      500  {object}  Problem
 // @Router       /auth/sign-in [Get]
 func (h *Handler) refresh(c *gin.Context) {
 	cookie, err := c.Cookie("refresh-token")
 	if err != nil {
-		httputil.NewError(c, http.StatusBadRequest, err)
+		newProblem(c, err)
 		return
 	}
 
-	logrus.Infof("%s", cookie)
-
 	accessToken, refreshToken, err := h.authServie.RefreshTokens(c.Request.Context(), cookie)
 	if err != nil {
-		httputil.NewError(c, http.StatusInternalServerError, err)
+		newProblem(c, err)
 		return
 	}
 
 	c.SetCookie("refresh-token", refreshToken, 3600, "/", "localhost", true, true)
 	c.JSON(http.StatusOK, gin.H{"token": accessToken})
 }
+
+// VerifyEmail godoc
+// @Summary      verify email
+// @Description  confirm the email address with the token sent by email
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param input body domain.VerifyEmailInput true "verification token"
+// @Success      200
+// @Failure      400  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Router       /auth/verify [post]
+func (h *Handler) verifyEmail(c *gin.Context) {
+	var inp domain.VerifyEmailInput
+	if err := c.ShouldBindJSON(&inp); err != nil {
+		newProblem(c, err)
+
+		return
+	}
+
+	if err := h.authServie.VerifyEmail(c.Request.Context(), inp.Token); err != nil {
+		if errors.Is(err, domain.ErrNotFoundUser) {
+			newProblem(c, domain.ErrInvalidEmailToken)
+			return
+		}
+
+		newProblem(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Verified."})
+}
+
+// ResendVerification godoc
+// @Summary      resend verification email
+// @Description  send a new verification email if the account exists and is not verified
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param input body domain.EmailInput true "email"
+// @Success      202
+// @Failure      422  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Router       /auth/verify/resend [post]
+func (h *Handler) resendVerification(c *gin.Context) {
+	var inp domain.EmailInput
+	if err := c.ShouldBindJSON(&inp); err != nil {
+		newProblem(c, err)
+
+		return
+	}
+
+	if err := h.authServie.ResendVerification(c.Request.Context(), inp.Email); err != nil {
+		newProblem(c, err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "If the account exists, an email has been sent."})
+}
+
+// ForgotPassword godoc
+// @Summary      forgot password
+// @Description  send a password reset link if the account exists
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param input body domain.EmailInput true "email"
+// @Success      202
+// @Failure      422  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Router       /auth/forgot-password [post]
+func (h *Handler) forgotPassword(c *gin.Context) {
+	var inp domain.EmailInput
+	if err := c.ShouldBindJSON(&inp); err != nil {
+		newProblem(c, err)
+
+		return
+	}
+
+	if err := h.authServie.ForgotPassword(c.Request.Context(), inp.Email); err != nil {
+		newProblem(c, err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "If the account exists, an email has been sent."})
+}
+
+// ResetPassword godoc
+// @Summary      reset password
+// @Description  set a new password with the token sent by email, all sessions are revoked
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param input body domain.ResetPasswordInput true "reset token and new password"
+// @Success      200
+// @Failure      400  {object}  Problem
+// @Failure      422  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Router       /auth/reset-password [post]
+func (h *Handler) resetPassword(c *gin.Context) {
+	var inp domain.ResetPasswordInput
+	if err := c.ShouldBindJSON(&inp); err != nil {
+		newProblem(c, err)
+
+		return
+	}
+
+	if err := h.authServie.ResetPassword(c.Request.Context(), &inp); err != nil {
+		if errors.Is(err, domain.ErrNotFoundUser) {
+			newProblem(c, domain.ErrInvalidEmailToken)
+			return
+		}
+
+		newProblem(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Password updated."})
+}
+
+// errInvalidCredentials does not tell unknown emails from wrong passwords.
+var errInvalidCredentials = apperror.New(http.StatusBadRequest, "invalid_credentials", "invalid email or password")
+
+func tooManyAttempts(c *gin.Context, err *domain.LoginLockedError) {
+	c.Header("Retry-After", strconv.Itoa(int(math.Ceil(err.RetryAfter.Seconds()))))
+	newProblem(c, err)
+}
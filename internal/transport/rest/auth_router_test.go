@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/magiconair/properties/assert"
@@ -38,10 +39,11 @@ func TestHandler_signUp(t *testing.T) {
 					ID:    1,
 					Name:  "Test",
 					Email: "test@test.com",
+					Role:  domain.RoleUser,
 				}, nil)
 			},
 			expectedStatusCode:  200,
-			expectedRequestBody: `{"message": "Created.", "user": {"id":1,"name":"Test","email":"test@test.com","registered_at":"0001-01-01T00:00:00Z","created_at":"0001-01-01T00:00:00Z","updated_at":"0001-01-01T00:00:00Z"}}`,
+			expectedRequestBody: `{"message": "Created.", "user": {"id":1,"name":"Test","email":"test@test.com","role":"user","email_verified_at":null,"disabled_at":null,"password_reset_required":false,"registered_at":"0001-01-01T00:00:00Z","created_at":"0001-01-01T00:00:00Z","updated_at":"0001-01-01T00:00:00Z"}}`,
 		},
 	}
 
@@ -54,7 +56,7 @@ func TestHandler_signUp(t *testing.T) {
 			auth := mock_rest.NewMockAuth(c)
 			testCase.mockBehavior(auth, &testCase.inputUser)
 
-			handler := NewHandler(&mock_rest.MockContacts{}, auth)
+			handler := NewHandler(&mock_rest.MockContacts{}, auth, &mock_rest.MockAPIKeys{}, &mock_rest.MockOIDC{}, &mock_rest.MockOAuth{}, &mock_rest.MockPrivacy{}, &mock_rest.MockIdempotency{}, &mock_rest.MockWebhooks{}, &mock_rest.MockCardDAV{}, &mock_rest.MockGraphQL{}, &mock_rest.MockHealth{})
 
 			// Test Server
 
@@ -76,3 +78,229 @@ func TestHandler_signUp(t *testing.T) {
 		})
 	}
 }
+
+func TestHandler_signIn(t *testing.T) {
+	type mockBehavior func(s *mock_rest.MockAuth, inp *domain.SignInInput)
+
+	expiresAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	testTable := []struct {
+		name                string
+		inputBody           string
+		inputUser           domain.SignInInput
+		mockBehavior        mockBehavior
+		expectedStatusCode  int
+		expectedRetryAfter  string
+		expectedRequestBody string
+	}{
+		{
+			name:      "OK",
+			inputBody: `{"email": "test@test.com", "password": "qwerty"}`,
+			inputUser: domain.SignInInput{
+				Email:    "test@test.com",
+				Password: "qwerty",
+				IP:       "192.0.2.1",
+			},
+			mockBehavior: func(s *mock_rest.MockAuth, inp *domain.SignInInput) {
+				s.EXPECT().SingIn(context.Background(), inp).Return("access", "refresh", nil)
+			},
+			expectedStatusCode:  200,
+			expectedRequestBody: `{"token": "access"}`,
+		},
+		{
+			name:      "MFA required",
+			inputBody: `{"email": "test@test.com", "password": "qwerty"}`,
+			inputUser: domain.SignInInput{
+				Email:    "test@test.com",
+				Password: "qwerty",
+				IP:       "192.0.2.1",
+			},
+			mockBehavior: func(s *mock_rest.MockAuth, inp *domain.SignInInput) {
+				s.EXPECT().SingIn(context.Background(), inp).Return("", "", &domain.MFARequiredError{
+					Challenge: "challenge",
+					ExpiresAt: expiresAt,
+				})
+			},
+			expectedStatusCode:  202,
+			expectedRequestBody: `{"mfa_required": true, "challenge": "challenge", "expires_at": "2024-01-01T00:00:00Z"}`,
+		},
+		{
+			name:      "Locked",
+			inputBody: `{"email": "test@test.com", "password": "qwerty"}`,
+			inputUser: domain.SignInInput{
+				Email:    "test@test.com",
+				Password: "qwerty",
+				IP:       "192.0.2.1",
+			},
+			mockBehavior: func(s *mock_rest.MockAuth, inp *domain.SignInInput) {
+				s.EXPECT().SingIn(context.Background(), inp).Return("", "", &domain.LoginLockedError{RetryAfter: 1500 * time.Millisecond})
+			},
+			expectedStatusCode:  429,
+			expectedRetryAfter:  "2",
+			expectedRequestBody: `{"type": "urn:contact-list:problem:too_many_attempts", "title": "Too Many Requests", "status": 429, "detail": "too many failed sign in attempts", "instance": "/sign-in", "code": "too_many_attempts"}`,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			auth := mock_rest.NewMockAuth(c)
+			testCase.mockBehavior(auth, &testCase.inputUser)
+
+			handler := NewHandler(&mock_rest.MockContacts{}, auth, &mock_rest.MockAPIKeys{}, &mock_rest.MockOIDC{}, &mock_rest.MockOAuth{}, &mock_rest.MockPrivacy{}, &mock_rest.MockIdempotency{}, &mock_rest.MockWebhooks{}, &mock_rest.MockCardDAV{}, &mock_rest.MockGraphQL{}, &mock_rest.MockHealth{})
+
+			r := gin.New()
+			r.GET("/sign-in", handler.signIn)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", "/sign-in", bytes.NewBufferString(testCase.inputBody))
+
+			r.ServeHTTP(w, req)
+
+			var actual, expected map[string]interface{}
+			json.Unmarshal(w.Body.Bytes(), &actual)
+			json.Unmarshal([]byte(testCase.expectedRequestBody), &expected)
+
+			assert.Equal(t, testCase.expectedStatusCode, w.Code)
+			assert.Equal(t, w.Header().Get("Retry-After"), testCase.expectedRetryAfter)
+			assert.Equal(t, actual, expected)
+		})
+	}
+}
+
+func TestHandler_accountFlows(t *testing.T) {
+	type mockBehavior func(s *mock_rest.MockAuth)
+
+	testTable := []struct {
+		name               string
+		path               string
+		inputBody          string
+		mockBehavior       mockBehavior
+		expectedStatusCode int
+		expectedCode       string
+	}{
+		{
+			name:      "Verify",
+			path:      "/auth/verify",
+			inputBody: `{"token": "t"}`,
+			mockBehavior: func(s *mock_rest.MockAuth) {
+				s.EXPECT().VerifyEmail(gomock.Any(), "t").Return(nil)
+			},
+			expectedStatusCode: 200,
+		},
+		{
+			name:      "Verify with an expired token",
+			path:      "/auth/verify",
+			inputBody: `{"token": "t"}`,
+			mockBehavior: func(s *mock_rest.MockAuth) {
+				s.EXPECT().VerifyEmail(gomock.Any(), "t").Return(domain.ErrInvalidEmailToken)
+			},
+			expectedStatusCode: 400,
+			expectedCode:       "invalid_email_token",
+		},
+		{
+			name:      "Verify for a deleted user",
+			path:      "/auth/verify",
+			inputBody: `{"token": "t"}`,
+			mockBehavior: func(s *mock_rest.MockAuth) {
+				s.EXPECT().VerifyEmail(gomock.Any(), "t").Return(domain.ErrNotFoundUser)
+			},
+			expectedStatusCode: 400,
+			expectedCode:       "invalid_email_token",
+		},
+		{
+			name:               "Verify without a token",
+			path:               "/auth/verify",
+			inputBody:          `{}`,
+			mockBehavior:       func(s *mock_rest.MockAuth) {},
+			expectedStatusCode: 422,
+			expectedCode:       "validation_failed",
+		},
+		{
+			name:      "Resend",
+			path:      "/auth/verify/resend",
+			inputBody: `{"email": "test@test.com"}`,
+			mockBehavior: func(s *mock_rest.MockAuth) {
+				s.EXPECT().ResendVerification(gomock.Any(), "test@test.com").Return(nil)
+			},
+			expectedStatusCode: 202,
+		},
+		{
+			name:               "Resend to an invalid email",
+			path:               "/auth/verify/resend",
+			inputBody:          `{"email": "test"}`,
+			mockBehavior:       func(s *mock_rest.MockAuth) {},
+			expectedStatusCode: 422,
+			expectedCode:       "validation_failed",
+		},
+		{
+			name:      "Forgot",
+			path:      "/auth/forgot-password",
+			inputBody: `{"email": "test@test.com"}`,
+			mockBehavior: func(s *mock_rest.MockAuth) {
+				s.EXPECT().ForgotPassword(gomock.Any(), "test@test.com").Return(nil)
+			},
+			expectedStatusCode: 202,
+		},
+		{
+			name:      "Reset",
+			path:      "/auth/reset-password",
+			inputBody: `{"token": "t", "password": "qwerty"}`,
+			mockBehavior: func(s *mock_rest.MockAuth) {
+				s.EXPECT().ResetPassword(gomock.Any(), &domain.ResetPasswordInput{Token: "t", Password: "qwerty"}).Return(nil)
+			},
+			expectedStatusCode: 200,
+		},
+		{
+			name:      "Reset with a used token",
+			path:      "/auth/reset-password",
+			inputBody: `{"token": "t", "password": "qwerty"}`,
+			mockBehavior: func(s *mock_rest.MockAuth) {
+				s.EXPECT().ResetPassword(gomock.Any(), &domain.ResetPasswordInput{Token: "t", Password: "qwerty"}).Return(domain.ErrInvalidEmailToken)
+			},
+			expectedStatusCode: 400,
+			expectedCode:       "invalid_email_token",
+		},
+		{
+			name:               "Reset to a short password",
+			path:               "/auth/reset-password",
+			inputBody:          `{"token": "t", "password": "qw"}`,
+			mockBehavior:       func(s *mock_rest.MockAuth) {},
+			expectedStatusCode: 422,
+			expectedCode:       "validation_failed",
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			auth := mock_rest.NewMockAuth(c)
+			testCase.mockBehavior(auth)
+
+			handler := NewHandler(&mock_rest.MockContacts{}, auth, &mock_rest.MockAPIKeys{}, &mock_rest.MockOIDC{}, &mock_rest.MockOAuth{}, &mock_rest.MockPrivacy{}, &mock_rest.MockIdempotency{}, &mock_rest.MockWebhooks{}, &mock_rest.MockCardDAV{}, &mock_rest.MockGraphQL{}, &mock_rest.MockHealth{})
+
+			r := gin.New()
+			r.POST("/auth/verify", handler.verifyEmail)
+			r.POST("/auth/verify/resend", handler.resendVerification)
+			r.POST("/auth/forgot-password", handler.forgotPassword)
+			r.POST("/auth/reset-password", handler.resetPassword)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("POST", testCase.path, bytes.NewBufferString(testCase.inputBody))
+
+			r.ServeHTTP(w, req)
+
+			var actual struct {
+				Code string `json:"code"`
+			}
+			json.Unmarshal(w.Body.Bytes(), &actual)
+
+			assert.Equal(t, w.Code, testCase.expectedStatusCode)
+			assert.Equal(t, actual.Code, testCase.expectedCode)
+		})
+	}
+}
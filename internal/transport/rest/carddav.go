@@ -0,0 +1,337 @@
+package rest
+
+import (
+	"crypto/sha256"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/wilfridterry/contact-list/internal/domain"
+	"github.com/wilfridterry/contact-list/pkg/vcard"
+)
+
+const (
+	davNamespace     = "DAV:"
+	cardDAVNamespace = "urn:ietf:params:xml:ns:carddav"
+	// calendarServerNamespace holds getctag, which Apple clients still poll.
+	calendarServerNamespace = "http://calendarserver.org/ns/"
+
+	carddavPrefix   = "/carddav/"
+	syncTokenPrefix = "urn:contact-list:sync:"
+
+	vcardContentType = "text/vcard; charset=utf-8"
+	xmlContentType   = "application/xml; charset=utf-8"
+)
+
+var (
+	propResourceType         = xml.Name{Space: davNamespace, Local: "resourcetype"}
+	propDisplayName          = xml.Name{Space: davNamespace, Local: "displayname"}
+	propCurrentUserPrincipal = xml.Name{Space: davNamespace, Local: "current-user-principal"}
+	propPrincipalURL         = xml.Name{Space: davNamespace, Local: "principal-URL"}
+	propPrivilegeSet         = xml.Name{Space: davNamespace, Local: "current-user-privilege-set"}
+	propSupportedReports     = xml.Name{Space: davNamespace, Local: "supported-report-set"}
+	propSyncToken            = xml.Name{Space: davNamespace, Local: "sync-token"}
+	propETag                 = xml.Name{Space: davNamespace, Local: "getetag"}
+	propContentType          = xml.Name{Space: davNamespace, Local: "getcontenttype"}
+	propCTag                 = xml.Name{Space: calendarServerNamespace, Local: "getctag"}
+	propHomeSet              = xml.Name{Space: cardDAVNamespace, Local: "addressbook-home-set"}
+	propSupportedData        = xml.Name{Space: cardDAVNamespace, Local: "supported-address-data"}
+	propAddressData          = xml.Name{Space: cardDAVNamespace, Local: "address-data"}
+
+	reportMultiget = xml.Name{Space: cardDAVNamespace, Local: "addressbook-multiget"}
+	reportQuery    = xml.Name{Space: cardDAVNamespace, Local: "addressbook-query"}
+	reportSync     = xml.Name{Space: davNamespace, Local: "sync-collection"}
+)
+
+type davPropNames struct {
+	Names []struct {
+		XMLName xml.Name
+	} `xml:",any"`
+}
+
+func (p *davPropNames) names() []xml.Name {
+	if p == nil {
+		return nil
+	}
+
+	names := make([]xml.Name, 0, len(p.Names))
+	for _, n := range p.Names {
+		names = append(names, n.XMLName)
+	}
+
+	return names
+}
+
+type propfindRequest struct {
+	XMLName xml.Name      `xml:"DAV: propfind"`
+	Prop    *davPropNames `xml:"DAV: prop"`
+}
+
+// reportRequest covers the addressbook-multiget, addressbook-query and
+// sync-collection reports. Query filters are not applied, every card matches.
+type reportRequest struct {
+	XMLName   xml.Name
+	Prop      *davPropNames `xml:"DAV: prop"`
+	Hrefs     []string      `xml:"DAV: href"`
+	SyncToken string        `xml:"DAV: sync-token"`
+}
+
+type davMultistatus struct {
+	XMLName   xml.Name      `xml:"DAV: multistatus"`
+	Responses []davResponse `xml:"response"`
+	SyncToken string        `xml:"sync-token,omitempty"`
+}
+
+type davResponse struct {
+	Href      string        `xml:"href"`
+	Status    string        `xml:"status,omitempty"`
+	Propstats []davPropstat `xml:"propstat,omitempty"`
+}
+
+type davPropstat struct {
+	Prop   davProp `xml:"prop"`
+	Status string  `xml:"status"`
+}
+
+type davProp struct {
+	Properties []davProperty
+}
+
+// davProperty is a property with its value, Inner is escaped XML.
+type davProperty struct {
+	XMLName xml.Name
+	Inner   string `xml:",innerxml"`
+}
+
+type davError struct {
+	XMLName   xml.Name `xml:"DAV: error"`
+	Condition davProperty
+}
+
+// davResource is a resource with the properties it has, in the order they are
+// listed by allprop.
+type davResource struct {
+	href       string
+	properties []davProperty
+}
+
+func (r *davResource) set(name xml.Name, inner string) {
+	r.properties = append(r.properties, davProperty{XMLName: name, Inner: inner})
+}
+
+// response lists the requested properties, properties the resource lacks are
+// listed as not found. All properties but the address data are listed when
+// none are requested.
+func (r *davResource) response(names []xml.Name) davResponse {
+	found := make([]davProperty, 0)
+	missing := make([]davProperty, 0)
+
+	if len(names) == 0 {
+		for _, p := range r.properties {
+			if p.XMLName != propAddressData {
+				found = append(found, p)
+			}
+		}
+	}
+
+	for _, name := range names {
+		p, ok := r.get(name)
+		if ok {
+			found = append(found, p)
+		} else {
+			missing = append(missing, davProperty{XMLName: name})
+		}
+	}
+
+	response := davResponse{Href: r.href}
+	if len(found) > 0 {
+		response.Propstats = append(response.Propstats, davPropstat{Prop: davProp{found}, Status: davStatus(http.StatusOK)})
+	}
+
+	if len(missing) > 0 {
+		response.Propstats = append(response.Propstats, davPropstat{Prop: davProp{missing}, Status: davStatus(http.StatusNotFound)})
+	}
+
+	return response
+}
+
+func (r *davResource) get(name xml.Name) (davProperty, bool) {
+	for _, p := range r.properties {
+		if p.XMLName == name {
+			return p, true
+		}
+	}
+
+	return davProperty{}, false
+}
+
+func davStatus(status int) string {
+	return fmt.Sprintf("HTTP/1.1 %d %s", status, http.StatusText(status))
+}
+
+func davHref(href string) string {
+	return "<href>" + escapeXML(href) + "</href>"
+}
+
+func escapeXML(s string) string {
+	var b strings.Builder
+	_ = xml.EscapeText(&b, []byte(s))
+
+	return b.String()
+}
+
+func principalHref(userId int64) string {
+	return fmt.Sprintf("%sprincipals/%d/", carddavPrefix, userId)
+}
+
+func addressBookHomeHref(userId int64) string {
+	return fmt.Sprintf("%saddressbooks/%d/", carddavPrefix, userId)
+}
+
+func addressBookHref(userId int64) string {
+	return addressBookHomeHref(userId) + "contacts/"
+}
+
+func cardHref(userId int64, name string) string {
+	return addressBookHref(userId) + url.PathEscape(name)
+}
+
+// cardName returns the name of the card at href, which may be a full URL, if
+// it is in the address book of the user.
+func cardName(userId int64, href string) (string, bool) {
+	u, err := url.Parse(href)
+	if err != nil {
+		return "", false
+	}
+
+	name, ok := strings.CutPrefix(u.Path, addressBookHref(userId))
+	if !ok || name == "" || strings.Contains(name, "/") {
+		return "", false
+	}
+
+	return name, true
+}
+
+// encodeCard renders the contact as a vCard 3.0, which every client reads.
+func encodeCard(card *domain.Card) []byte {
+	c := card.Contact
+
+	v := vcard.Card{
+		{Name: "VERSION", Value: "3.0"},
+		{Name: "UID", Value: vcard.Escape(card.UID)},
+		{Name: "FN", Value: vcard.Escape(strings.TrimSpace(c.Name + " " + c.LastName))},
+		{Name: "N", Value: vcard.Structured(c.LastName, c.Name, "", "", "")},
+	}
+
+	if c.Phone != "" {
+		v = append(v, vcard.Property{Name: "TEL", Params: map[string][]string{"TYPE": {"CELL"}}, Value: vcard.Escape(c.Phone)})
+	}
+
+	if c.Email != "" {
+		v = append(v, vcard.Property{Name: "EMAIL", Params: map[string][]string{"TYPE": {"INTERNET"}}, Value: vcard.Escape(c.Email)})
+	}
+
+	if c.Address != "" {
+		v = append(v, vcard.Property{Name: "ADR", Value: vcard.Structured("", "", c.Address, "", "", "", "")})
+	}
+
+	v = append(v, vcard.Property{Name: "REV", Value: c.UpdatedAt.UTC().Format("20060102T150405Z")})
+
+	return v.Encode()
+}
+
+// decodeCard reads the contact and the UID from a vCard. Only the properties a
+// contact has are kept, of several phones or emails the preferred one.
+func decodeCard(data []byte) (*domain.SaveInputContact, string, error) {
+	v, err := vcard.Decode(data)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var inp domain.SaveInputContact
+
+	if n := v.Get("N"); n != nil {
+		components := vcard.Components(n.Value)
+		inp.LastName = strings.TrimSpace(components[0])
+		if len(components) > 1 {
+			inp.Name = strings.TrimSpace(components[1])
+		}
+	}
+
+	if inp.Name == "" && inp.LastName == "" {
+		name, lastName, _ := strings.Cut(strings.TrimSpace(vcard.Text(v.Value("FN"))), " ")
+		inp.Name, inp.LastName = name, strings.TrimSpace(lastName)
+	}
+
+	if tel := preferred(v, "TEL"); tel != nil {
+		inp.Phone = normalizePhone(vcard.Text(tel.Value))
+	}
+
+	if email := preferred(v, "EMAIL"); email != nil {
+		inp.Email = strings.TrimSpace(vcard.Text(email.Value))
+	}
+
+	if adr := preferred(v, "ADR"); adr != nil {
+		parts := make([]string, 0)
+		for _, c := range vcard.Components(adr.Value) {
+			if c = strings.TrimSpace(c); c != "" {
+				parts = append(parts, c)
+			}
+		}
+		inp.Address = strings.Join(parts, ", ")
+	}
+
+	return &inp, vcard.Text(v.Value("UID")), nil
+}
+
+// preferred returns the property marked as preferred, by vCard 3.0 type or
+// vCard 4.0 parameter, or else the first one.
+func preferred(v vcard.Card, name string) *vcard.Property {
+	var first *vcard.Property
+
+	for i := range v {
+		p := &v[i]
+		if p.Name != name {
+			continue
+		}
+
+		if len(p.Params["PREF"]) > 0 {
+			return p
+		}
+
+		for _, t := range p.Params["TYPE"] {
+			if strings.EqualFold(t, "pref") {
+				return p
+			}
+		}
+
+		if first == nil {
+			first = p
+		}
+	}
+
+	return first
+}
+
+// normalizePhone drops the tel: scheme and the formatting clients add, so the
+// number can be checked as E.164.
+func normalizePhone(phone string) string {
+	phone = strings.TrimPrefix(strings.TrimSpace(phone), "tel:")
+
+	var b strings.Builder
+	for i, r := range phone {
+		if (r >= '0' && r <= '9') || (r == '+' && i == 0) {
+			b.WriteRune(r)
+		}
+	}
+
+	return b.String()
+}
+
+func cardETag(data []byte) string {
+	sum := sha256.Sum256(data)
+
+	return fmt.Sprintf(`"%x"`, sum[:16])
+}
@@ -0,0 +1,512 @@
+package rest
+
+import (
+	"encoding/xml"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/wilfridterry/contact-list/internal/domain"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+const (
+	methodPropfind = "PROPFIND"
+	methodReport   = "REPORT"
+
+	// maxCardSize limits the vCards clients may store.
+	maxCardSize = 1 << 20
+)
+
+var carddavMethods = []string{
+	http.MethodOptions,
+	methodPropfind,
+	methodReport,
+	http.MethodGet,
+	http.MethodHead,
+	http.MethodPut,
+	http.MethodDelete,
+}
+
+// carddavTarget is the resource a CardDAV path points at. The address book of
+// every user (RFC 6352) is served below /carddav/:
+//
+//	/carddav/principals/{user}/
+//	/carddav/addressbooks/{user}/
+//	/carddav/addressbooks/{user}/contacts/
+//	/carddav/addressbooks/{user}/contacts/{card}
+//
+// Clients sign in with HTTP Basic auth and an API key as the password.
+type carddavTarget int
+
+const (
+	carddavRoot carddavTarget = iota
+	carddavPrincipal
+	carddavHome
+	carddavAddressBook
+	carddavCard
+)
+
+// carddavPath resolves the path of the request. It fails for paths of other
+// users.
+func carddavPath(c *gin.Context, userId int64) (carddavTarget, string, bool) {
+	path := strings.Trim(c.Param("path"), "/")
+	if path == "" {
+		return carddavRoot, "", true
+	}
+
+	segments := strings.Split(path, "/")
+	if len(segments) < 2 || segments[1] != strconv.FormatInt(userId, 10) {
+		return 0, "", false
+	}
+
+	switch {
+	case segments[0] == "principals" && len(segments) == 2:
+		return carddavPrincipal, "", true
+	case segments[0] != "addressbooks":
+		return 0, "", false
+	case len(segments) == 2:
+		return carddavHome, "", true
+	case segments[2] != "contacts":
+		return 0, "", false
+	case len(segments) == 3:
+		return carddavAddressBook, "", true
+	case len(segments) == 4 && segments[3] != "":
+		return carddavCard, segments[3], true
+	}
+
+	return 0, "", false
+}
+
+func (h *Handler) carddavWellKnown(c *gin.Context) {
+	c.Redirect(http.StatusMovedPermanently, carddavPrefix)
+}
+
+func (h *Handler) carddavOptions(c *gin.Context) {
+	c.Header("DAV", "1, 3, addressbook")
+	c.Header("Allow", strings.Join(carddavMethods, ", "))
+	c.Status(http.StatusOK)
+}
+
+func (h *Handler) carddavPropfind(c *gin.Context) {
+	identity, _ := getIdentity(c)
+
+	target, name, ok := carddavPath(c, identity.UserID)
+	if !ok {
+		c.Status(http.StatusNotFound)
+		return
+	}
+
+	var req propfindRequest
+	if err := decodeDAVBody(c, &req); err != nil {
+		c.Status(http.StatusBadRequest)
+		return
+	}
+
+	names := req.Prop.names()
+	children := c.GetHeader("Depth") != "0"
+
+	resources := make([]davResource, 0)
+
+	switch target {
+	case carddavRoot:
+		resources = append(resources, h.davCollection(identity, carddavPrefix, ""))
+	case carddavPrincipal:
+		resources = append(resources, h.davPrincipal(identity))
+	case carddavHome:
+		resources = append(resources, h.davCollection(identity, addressBookHomeHref(identity.UserID), ""))
+		if children {
+			book, err := h.davAddressBook(c, identity)
+			if err != nil {
+				newProblem(c, err)
+				return
+			}
+			resources = append(resources, book)
+		}
+	case carddavAddressBook:
+		book, err := h.davAddressBook(c, identity)
+		if err != nil {
+			newProblem(c, err)
+			return
+		}
+		resources = append(resources, book)
+
+		if children {
+			cards, err := h.carddavService.Cards(c.Request.Context(), identity.UserID)
+			if err != nil {
+				newProblem(c, err)
+				return
+			}
+
+			for i := range cards {
+				resources = append(resources, h.davCard(identity, &cards[i]))
+			}
+		}
+	case carddavCard:
+		card, err := h.carddavService.Card(c.Request.Context(), identity.UserID, name)
+		if err != nil {
+			newProblem(c, err)
+			return
+		}
+		resources = append(resources, h.davCard(identity, card))
+	}
+
+	status := davMultistatus{Responses: make([]davResponse, 0, len(resources))}
+	for i := range resources {
+		status.Responses = append(status.Responses, resources[i].response(names))
+	}
+
+	writeMultistatus(c, &status)
+}
+
+func (h *Handler) carddavReport(c *gin.Context) {
+	identity, _ := getIdentity(c)
+
+	target, _, ok := carddavPath(c, identity.UserID)
+	if !ok {
+		c.Status(http.StatusNotFound)
+		return
+	}
+
+	var req reportRequest
+	if err := decodeDAVBody(c, &req); err != nil || req.XMLName.Local == "" {
+		c.Status(http.StatusBadRequest)
+		return
+	}
+
+	if target != carddavAddressBook {
+		writeDAVError(c, http.StatusForbidden, xml.Name{Space: davNamespace, Local: "supported-report"})
+		return
+	}
+
+	names := req.Prop.names()
+	if len(names) == 0 {
+		names = []xml.Name{propETag}
+	}
+
+	status := davMultistatus{Responses: make([]davResponse, 0)}
+
+	switch req.XMLName {
+	case reportMultiget:
+		for _, href := range req.Hrefs {
+			name, ok := cardName(identity.UserID, href)
+			if !ok {
+				status.Responses = append(status.Responses, davResponse{Href: href, Status: davStatus(http.StatusNotFound)})
+				continue
+			}
+
+			card, err := h.carddavService.Card(c.Request.Context(), identity.UserID, name)
+			if errors.Is(err, domain.ErrCardNotFound) {
+				status.Responses = append(status.Responses, davResponse{Href: href, Status: davStatus(http.StatusNotFound)})
+				continue
+			}
+
+			if err != nil {
+				newProblem(c, err)
+				return
+			}
+
+			resource := h.davCard(identity, card)
+			status.Responses = append(status.Responses, resource.response(names))
+		}
+	case reportQuery:
+		cards, err := h.carddavService.Cards(c.Request.Context(), identity.UserID)
+		if err != nil {
+			newProblem(c, err)
+			return
+		}
+
+		for i := range cards {
+			resource := h.davCard(identity, &cards[i])
+			status.Responses = append(status.Responses, resource.response(names))
+		}
+	case reportSync:
+		token, ok := strings.CutPrefix(req.SyncToken, syncTokenPrefix)
+		if !ok && req.SyncToken != "" {
+			writeDAVError(c, http.StatusForbidden, xml.Name{Space: davNamespace, Local: "valid-sync-token"})
+			return
+		}
+
+		changes, err := h.carddavService.Changes(c.Request.Context(), identity.UserID, token)
+		if errors.Is(err, domain.ErrInvalidSyncToken) || errors.Is(err, domain.ErrSyncTokenExpired) {
+			writeDAVError(c, http.StatusForbidden, xml.Name{Space: davNamespace, Local: "valid-sync-token"})
+			return
+		}
+
+		if err != nil {
+			newProblem(c, err)
+			return
+		}
+
+		for i := range changes.Cards {
+			resource := h.davCard(identity, &changes.Cards[i])
+			status.Responses = append(status.Responses, resource.response(names))
+		}
+
+		for _, name := range changes.Deleted {
+			status.Responses = append(status.Responses, davResponse{Href: cardHref(identity.UserID, name), Status: davStatus(http.StatusNotFound)})
+		}
+
+		status.SyncToken = syncTokenPrefix + changes.SyncToken
+	default:
+		writeDAVError(c, http.StatusForbidden, xml.Name{Space: davNamespace, Local: "supported-report"})
+		return
+	}
+
+	writeMultistatus(c, &status)
+}
+
+func (h *Handler) getCard(c *gin.Context) {
+	identity, _ := getIdentity(c)
+
+	target, name, ok := carddavPath(c, identity.UserID)
+	if !ok || target != carddavCard {
+		c.Status(http.StatusNotFound)
+		return
+	}
+
+	card, err := h.carddavService.Card(c.Request.Context(), identity.UserID, name)
+	if err != nil {
+		newProblem(c, err)
+		return
+	}
+
+	data := encodeCard(card)
+
+	c.Header("ETag", cardETag(data))
+	c.Data(http.StatusOK, vcardContentType, data)
+}
+
+// putCard stores the card. Properties a contact has no field for are dropped,
+// so no ETag is returned and clients fetch the card as stored.
+func (h *Handler) putCard(c *gin.Context) {
+	identity, _ := getIdentity(c)
+
+	target, name, ok := carddavPath(c, identity.UserID)
+	if !ok || target != carddavCard {
+		c.Status(http.StatusForbidden)
+		return
+	}
+
+	existing, err := h.carddavService.Card(c.Request.Context(), identity.UserID, name)
+	if err != nil && !errors.Is(err, domain.ErrCardNotFound) {
+		newProblem(c, err)
+		return
+	}
+
+	if !preconditionsMet(c, existing) {
+		c.Status(http.StatusPreconditionFailed)
+		return
+	}
+
+	data, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxCardSize))
+	if err != nil {
+		c.Status(http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	inp, uid, err := decodeCard(data)
+	if err != nil {
+		writeDAVError(c, http.StatusForbidden, xml.Name{Space: cardDAVNamespace, Local: "valid-address-data"})
+		return
+	}
+
+	if existing != nil {
+		inp.Author = existing.Contact.Author
+	} else {
+		user, err := h.authServie.Profile(c.Request.Context(), identity.UserID)
+		if err != nil {
+			newProblem(c, err)
+			return
+		}
+		inp.Author = user.Name
+	}
+
+	if err := binding.Validator.ValidateStruct(inp); err != nil {
+		writeDAVError(c, http.StatusForbidden, xml.Name{Space: cardDAVNamespace, Local: "valid-address-data"})
+		return
+	}
+
+	created, err := h.carddavService.SaveCard(c.Request.Context(), identity.UserID, name, uid, inp, existing)
+	if errors.Is(err, domain.ErrCardModified) {
+		c.Status(http.StatusPreconditionFailed)
+		return
+	}
+
+	if err != nil {
+		newProblem(c, err)
+		return
+	}
+
+	if created {
+		c.Status(http.StatusCreated)
+	} else {
+		c.Status(http.StatusNoContent)
+	}
+}
+
+func (h *Handler) deleteCard(c *gin.Context) {
+	identity, _ := getIdentity(c)
+
+	target, name, ok := carddavPath(c, identity.UserID)
+	if !ok || target != carddavCard {
+		c.Status(http.StatusForbidden)
+		return
+	}
+
+	existing, err := h.carddavService.Card(c.Request.Context(), identity.UserID, name)
+	if err != nil {
+		newProblem(c, err)
+		return
+	}
+
+	if !preconditionsMet(c, existing) {
+		c.Status(http.StatusPreconditionFailed)
+		return
+	}
+
+	err = h.carddavService.DeleteCard(c.Request.Context(), existing)
+	if errors.Is(err, domain.ErrCardModified) {
+		c.Status(http.StatusPreconditionFailed)
+		return
+	}
+
+	if err != nil {
+		newProblem(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// preconditionsMet checks If-Match and If-None-Match against the current card,
+// nil when there is none. The card is only written if it is still the one
+// checked, so the outcome holds until then.
+func preconditionsMet(c *gin.Context, existing *domain.Card) bool {
+	etag := ""
+	if existing != nil {
+		etag = cardETag(encodeCard(existing))
+	}
+
+	if match := c.GetHeader("If-Match"); match != "" {
+		if existing == nil || (match != "*" && !etagListed(match, etag)) {
+			return false
+		}
+	}
+
+	if noneMatch := c.GetHeader("If-None-Match"); noneMatch != "" && existing != nil {
+		if noneMatch == "*" || etagListed(noneMatch, etag) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func etagListed(header, etag string) bool {
+	for _, e := range strings.Split(header, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(e), "W/") == etag {
+			return true
+		}
+	}
+
+	return false
+}
+
+// davPrivileges lists what the caller may do with the address book.
+func davPrivileges(identity *domain.Identity) string {
+	privileges := []string{"read"}
+	if identity.HasPermission(domain.PermissionContactsWrite) {
+		privileges = append(privileges, "write", "write-content", "bind", "unbind")
+	}
+
+	var b strings.Builder
+	for _, p := range privileges {
+		b.WriteString("<privilege><" + p + "/></privilege>")
+	}
+
+	return b.String()
+}
+
+func (h *Handler) davCollection(identity *domain.Identity, href, resourceType string) davResource {
+	r := davResource{href: href}
+	r.set(propResourceType, "<collection/>"+resourceType)
+	r.set(propCurrentUserPrincipal, davHref(principalHref(identity.UserID)))
+	r.set(propPrivilegeSet, davPrivileges(identity))
+
+	return r
+}
+
+func (h *Handler) davPrincipal(identity *domain.Identity) davResource {
+	href := principalHref(identity.UserID)
+
+	r := davResource{href: href}
+	r.set(propResourceType, "<principal/>")
+	r.set(propCurrentUserPrincipal, davHref(href))
+	r.set(propPrincipalURL, davHref(href))
+	r.set(propHomeSet, davHref(addressBookHomeHref(identity.UserID)))
+
+	return r
+}
+
+func (h *Handler) davAddressBook(c *gin.Context, identity *domain.Identity) (davResource, error) {
+	token, err := h.carddavService.SyncToken(c.Request.Context())
+	if err != nil {
+		return davResource{}, err
+	}
+
+	r := h.davCollection(identity, addressBookHref(identity.UserID), `<addressbook xmlns="`+cardDAVNamespace+`"/>`)
+	r.set(propDisplayName, "Contacts")
+	r.set(propCTag, escapeXML(token))
+	r.set(propSyncToken, escapeXML(syncTokenPrefix+token))
+	r.set(propSupportedReports,
+		`<supported-report><report><addressbook-multiget xmlns="`+cardDAVNamespace+`"/></report></supported-report>`+
+			`<supported-report><report><addressbook-query xmlns="`+cardDAVNamespace+`"/></report></supported-report>`+
+			`<supported-report><report><sync-collection/></report></supported-report>`)
+	r.set(propSupportedData, `<address-data-type content-type="text/vcard" version="3.0"/>`)
+
+	return r, nil
+}
+
+func (h *Handler) davCard(identity *domain.Identity, card *domain.Card) davResource {
+	data := encodeCard(card)
+
+	r := davResource{href: cardHref(identity.UserID, card.Name)}
+	r.set(propResourceType, "")
+	r.set(propETag, escapeXML(cardETag(data)))
+	r.set(propContentType, escapeXML(vcardContentType))
+	r.set(propAddressData, escapeXML(string(data)))
+
+	return r
+}
+
+// decodeDAVBody reads the XML body, an empty one leaves v as it is.
+func decodeDAVBody(c *gin.Context, v any) error {
+	err := xml.NewDecoder(c.Request.Body).Decode(v)
+	if errors.Is(err, io.EOF) {
+		return nil
+	}
+
+	return err
+}
+
+func writeMultistatus(c *gin.Context, status *davMultistatus) {
+	body, err := xml.Marshal(status)
+	if err != nil {
+		newProblem(c, err)
+		return
+	}
+
+	c.Data(http.StatusMultiStatus, xmlContentType, append([]byte(xml.Header), body...))
+}
+
+// writeDAVError responds with a failed precondition (RFC 4918 section 16).
+func writeDAVError(c *gin.Context, status int, condition xml.Name) {
+	body, _ := xml.Marshal(davError{Condition: davProperty{XMLName: condition}})
+
+	c.Data(status, xmlContentType, append([]byte(xml.Header), body...))
+}
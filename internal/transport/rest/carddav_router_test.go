@@ -0,0 +1,395 @@
+package rest
+
+import (
+	"bytes"
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/magiconair/properties/assert"
+	"github.com/wilfridterry/contact-list/internal/domain"
+	mock_rest "github.com/wilfridterry/contact-list/internal/transport/rest/mocks"
+	"go.uber.org/mock/gomock"
+)
+
+var testCard = domain.Card{
+	Name: "a1.vcf",
+	UID:  "a1",
+	Contact: domain.Contact{
+		ID:        1,
+		Name:      "John",
+		LastName:  "Doe",
+		Phone:     "+15550100",
+		Email:     "john@example.com",
+		Address:   "1 Main St",
+		Author:    "Jane",
+		UpdatedAt: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+	},
+}
+
+const testVCard = "BEGIN:VCARD\r\n" +
+	"VERSION:3.0\r\n" +
+	"UID:a1\r\n" +
+	"FN:John Doe\r\n" +
+	"N:Doe;John;;;\r\n" +
+	"TEL;TYPE=CELL:+15550100\r\n" +
+	"EMAIL;TYPE=INTERNET:john@example.com\r\n" +
+	"ADR:;;1 Main St;;;;\r\n" +
+	"REV:20240101T000000Z\r\n" +
+	"END:VCARD\r\n"
+
+func newCardDAVRouter(handler *Handler, permissions ...domain.Permission) *gin.Engine {
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), ctxIdentity, &domain.Identity{UserID: 7, Permissions: permissions}))
+	})
+	r.Handle(methodPropfind, "/carddav/*path", handler.carddavPropfind)
+	r.Handle(methodReport, "/carddav/*path", handler.carddavReport)
+	r.GET("/carddav/*path", handler.getCard)
+	r.PUT("/carddav/*path", handler.putCard)
+	r.DELETE("/carddav/*path", handler.deleteCard)
+
+	return r
+}
+
+func TestHandler_getCard(t *testing.T) {
+	c := gomock.NewController(t)
+	defer c.Finish()
+
+	carddav := mock_rest.NewMockCardDAV(c)
+	carddav.EXPECT().Card(gomock.Any(), int64(7), "a1.vcf").Return(&testCard, nil)
+
+	handler := NewHandler(&mock_rest.MockContacts{}, &mock_rest.MockAuth{}, &mock_rest.MockAPIKeys{}, &mock_rest.MockOIDC{}, &mock_rest.MockOAuth{}, &mock_rest.MockPrivacy{}, &mock_rest.MockIdempotency{}, &mock_rest.MockWebhooks{}, carddav, &mock_rest.MockGraphQL{}, &mock_rest.MockHealth{})
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/carddav/addressbooks/7/contacts/a1.vcf", nil)
+
+	newCardDAVRouter(handler).ServeHTTP(w, req)
+
+	assert.Equal(t, w.Code, 200)
+	assert.Equal(t, w.Header().Get("Content-Type"), vcardContentType)
+	assert.Equal(t, w.Header().Get("ETag"), cardETag([]byte(testVCard)))
+	assert.Equal(t, w.Body.String(), testVCard)
+}
+
+func TestHandler_carddavPropfind(t *testing.T) {
+	type mockBehavior func(s *mock_rest.MockCardDAV)
+
+	testTable := []struct {
+		name                 string
+		path                 string
+		depth                string
+		inputBody            string
+		mockBehavior         mockBehavior
+		expectedStatusCode   int
+		expectedResponseBody string
+	}{
+		{
+			name:                 "Principal",
+			path:                 "/carddav/principals/7/",
+			depth:                "0",
+			inputBody:            `<propfind xmlns="DAV:" xmlns:C="urn:ietf:params:xml:ns:carddav"><prop><C:addressbook-home-set/><displayname/></prop></propfind>`,
+			mockBehavior:         func(s *mock_rest.MockCardDAV) {},
+			expectedStatusCode:   207,
+			expectedResponseBody: xmlHeader + `<multistatus xmlns="DAV:"><response><href>/carddav/principals/7/</href><propstat><prop><addressbook-home-set xmlns="urn:ietf:params:xml:ns:carddav"><href>/carddav/addressbooks/7/</href></addressbook-home-set></prop><status>HTTP/1.1 200 OK</status></propstat><propstat><prop><displayname xmlns="DAV:"></displayname></prop><status>HTTP/1.1 404 Not Found</status></propstat></response></multistatus>`,
+		},
+		{
+			name:      "Address book",
+			path:      "/carddav/addressbooks/7/contacts/",
+			depth:     "1",
+			inputBody: `<propfind xmlns="DAV:"><prop><getetag/><sync-token/></prop></propfind>`,
+			mockBehavior: func(s *mock_rest.MockCardDAV) {
+				s.EXPECT().SyncToken(gomock.Any()).Return("AAAAAAAAAAU", nil)
+				s.EXPECT().Cards(gomock.Any(), int64(7)).Return([]domain.Card{testCard}, nil)
+			},
+			expectedStatusCode: 207,
+			expectedResponseBody: xmlHeader + `<multistatus xmlns="DAV:">` +
+				`<response><href>/carddav/addressbooks/7/contacts/</href><propstat><prop><sync-token xmlns="DAV:">urn:contact-list:sync:AAAAAAAAAAU</sync-token></prop><status>HTTP/1.1 200 OK</status></propstat><propstat><prop><getetag xmlns="DAV:"></getetag></prop><status>HTTP/1.1 404 Not Found</status></propstat></response>` +
+				`<response><href>/carddav/addressbooks/7/contacts/a1.vcf</href><propstat><prop><getetag xmlns="DAV:">` + escapeXML(cardETag([]byte(testVCard))) + `</getetag></prop><status>HTTP/1.1 200 OK</status></propstat><propstat><prop><sync-token xmlns="DAV:"></sync-token></prop><status>HTTP/1.1 404 Not Found</status></propstat></response>` +
+				`</multistatus>`,
+		},
+		{
+			name:               "Other user",
+			path:               "/carddav/addressbooks/8/contacts/",
+			mockBehavior:       func(s *mock_rest.MockCardDAV) {},
+			expectedStatusCode: 404,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			carddav := mock_rest.NewMockCardDAV(c)
+			testCase.mockBehavior(carddav)
+
+			handler := NewHandler(&mock_rest.MockContacts{}, &mock_rest.MockAuth{}, &mock_rest.MockAPIKeys{}, &mock_rest.MockOIDC{}, &mock_rest.MockOAuth{}, &mock_rest.MockPrivacy{}, &mock_rest.MockIdempotency{}, &mock_rest.MockWebhooks{}, carddav, &mock_rest.MockGraphQL{}, &mock_rest.MockHealth{})
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(methodPropfind, testCase.path, bytes.NewBufferString(testCase.inputBody))
+			req.Header.Set("Depth", testCase.depth)
+
+			newCardDAVRouter(handler, domain.PermissionContactsRead).ServeHTTP(w, req)
+
+			assert.Equal(t, w.Code, testCase.expectedStatusCode)
+			assert.Equal(t, w.Body.String(), testCase.expectedResponseBody)
+		})
+	}
+}
+
+func TestHandler_carddavReport(t *testing.T) {
+	type mockBehavior func(s *mock_rest.MockCardDAV)
+
+	testTable := []struct {
+		name                 string
+		inputBody            string
+		mockBehavior         mockBehavior
+		expectedStatusCode   int
+		expectedResponseBody string
+	}{
+		{
+			name:      "Sync collection",
+			inputBody: `<sync-collection xmlns="DAV:"><sync-token>urn:contact-list:sync:AAAAAAAAAAU</sync-token><sync-level>1</sync-level><prop><getetag/></prop></sync-collection>`,
+			mockBehavior: func(s *mock_rest.MockCardDAV) {
+				s.EXPECT().Changes(gomock.Any(), int64(7), "AAAAAAAAAAU").Return(&domain.CardChanges{
+					Cards:     []domain.Card{testCard},
+					Deleted:   []string{"b 2.vcf"},
+					SyncToken: "AAAAAAAAAAk",
+				}, nil)
+			},
+			expectedStatusCode: 207,
+			expectedResponseBody: xmlHeader + `<multistatus xmlns="DAV:">` +
+				`<response><href>/carddav/addressbooks/7/contacts/a1.vcf</href><propstat><prop><getetag xmlns="DAV:">` + escapeXML(cardETag([]byte(testVCard))) + `</getetag></prop><status>HTTP/1.1 200 OK</status></propstat></response>` +
+				`<response><href>/carddav/addressbooks/7/contacts/b%202.vcf</href><status>HTTP/1.1 404 Not Found</status></response>` +
+				`<sync-token>urn:contact-list:sync:AAAAAAAAAAk</sync-token></multistatus>`,
+		},
+		{
+			name:      "Sync token too old",
+			inputBody: `<sync-collection xmlns="DAV:"><sync-token>urn:contact-list:sync:AAAAAAAAAAE</sync-token><prop><getetag/></prop></sync-collection>`,
+			mockBehavior: func(s *mock_rest.MockCardDAV) {
+				s.EXPECT().Changes(gomock.Any(), int64(7), "AAAAAAAAAAE").Return(nil, domain.ErrSyncTokenExpired)
+			},
+			expectedStatusCode:   403,
+			expectedResponseBody: xmlHeader + `<error xmlns="DAV:"><valid-sync-token xmlns="DAV:"></valid-sync-token></error>`,
+		},
+		{
+			name:      "Multiget",
+			inputBody: `<C:addressbook-multiget xmlns="DAV:" xmlns:C="urn:ietf:params:xml:ns:carddav"><prop><getetag/></prop><href>/carddav/addressbooks/7/contacts/a1.vcf</href><href>/carddav/addressbooks/7/contacts/gone.vcf</href></C:addressbook-multiget>`,
+			mockBehavior: func(s *mock_rest.MockCardDAV) {
+				s.EXPECT().Card(gomock.Any(), int64(7), "a1.vcf").Return(&testCard, nil)
+				s.EXPECT().Card(gomock.Any(), int64(7), "gone.vcf").Return(nil, domain.ErrCardNotFound)
+			},
+			expectedStatusCode: 207,
+			expectedResponseBody: xmlHeader + `<multistatus xmlns="DAV:">` +
+				`<response><href>/carddav/addressbooks/7/contacts/a1.vcf</href><propstat><prop><getetag xmlns="DAV:">` + escapeXML(cardETag([]byte(testVCard))) + `</getetag></prop><status>HTTP/1.1 200 OK</status></propstat></response>` +
+				`<response><href>/carddav/addressbooks/7/contacts/gone.vcf</href><status>HTTP/1.1 404 Not Found</status></response>` +
+				`</multistatus>`,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			carddav := mock_rest.NewMockCardDAV(c)
+			testCase.mockBehavior(carddav)
+
+			handler := NewHandler(&mock_rest.MockContacts{}, &mock_rest.MockAuth{}, &mock_rest.MockAPIKeys{}, &mock_rest.MockOIDC{}, &mock_rest.MockOAuth{}, &mock_rest.MockPrivacy{}, &mock_rest.MockIdempotency{}, &mock_rest.MockWebhooks{}, carddav, &mock_rest.MockGraphQL{}, &mock_rest.MockHealth{})
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(methodReport, "/carddav/addressbooks/7/contacts/", bytes.NewBufferString(testCase.inputBody))
+
+			newCardDAVRouter(handler, domain.PermissionContactsRead).ServeHTTP(w, req)
+
+			assert.Equal(t, w.Code, testCase.expectedStatusCode)
+			assert.Equal(t, w.Body.String(), testCase.expectedResponseBody)
+		})
+	}
+}
+
+func TestHandler_putCard(t *testing.T) {
+	type mockBehavior func(s *mock_rest.MockCardDAV, a *mock_rest.MockAuth)
+
+	input := &domain.SaveInputContact{
+		Name:     "John",
+		LastName: "Doe",
+		Phone:    "+15550100",
+		Email:    "john@example.com",
+		Address:  "1 Main St",
+		Author:   "Jane",
+	}
+
+	testTable := []struct {
+		name               string
+		inputBody          string
+		ifNoneMatch        string
+		ifMatch            string
+		mockBehavior       mockBehavior
+		expectedStatusCode int
+	}{
+		{
+			name:        "Create",
+			inputBody:   "BEGIN:VCARD\r\nVERSION:4.0\r\nUID:a1\r\nFN:John Doe\r\nN:Doe;John;;;\r\nTEL;VALUE=uri;PREF=1:tel:+1-555-0100\r\nTEL:+15550199\r\nEMAIL:john@example.com\r\nADR:;;1 Main St;;;;\r\nPHOTO:data:,\r\nEND:VCARD\r\n",
+			ifNoneMatch: "*",
+			mockBehavior: func(s *mock_rest.MockCardDAV, a *mock_rest.MockAuth) {
+				s.EXPECT().Card(gomock.Any(), int64(7), "a1.vcf").Return(nil, domain.ErrCardNotFound)
+				a.EXPECT().Profile(gomock.Any(), int64(7)).Return(&domain.User{ID: 7, Name: "Jane"}, nil)
+				s.EXPECT().SaveCard(gomock.Any(), int64(7), "a1.vcf", "a1", input, nil).Return(true, nil)
+			},
+			expectedStatusCode: 201,
+		},
+		{
+			name:        "Already exists",
+			inputBody:   testVCard,
+			ifNoneMatch: "*",
+			mockBehavior: func(s *mock_rest.MockCardDAV, a *mock_rest.MockAuth) {
+				s.EXPECT().Card(gomock.Any(), int64(7), "a1.vcf").Return(&testCard, nil)
+			},
+			expectedStatusCode: 412,
+		},
+		{
+			name:      "Update",
+			inputBody: testVCard,
+			ifMatch:   cardETag([]byte(testVCard)),
+			mockBehavior: func(s *mock_rest.MockCardDAV, a *mock_rest.MockAuth) {
+				s.EXPECT().Card(gomock.Any(), int64(7), "a1.vcf").Return(&testCard, nil)
+				s.EXPECT().SaveCard(gomock.Any(), int64(7), "a1.vcf", "a1", input, &testCard).Return(false, nil)
+			},
+			expectedStatusCode: 204,
+		},
+		{
+			name:      "Modified meanwhile",
+			inputBody: testVCard,
+			ifMatch:   cardETag([]byte(testVCard)),
+			mockBehavior: func(s *mock_rest.MockCardDAV, a *mock_rest.MockAuth) {
+				s.EXPECT().Card(gomock.Any(), int64(7), "a1.vcf").Return(&testCard, nil)
+				s.EXPECT().SaveCard(gomock.Any(), int64(7), "a1.vcf", "a1", input, &testCard).Return(false, domain.ErrCardModified)
+			},
+			expectedStatusCode: 412,
+		},
+		{
+			name:      "Stale ETag",
+			inputBody: testVCard,
+			ifMatch:   `"stale"`,
+			mockBehavior: func(s *mock_rest.MockCardDAV, a *mock_rest.MockAuth) {
+				s.EXPECT().Card(gomock.Any(), int64(7), "a1.vcf").Return(&testCard, nil)
+			},
+			expectedStatusCode: 412,
+		},
+		{
+			name:      "Invalid phone",
+			inputBody: "BEGIN:VCARD\r\nVERSION:3.0\r\nN:Doe;John;;;\r\nTEL:call me\r\nEMAIL:john@example.com\r\nADR:;;1 Main St;;;;\r\nEND:VCARD\r\n",
+			mockBehavior: func(s *mock_rest.MockCardDAV, a *mock_rest.MockAuth) {
+				s.EXPECT().Card(gomock.Any(), int64(7), "a1.vcf").Return(&testCard, nil)
+			},
+			expectedStatusCode: 403,
+		},
+		{
+			name:      "Not a vCard",
+			inputBody: "hello",
+			mockBehavior: func(s *mock_rest.MockCardDAV, a *mock_rest.MockAuth) {
+				s.EXPECT().Card(gomock.Any(), int64(7), "a1.vcf").Return(&testCard, nil)
+			},
+			expectedStatusCode: 403,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			carddav := mock_rest.NewMockCardDAV(c)
+			auth := mock_rest.NewMockAuth(c)
+			testCase.mockBehavior(carddav, auth)
+
+			handler := NewHandler(&mock_rest.MockContacts{}, auth, &mock_rest.MockAPIKeys{}, &mock_rest.MockOIDC{}, &mock_rest.MockOAuth{}, &mock_rest.MockPrivacy{}, &mock_rest.MockIdempotency{}, &mock_rest.MockWebhooks{}, carddav, &mock_rest.MockGraphQL{}, &mock_rest.MockHealth{})
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("PUT", "/carddav/addressbooks/7/contacts/a1.vcf", bytes.NewBufferString(testCase.inputBody))
+			if testCase.ifNoneMatch != "" {
+				req.Header.Set("If-None-Match", testCase.ifNoneMatch)
+			}
+			if testCase.ifMatch != "" {
+				req.Header.Set("If-Match", testCase.ifMatch)
+			}
+
+			newCardDAVRouter(handler, domain.PermissionContactsWrite).ServeHTTP(w, req)
+
+			assert.Equal(t, w.Code, testCase.expectedStatusCode)
+		})
+	}
+}
+
+func TestHandler_deleteCard(t *testing.T) {
+	type mockBehavior func(s *mock_rest.MockCardDAV)
+
+	testTable := []struct {
+		name               string
+		ifMatch            string
+		mockBehavior       mockBehavior
+		expectedStatusCode int
+	}{
+		{
+			name:    "OK",
+			ifMatch: cardETag([]byte(testVCard)),
+			mockBehavior: func(s *mock_rest.MockCardDAV) {
+				s.EXPECT().Card(gomock.Any(), int64(7), "a1.vcf").Return(&testCard, nil)
+				s.EXPECT().DeleteCard(gomock.Any(), &testCard).Return(nil)
+			},
+			expectedStatusCode: 204,
+		},
+		{
+			name:    "Stale ETag",
+			ifMatch: `"stale"`,
+			mockBehavior: func(s *mock_rest.MockCardDAV) {
+				s.EXPECT().Card(gomock.Any(), int64(7), "a1.vcf").Return(&testCard, nil)
+			},
+			expectedStatusCode: 412,
+		},
+		{
+			name:    "Modified meanwhile",
+			ifMatch: cardETag([]byte(testVCard)),
+			mockBehavior: func(s *mock_rest.MockCardDAV) {
+				s.EXPECT().Card(gomock.Any(), int64(7), "a1.vcf").Return(&testCard, nil)
+				s.EXPECT().DeleteCard(gomock.Any(), &testCard).Return(domain.ErrCardModified)
+			},
+			expectedStatusCode: 412,
+		},
+		{
+			name: "Not found",
+			mockBehavior: func(s *mock_rest.MockCardDAV) {
+				s.EXPECT().Card(gomock.Any(), int64(7), "a1.vcf").Return(nil, domain.ErrCardNotFound)
+			},
+			expectedStatusCode: 404,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			carddav := mock_rest.NewMockCardDAV(c)
+			testCase.mockBehavior(carddav)
+
+			handler := NewHandler(&mock_rest.MockContacts{}, &mock_rest.MockAuth{}, &mock_rest.MockAPIKeys{}, &mock_rest.MockOIDC{}, &mock_rest.MockOAuth{}, &mock_rest.MockPrivacy{}, &mock_rest.MockIdempotency{}, &mock_rest.MockWebhooks{}, carddav, &mock_rest.MockGraphQL{}, &mock_rest.MockHealth{})
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("DELETE", "/carddav/addressbooks/7/contacts/a1.vcf", nil)
+			if testCase.ifMatch != "" {
+				req.Header.Set("If-Match", testCase.ifMatch)
+			}
+
+			newCardDAVRouter(handler, domain.PermissionContactsWrite).ServeHTTP(w, req)
+
+			assert.Equal(t, w.Code, testCase.expectedStatusCode)
+		})
+	}
+}
+
+const xmlHeader = `<?xml version="1.0" encoding="UTF-8"?>` + "\n"
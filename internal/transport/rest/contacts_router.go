@@ -1,13 +1,12 @@
 package rest
 
 import (
+	"github.com/wilfridterry/contact-list/internal/apperror"
 	"github.com/wilfridterry/contact-list/internal/domain"
 
-	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
-	"github.com/swaggo/swag/example/celler/httputil"
 )
 
 // ListContacts godoc
@@ -17,19 +16,49 @@ import (
 // @Accept       json
 // @Produce      json
 // @Success      200  {array}   domain.Contact
-// @Failure      400  {object}  httputil.HTTPError
-// @Failure      404  {object}  httputil.HTTPError
-// @Failure      500  {object}  httputil.HTTPError
+// @Failure      400  {object}  Problem
+// @Failure      404  {object}  Problem
+// @Failure      500  {object}  Problem
 // @Router       /contacts [get]
 func (h *Handler) getContacts(c *gin.Context) {
 	contacts, err := h.contactService.All(c.Request.Context())
 	if err != nil {
-		httputil.NewError(c, http.StatusBadRequest, err)
+		newProblem(c, err)
 	} else {
 		c.JSON(http.StatusOK, contacts)
 	}
 }
 
+// ListContactChanges godoc
+// @Summary      List contact changes
+// @Description  get the contacts created, updated and deleted since a sync token, or every contact without one, along with the token to pass next time
+// @Tags         contacts
+// @Accept       json
+// @Produce      json
+// @Param        since  query     string  false  "Sync token of the previous response"
+// @Param        limit  query     int     false  "Changes per response, up to 500"
+// @Success      200  {object}  domain.ContactChanges
+// @Failure      400  {object}  Problem
+// @Failure      410  {object}  Problem
+// @Failure      422  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Router       /contacts/changes [get]
+func (h *Handler) getContactChanges(c *gin.Context) {
+	var filter domain.ContactChangesFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		newProblem(c, err)
+		return
+	}
+
+	changes, err := h.contactService.Changes(c.Request.Context(), &filter)
+	if err != nil {
+		newProblem(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, changes)
+}
+
 // ShowContact godoc
 // @Summary      Show a contact
 // @Description  get string by ID
@@ -38,26 +67,21 @@ func (h *Handler) getContacts(c *gin.Context) {
 // @Produce      json
 // @Param        id   path      int  true  "Contact ID"
 // @Success      200  {object}  domain.Contact
-// @Failure      400  {object}  httputil.HTTPError
-// @Failure      404  {object}  httputil.HTTPError
-// @Failure      500  {object}  httputil.HTTPError
+// @Failure      400  {object}  Problem
+// @Failure      404  {object}  Problem
+// @Failure      500  {object}  Problem
 // @Router       /contacts/{id} [get]
 func (h *Handler) getContact(c *gin.Context) {
 	var uri Uri
 	if err := c.ShouldBindUri(&uri); err != nil {
-		httputil.NewError(c, http.StatusBadRequest, err)
+		newProblem(c, err)
 		return
 	}
 
 	contact, err := h.contactService.GetOne(c.Request.Context(), uri.ID)
 
 	if err != nil {
-		if errors.Is(err, domain.ErrContactNotFound) {
-			httputil.NewError(c, http.StatusNotFound, err)
-
-			return
-		}
-		httputil.NewError(c, http.StatusBadRequest, err)
+		newProblem(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, contact)
@@ -70,21 +94,26 @@ func (h *Handler) getContact(c *gin.Context) {
 // @Accept       json
 // @Produce      json
 // @Param contact body domain.SaveInputContact true "Contact paylaod"
+// @Param        Idempotency-Key  header  string  false  "retries with the same key run the request once"
 // @Success      201
-// @Failure      400  {object}  httputil.HTTPError
-// @Failure      404  {object}  httputil.HTTPError
-// @Failure      500  {object}  httputil.HTTPError
+// @Failure      400  {object}  Problem
+// @Failure      404  {object}  Problem
+// @Failure      409  {object}  Problem
+// @Failure      500  {object}  Problem
 // @Router       /contacts [post]
 func (h *Handler) createContact(c *gin.Context) {
 	var inp domain.SaveInputContact
 	if err := c.ShouldBindJSON(&inp); err != nil {
-		httputil.NewError(c, http.StatusUnprocessableEntity, err)
+		newProblem(c, err)
 
 		return
 	}
 
-	if err := h.contactService.Create(c.Request.Context(), &inp); err != nil {
-		httputil.NewError(c, http.StatusInternalServerError, err)
+	identity, _ := getIdentity(c)
+	inp.UserID = identity.UserID
+
+	if _, err := h.contactService.Create(c.Request.Context(), &inp); err != nil {
+		newProblem(c, err)
 
 		return
 	}
@@ -100,20 +129,20 @@ func (h *Handler) createContact(c *gin.Context) {
 // @Produce      json
 // @Param        id   path      int  true  "Contact ID"
 // @Success      204
-// @Failure      400  {object}  httputil.HTTPError
-// @Failure      404  {object}  httputil.HTTPError
-// @Failure      500  {object}  httputil.HTTPError
+// @Failure      400  {object}  Problem
+// @Failure      404  {object}  Problem
+// @Failure      500  {object}  Problem
 // @Router       /contacts/{id} [delete]
 func (h *Handler) deleteContact(c *gin.Context) {
 	var uri Uri
 	if err := c.ShouldBindUri(&uri); err != nil {
-		httputil.NewError(c, http.StatusBadRequest, err)
+		newProblem(c, err)
 
 		return
 	}
 
 	if err := h.contactService.Delete(c.Request.Context(), uri.ID); err != nil {
-		httputil.NewError(c, http.StatusBadRequest, err)
+		newProblem(c, err)
 
 		return
 	}
@@ -130,40 +159,103 @@ func (h *Handler) deleteContact(c *gin.Context) {
 // @Param contact body domain.SaveInputContact true "Contact paylaod"
 // @Param        id   path      int  true  "Contact ID"
 // @Success      200  {object}  domain.Contact
-// @Failure      400  {object}  httputil.HTTPError
-// @Failure      404  {object}  httputil.HTTPError
-// @Failure      500  {object}  httputil.HTTPError
+// @Failure      400  {object}  Problem
+// @Failure      404  {object}  Problem
+// @Failure      500  {object}  Problem
 // @Router       /contacts/{id} [put]
 func (h *Handler) updateAccount(c *gin.Context) {
 	var uri Uri
 	if err := c.ShouldBindUri(&uri); err != nil {
-		httputil.NewError(c, http.StatusBadRequest, err)
+		newProblem(c, err)
 		return
 	}
 
 	var inp domain.SaveInputContact
 	if err := c.ShouldBindJSON(&inp); err != nil {
-		httputil.NewError(c, http.StatusUnprocessableEntity, err)
+		newProblem(c, err)
 
 		return
 	}
 
 	if err := h.contactService.Update(c.Request.Context(), uri.ID, &inp); err != nil {
-		httputil.NewError(c, http.StatusBadRequest, err)
+		newProblem(c, err)
 		return
 	}
 
 	contact, err := h.contactService.GetOne(c.Request.Context(), uri.ID)
 	if err != nil {
-		if errors.Is(err, domain.ErrContactNotFound) {
-			httputil.NewError(c, http.StatusNotFound, err)
-
-			return
-		}
-		httputil.NewError(c, http.StatusBadRequest, err)
+		newProblem(c, err)
 
 		return
 	}
 
 	c.JSON(http.StatusOK, contact)
 }
+
+// BatchResult is the outcome of a single batch operation. Status is the one
+// the operation would have had as a single request, a failed operation carries
+// the problem code and detail.
+type BatchResult struct {
+	Index  int    `json:"index" example:"0"`
+	Op     string `json:"op" example:"update"`
+	ID     int64  `json:"id,omitempty" example:"1"`
+	Status int    `json:"status" example:"200"`
+	Code   string `json:"code,omitempty" example:"contact_not_found"`
+	Detail string `json:"detail,omitempty" example:"contact not found"`
+}
+
+type BatchResponse struct {
+	Mode    string        `json:"mode" example:"partial"`
+	Results []BatchResult `json:"results"`
+}
+
+var batchStatuses = map[string]int{
+	domain.BatchCreate: http.StatusCreated,
+	domain.BatchUpdate: http.StatusOK,
+	domain.BatchDelete: http.StatusNoContent,
+}
+
+// BatchContacts godoc
+// @Summary      Create, update and delete contacts at once
+// @Description  apply up to 100 operations, in the atomic mode (default) all of them or none, in the partial mode each one on its own
+// @Tags         contacts
+// @Accept       json
+// @Produce      json
+// @Param        batch  body  domain.BatchInput  true  "Batch operations"
+// @Param        Idempotency-Key  header  string  false  "retries with the same key run the request once"
+// @Success      200  {object}  BatchResponse
+// @Failure      400  {object}  Problem
+// @Failure      404  {object}  Problem
+// @Failure      409  {object}  Problem
+// @Failure      422  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Router       /contacts/batch [post]
+func (h *Handler) batchContacts(c *gin.Context) {
+	var inp domain.BatchInput
+	if err := c.ShouldBindJSON(&inp); err != nil {
+		newProblem(c, err)
+		return
+	}
+
+	identity, _ := getIdentity(c)
+
+	results, err := h.contactService.Batch(c.Request.Context(), identity.UserID, &inp)
+	if err != nil {
+		newProblem(c, err)
+		return
+	}
+
+	response := BatchResponse{Mode: inp.Mode, Results: make([]BatchResult, 0, len(results))}
+	for _, result := range results {
+		item := BatchResult{Index: result.Index, Op: result.Op, ID: result.ID, Status: batchStatuses[result.Op]}
+
+		if result.Err != nil {
+			appErr := apperror.From(result.Err)
+			item.Status, item.Code, item.Detail = appErr.Status, appErr.Code, appErr.Message
+		}
+
+		response.Results = append(response.Results, item)
+	}
+
+	c.JSON(http.StatusOK, response)
+}
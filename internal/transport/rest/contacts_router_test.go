@@ -0,0 +1,147 @@
+package rest
+
+import (
+	"bytes"
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/magiconair/properties/assert"
+	"github.com/wilfridterry/contact-list/internal/domain"
+	mock_rest "github.com/wilfridterry/contact-list/internal/transport/rest/mocks"
+	"go.uber.org/mock/gomock"
+)
+
+func TestHandler_batchContacts(t *testing.T) {
+	type mockBehavior func(s *mock_rest.MockContacts)
+
+	testTable := []struct {
+		name                 string
+		inputBody            string
+		mockBehavior         mockBehavior
+		expectedStatusCode   int
+		expectedResponseBody string
+	}{
+		{
+			name:      "Partial",
+			inputBody: `{"mode":"partial","operations":[{"op":"delete","id":1},{"op":"delete","id":2}]}`,
+			mockBehavior: func(s *mock_rest.MockContacts) {
+				s.EXPECT().Batch(gomock.Any(), int64(1), gomock.Any()).Return([]domain.BatchResult{
+					{Index: 0, Op: domain.BatchDelete, ID: 1},
+					{Index: 1, Op: domain.BatchDelete, ID: 2, Err: domain.ErrContactNotFound},
+				}, nil)
+			},
+			expectedStatusCode:   200,
+			expectedResponseBody: `{"mode":"partial","results":[{"index":0,"op":"delete","id":1,"status":204},{"index":1,"op":"delete","id":2,"status":404,"code":"contact_not_found","detail":"contact not found"}]}`,
+		},
+		{
+			name:      "Atomic failure",
+			inputBody: `{"operations":[{"op":"delete","id":1},{"op":"delete","id":2}]}`,
+			mockBehavior: func(s *mock_rest.MockContacts) {
+				s.EXPECT().Batch(gomock.Any(), int64(1), gomock.Any()).Return(nil, &domain.BatchOperationError{Index: 1, Err: domain.ErrContactNotFound})
+			},
+			expectedStatusCode:   404,
+			expectedResponseBody: `{"type":"urn:contact-list:problem:contact_not_found","title":"Not Found","status":404,"detail":"operation 1: contact not found","instance":"/contacts/batch","code":"contact_not_found"}`,
+		},
+		{
+			name:                 "Missing contact",
+			inputBody:            `{"operations":[{"op":"update","id":1}]}`,
+			mockBehavior:         func(s *mock_rest.MockContacts) {},
+			expectedStatusCode:   422,
+			expectedResponseBody: `{"type":"urn:contact-list:problem:validation_failed","title":"Unprocessable Entity","status":422,"detail":"request validation failed","instance":"/contacts/batch","code":"validation_failed","errors":[{"field":"operations[0].contact","code":"required_unless","message":"is required"}]}`,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			contacts := mock_rest.NewMockContacts(c)
+			testCase.mockBehavior(contacts)
+
+			handler := NewHandler(contacts, &mock_rest.MockAuth{}, &mock_rest.MockAPIKeys{}, &mock_rest.MockOIDC{}, &mock_rest.MockOAuth{}, &mock_rest.MockPrivacy{}, &mock_rest.MockIdempotency{}, &mock_rest.MockWebhooks{}, &mock_rest.MockCardDAV{}, &mock_rest.MockGraphQL{}, &mock_rest.MockHealth{})
+
+			r := gin.New()
+			r.POST("/contacts/batch", func(c *gin.Context) {
+				c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), ctxIdentity, &domain.Identity{UserID: 1}))
+			}, handler.batchContacts)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("POST", "/contacts/batch", bytes.NewBufferString(testCase.inputBody))
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, w.Code, testCase.expectedStatusCode)
+			assert.Equal(t, w.Body.String(), testCase.expectedResponseBody)
+		})
+	}
+}
+
+func TestHandler_getContactChanges(t *testing.T) {
+	type mockBehavior func(s *mock_rest.MockContacts)
+
+	testTable := []struct {
+		name                 string
+		query                string
+		mockBehavior         mockBehavior
+		expectedStatusCode   int
+		expectedResponseBody string
+	}{
+		{
+			name:  "OK",
+			query: "?since=AAAAAAAAAAU&limit=2",
+			mockBehavior: func(s *mock_rest.MockContacts) {
+				s.EXPECT().Changes(gomock.Any(), &domain.ContactChangesFilter{Since: "AAAAAAAAAAU", Limit: 2}).Return(&domain.ContactChanges{
+					Created:   []domain.Contact{},
+					Updated:   []domain.Contact{},
+					Deleted:   []int64{3, 4},
+					SyncToken: "AAAAAAAAAAc",
+					HasMore:   true,
+				}, nil)
+			},
+			expectedStatusCode:   200,
+			expectedResponseBody: `{"created":[],"updated":[],"deleted":[3,4],"sync_token":"AAAAAAAAAAc","has_more":true}`,
+		},
+		{
+			name:  "Token too old",
+			query: "?since=AAAAAAAAAAE",
+			mockBehavior: func(s *mock_rest.MockContacts) {
+				s.EXPECT().Changes(gomock.Any(), &domain.ContactChangesFilter{Since: "AAAAAAAAAAE"}).Return(nil, domain.ErrSyncTokenExpired)
+			},
+			expectedStatusCode:   410,
+			expectedResponseBody: `{"type":"urn:contact-list:problem:sync_token_expired","title":"Gone","status":410,"detail":"sync token is too old, a full sync is required","instance":"/contacts/changes","code":"sync_token_expired"}`,
+		},
+		{
+			name:                 "Limit too large",
+			query:                "?limit=501",
+			mockBehavior:         func(s *mock_rest.MockContacts) {},
+			expectedStatusCode:   422,
+			expectedResponseBody: `{"type":"urn:contact-list:problem:validation_failed","title":"Unprocessable Entity","status":422,"detail":"request validation failed","instance":"/contacts/changes","code":"validation_failed","errors":[{"field":"limit","code":"max","message":"must be at most 500"}]}`,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			contacts := mock_rest.NewMockContacts(c)
+			testCase.mockBehavior(contacts)
+
+			handler := NewHandler(contacts, &mock_rest.MockAuth{}, &mock_rest.MockAPIKeys{}, &mock_rest.MockOIDC{}, &mock_rest.MockOAuth{}, &mock_rest.MockPrivacy{}, &mock_rest.MockIdempotency{}, &mock_rest.MockWebhooks{}, &mock_rest.MockCardDAV{}, &mock_rest.MockGraphQL{}, &mock_rest.MockHealth{})
+
+			r := gin.New()
+			r.GET("/contacts/changes", handler.getContactChanges)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", "/contacts/changes"+testCase.query, nil)
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, w.Code, testCase.expectedStatusCode)
+			assert.Equal(t, w.Body.String(), testCase.expectedResponseBody)
+		})
+	}
+}
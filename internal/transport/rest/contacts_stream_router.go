@@ -0,0 +1,208 @@
+package rest
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/wilfridterry/contact-list/internal/domain"
+	"github.com/wilfridterry/contact-list/pkg/logger"
+
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/net/websocket"
+)
+
+const (
+	lastEventIDHeader = "Last-Event-ID"
+	streamHeartbeat   = 15 * time.Second
+	streamTokenParam  = "access_token"
+
+	// Stream messages which are not contact events.
+	streamReset            = "reset"
+	streamHeartbeatMessage = "heartbeat"
+)
+
+// CreateStreamToken godoc
+// @Summary      Create a stream token
+// @Description  issue a token valid for a minute to open /contacts/stream, /contacts/ws or a /graphql WebSocket from a browser, which can not set the Authorization header on EventSource and WebSocket requests, pass it as the access_token query parameter
+// @Tags         contacts
+// @Produce      json
+// @Success      201  {object}  domain.StreamToken
+// @Failure      401  {object}  Problem
+// @Failure      403  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Router       /contacts/stream-token [post]
+func (h *Handler) createStreamToken(c *gin.Context) {
+	// The token only names the user, it could not keep the restrictions of
+	// an API key, OAuth or impersonation token.
+	identity, ok := refuseDelegated(c)
+	if !ok {
+		return
+	}
+
+	token, err := h.authServie.IssueStreamToken(c.Request.Context(), identity.UserID)
+	if err != nil {
+		newProblem(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, token)
+}
+
+// StreamContacts godoc
+// @Summary      Stream contact changes
+// @Description  push created, updated and deleted events of the contacts as server-sent events, a reconnecting client resumes after Last-Event-ID or gets a "reset" event telling it to reload the contacts
+// @Tags         contacts
+// @Produce      text/event-stream
+// @Param        Last-Event-ID  header  int     false  "Resume after this event"
+// @Param        access_token   query   string  false  "Stream token, for browsers"
+// @Success      200  {object}  domain.ContactEvent
+// @Failure      401  {object}  Problem
+// @Failure      403  {object}  PermissionProblem
+// @Router       /contacts/stream [get]
+func (h *Handler) streamContacts(c *gin.Context) {
+	var cancel context.CancelFunc
+	c.Request, cancel = h.streamRequest(c.Request)
+	defer cancel()
+
+	sub := h.contactService.Subscribe(lastEventID(c))
+	defer sub.Close()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	if sub.Reset {
+		c.Render(-1, sse.Event{Event: streamReset, Data: gin.H{"type": streamReset}})
+	}
+
+	for _, event := range sub.Missed {
+		renderContactEvent(c, event)
+	}
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case event, ok := <-sub.Events:
+			if !ok {
+				return
+			}
+
+			renderContactEvent(c, event)
+		case <-heartbeat.C:
+			io.WriteString(c.Writer, ": "+streamHeartbeatMessage+"\n\n")
+		}
+
+		c.Writer.Flush()
+	}
+}
+
+func renderContactEvent(c *gin.Context, event domain.ContactEvent) {
+	c.Render(-1, sse.Event{
+		Id:    strconv.FormatInt(event.ID, 10),
+		Event: event.Type,
+		Data:  event,
+	})
+}
+
+// ContactsWebSocket godoc
+// @Summary      Stream contact changes over WebSocket
+// @Description  the WebSocket equivalent of /contacts/stream, every message is a JSON contact event, a {"type":"reset"} or a {"type":"heartbeat"} message, resume with the last_event_id query parameter
+// @Tags         contacts
+// @Param        last_event_id  query  int     false  "Resume after this event"
+// @Param        access_token   query  string  false  "Stream token, for browsers"
+// @Success      101
+// @Failure      401  {object}  Problem
+// @Failure      403  {object}  PermissionProblem
+// @Router       /contacts/ws [get]
+func (h *Handler) contactsWebSocket(c *gin.Context) {
+	var cancel context.CancelFunc
+	c.Request, cancel = h.streamRequest(c.Request)
+	defer cancel()
+
+	sub := h.contactService.Subscribe(lastEventID(c))
+	defer sub.Close()
+
+	server := websocket.Server{
+		// Credentials come in the Authorization header or in a stream token
+		// checked against the origin by AuthStream, never in cookies, so
+		// connections from other origins can not act on behalf of the user.
+		Handshake: func(*websocket.Config, *http.Request) error { return nil },
+		Handler: func(ws *websocket.Conn) {
+			defer ws.Close()
+
+			closed := make(chan struct{})
+			go func() {
+				defer close(closed)
+
+				var message string
+				for websocket.Message.Receive(ws, &message) == nil {
+				}
+			}()
+
+			if sub.Reset {
+				if err := websocket.JSON.Send(ws, gin.H{"type": streamReset}); err != nil {
+					return
+				}
+			}
+
+			for _, event := range sub.Missed {
+				if err := websocket.JSON.Send(ws, event); err != nil {
+					return
+				}
+			}
+
+			heartbeat := time.NewTicker(streamHeartbeat)
+			defer heartbeat.Stop()
+
+			for {
+				var err error
+
+				select {
+				case <-closed:
+					return
+				case <-c.Request.Context().Done():
+					return
+				case event, ok := <-sub.Events:
+					if !ok {
+						return
+					}
+
+					err = websocket.JSON.Send(ws, event)
+				case <-heartbeat.C:
+					err = websocket.JSON.Send(ws, gin.H{"type": streamHeartbeatMessage})
+				}
+
+				if err != nil {
+					logger.FromContext(c.Request.Context()).WithField("handler", "contactsWebSocket").Debug("websocket send failed:", err)
+					return
+				}
+			}
+		},
+	}
+
+	server.ServeHTTP(c.Writer, c.Request)
+}
+
+// lastEventID reads the event a client has seen from the header EventSource
+// sends on reconnect, or from the query as browsers can not set headers on
+// WebSocket and EventSource requests.
+func lastEventID(c *gin.Context) int64 {
+	value := c.GetHeader(lastEventIDHeader)
+	if value == "" {
+		value = c.Query("last_event_id")
+	}
+
+	id, _ := strconv.ParseInt(value, 10, 64)
+
+	return id
+}
@@ -0,0 +1,248 @@
+package rest
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/magiconair/properties/assert"
+	"github.com/wilfridterry/contact-list/internal/domain"
+	mock_rest "github.com/wilfridterry/contact-list/internal/transport/rest/mocks"
+	"go.uber.org/mock/gomock"
+	"golang.org/x/net/websocket"
+)
+
+func TestHandler_streamContacts(t *testing.T) {
+	eventTime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	testTable := []struct {
+		name                 string
+		lastEventID          string
+		expectedLastEventID  int64
+		subscription         *domain.ContactSubscription
+		published            []domain.ContactEvent
+		expectedResponseBody string
+	}{
+		{
+			name:                "Resume",
+			lastEventID:         "4",
+			expectedLastEventID: 4,
+			subscription: &domain.ContactSubscription{
+				Missed: []domain.ContactEvent{{ID: 5, Type: domain.ContactUpdated, ContactID: 1, Time: eventTime}},
+			},
+			published: []domain.ContactEvent{{ID: 6, Type: domain.ContactDeleted, ContactID: 1, Time: eventTime}},
+			expectedResponseBody: "id:5\nevent:contact.updated\ndata:{\"id\":5,\"type\":\"contact.updated\",\"contact_id\":1,\"time\":\"2024-01-01T00:00:00Z\"}\n\n" +
+				"id:6\nevent:contact.deleted\ndata:{\"id\":6,\"type\":\"contact.deleted\",\"contact_id\":1,\"time\":\"2024-01-01T00:00:00Z\"}\n\n",
+		},
+		{
+			name:                 "Reset",
+			lastEventID:          "100",
+			expectedLastEventID:  100,
+			subscription:         &domain.ContactSubscription{Reset: true},
+			expectedResponseBody: "event:reset\ndata:{\"type\":\"reset\"}\n\n",
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			// The subscriber is dropped after the published events, which ends
+			// the stream.
+			events := make(chan domain.ContactEvent, len(testCase.published))
+			for _, event := range testCase.published {
+				events <- event
+			}
+			close(events)
+
+			closed := false
+			testCase.subscription.Events = events
+			testCase.subscription.Close = func() { closed = true }
+
+			contacts := mock_rest.NewMockContacts(c)
+			contacts.EXPECT().Subscribe(testCase.expectedLastEventID).Return(testCase.subscription)
+
+			handler := NewHandler(contacts, &mock_rest.MockAuth{}, &mock_rest.MockAPIKeys{}, &mock_rest.MockOIDC{}, &mock_rest.MockOAuth{}, &mock_rest.MockPrivacy{}, &mock_rest.MockIdempotency{}, &mock_rest.MockWebhooks{}, &mock_rest.MockCardDAV{}, &mock_rest.MockGraphQL{}, &mock_rest.MockHealth{})
+
+			r := gin.New()
+			r.GET("/contacts/stream", handler.streamContacts)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", "/contacts/stream", nil)
+			req.Header.Set(lastEventIDHeader, testCase.lastEventID)
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, w.Code, 200)
+			assert.Equal(t, w.Header().Get("Content-Type"), "text/event-stream")
+			assert.Equal(t, w.Body.String(), testCase.expectedResponseBody)
+			assert.Equal(t, closed, true)
+		})
+	}
+}
+
+func TestHandler_StopStreams(t *testing.T) {
+	c := gomock.NewController(t)
+	defer c.Finish()
+
+	// Nothing is ever published, the streams only end on shutdown.
+	contacts := mock_rest.NewMockContacts(c)
+	contacts.EXPECT().Subscribe(int64(0)).Return(&domain.ContactSubscription{Events: make(chan domain.ContactEvent), Close: func() {}}).Times(2)
+
+	handler := NewHandler(contacts, &mock_rest.MockAuth{}, &mock_rest.MockAPIKeys{}, &mock_rest.MockOIDC{}, &mock_rest.MockOAuth{}, &mock_rest.MockPrivacy{}, &mock_rest.MockIdempotency{}, &mock_rest.MockWebhooks{}, &mock_rest.MockCardDAV{}, &mock_rest.MockGraphQL{}, &mock_rest.MockHealth{})
+
+	r := gin.New()
+	r.GET("/contacts/stream", handler.streamContacts)
+	r.GET("/contacts/ws", handler.contactsWebSocket)
+
+	srv := httptest.NewUnstartedServer(r)
+	srv.Config.RegisterOnShutdown(handler.StopStreams)
+	srv.Start()
+	defer srv.Close()
+
+	res, err := http.Get(srv.URL + "/contacts/stream")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	ws, err := websocket.Dial(strings.Replace(srv.URL, "http", "ws", 1)+"/contacts/ws", "", srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ws.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	if err := srv.Config.Shutdown(ctx); err != nil {
+		t.Fatalf("shutdown waited for the event stream: %v", err)
+	}
+
+	if _, err := bufio.NewReader(res.Body).ReadString('\n'); err == nil {
+		t.Error("event stream still open after shutdown")
+	}
+
+	ws.SetReadDeadline(time.Now().Add(2 * time.Second))
+
+	var message string
+	if err := websocket.Message.Receive(ws, &message); err == nil || strings.Contains(err.Error(), "timeout") {
+		t.Errorf("websocket still open after shutdown: %v", err)
+	}
+}
+
+func TestHandler_createStreamToken(t *testing.T) {
+	c := gomock.NewController(t)
+	defer c.Finish()
+
+	expiresAt := time.Date(2024, 1, 1, 0, 1, 0, 0, time.UTC)
+
+	auth := mock_rest.NewMockAuth(c)
+	auth.EXPECT().IssueStreamToken(gomock.Any(), int64(1)).Return(&domain.StreamToken{Token: "token", ExpiresAt: expiresAt}, nil)
+
+	handler := NewHandler(&mock_rest.MockContacts{}, auth, &mock_rest.MockAPIKeys{}, &mock_rest.MockOIDC{}, &mock_rest.MockOAuth{}, &mock_rest.MockPrivacy{}, &mock_rest.MockIdempotency{}, &mock_rest.MockWebhooks{}, &mock_rest.MockCardDAV{}, &mock_rest.MockGraphQL{}, &mock_rest.MockHealth{})
+
+	r := gin.New()
+	r.POST("/contacts/stream-token", handler.createStreamToken)
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("POST", "/contacts/stream-token", nil)
+	req = req.WithContext(context.WithValue(req.Context(), ctxIdentity, &domain.Identity{UserID: 1, Role: domain.RoleUser}))
+
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, w.Code, 201)
+	assert.Equal(t, w.Body.String(), `{"token":"token","expires_at":"2024-01-01T00:01:00Z"}`)
+}
+
+func TestHandler_AuthStream(t *testing.T) {
+	const origin = "https://app.example.com"
+
+	identity := &domain.Identity{UserID: 1, Role: domain.RoleUser, Permissions: []domain.Permission{domain.PermissionContactsRead}}
+
+	type mockBehavior func(r *mock_rest.MockAuth)
+
+	testTable := []struct {
+		name                 string
+		query                string
+		authorization        string
+		mockBehavior         mockBehavior
+		expectedStatusCode   int
+		expectedResponseBody string
+	}{
+		{
+			name:  "Stream token",
+			query: "?access_token=token",
+			mockBehavior: func(r *mock_rest.MockAuth) {
+				r.EXPECT().ParseStreamToken(gomock.Any(), "token", origin).Return(identity, nil)
+			},
+			expectedStatusCode: 200,
+		},
+		{
+			name:          "Authorization header",
+			authorization: "Bearer jwt",
+			mockBehavior: func(r *mock_rest.MockAuth) {
+				r.EXPECT().ParseJWTToken(gomock.Any(), "jwt").Return(identity, nil)
+			},
+			expectedStatusCode: 200,
+		},
+		{
+			name:  "Foreign origin",
+			query: "?access_token=token",
+			mockBehavior: func(r *mock_rest.MockAuth) {
+				r.EXPECT().ParseStreamToken(gomock.Any(), "token", origin).Return(nil, domain.ErrStreamOriginForbidden)
+			},
+			expectedStatusCode:   403,
+			expectedResponseBody: `{"type":"urn:contact-list:problem:stream_origin_forbidden","title":"Forbidden","status":403,"detail":"stream token used from a foreign origin","instance":"/contacts/stream","code":"stream_origin_forbidden"}`,
+		},
+		{
+			name:  "Invalid stream token",
+			query: "?access_token=token",
+			mockBehavior: func(r *mock_rest.MockAuth) {
+				r.EXPECT().ParseStreamToken(gomock.Any(), "token", origin).Return(nil, domain.ErrInvalidAccessToken)
+			},
+			expectedStatusCode:   401,
+			expectedResponseBody: `{"type":"urn:contact-list:problem:invalid_access_token","title":"Unauthorized","status":401,"detail":"invalid or expired access token","instance":"/contacts/stream","code":"invalid_access_token"}`,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			auth := mock_rest.NewMockAuth(c)
+			testCase.mockBehavior(auth)
+
+			// The stream ends right away once authenticated.
+			events := make(chan domain.ContactEvent)
+			close(events)
+
+			contacts := mock_rest.NewMockContacts(c)
+			contacts.EXPECT().Subscribe(int64(0)).Return(&domain.ContactSubscription{Events: events, Close: func() {}}).MaxTimes(1)
+
+			handler := NewHandler(contacts, auth, &mock_rest.MockAPIKeys{}, &mock_rest.MockOIDC{}, &mock_rest.MockOAuth{}, &mock_rest.MockPrivacy{}, &mock_rest.MockIdempotency{}, &mock_rest.MockWebhooks{}, &mock_rest.MockCardDAV{}, &mock_rest.MockGraphQL{}, &mock_rest.MockHealth{})
+
+			r := gin.New()
+			r.GET("/contacts/stream", handler.AuthStream(), handler.RequirePermissions(domain.PermissionContactsRead), handler.streamContacts)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", "/contacts/stream"+testCase.query, nil)
+			req.Header.Set("Origin", origin)
+			if testCase.authorization != "" {
+				req.Header.Set("Authorization", testCase.authorization)
+			}
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, w.Code, testCase.expectedStatusCode)
+			assert.Equal(t, w.Body.String(), testCase.expectedResponseBody)
+		})
+	}
+}
@@ -0,0 +1,38 @@
+package rest
+
+import (
+	"context"
+
+	"github.com/wilfridterry/contact-list/internal/domain"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GraphQL godoc
+// @Summary      GraphQL endpoint
+// @Description  run GraphQL queries and mutations over contacts and users, with GET for queries only; subscriptions are served over WebSocket with the graphql-transport-ws protocol on the same path
+// @Tags         graphql
+// @Accept       json
+// @Produce      json
+// @Param        query          query  string  false  "Query, for GET requests"
+// @Param        operationName  query  string  false  "Operation to run, for GET requests"
+// @Param        variables      query  string  false  "JSON encoded variables, for GET requests"
+// @Param        access_token   query  string  false  "Stream token, for browsers opening a WebSocket"
+// @Success      200
+// @Failure      400
+// @Failure      401  {object}  Problem
+// @Router       /graphql [get]
+// @Router       /graphql [post]
+func (h *Handler) graphQL(c *gin.Context) {
+	identity, _ := getIdentity(c)
+
+	// A WebSocket connection lives as long as its request.
+	if c.IsWebsocket() {
+		var cancel context.CancelFunc
+		c.Request, cancel = h.streamRequest(c.Request)
+		defer cancel()
+	}
+
+	ctx := domain.WithIdentity(c.Request.Context(), identity)
+	h.graphqlService.ServeHTTP(c.Writer, c.Request.WithContext(ctx))
+}
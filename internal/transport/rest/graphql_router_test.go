@@ -0,0 +1,77 @@
+package rest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/magiconair/properties/assert"
+	"github.com/wilfridterry/contact-list/internal/domain"
+	mock_rest "github.com/wilfridterry/contact-list/internal/transport/rest/mocks"
+	"go.uber.org/mock/gomock"
+)
+
+func TestHandler_AuthGraphQL(t *testing.T) {
+	const origin = "https://app.example.com"
+
+	identity := &domain.Identity{UserID: 1, Role: domain.RoleUser, Permissions: []domain.Permission{domain.PermissionContactsRead}}
+
+	type mockBehavior func(r *mock_rest.MockAuth)
+
+	testTable := []struct {
+		name                 string
+		websocket            bool
+		mockBehavior         mockBehavior
+		expectedStatusCode   int
+		expectedResponseBody string
+	}{
+		{
+			name:      "WebSocket with stream token",
+			websocket: true,
+			mockBehavior: func(r *mock_rest.MockAuth) {
+				r.EXPECT().ParseStreamToken(gomock.Any(), "token", origin).Return(identity, nil)
+			},
+			expectedStatusCode: 200,
+		},
+		{
+			name:                 "Query with stream token",
+			mockBehavior:         func(r *mock_rest.MockAuth) {},
+			expectedStatusCode:   401,
+			expectedResponseBody: `{"type":"urn:contact-list:problem:unauthenticated","title":"Unauthorized","status":401,"detail":"invalid auth header","instance":"/graphql","code":"unauthenticated"}`,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			auth := mock_rest.NewMockAuth(c)
+			testCase.mockBehavior(auth)
+
+			graphql := mock_rest.NewMockGraphQL(c)
+			graphql.EXPECT().ServeHTTP(gomock.Any(), gomock.Any()).Do(func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}).MaxTimes(1)
+
+			handler := NewHandler(&mock_rest.MockContacts{}, auth, &mock_rest.MockAPIKeys{}, &mock_rest.MockOIDC{}, &mock_rest.MockOAuth{}, &mock_rest.MockPrivacy{}, &mock_rest.MockIdempotency{}, &mock_rest.MockWebhooks{}, &mock_rest.MockCardDAV{}, graphql, &mock_rest.MockHealth{})
+
+			r := gin.New()
+			r.GET("/graphql", handler.AuthGraphQL(), handler.graphQL)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", "/graphql?access_token=token", nil)
+			req.Header.Set("Origin", origin)
+			if testCase.websocket {
+				req.Header.Set("Connection", "Upgrade")
+				req.Header.Set("Upgrade", "websocket")
+			}
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, w.Code, testCase.expectedStatusCode)
+			assert.Equal(t, w.Body.String(), testCase.expectedResponseBody)
+		})
+	}
+}
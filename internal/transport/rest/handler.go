@@ -2,6 +2,8 @@ package rest
 
 import (
 	"context"
+	"io"
+	"net/http"
 
 	"github.com/wilfridterry/contact-list/internal/domain"
 
@@ -15,23 +17,128 @@ import (
 //go:generate mockgen -source=handler.go -destination=mocks/mock.go
 
 type Handler struct {
-	contactService Contacts
-	authServie     Auth
+	contactService     Contacts
+	authServie         Auth
+	apiKeyService      APIKeys
+	oidcService        OIDC
+	oauthService       OAuth
+	privacyService     Privacy
+	idempotencyService Idempotency
+	webhookService     Webhooks
+	carddavService     CardDAV
+	graphqlService     GraphQL
+	healthService      Health
+
+	// streams is done once the server shuts down, which ends the streams
+	// as they would otherwise only end when the client goes away.
+	streams     context.Context
+	stopStreams context.CancelFunc
 }
 
 type Contacts interface {
 	All(context.Context) ([]domain.Contact, error)
 	GetOne(context.Context, int64) (*domain.Contact, error)
-	Create(context.Context, *domain.SaveInputContact) error
+	Create(context.Context, *domain.SaveInputContact) (int64, error)
 	Update(context.Context, int64, *domain.SaveInputContact) error
 	Delete(context.Context, int64) error
+	Batch(context.Context, int64, *domain.BatchInput) ([]domain.BatchResult, error)
+	Subscribe(int64) *domain.ContactSubscription
+	Changes(context.Context, *domain.ContactChangesFilter) (*domain.ContactChanges, error)
 }
 
 type Auth interface {
 	SignUp(context.Context, *domain.SignUpInput) (*domain.User, error)
 	SingIn(context.Context, *domain.SignInInput) (string, string, error)
-	ParseJWTToken(context.Context, string) (int64, error)
+	ParseJWTToken(context.Context, string) (*domain.Identity, error)
 	RefreshTokens(context.Context, string) (string, string, error)
+	AssignRole(context.Context, int64, domain.Role) error
+	EnrollMFA(context.Context, int64) (*domain.MFAEnrollment, error)
+	ConfirmMFA(context.Context, int64, string) ([]string, error)
+	DisableMFA(context.Context, int64, string) error
+	VerifyMFA(context.Context, *domain.MFAVerifyInput) (string, string, error)
+	VerifyEmail(context.Context, string) error
+	ResendVerification(context.Context, string) error
+	ForgotPassword(context.Context, string) error
+	ResetPassword(context.Context, *domain.ResetPasswordInput) error
+	Unlock(context.Context, int64) error
+	Profile(context.Context, int64) (*domain.User, error)
+	UpdateProfile(context.Context, int64, *domain.UpdateProfileInput) (*domain.User, error)
+	ChangePassword(context.Context, int64, *domain.ChangePasswordInput) (string, string, error)
+	DeleteAccount(context.Context, int64, *domain.DeleteAccountInput) error
+	ListUsers(context.Context, *domain.UserFilter) (*domain.UserPage, error)
+	UserDetails(context.Context, int64) (*domain.UserDetails, error)
+	DisableUser(context.Context, int64, int64) error
+	EnableUser(context.Context, int64, int64) error
+	ForcePasswordReset(context.Context, int64, int64) error
+	RevokeSessions(context.Context, int64, int64) error
+	Impersonate(context.Context, int64, int64) (*domain.ImpersonationToken, error)
+	IssueStreamToken(context.Context, int64) (*domain.StreamToken, error)
+	ParseStreamToken(context.Context, string, string) (*domain.Identity, error)
+}
+
+type APIKeys interface {
+	Create(context.Context, int64, *domain.CreateAPIKeyInput) (*domain.APIKey, string, error)
+	All(context.Context, int64) ([]domain.APIKey, error)
+	Revoke(context.Context, int64, int64) error
+	Authenticate(context.Context, string) (*domain.Identity, error)
+}
+
+type OIDC interface {
+	Begin(context.Context, string, int64) (string, string, error)
+	Complete(context.Context, string, string, string, string, string) (string, string, error)
+}
+
+type OAuth interface {
+	RegisterClient(context.Context, int64, *domain.RegisterOAuthClientInput) (*domain.OAuthClient, string, error)
+	Clients(context.Context, int64) ([]domain.OAuthClient, error)
+	DeleteClient(context.Context, int64, int64) error
+	Authorize(context.Context, int64, *domain.AuthorizeInput) (*domain.AuthorizePrompt, error)
+	Consent(context.Context, int64, *domain.ConsentInput) (string, error)
+	Consents(context.Context, int64) ([]domain.OAuthConsent, error)
+	RevokeConsent(context.Context, int64, string) error
+	Token(context.Context, *domain.TokenInput) (*domain.TokenResponse, error)
+	Introspect(context.Context, *domain.OAuthTokenInput) (*domain.TokenIntrospection, error)
+	Revoke(context.Context, *domain.OAuthTokenInput) error
+	Authenticate(context.Context, string) (*domain.Identity, error)
+}
+
+type Privacy interface {
+	Export(context.Context, int64, io.Writer) error
+	EraseUser(context.Context, int64, domain.ErasureMode) error
+	EraseContact(context.Context, int64, domain.ErasureMode) error
+}
+
+type Idempotency interface {
+	Begin(context.Context, int64, string, string) (*domain.IdempotencyRecord, error)
+	Complete(context.Context, int64, string, int, map[string][]string, []byte) error
+	Release(context.Context, int64, string) error
+}
+
+type Webhooks interface {
+	Create(context.Context, int64, *domain.CreateWebhookInput) (*domain.Webhook, string, error)
+	All(context.Context, int64) ([]domain.Webhook, error)
+	Delete(context.Context, int64, int64) error
+	Deliveries(context.Context, int64, int64) ([]domain.WebhookDelivery, error)
+	Delivery(context.Context, int64, int64, int64) (*domain.WebhookDelivery, error)
+	Redeliver(context.Context, int64, int64, int64) error
+}
+
+type CardDAV interface {
+	SyncToken(context.Context) (string, error)
+	Cards(context.Context, int64) ([]domain.Card, error)
+	Card(context.Context, int64, string) (*domain.Card, error)
+	SaveCard(context.Context, int64, string, string, *domain.SaveInputContact, *domain.Card) (bool, error)
+	DeleteCard(context.Context, *domain.Card) error
+	Changes(context.Context, int64, string) (*domain.CardChanges, error)
+}
+
+// GraphQL serves the GraphQL API, the caller comes in the request context.
+type GraphQL interface {
+	ServeHTTP(http.ResponseWriter, *http.Request)
+}
+
+type Health interface {
+	Check(context.Context) *domain.HealthReport
 }
 
 type Uri struct {
@@ -39,19 +146,78 @@ type Uri struct {
 }
 
 func (h *Handler) InitRouter() *gin.Engine {
-	r := gin.Default()
-	r.Use(gin.Logger())
+	r := gin.New()
 	r.Use(gin.Recovery())
+	r.Use(RequestID())
+	r.Use(Tracing())
+	r.Use(Metrics())
+	r.Use(Logger())
+
+	r.GET("/healthz", h.healthz)
+	r.GET("/readyz", h.readyz)
 
 	v1 := r.Group("/api/v1")
 	{
 		contacts := v1.Group("/contacts").Use(h.AuthJWT())
 		{
-			contacts.POST("/", h.createContact)
-			contacts.GET("/", h.getContacts)
-			contacts.GET("/:id", h.getContact)
-			contacts.DELETE("/:id", h.deleteContact)
-			contacts.PUT("/:id", h.updateAccount)
+			contacts.POST("/", h.RequirePermissions(domain.PermissionContactsWrite), h.Idempotent(), h.createContact)
+			contacts.POST("/batch", h.RequirePermissions(domain.PermissionContactsWrite), h.Idempotent(), h.batchContacts)
+			contacts.GET("/", h.RequirePermissions(domain.PermissionContactsRead), h.getContacts)
+			contacts.GET("/changes", h.RequirePermissions(domain.PermissionContactsRead), h.getContactChanges)
+			contacts.POST("/stream-token", h.RequirePermissions(domain.PermissionContactsRead), h.createStreamToken)
+			contacts.GET("/:id", h.RequirePermissions(domain.PermissionContactsRead), h.getContact)
+			contacts.DELETE("/:id", h.RequirePermissions(domain.PermissionContactsWrite), h.deleteContact)
+			contacts.PUT("/:id", h.RequirePermissions(domain.PermissionContactsWrite), h.updateAccount)
+		}
+
+		contactStreams := v1.Group("/contacts").Use(h.AuthStream(), h.RequirePermissions(domain.PermissionContactsRead))
+		{
+			contactStreams.GET("/stream", h.streamContacts)
+			contactStreams.GET("/ws", h.contactsWebSocket)
+		}
+
+		me := v1.Group("/me").Use(h.AuthJWT())
+		{
+			me.GET("", h.getProfile)
+			me.PATCH("", h.Idempotent(), h.updateProfile)
+			me.POST("/password", h.changePassword)
+			me.DELETE("", h.deleteAccount)
+			me.GET("/export", h.exportMe)
+			me.POST("/identities/:provider", h.linkIdentity)
+		}
+
+		apiKeys := v1.Group("/api-keys").Use(h.AuthJWT())
+		{
+			apiKeys.POST("/", h.createAPIKey)
+			apiKeys.GET("/", h.getAPIKeys)
+			apiKeys.DELETE("/:id", h.revokeAPIKey)
+		}
+
+		webhooks := v1.Group("/webhooks").Use(h.AuthJWT(), h.RequirePermissions(domain.PermissionContactsRead))
+		{
+			webhooks.POST("/", h.createWebhook)
+			webhooks.GET("/", h.getWebhooks)
+			webhooks.DELETE("/:id", h.deleteWebhook)
+			webhooks.GET("/:id/deliveries", h.getWebhookDeliveries)
+			webhooks.GET("/:id/deliveries/:delivery_id", h.getWebhookDelivery)
+			webhooks.POST("/:id/deliveries/:delivery_id/redeliver", h.redeliverWebhook)
+		}
+
+		admin := v1.Group("/admin").Use(h.AuthJWT(), h.RequirePermissions(domain.PermissionUsersAdmin))
+		{
+			admin.GET("/status", h.healthStatus)
+			admin.GET("/users", h.listUsers)
+			admin.GET("/users/:id", h.getUserDetails)
+			admin.PUT("/users/:id/role", h.assignRole)
+			admin.POST("/users/:id/disable", h.Idempotent(), h.disableUser)
+			admin.POST("/users/:id/enable", h.Idempotent(), h.enableUser)
+			admin.POST("/users/:id/password-reset", h.Idempotent(), h.forcePasswordReset)
+			admin.DELETE("/users/:id/sessions", h.revokeSessions)
+			admin.POST("/users/:id/impersonate", h.impersonateUser)
+			admin.DELETE("/users/:id/lockout", h.unlockUser)
+			admin.GET("/users/:id/export", h.exportUser)
+			admin.POST("/users/:id/erase", h.Idempotent(), h.eraseUser)
+			admin.POST("/contacts/:id/erase", h.Idempotent(), h.eraseContact)
 		}
 
 		auth := v1.Group("/auth")
@@ -59,14 +225,102 @@ func (h *Handler) InitRouter() *gin.Engine {
 			auth.POST("/sign-up", h.signUp)
 			auth.GET("/sign-in", h.signIn)
 			auth.GET("/refresh", h.refresh)
+			auth.POST("/mfa/verify", h.verifyMFA)
+			auth.POST("/verify", h.verifyEmail)
+			auth.POST("/verify/resend", h.resendVerification)
+			auth.POST("/forgot-password", h.forgotPassword)
+			auth.POST("/reset-password", h.resetPassword)
+			auth.GET("/oidc/:provider/login", h.oidcLogin)
+			auth.GET("/oidc/:provider/callback", h.oidcCallback)
+		}
+
+		oauth := v1.Group("/oauth")
+		{
+			oauth.POST("/token", h.oauthToken)
+			oauth.POST("/introspect", h.oauthIntrospect)
+			oauth.POST("/revoke", h.oauthRevoke)
+		}
+
+		oauthUser := v1.Group("/oauth").Use(h.AuthJWT())
+		{
+			oauthUser.GET("/authorize", h.oauthAuthorize)
+			oauthUser.POST("/authorize", h.oauthConsent)
+			oauthUser.POST("/clients", h.registerOAuthClient)
+			oauthUser.GET("/clients", h.getOAuthClients)
+			oauthUser.DELETE("/clients/:id", h.deleteOAuthClient)
+			oauthUser.GET("/consents", h.getOAuthConsents)
+			oauthUser.DELETE("/consents/:client_id", h.revokeOAuthConsent)
+		}
+
+		graphql := v1.Group("/graphql").Use(h.AuthGraphQL())
+		{
+			graphql.GET("", h.graphQL)
+			graphql.POST("", h.graphQL)
+		}
+
+		mfa := v1.Group("/mfa").Use(h.AuthJWT())
+		{
+			mfa.POST("/enroll", h.enrollMFA)
+			mfa.POST("/confirm", h.confirmMFA)
+			mfa.DELETE("/", h.disableMFA)
 		}
 	}
 
+	for _, method := range carddavMethods {
+		r.Handle(method, "/.well-known/carddav", h.carddavWellKnown)
+	}
+
+	carddav := r.Group("/carddav", h.AuthBasic())
+	{
+		carddav.OPTIONS("/*path", h.carddavOptions)
+		carddav.Handle(methodPropfind, "/*path", h.RequirePermissions(domain.PermissionContactsRead), h.carddavPropfind)
+		carddav.Handle(methodReport, "/*path", h.RequirePermissions(domain.PermissionContactsRead), h.carddavReport)
+		carddav.GET("/*path", h.RequirePermissions(domain.PermissionContactsRead), h.getCard)
+		carddav.HEAD("/*path", h.RequirePermissions(domain.PermissionContactsRead), h.getCard)
+		carddav.PUT("/*path", h.RequirePermissions(domain.PermissionContactsWrite), h.putCard)
+		carddav.DELETE("/*path", h.RequirePermissions(domain.PermissionContactsWrite), h.deleteCard)
+	}
+
 	r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
 	return r
 }
 
-func NewHandler(contacts Contacts, auth Auth) *Handler {
-	return &Handler{contacts, auth}
+func NewHandler(contacts Contacts, auth Auth, apiKeys APIKeys, oidc OIDC, oauth OAuth, privacy Privacy, idempotency Idempotency, webhooks Webhooks, carddav CardDAV, graphql GraphQL, health Health) *Handler {
+	streams, stopStreams := context.WithCancel(context.Background())
+
+	return &Handler{
+		contactService:     contacts,
+		authServie:         auth,
+		apiKeyService:      apiKeys,
+		oidcService:        oidc,
+		oauthService:       oauth,
+		privacyService:     privacy,
+		idempotencyService: idempotency,
+		webhookService:     webhooks,
+		carddavService:     carddav,
+		graphqlService:     graphql,
+		healthService:      health,
+		streams:            streams,
+		stopStreams:        stopStreams,
+	}
+}
+
+// StopStreams ends the contact streams and the GraphQL WebSocket connections.
+// Register it with http.Server.RegisterOnShutdown: Shutdown does not wait for
+// hijacked connections and would wait for event streams until it times out.
+func (h *Handler) StopStreams() {
+	h.stopStreams()
+}
+
+// streamRequest gives the request of a stream a context which is also done
+// once the server shuts down.
+func (h *Handler) streamRequest(r *http.Request) (*http.Request, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(r.Context())
+	stop := context.AfterFunc(h.streams, cancel)
+
+	return r.WithContext(ctx), func() {
+		stop()
+		cancel()
+	}
 }
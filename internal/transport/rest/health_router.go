@@ -0,0 +1,47 @@
+package rest
+
+import (
+	"net/http"
+
+	"github.com/wilfridterry/contact-list/internal/domain"
+
+	"github.com/gin-gonic/gin"
+)
+
+// healthz tells the orchestrator the process is alive, it does not depend on
+// anything else so a database outage does not get the app restarted.
+func (h *Handler) healthz(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "up"})
+}
+
+// readyz tells the orchestrator whether to route requests to the app, it
+// fails while a dependency is down and once the app is shutting down.
+func (h *Handler) readyz(c *gin.Context) {
+	report := h.healthService.Check(c.Request.Context())
+
+	c.JSON(healthStatusCode(report), gin.H{"status": report.Status})
+}
+
+// HealthStatus godoc
+// @Summary      Show the health of the app
+// @Description  check every dependency of the app and report its status, latency and error
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  domain.HealthReport
+// @Failure      403  {object}  PermissionProblem
+// @Failure      503  {object}  domain.HealthReport
+// @Router       /admin/status [get]
+func (h *Handler) healthStatus(c *gin.Context) {
+	report := h.healthService.Check(c.Request.Context())
+
+	c.JSON(healthStatusCode(report), report)
+}
+
+func healthStatusCode(report *domain.HealthReport) int {
+	if !report.Ready() {
+		return http.StatusServiceUnavailable
+	}
+
+	return http.StatusOK
+}
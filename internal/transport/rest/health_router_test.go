@@ -0,0 +1,104 @@
+package rest
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/magiconair/properties/assert"
+	"github.com/wilfridterry/contact-list/internal/domain"
+	mock_rest "github.com/wilfridterry/contact-list/internal/transport/rest/mocks"
+	"go.uber.org/mock/gomock"
+)
+
+func TestHandler_health(t *testing.T) {
+	type mockBehavior func(s *mock_rest.MockHealth)
+
+	startedAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	checkedAt := time.Date(2024, 1, 1, 1, 0, 0, 0, time.UTC)
+
+	report := func(status string, dependencies ...domain.DependencyHealth) *domain.HealthReport {
+		return &domain.HealthReport{Status: status, StartedAt: startedAt, CheckedAt: checkedAt, Dependencies: dependencies}
+	}
+
+	testTable := []struct {
+		name                 string
+		path                 string
+		mockBehavior         mockBehavior
+		expectedStatusCode   int
+		expectedResponseBody string
+	}{
+		{
+			name:                 "Alive",
+			path:                 "/healthz",
+			mockBehavior:         func(s *mock_rest.MockHealth) {},
+			expectedStatusCode:   200,
+			expectedResponseBody: `{"status":"up"}`,
+		},
+		{
+			name: "Ready",
+			path: "/readyz",
+			mockBehavior: func(s *mock_rest.MockHealth) {
+				s.EXPECT().Check(gomock.Any()).Return(report(domain.HealthUp))
+			},
+			expectedStatusCode:   200,
+			expectedResponseBody: `{"status":"up"}`,
+		},
+		{
+			name: "Dependency down",
+			path: "/readyz",
+			mockBehavior: func(s *mock_rest.MockHealth) {
+				s.EXPECT().Check(gomock.Any()).Return(report(domain.HealthDown))
+			},
+			expectedStatusCode:   503,
+			expectedResponseBody: `{"status":"down"}`,
+		},
+		{
+			name: "Draining",
+			path: "/readyz",
+			mockBehavior: func(s *mock_rest.MockHealth) {
+				s.EXPECT().Check(gomock.Any()).Return(report(domain.HealthDraining))
+			},
+			expectedStatusCode:   503,
+			expectedResponseBody: `{"status":"draining"}`,
+		},
+		{
+			name: "Status",
+			path: "/admin/status",
+			mockBehavior: func(s *mock_rest.MockHealth) {
+				s.EXPECT().Check(gomock.Any()).Return(report(domain.HealthDown,
+					domain.DependencyHealth{Name: "amqp", Status: domain.HealthDown, Error: "amqp: not connected"},
+					domain.DependencyHealth{Name: "postgres", Status: domain.HealthUp, LatencyMs: 3},
+				))
+			},
+			expectedStatusCode:   503,
+			expectedResponseBody: `{"status":"down","started_at":"2024-01-01T00:00:00Z","checked_at":"2024-01-01T01:00:00Z","dependencies":[{"name":"amqp","status":"down","latency_ms":0,"error":"amqp: not connected"},{"name":"postgres","status":"up","latency_ms":3}]}`,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			health := mock_rest.NewMockHealth(c)
+			testCase.mockBehavior(health)
+
+			handler := NewHandler(&mock_rest.MockContacts{}, &mock_rest.MockAuth{}, &mock_rest.MockAPIKeys{}, &mock_rest.MockOIDC{}, &mock_rest.MockOAuth{}, &mock_rest.MockPrivacy{}, &mock_rest.MockIdempotency{}, &mock_rest.MockWebhooks{}, &mock_rest.MockCardDAV{}, &mock_rest.MockGraphQL{}, health)
+
+			r := gin.New()
+			r.GET("/healthz", handler.healthz)
+			r.GET("/readyz", handler.readyz)
+			r.GET("/admin/status", handler.healthStatus)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", testCase.path, nil)
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, w.Code, testCase.expectedStatusCode)
+			assert.Equal(t, w.Body.String(), testCase.expectedResponseBody)
+		})
+	}
+}
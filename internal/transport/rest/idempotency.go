@@ -0,0 +1,149 @@
+package rest
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+
+	"github.com/wilfridterry/contact-list/internal/domain"
+	"github.com/wilfridterry/contact-list/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	idempotencyKeyHeader      = "Idempotency-Key"
+	idempotencyReplayedHeader = "Idempotent-Replayed"
+	maxIdempotencyKeyLength   = 255
+)
+
+// replayedHeaders are the response headers stored with the response, the
+// others are tied to the request which ran.
+var replayedHeaders = []string{"Content-Type", "Content-Location", "Location", "ETag", "Last-Modified", "Link"}
+
+// responseRecorder keeps a copy of the response body written by the handler.
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *responseRecorder) Write(data []byte) (int, error) {
+	w.body.Write(data)
+
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *responseRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+
+	return w.ResponseWriter.WriteString(s)
+}
+
+// Idempotent must be used after AuthJWT. A request with an Idempotency-Key
+// header runs once per user and key: a retry with the same method, path and
+// body gets the stored response, a retry with a different one gets 409, as
+// does a retry while the first request is still running. Server errors are not
+// stored, so the request can be retried with the same key. Routes returning
+// secrets (tokens, API keys, client secrets) must not use it, the responses
+// are stored as they are.
+func (h *Handler) Idempotent() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		key := ctx.GetHeader(idempotencyKeyHeader)
+		if key == "" {
+			ctx.Next()
+			return
+		}
+
+		if len(key) > maxIdempotencyKeyLength {
+			newProblem(ctx, domain.ErrInvalidIdempotencyKey)
+			return
+		}
+
+		identity, ok := getIdentity(ctx)
+		if !ok {
+			newProblem(ctx, errInvalidAuthHeader)
+			return
+		}
+
+		body, err := io.ReadAll(ctx.Request.Body)
+		if err != nil {
+			newProblem(ctx, err)
+			return
+		}
+		ctx.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		record, err := h.idempotencyService.Begin(ctx.Request.Context(), identity.UserID, key, requestFingerprint(ctx.Request, body))
+		if err != nil {
+			newProblem(ctx, err)
+			return
+		}
+
+		if record != nil {
+			header := http.Header(record.Header)
+			for _, name := range replayedHeaders {
+				for _, value := range header.Values(name) {
+					ctx.Writer.Header().Add(name, value)
+				}
+			}
+
+			ctx.Header(idempotencyReplayedHeader, "true")
+			ctx.Data(record.Status, header.Get("Content-Type"), record.Body)
+			ctx.Abort()
+			return
+		}
+
+		// The outcome is stored even if the client went away meanwhile.
+		storeCtx := context.WithoutCancel(ctx.Request.Context())
+
+		recorder := &responseRecorder{ResponseWriter: ctx.Writer}
+		ctx.Writer = recorder
+
+		stored := false
+		defer func() {
+			if stored {
+				return
+			}
+
+			if err := h.idempotencyService.Release(storeCtx, identity.UserID, key); err != nil {
+				logger.FromContext(ctx.Request.Context()).WithField("key", key).Error("failed to release idempotency key:", err)
+			}
+		}()
+
+		ctx.Next()
+
+		status := recorder.Status()
+		if status >= http.StatusInternalServerError {
+			return
+		}
+
+		if err := h.idempotencyService.Complete(storeCtx, identity.UserID, key, status, storedHeader(recorder.Header()), recorder.body.Bytes()); err != nil {
+			logger.FromContext(ctx.Request.Context()).WithField("key", key).Error("failed to store idempotent response:", err)
+			return
+		}
+
+		stored = true
+	}
+}
+
+func storedHeader(header http.Header) map[string][]string {
+	stored := make(map[string][]string)
+	for _, name := range replayedHeaders {
+		if values := header.Values(name); len(values) > 0 {
+			stored[http.CanonicalHeaderKey(name)] = values
+		}
+	}
+
+	return stored
+}
+
+// requestFingerprint tells whether a retry is the same request.
+func requestFingerprint(r *http.Request, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(r.Method + " " + r.URL.Path + "\n"))
+	hash.Write(body)
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
@@ -0,0 +1,156 @@
+package rest
+
+import (
+	"bytes"
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/magiconair/properties/assert"
+	"github.com/wilfridterry/contact-list/internal/domain"
+	mock_rest "github.com/wilfridterry/contact-list/internal/transport/rest/mocks"
+	"go.uber.org/mock/gomock"
+)
+
+func TestHandler_Idempotent(t *testing.T) {
+	type mockBehavior func(s *mock_rest.MockIdempotency)
+
+	completedAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	fingerprint := requestFingerprint(httptest.NewRequest("POST", "/contacts", nil), []byte(`{"name":"John"}`))
+
+	testTable := []struct {
+		name                 string
+		key                  string
+		handlerStatus        int
+		mockBehavior         mockBehavior
+		expectedStatusCode   int
+		expectedResponseBody string
+		expectedReplayed     string
+		expectedCalls        int
+	}{
+		{
+			name:                 "Without key",
+			handlerStatus:        201,
+			mockBehavior:         func(s *mock_rest.MockIdempotency) {},
+			expectedStatusCode:   201,
+			expectedResponseBody: `{"id":1}`,
+			expectedCalls:        1,
+		},
+		{
+			name:          "First request",
+			key:           "key",
+			handlerStatus: 201,
+			mockBehavior: func(s *mock_rest.MockIdempotency) {
+				s.EXPECT().Begin(gomock.Any(), int64(1), "key", fingerprint).Return(nil, nil)
+				s.EXPECT().Complete(gomock.Any(), int64(1), "key", 201, map[string][]string{
+					"Content-Type": {"application/json; charset=utf-8"},
+					"Location":     {"/contacts/1"},
+					"Etag":         {`"1"`},
+				}, []byte(`{"id":1}`)).Return(nil)
+			},
+			expectedStatusCode:   201,
+			expectedResponseBody: `{"id":1}`,
+			expectedCalls:        1,
+		},
+		{
+			name: "Replay",
+			key:  "key",
+			mockBehavior: func(s *mock_rest.MockIdempotency) {
+				s.EXPECT().Begin(gomock.Any(), int64(1), "key", fingerprint).Return(&domain.IdempotencyRecord{
+					Status: 201,
+					Header: map[string][]string{
+						"Content-Type": {"application/json; charset=utf-8"},
+						"Location":     {"/contacts/1"},
+						"Etag":         {`"1"`},
+					},
+					Body:        []byte(`{"id":1}`),
+					CompletedAt: &completedAt,
+				}, nil)
+			},
+			expectedStatusCode:   201,
+			expectedResponseBody: `{"id":1}`,
+			expectedReplayed:     "true",
+		},
+		{
+			name: "Key reused",
+			key:  "key",
+			mockBehavior: func(s *mock_rest.MockIdempotency) {
+				s.EXPECT().Begin(gomock.Any(), int64(1), "key", fingerprint).Return(nil, domain.ErrIdempotencyKeyReused)
+			},
+			expectedStatusCode:   409,
+			expectedResponseBody: `{"type":"urn:contact-list:problem:idempotency_key_reused","title":"Conflict","status":409,"detail":"idempotency key was already used with another request","instance":"/contacts","code":"idempotency_key_reused"}`,
+		},
+		{
+			name: "In progress",
+			key:  "key",
+			mockBehavior: func(s *mock_rest.MockIdempotency) {
+				s.EXPECT().Begin(gomock.Any(), int64(1), "key", fingerprint).Return(nil, domain.ErrIdempotencyInProgress)
+			},
+			expectedStatusCode:   409,
+			expectedResponseBody: `{"type":"urn:contact-list:problem:idempotency_in_progress","title":"Conflict","status":409,"detail":"a request with the same idempotency key is in progress","instance":"/contacts","code":"idempotency_in_progress"}`,
+		},
+		{
+			name:          "Server error",
+			key:           "key",
+			handlerStatus: 500,
+			mockBehavior: func(s *mock_rest.MockIdempotency) {
+				s.EXPECT().Begin(gomock.Any(), int64(1), "key", fingerprint).Return(nil, nil)
+				s.EXPECT().Release(gomock.Any(), int64(1), "key").Return(nil)
+			},
+			expectedStatusCode:   500,
+			expectedResponseBody: `{"id":1}`,
+			expectedCalls:        1,
+		},
+		{
+			name:                 "Key too long",
+			key:                  string(bytes.Repeat([]byte("k"), 256)),
+			mockBehavior:         func(s *mock_rest.MockIdempotency) {},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"type":"urn:contact-list:problem:invalid_idempotency_key","title":"Bad Request","status":400,"detail":"invalid idempotency key","instance":"/contacts","code":"invalid_idempotency_key"}`,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			idempotency := mock_rest.NewMockIdempotency(c)
+			testCase.mockBehavior(idempotency)
+
+			handler := NewHandler(&mock_rest.MockContacts{}, &mock_rest.MockAuth{}, &mock_rest.MockAPIKeys{}, &mock_rest.MockOIDC{}, &mock_rest.MockOAuth{}, &mock_rest.MockPrivacy{}, idempotency, &mock_rest.MockWebhooks{}, &mock_rest.MockCardDAV{}, &mock_rest.MockGraphQL{}, &mock_rest.MockHealth{})
+
+			calls := 0
+
+			r := gin.New()
+			r.POST("/contacts", func(c *gin.Context) {
+				c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), ctxIdentity, &domain.Identity{UserID: 1}))
+			}, handler.Idempotent(), func(c *gin.Context) {
+				calls++
+				c.Header("Location", "/contacts/1")
+				c.Header("ETag", `"1"`)
+				c.JSON(testCase.handlerStatus, gin.H{"id": 1})
+			})
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("POST", "/contacts", bytes.NewBufferString(`{"name":"John"}`))
+			if testCase.key != "" {
+				req.Header.Set(idempotencyKeyHeader, testCase.key)
+			}
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, w.Code, testCase.expectedStatusCode)
+			assert.Equal(t, w.Body.String(), testCase.expectedResponseBody)
+			assert.Equal(t, w.Header().Get(idempotencyReplayedHeader), testCase.expectedReplayed)
+			assert.Equal(t, calls, testCase.expectedCalls)
+
+			if testCase.expectedStatusCode == 201 {
+				assert.Equal(t, w.Header().Get("Location"), "/contacts/1")
+				assert.Equal(t, w.Header().Get("ETag"), `"1"`)
+			}
+		})
+	}
+}
@@ -0,0 +1,139 @@
+package rest
+
+import (
+	"net/http"
+
+	"github.com/wilfridterry/contact-list/internal/domain"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetProfile godoc
+// @Summary      Show the current user
+// @Description  get the profile of the signed in user
+// @Tags         me
+// @Produce      json
+// @Success      200  {object}  domain.User
+// @Failure      401  {object}  Problem
+// @Failure      404  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Router       /me [get]
+func (h *Handler) getProfile(c *gin.Context) {
+	identity, _ := getIdentity(c)
+
+	user, err := h.authServie.Profile(c.Request.Context(), identity.UserID)
+	if err != nil {
+		newProblem(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+}
+
+// UpdateProfile godoc
+// @Summary      Update the current user
+// @Description  change the name or the email, a new email has to be verified again
+// @Tags         me
+// @Accept       json
+// @Produce      json
+// @Param        input  body  domain.UpdateProfileInput  true  "profile fields to change"
+// @Param        Idempotency-Key  header  string  false  "retries with the same key run the request once"
+// @Success      200  {object}  domain.User
+// @Failure      401  {object}  Problem
+// @Failure      403  {object}  Problem
+// @Failure      409  {object}  Problem
+// @Failure      422  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Router       /me [patch]
+func (h *Handler) updateProfile(c *gin.Context) {
+	identity, ok := refuseDelegated(c)
+	if !ok {
+		return
+	}
+
+	var inp domain.UpdateProfileInput
+	if err := c.ShouldBindJSON(&inp); err != nil {
+		newProblem(c, err)
+
+		return
+	}
+
+	user, err := h.authServie.UpdateProfile(c.Request.Context(), identity.UserID, &inp)
+	if err != nil {
+		newProblem(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+}
+
+// ChangePassword godoc
+// @Summary      Change the password
+// @Description  change the password with the current one, every other session is signed out
+// @Tags         me
+// @Accept       json
+// @Produce      json
+// @Param        input  body  domain.ChangePasswordInput  true  "current and new password"
+// @Success      200
+// @Failure      401  {object}  Problem
+// @Failure      403  {object}  Problem
+// @Failure      422  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Router       /me/password [post]
+func (h *Handler) changePassword(c *gin.Context) {
+	identity, ok := refuseDelegated(c)
+	if !ok {
+		return
+	}
+
+	var inp domain.ChangePasswordInput
+	if err := c.ShouldBindJSON(&inp); err != nil {
+		newProblem(c, err)
+
+		return
+	}
+
+	accessToken, refreshToken, err := h.authServie.ChangePassword(c.Request.Context(), identity.UserID, &inp)
+	if err != nil {
+		newProblem(c, err)
+		return
+	}
+
+	c.SetCookie("refresh-token", refreshToken, 3600, "/", "localhost", true, true)
+	c.JSON(http.StatusOK, gin.H{"token": accessToken})
+}
+
+// DeleteAccount godoc
+// @Summary      Delete the current user
+// @Description  delete the account after confirming the password, owned contacts are deleted or anonymized
+// @Tags         me
+// @Accept       json
+// @Produce      json
+// @Param        input  body  domain.DeleteAccountInput  true  "password and what to do with owned contacts"
+// @Success      204
+// @Failure      401  {object}  Problem
+// @Failure      403  {object}  Problem
+// @Failure      422  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Router       /me [delete]
+func (h *Handler) deleteAccount(c *gin.Context) {
+	identity, ok := refuseDelegated(c)
+	if !ok {
+		return
+	}
+
+	var inp domain.DeleteAccountInput
+	if err := c.ShouldBindJSON(&inp); err != nil {
+		newProblem(c, err)
+
+		return
+	}
+
+	if err := h.authServie.DeleteAccount(c.Request.Context(), identity.UserID, &inp); err != nil {
+		newProblem(c, err)
+		return
+	}
+
+	c.SetCookie("refresh-token", "", -1, "/", "localhost", true, true)
+	c.JSON(http.StatusNoContent, gin.H{})
+}
@@ -0,0 +1,91 @@
+package rest
+
+import (
+	"bytes"
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/magiconair/properties/assert"
+	"github.com/wilfridterry/contact-list/internal/domain"
+	mock_rest "github.com/wilfridterry/contact-list/internal/transport/rest/mocks"
+	"go.uber.org/mock/gomock"
+)
+
+func TestHandler_changePassword(t *testing.T) {
+	type mockBehavior func(s *mock_rest.MockAuth, inp domain.ChangePasswordInput)
+
+	testTable := []struct {
+		name                 string
+		header               string
+		identity             *domain.Identity
+		inputBody            string
+		inputPassword        domain.ChangePasswordInput
+		mockBehavior         mockBehavior
+		expectedStatusCode   int
+		expectedResponseBody string
+	}{
+		{
+			name:      "OK",
+			identity:  &domain.Identity{UserID: 1},
+			inputBody: `{"current_password":"secret1","new_password":"secret2"}`,
+			inputPassword: domain.ChangePasswordInput{
+				CurrentPassword: "secret1",
+				NewPassword:     "secret2",
+			},
+			mockBehavior: func(s *mock_rest.MockAuth, inp domain.ChangePasswordInput) {
+				s.EXPECT().ChangePassword(gomock.Any(), int64(1), &inp).Return("access", "refresh", nil)
+			},
+			expectedStatusCode:   200,
+			expectedResponseBody: `{"token":"access"}`,
+		},
+		{
+			name:      "Wrong current password",
+			identity:  &domain.Identity{UserID: 1},
+			inputBody: `{"current_password":"secret1","new_password":"secret2"}`,
+			inputPassword: domain.ChangePasswordInput{
+				CurrentPassword: "secret1",
+				NewPassword:     "secret2",
+			},
+			mockBehavior: func(s *mock_rest.MockAuth, inp domain.ChangePasswordInput) {
+				s.EXPECT().ChangePassword(gomock.Any(), int64(1), &inp).Return("", "", domain.ErrInvalidPassword)
+			},
+			expectedStatusCode:   403,
+			expectedResponseBody: `{"type":"urn:contact-list:problem:invalid_password","title":"Forbidden","status":403,"detail":"current password is incorrect","instance":"/me/password","code":"invalid_password"}`,
+		},
+		{
+			name:                 "API key",
+			identity:             &domain.Identity{UserID: 1, APIKeyID: 2},
+			inputBody:            `{"current_password":"secret1","new_password":"secret2"}`,
+			mockBehavior:         func(s *mock_rest.MockAuth, inp domain.ChangePasswordInput) {},
+			expectedStatusCode:   403,
+			expectedResponseBody: `{"type":"urn:contact-list:problem:delegated_credential","title":"Forbidden","status":403,"detail":"not allowed with an api key, oauth or impersonation token","instance":"/me/password","code":"delegated_credential"}`,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			auth := mock_rest.NewMockAuth(c)
+			testCase.mockBehavior(auth, testCase.inputPassword)
+
+			handler := NewHandler(&mock_rest.MockContacts{}, auth, &mock_rest.MockAPIKeys{}, &mock_rest.MockOIDC{}, &mock_rest.MockOAuth{}, &mock_rest.MockPrivacy{}, &mock_rest.MockIdempotency{}, &mock_rest.MockWebhooks{}, &mock_rest.MockCardDAV{}, &mock_rest.MockGraphQL{}, &mock_rest.MockHealth{})
+
+			r := gin.New()
+			r.POST("/me/password", func(c *gin.Context) {
+				c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), ctxIdentity, testCase.identity))
+			}, handler.changePassword)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("POST", "/me/password", bytes.NewBufferString(testCase.inputBody))
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, w.Code, testCase.expectedStatusCode)
+			assert.Equal(t, w.Body.String(), testCase.expectedResponseBody)
+		})
+	}
+}
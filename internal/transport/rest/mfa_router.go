@@ -0,0 +1,159 @@
+package rest
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/wilfridterry/contact-list/internal/domain"
+
+	"github.com/gin-gonic/gin"
+)
+
+type MFAChallenge struct {
+	MFARequired bool      `json:"mfa_required"`
+	Challenge   string    `json:"challenge"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+type RecoveryCodes struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// VerifyMFA godoc
+// @Summary      complete sign in with the second factor
+// @Description  exchange the mfa challenge and a TOTP or recovery code for tokens
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param input body domain.MFAVerifyInput true "mfa challenge and code"
+// @Success      200
+// @Failure      400  {object}  Problem
+// @Failure      401  {object}  Problem
+// @Failure      429  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Router       /auth/mfa/verify [post]
+func (h *Handler) verifyMFA(c *gin.Context) {
+	var inp domain.MFAVerifyInput
+	if err := c.ShouldBindJSON(&inp); err != nil {
+		newProblem(c, err)
+
+		return
+	}
+
+	inp.IP = c.ClientIP()
+
+	accessToken, refreshToken, err := h.authServie.VerifyMFA(c.Request.Context(), &inp)
+	if err != nil {
+		var lockErr *domain.LoginLockedError
+		if errors.As(err, &lockErr) {
+			tooManyAttempts(c, lockErr)
+			return
+		}
+
+		newProblem(c, err)
+		return
+	}
+
+	c.SetCookie("refresh-token", refreshToken, 3600, "/", "localhost", true, true)
+	c.JSON(http.StatusOK, gin.H{"token": accessToken})
+}
+
+// EnrollMFA godoc
+// @Summary      start TOTP enrollment
+// @Description  generate a TOTP secret and the otpauth provisioning URI for QR codes
+// @Tags         mfa
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  domain.MFAEnrollment
+// @Failure      401  {object}  Problem
+// @Failure      403  {object}  Problem
+// @Failure      409  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Router       /mfa/enroll [post]
+func (h *Handler) enrollMFA(c *gin.Context) {
+	identity, ok := refuseDelegated(c)
+	if !ok {
+		return
+	}
+
+	enrollment, err := h.authServie.EnrollMFA(c.Request.Context(), identity.UserID)
+	if err != nil {
+		newProblem(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, enrollment)
+}
+
+// ConfirmMFA godoc
+// @Summary      confirm TOTP enrollment
+// @Description  enable the second factor with a valid code and get recovery codes
+// @Tags         mfa
+// @Accept       json
+// @Produce      json
+// @Param input body domain.MFACodeInput true "TOTP code"
+// @Success      200  {object}  RecoveryCodes
+// @Failure      400  {object}  Problem
+// @Failure      401  {object}  Problem
+// @Failure      403  {object}  Problem
+// @Failure      404  {object}  Problem
+// @Failure      409  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Router       /mfa/confirm [post]
+func (h *Handler) confirmMFA(c *gin.Context) {
+	identity, ok := refuseDelegated(c)
+	if !ok {
+		return
+	}
+
+	var inp domain.MFACodeInput
+	if err := c.ShouldBindJSON(&inp); err != nil {
+		newProblem(c, err)
+
+		return
+	}
+
+	codes, err := h.authServie.ConfirmMFA(c.Request.Context(), identity.UserID, inp.Code)
+	if err != nil {
+		newProblem(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, RecoveryCodes{RecoveryCodes: codes})
+}
+
+// DisableMFA godoc
+// @Summary      disable TOTP
+// @Description  disable the second factor with a valid TOTP or recovery code
+// @Tags         mfa
+// @Accept       json
+// @Produce      json
+// @Param input body domain.MFACodeInput true "TOTP or recovery code"
+// @Success      204
+// @Failure      400  {object}  Problem
+// @Failure      401  {object}  Problem
+// @Failure      403  {object}  Problem
+// @Failure      404  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Router       /mfa [delete]
+func (h *Handler) disableMFA(c *gin.Context) {
+	identity, ok := refuseDelegated(c)
+	if !ok {
+		return
+	}
+
+	var inp domain.MFACodeInput
+	if err := c.ShouldBindJSON(&inp); err != nil {
+		newProblem(c, err)
+
+		return
+	}
+
+	if err := h.authServie.DisableMFA(c.Request.Context(), identity.UserID, inp.Code); err != nil {
+		newProblem(c, err)
+		return
+	}
+
+	c.JSON(http.StatusNoContent, gin.H{})
+}
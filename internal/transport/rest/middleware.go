@@ -2,47 +2,291 @@ package rest
 
 import (
 	"context"
-	"errors"
+	"crypto/rand"
+	"encoding/hex"
 	"net/http"
 	"strings"
+	"time"
 
 	log "github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+
+	"github.com/wilfridterry/contact-list/internal/apperror"
+	"github.com/wilfridterry/contact-list/internal/domain"
+	"github.com/wilfridterry/contact-list/internal/metrics"
+	"github.com/wilfridterry/contact-list/pkg/logger"
 
 	"github.com/gin-gonic/gin"
-	"github.com/swaggo/swag/example/celler/httputil"
+)
+
+var errInvalidAuthHeader = apperror.New(http.StatusUnauthorized, apperror.CodeUnauthenticated, "invalid auth header")
+
+const basicChallenge = `Basic realm="contact-list", charset="UTF-8"`
+
+var errDelegated = apperror.New(http.StatusForbidden, "delegated_credential", "not allowed with an api key, oauth or impersonation token")
+
+const (
+	requestIDHeader    = "X-Request-ID"
+	maxRequestIDLength = 128
 )
 
 type CtxValue int
 const (
 	ctxUserId CtxValue = iota
+	ctxIdentity
 )
 
+// PermissionProblem is returned with 403 when the caller lacks a permission
+// required by the route.
+type PermissionProblem struct {
+	Problem
+	Required []domain.Permission `json:"required"`
+}
+
+// RequestID tags the request with the X-Request-ID sent by the caller, or a
+// new one, echoes it back and adds it to the entries logged for the request.
+func RequestID() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		id := ctx.GetHeader(requestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+
+		ctx.Header(requestIDHeader, id)
+		ctx.Request = ctx.Request.WithContext(logger.WithFields(ctx.Request.Context(), log.Fields{"request_id": id}))
+		ctx.Next()
+	}
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+
+	for _, r := range id {
+		if r < '!' || r > '~' {
+			return false
+		}
+	}
+
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+
+	return hex.EncodeToString(b)
+}
+
+// Logger logs every request once it is served. The query string is left out
+// since it may carry tokens, such as OAuth codes.
 func Logger() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
+		start := time.Now()
 		ctx.Next()
 
-		log.Printf("[%s] %s\n", ctx.Request.Method, ctx.Request.URL)
+		status := ctx.Writer.Status()
+		entry := logger.FromContext(ctx.Request.Context()).WithFields(log.Fields{
+			"method":     ctx.Request.Method,
+			"path":       ctx.Request.URL.Path,
+			"route":      ctx.FullPath(),
+			"status":     status,
+			"latency_ms": time.Since(start).Milliseconds(),
+			"client_ip":  ctx.ClientIP(),
+		})
+
+		switch {
+		case status >= http.StatusInternalServerError:
+			entry.Error("request served")
+		case status >= http.StatusBadRequest:
+			entry.Warn("request served")
+		default:
+			entry.Info("request served")
+		}
 	}
 }
 
+// Metrics records the latency and status of every request, labeled with the
+// route template rather than the path so IDs do not make a series each.
+func Metrics() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		start := time.Now()
+		ctx.Next()
+
+		metrics.ObserveRequest(ctx.Request.Method, ctx.FullPath(), ctx.Writer.Status(), time.Since(start))
+	}
+}
+
+// Tracing starts the span of the request, continuing the trace of the caller
+// when it sent a traceparent header.
+func Tracing() gin.HandlerFunc {
+	tracer := otel.Tracer("github.com/wilfridterry/contact-list/internal/transport/rest")
+
+	return func(ctx *gin.Context) {
+		parent := otel.GetTextMapPropagator().Extract(ctx.Request.Context(), propagation.HeaderCarrier(ctx.Request.Header))
+
+		name := ctx.Request.Method
+		attributes := []attribute.KeyValue{
+			semconv.HTTPRequestMethodKey.String(ctx.Request.Method),
+			semconv.URLPath(ctx.Request.URL.Path),
+		}
+
+		if route := ctx.FullPath(); route != "" {
+			name += " " + route
+			attributes = append(attributes, semconv.HTTPRoute(route))
+		}
+
+		spanCtx, span := tracer.Start(parent, name, trace.WithSpanKind(trace.SpanKindServer), trace.WithAttributes(attributes...))
+		defer span.End()
+
+		ctx.Request = ctx.Request.WithContext(spanCtx)
+		ctx.Next()
+
+		status := ctx.Writer.Status()
+		span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+	}
+}
+
+// AuthJWT authenticates the request either with a bearer JWT, a bearer OAuth
+// access token or a personal API key sent as "Authorization: ApiKey <key>".
 func (h *Handler) AuthJWT() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
-		token, err := getBearerToken(ctx)
+		var (
+			identity *domain.Identity
+			err      error
+		)
+
+		if key, ok := getApiKey(ctx); ok {
+			identity, err = h.apiKeyService.Authenticate(ctx.Request.Context(), key)
+		} else {
+			var token string
+			token, err = getBearerToken(ctx)
+			if err == nil && strings.HasPrefix(token, domain.OAuthAccessTokenPrefix) {
+				identity, err = h.oauthService.Authenticate(ctx.Request.Context(), token)
+			} else if err == nil {
+				identity, err = h.authServie.ParseJWTToken(ctx.Request.Context(), token)
+			}
+		}
+
 		if err != nil {
-			httputil.NewError(ctx, http.StatusUnauthorized, err)
-			ctx.Abort()
+			newProblem(ctx, unauthenticated(err))
 			return
 		}
 
-		userId, err := h.authServie.ParseJWTToken(ctx.Request.Context(), token)
+		setIdentity(ctx, identity)
+		ctx.Next()
+	}
+}
+
+// AuthStream authenticates the contact streams. Browsers can not set headers
+// on EventSource and WebSocket requests, so they pass a stream token in the
+// access_token query parameter, other clients authenticate as with AuthJWT.
+func (h *Handler) AuthStream() gin.HandlerFunc {
+	authJWT := h.AuthJWT()
+
+	return func(ctx *gin.Context) {
+		token := ctx.Query(streamTokenParam)
+		if token == "" {
+			authJWT(ctx)
+			return
+		}
+
+		identity, err := h.authServie.ParseStreamToken(ctx.Request.Context(), token, ctx.GetHeader("Origin"))
+		if err != nil {
+			newProblem(ctx, unauthenticated(err))
+			return
+		}
+
+		setIdentity(ctx, identity)
+		ctx.Next()
+	}
+}
+
+// AuthGraphQL authenticates GraphQL requests as AuthJWT does. Browsers can not
+// set headers on WebSocket requests either, so subscriptions may be opened
+// with a stream token as the contact streams are, which only reads contacts.
+func (h *Handler) AuthGraphQL() gin.HandlerFunc {
+	authJWT := h.AuthJWT()
+	authStream := h.AuthStream()
+
+	return func(ctx *gin.Context) {
+		if ctx.IsWebsocket() {
+			authStream(ctx)
+			return
+		}
+
+		authJWT(ctx)
+	}
+}
+
+// AuthBasic authenticates CardDAV clients, which only speak HTTP Basic auth.
+// The password is a personal API key used as an app password, the user name
+// is up to the client since the key tells the user.
+func (h *Handler) AuthBasic() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		_, password, ok := ctx.Request.BasicAuth()
+		if !ok {
+			ctx.Header("WWW-Authenticate", basicChallenge)
+			newProblem(ctx, errInvalidAuthHeader)
+			return
+		}
+
+		identity, err := h.apiKeyService.Authenticate(ctx.Request.Context(), password)
 		if err != nil {
-			httputil.NewError(ctx, http.StatusUnauthorized, err)
-			ctx.Abort()
+			ctx.Header("WWW-Authenticate", basicChallenge)
+			newProblem(ctx, unauthenticated(err))
+			return
+		}
+
+		setIdentity(ctx, identity)
+		ctx.Next()
+	}
+}
+
+func setIdentity(ctx *gin.Context, identity *domain.Identity) {
+	rCtx := context.WithValue(ctx.Request.Context(), ctxUserId, identity.UserID)
+	rCtx = context.WithValue(rCtx, ctxIdentity, identity)
+	if identity.ImpersonatorID != 0 {
+		rCtx = domain.WithImpersonator(rCtx, identity.ImpersonatorID)
+		rCtx = logger.WithFields(rCtx, log.Fields{"impersonator_id": identity.ImpersonatorID})
+	}
+	rCtx = logger.WithFields(rCtx, log.Fields{"user_id": identity.UserID})
+	ctx.Request = ctx.Request.WithContext(rCtx)
+}
+
+// RequirePermissions must be used after AuthJWT. It aborts with 403 unless the
+// caller has every listed permission.
+func (h *Handler) RequirePermissions(permissions ...domain.Permission) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		identity, ok := getIdentity(ctx)
+		if !ok {
+			newProblem(ctx, apperror.New(http.StatusUnauthorized, apperror.CodeUnauthenticated, "unauthenticated"))
 			return
 		}
 
-		rCtx := context.WithValue(ctx.Request.Context(), ctxUserId, userId) 
-		ctx.Request = ctx.Request.WithContext(rCtx)
+		for _, permission := range permissions {
+			if !identity.HasPermission(permission) {
+				appErr := apperror.New(http.StatusForbidden, "missing_permission", "permission denied: "+string(permission))
+
+				ctx.Header("Content-Type", problemContentType)
+				ctx.AbortWithStatusJSON(http.StatusForbidden, PermissionProblem{
+					Problem:  newProblemBody(ctx, appErr),
+					Required: permissions,
+				})
+				return
+			}
+		}
+
 		ctx.Next()
 	}
 }
@@ -51,14 +295,58 @@ func getBearerToken(ctx *gin.Context) (string, error) {
 	header := ctx.GetHeader("Authorization")
 
 	if header == "" {
-		return "", errors.New("invalid auth header")
+		return "", errInvalidAuthHeader
 	}
 
 	headerSectors := strings.Split(header, " ")
 
-	if len(headerSectors) != 2 && headerSectors[0] == "Bearer" {
-		return "", errors.New("invalid auth header")
+	if len(headerSectors) != 2 || headerSectors[0] != "Bearer" {
+		return "", errInvalidAuthHeader
 	}
 
 	return headerSectors[1], nil
+}
+
+func getApiKey(ctx *gin.Context) (string, bool) {
+	headerSectors := strings.Split(ctx.GetHeader("Authorization"), " ")
+
+	if len(headerSectors) != 2 || headerSectors[0] != "ApiKey" {
+		return "", false
+	}
+
+	return headerSectors[1], true
+}
+
+// refuseDelegated returns the identity of the caller unless it uses an API key,
+// an OAuth token or an impersonation token, which must not manage the account
+// itself.
+func refuseDelegated(c *gin.Context) (*domain.Identity, bool) {
+	identity, _ := getIdentity(c)
+	if identity.Delegated() {
+		newProblem(c, errDelegated)
+		return nil, false
+	}
+
+	return identity, true
+}
+
+// unauthenticated reports a rejected credential with 401, e.g. an unknown API
+// key is not a missing resource for the caller. Server errors stay 500.
+func unauthenticated(err error) *apperror.Error {
+	appErr := apperror.From(err)
+	if appErr.Status == http.StatusForbidden || appErr.Status >= http.StatusInternalServerError {
+		return appErr
+	}
+
+	if appErr.Status != http.StatusUnauthorized {
+		return apperror.Wrap(err, http.StatusUnauthorized, apperror.CodeUnauthenticated)
+	}
+
+	return appErr
+}
+
+func getIdentity(ctx *gin.Context) (*domain.Identity, bool) {
+	identity, ok := ctx.Request.Context().Value(ctxIdentity).(*domain.Identity)
+
+	return identity, ok
 }
\ No newline at end of file
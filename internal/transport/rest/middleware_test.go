@@ -0,0 +1,388 @@
+package rest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/magiconair/properties/assert"
+	log "github.com/sirupsen/logrus"
+	"github.com/wilfridterry/contact-list/internal/domain"
+	mock_rest "github.com/wilfridterry/contact-list/internal/transport/rest/mocks"
+	"github.com/wilfridterry/contact-list/pkg/logger"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/mock/gomock"
+)
+
+func TestHandler_RequirePermissions(t *testing.T) {
+	testTable := []struct {
+		name               string
+		identity           *domain.Identity
+		expectedStatusCode int
+		expectedReason     string
+	}{
+		{
+			name: "OK",
+			identity: &domain.Identity{
+				UserID:      1,
+				Role:        domain.RoleAdmin,
+				Permissions: domain.RoleAdmin.Permissions(),
+			},
+			expectedStatusCode: 200,
+		},
+		{
+			name: "Missing permission",
+			identity: &domain.Identity{
+				UserID:      1,
+				Role:        domain.RoleUser,
+				Permissions: domain.RoleUser.Permissions(),
+			},
+			expectedStatusCode: 403,
+			expectedReason:     "missing_permission",
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			auth := mock_rest.NewMockAuth(c)
+			auth.EXPECT().ParseJWTToken(context.Background(), "token").Return(testCase.identity, nil)
+
+			handler := NewHandler(&mock_rest.MockContacts{}, auth, &mock_rest.MockAPIKeys{}, &mock_rest.MockOIDC{}, &mock_rest.MockOAuth{}, &mock_rest.MockPrivacy{}, &mock_rest.MockIdempotency{}, &mock_rest.MockWebhooks{}, &mock_rest.MockCardDAV{}, &mock_rest.MockGraphQL{}, &mock_rest.MockHealth{})
+
+			r := gin.New()
+			r.GET("/admin", handler.AuthJWT(), handler.RequirePermissions(domain.PermissionUsersAdmin), func(c *gin.Context) {
+				c.Status(200)
+			})
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", "/admin", nil)
+			req.Header.Set("Authorization", "Bearer token")
+
+			r.ServeHTTP(w, req)
+
+			var actual PermissionProblem
+			json.Unmarshal(w.Body.Bytes(), &actual)
+
+			assert.Equal(t, w.Code, testCase.expectedStatusCode)
+			assert.Equal(t, actual.Code, testCase.expectedReason)
+		})
+	}
+}
+
+func TestHandler_AuthJWT(t *testing.T) {
+	type mockBehavior func(auth *mock_rest.MockAuth, apiKeys *mock_rest.MockAPIKeys, oauth *mock_rest.MockOAuth)
+
+	testTable := []struct {
+		name               string
+		header             string
+		mockBehavior       mockBehavior
+		expectedStatusCode int
+	}{
+		{
+			name:   "Bearer token",
+			header: "Bearer token",
+			mockBehavior: func(auth *mock_rest.MockAuth, apiKeys *mock_rest.MockAPIKeys, oauth *mock_rest.MockOAuth) {
+				auth.EXPECT().ParseJWTToken(context.Background(), "token").Return(&domain.Identity{UserID: 1}, nil)
+			},
+			expectedStatusCode: 200,
+		},
+		{
+			name:   "API key",
+			header: "ApiKey ck_prefix_secret",
+			mockBehavior: func(auth *mock_rest.MockAuth, apiKeys *mock_rest.MockAPIKeys, oauth *mock_rest.MockOAuth) {
+				apiKeys.EXPECT().Authenticate(context.Background(), "ck_prefix_secret").Return(&domain.Identity{UserID: 1, APIKeyID: 2}, nil)
+			},
+			expectedStatusCode: 200,
+		},
+		{
+			name:   "Revoked API key",
+			header: "ApiKey ck_prefix_secret",
+			mockBehavior: func(auth *mock_rest.MockAuth, apiKeys *mock_rest.MockAPIKeys, oauth *mock_rest.MockOAuth) {
+				apiKeys.EXPECT().Authenticate(context.Background(), "ck_prefix_secret").Return(nil, domain.ErrAPIKeyRevoked)
+			},
+			expectedStatusCode: 401,
+		},
+		{
+			name:   "OAuth access token",
+			header: "Bearer oat_secret",
+			mockBehavior: func(auth *mock_rest.MockAuth, apiKeys *mock_rest.MockAPIKeys, oauth *mock_rest.MockOAuth) {
+				oauth.EXPECT().Authenticate(context.Background(), "oat_secret").Return(&domain.Identity{UserID: 1, OAuthClientID: 3}, nil)
+			},
+			expectedStatusCode: 200,
+		},
+		{
+			name:   "Revoked OAuth access token",
+			header: "Bearer oat_secret",
+			mockBehavior: func(auth *mock_rest.MockAuth, apiKeys *mock_rest.MockAPIKeys, oauth *mock_rest.MockOAuth) {
+				oauth.EXPECT().Authenticate(context.Background(), "oat_secret").Return(nil, domain.ErrInvalidOAuthToken)
+			},
+			expectedStatusCode: 401,
+		},
+		{
+			name:               "Malformed header",
+			header:             "token",
+			mockBehavior:       func(auth *mock_rest.MockAuth, apiKeys *mock_rest.MockAPIKeys, oauth *mock_rest.MockOAuth) {},
+			expectedStatusCode: 401,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			auth := mock_rest.NewMockAuth(c)
+			apiKeys := mock_rest.NewMockAPIKeys(c)
+			oauth := mock_rest.NewMockOAuth(c)
+			testCase.mockBehavior(auth, apiKeys, oauth)
+
+			handler := NewHandler(&mock_rest.MockContacts{}, auth, apiKeys, &mock_rest.MockOIDC{}, oauth, &mock_rest.MockPrivacy{}, &mock_rest.MockIdempotency{}, &mock_rest.MockWebhooks{}, &mock_rest.MockCardDAV{}, &mock_rest.MockGraphQL{}, &mock_rest.MockHealth{})
+
+			r := gin.New()
+			r.GET("/protected", handler.AuthJWT(), func(c *gin.Context) {
+				c.Status(200)
+			})
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", "/protected", nil)
+			req.Header.Set("Authorization", testCase.header)
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, w.Code, testCase.expectedStatusCode)
+		})
+	}
+}
+
+func TestTracing(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+
+	provider, propagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	defer func() {
+		otel.SetTracerProvider(provider)
+		otel.SetTextMapPropagator(propagator)
+	}()
+
+	var handlerSpan trace.SpanContext
+
+	r := gin.New()
+	r.Use(Tracing())
+	r.GET("/contacts/:id", func(c *gin.Context) {
+		handlerSpan = trace.SpanContextFromContext(c.Request.Context())
+		c.Status(500)
+	})
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/contacts/1", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+
+	r.ServeHTTP(w, req)
+
+	spans := recorder.Ended()
+	assert.Equal(t, len(spans), 1)
+
+	span := spans[0]
+	assert.Equal(t, span.Name(), "GET /contacts/:id")
+	assert.Equal(t, span.SpanKind(), trace.SpanKindServer)
+	assert.Equal(t, span.Parent().SpanID().String(), "00f067aa0ba902b7")
+	assert.Equal(t, span.SpanContext().TraceID().String(), "4bf92f3577b34da6a3ce929d0e0e4736")
+	assert.Equal(t, span.Status().Code, codes.Error)
+	assert.Equal(t, handlerSpan.SpanID(), span.SpanContext().SpanID())
+
+	attributes := make(map[string]string)
+	for _, a := range span.Attributes() {
+		attributes[string(a.Key)] = a.Value.Emit()
+	}
+	assert.Equal(t, attributes, map[string]string{
+		"http.request.method":       "GET",
+		"url.path":                  "/contacts/1",
+		"http.route":                "/contacts/:id",
+		"http.response.status_code": "500",
+	})
+}
+
+func TestRequestID(t *testing.T) {
+	testTable := []struct {
+		name       string
+		header     string
+		expectSame bool
+	}{
+		{
+			name:       "Incoming",
+			header:     "req-123",
+			expectSame: true,
+		},
+		{
+			name:       "Missing",
+			header:     "",
+			expectSame: false,
+		},
+		{
+			name:       "Invalid",
+			header:     "two words",
+			expectSame: false,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			var logged any
+
+			r := gin.New()
+			r.Use(RequestID())
+			r.GET("/", func(c *gin.Context) {
+				logged = logger.FromContext(c.Request.Context()).Data["request_id"]
+			})
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", "/", nil)
+			if testCase.header != "" {
+				req.Header.Set("X-Request-ID", testCase.header)
+			}
+
+			r.ServeHTTP(w, req)
+
+			id := w.Header().Get("X-Request-ID")
+			assert.Equal(t, id == testCase.header, testCase.expectSame)
+			assert.Equal(t, id != "", true)
+			assert.Equal(t, logged, any(id))
+		})
+	}
+}
+
+func TestLogger(t *testing.T) {
+	var buf bytes.Buffer
+
+	out, formatter := log.StandardLogger().Out, log.StandardLogger().Formatter
+	log.SetOutput(&buf)
+	log.SetFormatter(&log.JSONFormatter{})
+	defer func() {
+		log.SetOutput(out)
+		log.SetFormatter(formatter)
+	}()
+
+	r := gin.New()
+	r.Use(RequestID(), Logger())
+	r.GET("/oauth/callback", func(c *gin.Context) {
+		setIdentity(c, &domain.Identity{UserID: 7})
+		c.Status(404)
+	})
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/oauth/callback?code=secret", nil)
+	req.Header.Set("X-Request-ID", "req-1")
+
+	r.ServeHTTP(w, req)
+
+	var entry map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, entry["level"], any("warning"))
+	assert.Equal(t, entry["request_id"], any("req-1"))
+	assert.Equal(t, entry["path"], any("/oauth/callback"))
+	assert.Equal(t, entry["route"], any("/oauth/callback"))
+	assert.Equal(t, entry["status"], any(float64(404)))
+	assert.Equal(t, entry["user_id"], any(float64(7)))
+}
+
+// TestHandler_refuseDelegated covers the account routes an OAuth client must
+// not reach with a token delegated by the user.
+func TestHandler_refuseDelegated(t *testing.T) {
+	oauthIdentity := &domain.Identity{UserID: 1, Role: domain.RoleUser, OAuthClientID: 3}
+
+	testTable := []struct {
+		name    string
+		method  string
+		route   string
+		path    string
+		body    string
+		handler func(h *Handler) gin.HandlerFunc
+	}{
+		{
+			name:    "Enroll MFA",
+			method:  "POST",
+			route:   "/mfa/enroll",
+			path:    "/mfa/enroll",
+			handler: func(h *Handler) gin.HandlerFunc { return h.enrollMFA },
+		},
+		{
+			name:    "Confirm MFA",
+			method:  "POST",
+			route:   "/mfa/confirm",
+			path:    "/mfa/confirm",
+			body:    `{"code":"123456"}`,
+			handler: func(h *Handler) gin.HandlerFunc { return h.confirmMFA },
+		},
+		{
+			name:    "Disable MFA",
+			method:  "DELETE",
+			route:   "/mfa",
+			path:    "/mfa",
+			body:    `{"code":"123456"}`,
+			handler: func(h *Handler) gin.HandlerFunc { return h.disableMFA },
+		},
+		{
+			name:    "List API keys",
+			method:  "GET",
+			route:   "/api-keys",
+			path:    "/api-keys",
+			handler: func(h *Handler) gin.HandlerFunc { return h.getAPIKeys },
+		},
+		{
+			name:    "Revoke API key",
+			method:  "DELETE",
+			route:   "/api-keys/:id",
+			path:    "/api-keys/2",
+			handler: func(h *Handler) gin.HandlerFunc { return h.revokeAPIKey },
+		},
+		{
+			name:    "Create stream token",
+			method:  "POST",
+			route:   "/contacts/stream-token",
+			path:    "/contacts/stream-token",
+			handler: func(h *Handler) gin.HandlerFunc { return h.createStreamToken },
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			// No call is expected on either service.
+			auth := mock_rest.NewMockAuth(c)
+			apiKeys := mock_rest.NewMockAPIKeys(c)
+
+			handler := NewHandler(&mock_rest.MockContacts{}, auth, apiKeys, &mock_rest.MockOIDC{}, &mock_rest.MockOAuth{}, &mock_rest.MockPrivacy{}, &mock_rest.MockIdempotency{}, &mock_rest.MockWebhooks{}, &mock_rest.MockCardDAV{}, &mock_rest.MockGraphQL{}, &mock_rest.MockHealth{})
+
+			r := gin.New()
+			r.Handle(testCase.method, testCase.route, func(c *gin.Context) {
+				c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), ctxIdentity, oauthIdentity))
+			}, testCase.handler(handler))
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(testCase.method, testCase.path, strings.NewReader(testCase.body))
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, w.Code, 403)
+			assert.Equal(t, w.Body.String(), `{"type":"urn:contact-list:problem:delegated_credential","title":"Forbidden","status":403,"detail":"not allowed with an api key, oauth or impersonation token","instance":"`+testCase.path+`","code":"delegated_credential"}`)
+		})
+	}
+}
@@ -11,6 +11,8 @@ package mock_rest
 
 import (
 	context "context"
+	io "io"
+	http "net/http"
 	reflect "reflect"
 
 	domain "github.com/wilfridterry/contact-list/internal/domain"
@@ -55,12 +57,43 @@ func (mr *MockContactsMockRecorder) All(arg0 any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "All", reflect.TypeOf((*MockContacts)(nil).All), arg0)
 }
 
+// Batch mocks base method.
+func (m *MockContacts) Batch(arg0 context.Context, arg1 int64, arg2 *domain.BatchInput) ([]domain.BatchResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Batch", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]domain.BatchResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Batch indicates an expected call of Batch.
+func (mr *MockContactsMockRecorder) Batch(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Batch", reflect.TypeOf((*MockContacts)(nil).Batch), arg0, arg1, arg2)
+}
+
+// Changes mocks base method.
+func (m *MockContacts) Changes(arg0 context.Context, arg1 *domain.ContactChangesFilter) (*domain.ContactChanges, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Changes", arg0, arg1)
+	ret0, _ := ret[0].(*domain.ContactChanges)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Changes indicates an expected call of Changes.
+func (mr *MockContactsMockRecorder) Changes(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Changes", reflect.TypeOf((*MockContacts)(nil).Changes), arg0, arg1)
+}
+
 // Create mocks base method.
-func (m *MockContacts) Create(arg0 context.Context, arg1 *domain.SaveInputContact) error {
+func (m *MockContacts) Create(arg0 context.Context, arg1 *domain.SaveInputContact) (int64, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "Create", arg0, arg1)
-	ret0, _ := ret[0].(error)
-	return ret0
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
 // Create indicates an expected call of Create.
@@ -98,6 +131,20 @@ func (mr *MockContactsMockRecorder) GetOne(arg0, arg1 any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOne", reflect.TypeOf((*MockContacts)(nil).GetOne), arg0, arg1)
 }
 
+// Subscribe mocks base method.
+func (m *MockContacts) Subscribe(arg0 int64) *domain.ContactSubscription {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Subscribe", arg0)
+	ret0, _ := ret[0].(*domain.ContactSubscription)
+	return ret0
+}
+
+// Subscribe indicates an expected call of Subscribe.
+func (mr *MockContactsMockRecorder) Subscribe(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Subscribe", reflect.TypeOf((*MockContacts)(nil).Subscribe), arg0)
+}
+
 // Update mocks base method.
 func (m *MockContacts) Update(arg0 context.Context, arg1 int64, arg2 *domain.SaveInputContact) error {
 	m.ctrl.T.Helper()
@@ -135,11 +182,200 @@ func (m *MockAuth) EXPECT() *MockAuthMockRecorder {
 	return m.recorder
 }
 
+// AssignRole mocks base method.
+func (m *MockAuth) AssignRole(arg0 context.Context, arg1 int64, arg2 domain.Role) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AssignRole", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AssignRole indicates an expected call of AssignRole.
+func (mr *MockAuthMockRecorder) AssignRole(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AssignRole", reflect.TypeOf((*MockAuth)(nil).AssignRole), arg0, arg1, arg2)
+}
+
+// ChangePassword mocks base method.
+func (m *MockAuth) ChangePassword(arg0 context.Context, arg1 int64, arg2 *domain.ChangePasswordInput) (string, string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ChangePassword", arg0, arg1, arg2)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(string)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// ChangePassword indicates an expected call of ChangePassword.
+func (mr *MockAuthMockRecorder) ChangePassword(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ChangePassword", reflect.TypeOf((*MockAuth)(nil).ChangePassword), arg0, arg1, arg2)
+}
+
+// ConfirmMFA mocks base method.
+func (m *MockAuth) ConfirmMFA(arg0 context.Context, arg1 int64, arg2 string) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ConfirmMFA", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ConfirmMFA indicates an expected call of ConfirmMFA.
+func (mr *MockAuthMockRecorder) ConfirmMFA(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConfirmMFA", reflect.TypeOf((*MockAuth)(nil).ConfirmMFA), arg0, arg1, arg2)
+}
+
+// DeleteAccount mocks base method.
+func (m *MockAuth) DeleteAccount(arg0 context.Context, arg1 int64, arg2 *domain.DeleteAccountInput) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteAccount", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteAccount indicates an expected call of DeleteAccount.
+func (mr *MockAuthMockRecorder) DeleteAccount(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteAccount", reflect.TypeOf((*MockAuth)(nil).DeleteAccount), arg0, arg1, arg2)
+}
+
+// DisableMFA mocks base method.
+func (m *MockAuth) DisableMFA(arg0 context.Context, arg1 int64, arg2 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DisableMFA", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DisableMFA indicates an expected call of DisableMFA.
+func (mr *MockAuthMockRecorder) DisableMFA(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DisableMFA", reflect.TypeOf((*MockAuth)(nil).DisableMFA), arg0, arg1, arg2)
+}
+
+// DisableUser mocks base method.
+func (m *MockAuth) DisableUser(arg0 context.Context, arg1, arg2 int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DisableUser", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DisableUser indicates an expected call of DisableUser.
+func (mr *MockAuthMockRecorder) DisableUser(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DisableUser", reflect.TypeOf((*MockAuth)(nil).DisableUser), arg0, arg1, arg2)
+}
+
+// EnableUser mocks base method.
+func (m *MockAuth) EnableUser(arg0 context.Context, arg1, arg2 int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EnableUser", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// EnableUser indicates an expected call of EnableUser.
+func (mr *MockAuthMockRecorder) EnableUser(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EnableUser", reflect.TypeOf((*MockAuth)(nil).EnableUser), arg0, arg1, arg2)
+}
+
+// EnrollMFA mocks base method.
+func (m *MockAuth) EnrollMFA(arg0 context.Context, arg1 int64) (*domain.MFAEnrollment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EnrollMFA", arg0, arg1)
+	ret0, _ := ret[0].(*domain.MFAEnrollment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// EnrollMFA indicates an expected call of EnrollMFA.
+func (mr *MockAuthMockRecorder) EnrollMFA(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EnrollMFA", reflect.TypeOf((*MockAuth)(nil).EnrollMFA), arg0, arg1)
+}
+
+// ForcePasswordReset mocks base method.
+func (m *MockAuth) ForcePasswordReset(arg0 context.Context, arg1, arg2 int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ForcePasswordReset", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ForcePasswordReset indicates an expected call of ForcePasswordReset.
+func (mr *MockAuthMockRecorder) ForcePasswordReset(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ForcePasswordReset", reflect.TypeOf((*MockAuth)(nil).ForcePasswordReset), arg0, arg1, arg2)
+}
+
+// ForgotPassword mocks base method.
+func (m *MockAuth) ForgotPassword(arg0 context.Context, arg1 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ForgotPassword", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ForgotPassword indicates an expected call of ForgotPassword.
+func (mr *MockAuthMockRecorder) ForgotPassword(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ForgotPassword", reflect.TypeOf((*MockAuth)(nil).ForgotPassword), arg0, arg1)
+}
+
+// Impersonate mocks base method.
+func (m *MockAuth) Impersonate(arg0 context.Context, arg1, arg2 int64) (*domain.ImpersonationToken, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Impersonate", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*domain.ImpersonationToken)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Impersonate indicates an expected call of Impersonate.
+func (mr *MockAuthMockRecorder) Impersonate(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Impersonate", reflect.TypeOf((*MockAuth)(nil).Impersonate), arg0, arg1, arg2)
+}
+
+// IssueStreamToken mocks base method.
+func (m *MockAuth) IssueStreamToken(arg0 context.Context, arg1 int64) (*domain.StreamToken, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IssueStreamToken", arg0, arg1)
+	ret0, _ := ret[0].(*domain.StreamToken)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// IssueStreamToken indicates an expected call of IssueStreamToken.
+func (mr *MockAuthMockRecorder) IssueStreamToken(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IssueStreamToken", reflect.TypeOf((*MockAuth)(nil).IssueStreamToken), arg0, arg1)
+}
+
+// ListUsers mocks base method.
+func (m *MockAuth) ListUsers(arg0 context.Context, arg1 *domain.UserFilter) (*domain.UserPage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListUsers", arg0, arg1)
+	ret0, _ := ret[0].(*domain.UserPage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListUsers indicates an expected call of ListUsers.
+func (mr *MockAuthMockRecorder) ListUsers(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListUsers", reflect.TypeOf((*MockAuth)(nil).ListUsers), arg0, arg1)
+}
+
 // ParseJWTToken mocks base method.
-func (m *MockAuth) ParseJWTToken(arg0 context.Context, arg1 string) (int64, error) {
+func (m *MockAuth) ParseJWTToken(arg0 context.Context, arg1 string) (*domain.Identity, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "ParseJWTToken", arg0, arg1)
-	ret0, _ := ret[0].(int64)
+	ret0, _ := ret[0].(*domain.Identity)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
@@ -150,6 +386,36 @@ func (mr *MockAuthMockRecorder) ParseJWTToken(arg0, arg1 any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ParseJWTToken", reflect.TypeOf((*MockAuth)(nil).ParseJWTToken), arg0, arg1)
 }
 
+// ParseStreamToken mocks base method.
+func (m *MockAuth) ParseStreamToken(arg0 context.Context, arg1, arg2 string) (*domain.Identity, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ParseStreamToken", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*domain.Identity)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ParseStreamToken indicates an expected call of ParseStreamToken.
+func (mr *MockAuthMockRecorder) ParseStreamToken(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ParseStreamToken", reflect.TypeOf((*MockAuth)(nil).ParseStreamToken), arg0, arg1, arg2)
+}
+
+// Profile mocks base method.
+func (m *MockAuth) Profile(arg0 context.Context, arg1 int64) (*domain.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Profile", arg0, arg1)
+	ret0, _ := ret[0].(*domain.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Profile indicates an expected call of Profile.
+func (mr *MockAuthMockRecorder) Profile(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Profile", reflect.TypeOf((*MockAuth)(nil).Profile), arg0, arg1)
+}
+
 // RefreshTokens mocks base method.
 func (m *MockAuth) RefreshTokens(arg0 context.Context, arg1 string) (string, string, error) {
 	m.ctrl.T.Helper()
@@ -166,6 +432,48 @@ func (mr *MockAuthMockRecorder) RefreshTokens(arg0, arg1 any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RefreshTokens", reflect.TypeOf((*MockAuth)(nil).RefreshTokens), arg0, arg1)
 }
 
+// ResendVerification mocks base method.
+func (m *MockAuth) ResendVerification(arg0 context.Context, arg1 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ResendVerification", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ResendVerification indicates an expected call of ResendVerification.
+func (mr *MockAuthMockRecorder) ResendVerification(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResendVerification", reflect.TypeOf((*MockAuth)(nil).ResendVerification), arg0, arg1)
+}
+
+// ResetPassword mocks base method.
+func (m *MockAuth) ResetPassword(arg0 context.Context, arg1 *domain.ResetPasswordInput) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ResetPassword", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ResetPassword indicates an expected call of ResetPassword.
+func (mr *MockAuthMockRecorder) ResetPassword(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResetPassword", reflect.TypeOf((*MockAuth)(nil).ResetPassword), arg0, arg1)
+}
+
+// RevokeSessions mocks base method.
+func (m *MockAuth) RevokeSessions(arg0 context.Context, arg1, arg2 int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeSessions", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeSessions indicates an expected call of RevokeSessions.
+func (mr *MockAuthMockRecorder) RevokeSessions(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeSessions", reflect.TypeOf((*MockAuth)(nil).RevokeSessions), arg0, arg1, arg2)
+}
+
 // SignUp mocks base method.
 func (m *MockAuth) SignUp(arg0 context.Context, arg1 *domain.SignUpInput) (*domain.User, error) {
 	m.ctrl.T.Helper()
@@ -196,3 +504,828 @@ func (mr *MockAuthMockRecorder) SingIn(arg0, arg1 any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SingIn", reflect.TypeOf((*MockAuth)(nil).SingIn), arg0, arg1)
 }
+
+// Unlock mocks base method.
+func (m *MockAuth) Unlock(arg0 context.Context, arg1 int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Unlock", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Unlock indicates an expected call of Unlock.
+func (mr *MockAuthMockRecorder) Unlock(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Unlock", reflect.TypeOf((*MockAuth)(nil).Unlock), arg0, arg1)
+}
+
+// UpdateProfile mocks base method.
+func (m *MockAuth) UpdateProfile(arg0 context.Context, arg1 int64, arg2 *domain.UpdateProfileInput) (*domain.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateProfile", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*domain.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateProfile indicates an expected call of UpdateProfile.
+func (mr *MockAuthMockRecorder) UpdateProfile(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateProfile", reflect.TypeOf((*MockAuth)(nil).UpdateProfile), arg0, arg1, arg2)
+}
+
+// UserDetails mocks base method.
+func (m *MockAuth) UserDetails(arg0 context.Context, arg1 int64) (*domain.UserDetails, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UserDetails", arg0, arg1)
+	ret0, _ := ret[0].(*domain.UserDetails)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UserDetails indicates an expected call of UserDetails.
+func (mr *MockAuthMockRecorder) UserDetails(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UserDetails", reflect.TypeOf((*MockAuth)(nil).UserDetails), arg0, arg1)
+}
+
+// VerifyEmail mocks base method.
+func (m *MockAuth) VerifyEmail(arg0 context.Context, arg1 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "VerifyEmail", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// VerifyEmail indicates an expected call of VerifyEmail.
+func (mr *MockAuthMockRecorder) VerifyEmail(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "VerifyEmail", reflect.TypeOf((*MockAuth)(nil).VerifyEmail), arg0, arg1)
+}
+
+// VerifyMFA mocks base method.
+func (m *MockAuth) VerifyMFA(arg0 context.Context, arg1 *domain.MFAVerifyInput) (string, string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "VerifyMFA", arg0, arg1)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(string)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// VerifyMFA indicates an expected call of VerifyMFA.
+func (mr *MockAuthMockRecorder) VerifyMFA(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "VerifyMFA", reflect.TypeOf((*MockAuth)(nil).VerifyMFA), arg0, arg1)
+}
+
+// MockAPIKeys is a mock of APIKeys interface.
+type MockAPIKeys struct {
+	ctrl     *gomock.Controller
+	recorder *MockAPIKeysMockRecorder
+}
+
+// MockAPIKeysMockRecorder is the mock recorder for MockAPIKeys.
+type MockAPIKeysMockRecorder struct {
+	mock *MockAPIKeys
+}
+
+// NewMockAPIKeys creates a new mock instance.
+func NewMockAPIKeys(ctrl *gomock.Controller) *MockAPIKeys {
+	mock := &MockAPIKeys{ctrl: ctrl}
+	mock.recorder = &MockAPIKeysMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockAPIKeys) EXPECT() *MockAPIKeysMockRecorder {
+	return m.recorder
+}
+
+// All mocks base method.
+func (m *MockAPIKeys) All(arg0 context.Context, arg1 int64) ([]domain.APIKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "All", arg0, arg1)
+	ret0, _ := ret[0].([]domain.APIKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// All indicates an expected call of All.
+func (mr *MockAPIKeysMockRecorder) All(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "All", reflect.TypeOf((*MockAPIKeys)(nil).All), arg0, arg1)
+}
+
+// Authenticate mocks base method.
+func (m *MockAPIKeys) Authenticate(arg0 context.Context, arg1 string) (*domain.Identity, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Authenticate", arg0, arg1)
+	ret0, _ := ret[0].(*domain.Identity)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Authenticate indicates an expected call of Authenticate.
+func (mr *MockAPIKeysMockRecorder) Authenticate(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Authenticate", reflect.TypeOf((*MockAPIKeys)(nil).Authenticate), arg0, arg1)
+}
+
+// Create mocks base method.
+func (m *MockAPIKeys) Create(arg0 context.Context, arg1 int64, arg2 *domain.CreateAPIKeyInput) (*domain.APIKey, string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*domain.APIKey)
+	ret1, _ := ret[1].(string)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// Create indicates an expected call of Create.
+func (mr *MockAPIKeysMockRecorder) Create(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockAPIKeys)(nil).Create), arg0, arg1, arg2)
+}
+
+// Revoke mocks base method.
+func (m *MockAPIKeys) Revoke(arg0 context.Context, arg1, arg2 int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Revoke", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Revoke indicates an expected call of Revoke.
+func (mr *MockAPIKeysMockRecorder) Revoke(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Revoke", reflect.TypeOf((*MockAPIKeys)(nil).Revoke), arg0, arg1, arg2)
+}
+
+// MockOIDC is a mock of OIDC interface.
+type MockOIDC struct {
+	ctrl     *gomock.Controller
+	recorder *MockOIDCMockRecorder
+}
+
+// MockOIDCMockRecorder is the mock recorder for MockOIDC.
+type MockOIDCMockRecorder struct {
+	mock *MockOIDC
+}
+
+// NewMockOIDC creates a new mock instance.
+func NewMockOIDC(ctrl *gomock.Controller) *MockOIDC {
+	mock := &MockOIDC{ctrl: ctrl}
+	mock.recorder = &MockOIDCMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockOIDC) EXPECT() *MockOIDCMockRecorder {
+	return m.recorder
+}
+
+// Begin mocks base method.
+func (m *MockOIDC) Begin(arg0 context.Context, arg1 string, arg2 int64) (string, string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Begin", arg0, arg1, arg2)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(string)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// Begin indicates an expected call of Begin.
+func (mr *MockOIDCMockRecorder) Begin(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Begin", reflect.TypeOf((*MockOIDC)(nil).Begin), arg0, arg1, arg2)
+}
+
+// Complete mocks base method.
+func (m *MockOIDC) Complete(arg0 context.Context, arg1, arg2, arg3, arg4, arg5 string) (string, string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Complete", arg0, arg1, arg2, arg3, arg4, arg5)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(string)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// Complete indicates an expected call of Complete.
+func (mr *MockOIDCMockRecorder) Complete(arg0, arg1, arg2, arg3, arg4, arg5 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Complete", reflect.TypeOf((*MockOIDC)(nil).Complete), arg0, arg1, arg2, arg3, arg4, arg5)
+}
+
+// MockOAuth is a mock of OAuth interface.
+type MockOAuth struct {
+	ctrl     *gomock.Controller
+	recorder *MockOAuthMockRecorder
+}
+
+// MockOAuthMockRecorder is the mock recorder for MockOAuth.
+type MockOAuthMockRecorder struct {
+	mock *MockOAuth
+}
+
+// NewMockOAuth creates a new mock instance.
+func NewMockOAuth(ctrl *gomock.Controller) *MockOAuth {
+	mock := &MockOAuth{ctrl: ctrl}
+	mock.recorder = &MockOAuthMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockOAuth) EXPECT() *MockOAuthMockRecorder {
+	return m.recorder
+}
+
+// Authenticate mocks base method.
+func (m *MockOAuth) Authenticate(arg0 context.Context, arg1 string) (*domain.Identity, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Authenticate", arg0, arg1)
+	ret0, _ := ret[0].(*domain.Identity)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Authenticate indicates an expected call of Authenticate.
+func (mr *MockOAuthMockRecorder) Authenticate(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Authenticate", reflect.TypeOf((*MockOAuth)(nil).Authenticate), arg0, arg1)
+}
+
+// Authorize mocks base method.
+func (m *MockOAuth) Authorize(arg0 context.Context, arg1 int64, arg2 *domain.AuthorizeInput) (*domain.AuthorizePrompt, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Authorize", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*domain.AuthorizePrompt)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Authorize indicates an expected call of Authorize.
+func (mr *MockOAuthMockRecorder) Authorize(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Authorize", reflect.TypeOf((*MockOAuth)(nil).Authorize), arg0, arg1, arg2)
+}
+
+// Clients mocks base method.
+func (m *MockOAuth) Clients(arg0 context.Context, arg1 int64) ([]domain.OAuthClient, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Clients", arg0, arg1)
+	ret0, _ := ret[0].([]domain.OAuthClient)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Clients indicates an expected call of Clients.
+func (mr *MockOAuthMockRecorder) Clients(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Clients", reflect.TypeOf((*MockOAuth)(nil).Clients), arg0, arg1)
+}
+
+// Consent mocks base method.
+func (m *MockOAuth) Consent(arg0 context.Context, arg1 int64, arg2 *domain.ConsentInput) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Consent", arg0, arg1, arg2)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Consent indicates an expected call of Consent.
+func (mr *MockOAuthMockRecorder) Consent(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Consent", reflect.TypeOf((*MockOAuth)(nil).Consent), arg0, arg1, arg2)
+}
+
+// Consents mocks base method.
+func (m *MockOAuth) Consents(arg0 context.Context, arg1 int64) ([]domain.OAuthConsent, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Consents", arg0, arg1)
+	ret0, _ := ret[0].([]domain.OAuthConsent)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Consents indicates an expected call of Consents.
+func (mr *MockOAuthMockRecorder) Consents(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Consents", reflect.TypeOf((*MockOAuth)(nil).Consents), arg0, arg1)
+}
+
+// DeleteClient mocks base method.
+func (m *MockOAuth) DeleteClient(arg0 context.Context, arg1, arg2 int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteClient", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteClient indicates an expected call of DeleteClient.
+func (mr *MockOAuthMockRecorder) DeleteClient(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteClient", reflect.TypeOf((*MockOAuth)(nil).DeleteClient), arg0, arg1, arg2)
+}
+
+// Introspect mocks base method.
+func (m *MockOAuth) Introspect(arg0 context.Context, arg1 *domain.OAuthTokenInput) (*domain.TokenIntrospection, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Introspect", arg0, arg1)
+	ret0, _ := ret[0].(*domain.TokenIntrospection)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Introspect indicates an expected call of Introspect.
+func (mr *MockOAuthMockRecorder) Introspect(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Introspect", reflect.TypeOf((*MockOAuth)(nil).Introspect), arg0, arg1)
+}
+
+// RegisterClient mocks base method.
+func (m *MockOAuth) RegisterClient(arg0 context.Context, arg1 int64, arg2 *domain.RegisterOAuthClientInput) (*domain.OAuthClient, string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RegisterClient", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*domain.OAuthClient)
+	ret1, _ := ret[1].(string)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// RegisterClient indicates an expected call of RegisterClient.
+func (mr *MockOAuthMockRecorder) RegisterClient(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RegisterClient", reflect.TypeOf((*MockOAuth)(nil).RegisterClient), arg0, arg1, arg2)
+}
+
+// Revoke mocks base method.
+func (m *MockOAuth) Revoke(arg0 context.Context, arg1 *domain.OAuthTokenInput) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Revoke", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Revoke indicates an expected call of Revoke.
+func (mr *MockOAuthMockRecorder) Revoke(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Revoke", reflect.TypeOf((*MockOAuth)(nil).Revoke), arg0, arg1)
+}
+
+// RevokeConsent mocks base method.
+func (m *MockOAuth) RevokeConsent(arg0 context.Context, arg1 int64, arg2 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeConsent", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeConsent indicates an expected call of RevokeConsent.
+func (mr *MockOAuthMockRecorder) RevokeConsent(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeConsent", reflect.TypeOf((*MockOAuth)(nil).RevokeConsent), arg0, arg1, arg2)
+}
+
+// Token mocks base method.
+func (m *MockOAuth) Token(arg0 context.Context, arg1 *domain.TokenInput) (*domain.TokenResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Token", arg0, arg1)
+	ret0, _ := ret[0].(*domain.TokenResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Token indicates an expected call of Token.
+func (mr *MockOAuthMockRecorder) Token(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Token", reflect.TypeOf((*MockOAuth)(nil).Token), arg0, arg1)
+}
+
+// MockPrivacy is a mock of Privacy interface.
+type MockPrivacy struct {
+	ctrl     *gomock.Controller
+	recorder *MockPrivacyMockRecorder
+}
+
+// MockPrivacyMockRecorder is the mock recorder for MockPrivacy.
+type MockPrivacyMockRecorder struct {
+	mock *MockPrivacy
+}
+
+// NewMockPrivacy creates a new mock instance.
+func NewMockPrivacy(ctrl *gomock.Controller) *MockPrivacy {
+	mock := &MockPrivacy{ctrl: ctrl}
+	mock.recorder = &MockPrivacyMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockPrivacy) EXPECT() *MockPrivacyMockRecorder {
+	return m.recorder
+}
+
+// EraseContact mocks base method.
+func (m *MockPrivacy) EraseContact(arg0 context.Context, arg1 int64, arg2 domain.ErasureMode) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EraseContact", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// EraseContact indicates an expected call of EraseContact.
+func (mr *MockPrivacyMockRecorder) EraseContact(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EraseContact", reflect.TypeOf((*MockPrivacy)(nil).EraseContact), arg0, arg1, arg2)
+}
+
+// EraseUser mocks base method.
+func (m *MockPrivacy) EraseUser(arg0 context.Context, arg1 int64, arg2 domain.ErasureMode) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EraseUser", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// EraseUser indicates an expected call of EraseUser.
+func (mr *MockPrivacyMockRecorder) EraseUser(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EraseUser", reflect.TypeOf((*MockPrivacy)(nil).EraseUser), arg0, arg1, arg2)
+}
+
+// Export mocks base method.
+func (m *MockPrivacy) Export(arg0 context.Context, arg1 int64, arg2 io.Writer) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Export", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Export indicates an expected call of Export.
+func (mr *MockPrivacyMockRecorder) Export(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Export", reflect.TypeOf((*MockPrivacy)(nil).Export), arg0, arg1, arg2)
+}
+
+// MockIdempotency is a mock of Idempotency interface.
+type MockIdempotency struct {
+	ctrl     *gomock.Controller
+	recorder *MockIdempotencyMockRecorder
+}
+
+// MockIdempotencyMockRecorder is the mock recorder for MockIdempotency.
+type MockIdempotencyMockRecorder struct {
+	mock *MockIdempotency
+}
+
+// NewMockIdempotency creates a new mock instance.
+func NewMockIdempotency(ctrl *gomock.Controller) *MockIdempotency {
+	mock := &MockIdempotency{ctrl: ctrl}
+	mock.recorder = &MockIdempotencyMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockIdempotency) EXPECT() *MockIdempotencyMockRecorder {
+	return m.recorder
+}
+
+// Begin mocks base method.
+func (m *MockIdempotency) Begin(arg0 context.Context, arg1 int64, arg2, arg3 string) (*domain.IdempotencyRecord, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Begin", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(*domain.IdempotencyRecord)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Begin indicates an expected call of Begin.
+func (mr *MockIdempotencyMockRecorder) Begin(arg0, arg1, arg2, arg3 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Begin", reflect.TypeOf((*MockIdempotency)(nil).Begin), arg0, arg1, arg2, arg3)
+}
+
+// Complete mocks base method.
+func (m *MockIdempotency) Complete(arg0 context.Context, arg1 int64, arg2 string, arg3 int, arg4 map[string][]string, arg5 []byte) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Complete", arg0, arg1, arg2, arg3, arg4, arg5)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Complete indicates an expected call of Complete.
+func (mr *MockIdempotencyMockRecorder) Complete(arg0, arg1, arg2, arg3, arg4, arg5 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Complete", reflect.TypeOf((*MockIdempotency)(nil).Complete), arg0, arg1, arg2, arg3, arg4, arg5)
+}
+
+// Release mocks base method.
+func (m *MockIdempotency) Release(arg0 context.Context, arg1 int64, arg2 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Release", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Release indicates an expected call of Release.
+func (mr *MockIdempotencyMockRecorder) Release(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Release", reflect.TypeOf((*MockIdempotency)(nil).Release), arg0, arg1, arg2)
+}
+
+// MockWebhooks is a mock of Webhooks interface.
+type MockWebhooks struct {
+	ctrl     *gomock.Controller
+	recorder *MockWebhooksMockRecorder
+}
+
+// MockWebhooksMockRecorder is the mock recorder for MockWebhooks.
+type MockWebhooksMockRecorder struct {
+	mock *MockWebhooks
+}
+
+// NewMockWebhooks creates a new mock instance.
+func NewMockWebhooks(ctrl *gomock.Controller) *MockWebhooks {
+	mock := &MockWebhooks{ctrl: ctrl}
+	mock.recorder = &MockWebhooksMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockWebhooks) EXPECT() *MockWebhooksMockRecorder {
+	return m.recorder
+}
+
+// All mocks base method.
+func (m *MockWebhooks) All(arg0 context.Context, arg1 int64) ([]domain.Webhook, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "All", arg0, arg1)
+	ret0, _ := ret[0].([]domain.Webhook)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// All indicates an expected call of All.
+func (mr *MockWebhooksMockRecorder) All(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "All", reflect.TypeOf((*MockWebhooks)(nil).All), arg0, arg1)
+}
+
+// Create mocks base method.
+func (m *MockWebhooks) Create(arg0 context.Context, arg1 int64, arg2 *domain.CreateWebhookInput) (*domain.Webhook, string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*domain.Webhook)
+	ret1, _ := ret[1].(string)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// Create indicates an expected call of Create.
+func (mr *MockWebhooksMockRecorder) Create(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockWebhooks)(nil).Create), arg0, arg1, arg2)
+}
+
+// Delete mocks base method.
+func (m *MockWebhooks) Delete(arg0 context.Context, arg1, arg2 int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Delete indicates an expected call of Delete.
+func (mr *MockWebhooksMockRecorder) Delete(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockWebhooks)(nil).Delete), arg0, arg1, arg2)
+}
+
+// Deliveries mocks base method.
+func (m *MockWebhooks) Deliveries(arg0 context.Context, arg1, arg2 int64) ([]domain.WebhookDelivery, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Deliveries", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]domain.WebhookDelivery)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Deliveries indicates an expected call of Deliveries.
+func (mr *MockWebhooksMockRecorder) Deliveries(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Deliveries", reflect.TypeOf((*MockWebhooks)(nil).Deliveries), arg0, arg1, arg2)
+}
+
+// Delivery mocks base method.
+func (m *MockWebhooks) Delivery(arg0 context.Context, arg1, arg2, arg3 int64) (*domain.WebhookDelivery, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delivery", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(*domain.WebhookDelivery)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Delivery indicates an expected call of Delivery.
+func (mr *MockWebhooksMockRecorder) Delivery(arg0, arg1, arg2, arg3 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delivery", reflect.TypeOf((*MockWebhooks)(nil).Delivery), arg0, arg1, arg2, arg3)
+}
+
+// Redeliver mocks base method.
+func (m *MockWebhooks) Redeliver(arg0 context.Context, arg1, arg2, arg3 int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Redeliver", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Redeliver indicates an expected call of Redeliver.
+func (mr *MockWebhooksMockRecorder) Redeliver(arg0, arg1, arg2, arg3 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Redeliver", reflect.TypeOf((*MockWebhooks)(nil).Redeliver), arg0, arg1, arg2, arg3)
+}
+
+// MockCardDAV is a mock of CardDAV interface.
+type MockCardDAV struct {
+	ctrl     *gomock.Controller
+	recorder *MockCardDAVMockRecorder
+}
+
+// MockCardDAVMockRecorder is the mock recorder for MockCardDAV.
+type MockCardDAVMockRecorder struct {
+	mock *MockCardDAV
+}
+
+// NewMockCardDAV creates a new mock instance.
+func NewMockCardDAV(ctrl *gomock.Controller) *MockCardDAV {
+	mock := &MockCardDAV{ctrl: ctrl}
+	mock.recorder = &MockCardDAVMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockCardDAV) EXPECT() *MockCardDAVMockRecorder {
+	return m.recorder
+}
+
+// Card mocks base method.
+func (m *MockCardDAV) Card(arg0 context.Context, arg1 int64, arg2 string) (*domain.Card, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Card", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*domain.Card)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Card indicates an expected call of Card.
+func (mr *MockCardDAVMockRecorder) Card(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Card", reflect.TypeOf((*MockCardDAV)(nil).Card), arg0, arg1, arg2)
+}
+
+// Cards mocks base method.
+func (m *MockCardDAV) Cards(arg0 context.Context, arg1 int64) ([]domain.Card, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Cards", arg0, arg1)
+	ret0, _ := ret[0].([]domain.Card)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Cards indicates an expected call of Cards.
+func (mr *MockCardDAVMockRecorder) Cards(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Cards", reflect.TypeOf((*MockCardDAV)(nil).Cards), arg0, arg1)
+}
+
+// Changes mocks base method.
+func (m *MockCardDAV) Changes(arg0 context.Context, arg1 int64, arg2 string) (*domain.CardChanges, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Changes", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*domain.CardChanges)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Changes indicates an expected call of Changes.
+func (mr *MockCardDAVMockRecorder) Changes(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Changes", reflect.TypeOf((*MockCardDAV)(nil).Changes), arg0, arg1, arg2)
+}
+
+// DeleteCard mocks base method.
+func (m *MockCardDAV) DeleteCard(arg0 context.Context, arg1 *domain.Card) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteCard", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteCard indicates an expected call of DeleteCard.
+func (mr *MockCardDAVMockRecorder) DeleteCard(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteCard", reflect.TypeOf((*MockCardDAV)(nil).DeleteCard), arg0, arg1)
+}
+
+// SaveCard mocks base method.
+func (m *MockCardDAV) SaveCard(arg0 context.Context, arg1 int64, arg2, arg3 string, arg4 *domain.SaveInputContact, arg5 *domain.Card) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveCard", arg0, arg1, arg2, arg3, arg4, arg5)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SaveCard indicates an expected call of SaveCard.
+func (mr *MockCardDAVMockRecorder) SaveCard(arg0, arg1, arg2, arg3, arg4, arg5 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveCard", reflect.TypeOf((*MockCardDAV)(nil).SaveCard), arg0, arg1, arg2, arg3, arg4, arg5)
+}
+
+// SyncToken mocks base method.
+func (m *MockCardDAV) SyncToken(arg0 context.Context) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SyncToken", arg0)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SyncToken indicates an expected call of SyncToken.
+func (mr *MockCardDAVMockRecorder) SyncToken(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SyncToken", reflect.TypeOf((*MockCardDAV)(nil).SyncToken), arg0)
+}
+
+// MockGraphQL is a mock of GraphQL interface.
+type MockGraphQL struct {
+	ctrl     *gomock.Controller
+	recorder *MockGraphQLMockRecorder
+}
+
+// MockGraphQLMockRecorder is the mock recorder for MockGraphQL.
+type MockGraphQLMockRecorder struct {
+	mock *MockGraphQL
+}
+
+// NewMockGraphQL creates a new mock instance.
+func NewMockGraphQL(ctrl *gomock.Controller) *MockGraphQL {
+	mock := &MockGraphQL{ctrl: ctrl}
+	mock.recorder = &MockGraphQLMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockGraphQL) EXPECT() *MockGraphQLMockRecorder {
+	return m.recorder
+}
+
+// ServeHTTP mocks base method.
+func (m *MockGraphQL) ServeHTTP(arg0 http.ResponseWriter, arg1 *http.Request) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "ServeHTTP", arg0, arg1)
+}
+
+// ServeHTTP indicates an expected call of ServeHTTP.
+func (mr *MockGraphQLMockRecorder) ServeHTTP(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ServeHTTP", reflect.TypeOf((*MockGraphQL)(nil).ServeHTTP), arg0, arg1)
+}
+
+// MockHealth is a mock of Health interface.
+type MockHealth struct {
+	ctrl     *gomock.Controller
+	recorder *MockHealthMockRecorder
+}
+
+// MockHealthMockRecorder is the mock recorder for MockHealth.
+type MockHealthMockRecorder struct {
+	mock *MockHealth
+}
+
+// NewMockHealth creates a new mock instance.
+func NewMockHealth(ctrl *gomock.Controller) *MockHealth {
+	mock := &MockHealth{ctrl: ctrl}
+	mock.recorder = &MockHealthMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockHealth) EXPECT() *MockHealthMockRecorder {
+	return m.recorder
+}
+
+// Check mocks base method.
+func (m *MockHealth) Check(arg0 context.Context) *domain.HealthReport {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Check", arg0)
+	ret0, _ := ret[0].(*domain.HealthReport)
+	return ret0
+}
+
+// Check indicates an expected call of Check.
+func (mr *MockHealthMockRecorder) Check(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Check", reflect.TypeOf((*MockHealth)(nil).Check), arg0)
+}
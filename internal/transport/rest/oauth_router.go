@@ -0,0 +1,419 @@
+package rest
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/wilfridterry/contact-list/internal/apperror"
+	"github.com/wilfridterry/contact-list/internal/domain"
+
+	"github.com/gin-gonic/gin"
+)
+
+// OAuthError is the RFC 6749 error response of the authorization server.
+type OAuthError struct {
+	Error            string `json:"error" example:"invalid_grant"`
+	ErrorDescription string `json:"error_description,omitempty" example:"invalid, expired or revoked grant"`
+}
+
+type RegisteredOAuthClient struct {
+	ClientSecret string              `json:"client_secret,omitempty"`
+	Client       *domain.OAuthClient `json:"client"`
+}
+
+type ConsentRedirect struct {
+	RedirectTo string `json:"redirect_to"`
+}
+
+type OAuthConsentUri struct {
+	ClientID string `uri:"client_id" binding:"required"`
+}
+
+var oauthErrorCodes = []struct {
+	err    error
+	code   string
+	status int
+}{
+	{domain.ErrInvalidOAuthClient, "invalid_client", http.StatusUnauthorized},
+	{domain.ErrInvalidOAuthGrant, "invalid_grant", http.StatusBadRequest},
+	{domain.ErrInvalidOAuthScope, "invalid_scope", http.StatusBadRequest},
+	{domain.ErrUnauthorizedOAuthClient, "unauthorized_client", http.StatusBadRequest},
+	{domain.ErrUnsupportedGrantType, "unsupported_grant_type", http.StatusBadRequest},
+	{domain.ErrUnsupportedResponseType, "unsupported_response_type", http.StatusBadRequest},
+	{domain.ErrInvalidOAuthRequest, "invalid_request", http.StatusBadRequest},
+	{domain.ErrInvalidRedirectURI, "invalid_request", http.StatusBadRequest},
+	{domain.ErrOAuthClientNotFound, "invalid_request", http.StatusBadRequest},
+}
+
+// oauthError renders the error in the RFC 6749 format. Unexpected errors are
+// not described to the client.
+func oauthError(c *gin.Context, err error) {
+	for _, e := range oauthErrorCodes {
+		if errors.Is(err, e.err) {
+			if e.status == http.StatusUnauthorized {
+				c.Header("WWW-Authenticate", `Basic realm="oauth"`)
+			}
+
+			c.AbortWithStatusJSON(e.status, OAuthError{Error: e.code, ErrorDescription: err.Error()})
+			return
+		}
+	}
+
+	c.AbortWithStatusJSON(http.StatusInternalServerError, OAuthError{Error: "server_error"})
+}
+
+// oauthInvalidRequest reports parameters which failed binding by their names
+// rather than with the validator message.
+func oauthInvalidRequest(c *gin.Context, err error) {
+	appErr := apperror.From(err)
+
+	description := appErr.Message
+	if len(appErr.Fields) > 0 {
+		names := make([]string, 0, len(appErr.Fields))
+		for _, field := range appErr.Fields {
+			names = append(names, field.Field)
+		}
+
+		description = "invalid or missing parameter: " + strings.Join(names, ", ")
+	}
+
+	c.AbortWithStatusJSON(http.StatusBadRequest, OAuthError{Error: "invalid_request", ErrorDescription: description})
+}
+
+// clientCredentials reads the client authentication from the Basic header,
+// falling back to the client_id and client_secret body parameters.
+func clientCredentials(c *gin.Context, clientId, clientSecret string) (string, string) {
+	user, password, ok := c.Request.BasicAuth()
+	if !ok {
+		return clientId, clientSecret
+	}
+
+	if id, err := url.QueryUnescape(user); err == nil {
+		user = id
+	}
+
+	if secret, err := url.QueryUnescape(password); err == nil {
+		password = secret
+	}
+
+	return user, password
+}
+
+// OAuthToken godoc
+// @Summary      OAuth2 token endpoint
+// @Description  exchange an authorization code, client credentials or a refresh token for an access token
+// @Tags         oauth
+// @Accept       x-www-form-urlencoded
+// @Produce      json
+// @Param        grant_type     formData  string  true   "authorization_code, client_credentials or refresh_token"
+// @Param        code           formData  string  false  "Authorization code"
+// @Param        redirect_uri   formData  string  false  "Redirect URI used for the code"
+// @Param        code_verifier  formData  string  false  "PKCE code verifier"
+// @Param        refresh_token  formData  string  false  "Refresh token"
+// @Param        scope          formData  string  false  "Space separated scopes"
+// @Param        client_id      formData  string  false  "Client ID when Basic auth is not used"
+// @Param        client_secret  formData  string  false  "Client secret when Basic auth is not used"
+// @Success      200  {object}  domain.TokenResponse
+// @Failure      400  {object}  OAuthError
+// @Failure      401  {object}  OAuthError
+// @Failure      500  {object}  OAuthError
+// @Router       /oauth/token [post]
+func (h *Handler) oauthToken(c *gin.Context) {
+	c.Header("Cache-Control", "no-store")
+	c.Header("Pragma", "no-cache")
+
+	var inp domain.TokenInput
+	if err := c.ShouldBind(&inp); err != nil {
+		oauthInvalidRequest(c, err)
+		return
+	}
+
+	inp.ClientID, inp.ClientSecret = clientCredentials(c, inp.ClientID, inp.ClientSecret)
+
+	res, err := h.oauthService.Token(c.Request.Context(), &inp)
+	if err != nil {
+		oauthError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+// OAuthIntrospect godoc
+// @Summary      OAuth2 token introspection
+// @Description  describe a token issued to the calling client (RFC 7662)
+// @Tags         oauth
+// @Accept       x-www-form-urlencoded
+// @Produce      json
+// @Param        token            formData  string  true   "Token"
+// @Param        token_type_hint  formData  string  false  "access_token or refresh_token"
+// @Success      200  {object}  domain.TokenIntrospection
+// @Failure      400  {object}  OAuthError
+// @Failure      401  {object}  OAuthError
+// @Failure      500  {object}  OAuthError
+// @Router       /oauth/introspect [post]
+func (h *Handler) oauthIntrospect(c *gin.Context) {
+	var inp domain.OAuthTokenInput
+	if err := c.ShouldBind(&inp); err != nil {
+		oauthInvalidRequest(c, err)
+		return
+	}
+
+	inp.ClientID, inp.ClientSecret = clientCredentials(c, inp.ClientID, inp.ClientSecret)
+
+	res, err := h.oauthService.Introspect(c.Request.Context(), &inp)
+	if err != nil {
+		oauthError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+// OAuthRevoke godoc
+// @Summary      OAuth2 token revocation
+// @Description  revoke an access or refresh token issued to the calling client (RFC 7009)
+// @Tags         oauth
+// @Accept       x-www-form-urlencoded
+// @Produce      json
+// @Param        token            formData  string  true   "Token"
+// @Param        token_type_hint  formData  string  false  "access_token or refresh_token"
+// @Success      200
+// @Failure      400  {object}  OAuthError
+// @Failure      401  {object}  OAuthError
+// @Failure      500  {object}  OAuthError
+// @Router       /oauth/revoke [post]
+func (h *Handler) oauthRevoke(c *gin.Context) {
+	var inp domain.OAuthTokenInput
+	if err := c.ShouldBind(&inp); err != nil {
+		oauthInvalidRequest(c, err)
+		return
+	}
+
+	inp.ClientID, inp.ClientSecret = clientCredentials(c, inp.ClientID, inp.ClientSecret)
+
+	if err := h.oauthService.Revoke(c.Request.Context(), &inp); err != nil {
+		oauthError(c, err)
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+// OAuthAuthorize godoc
+// @Summary      OAuth2 authorization request
+// @Description  validate the authorization request of the signed in user and return the consent screen data, or the redirect when consent was already given
+// @Tags         oauth
+// @Produce      json
+// @Param        response_type          query  string  true   "code"
+// @Param        client_id              query  string  true   "Client ID"
+// @Param        redirect_uri           query  string  false  "Registered redirect URI"
+// @Param        scope                  query  string  false  "Space separated scopes"
+// @Param        state                  query  string  false  "Client state"
+// @Param        code_challenge         query  string  true   "PKCE code challenge"
+// @Param        code_challenge_method  query  string  true   "S256"
+// @Success      200  {object}  domain.AuthorizePrompt
+// @Failure      400  {object}  OAuthError
+// @Failure      401  {object}  Problem
+// @Failure      403  {object}  Problem
+// @Failure      500  {object}  OAuthError
+// @Router       /oauth/authorize [get]
+func (h *Handler) oauthAuthorize(c *gin.Context) {
+	identity, ok := refuseDelegated(c)
+	if !ok {
+		return
+	}
+
+	var inp domain.AuthorizeInput
+	if err := c.ShouldBindQuery(&inp); err != nil {
+		oauthInvalidRequest(c, err)
+		return
+	}
+
+	prompt, err := h.oauthService.Authorize(c.Request.Context(), identity.UserID, &inp)
+	if err != nil {
+		oauthError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, prompt)
+}
+
+// OAuthConsent godoc
+// @Summary      OAuth2 consent decision
+// @Description  approve or deny the authorization request, returns where to send the user back to
+// @Tags         oauth
+// @Accept       json
+// @Produce      json
+// @Param        input  body  domain.ConsentInput  true  "authorization request and decision"
+// @Success      200  {object}  ConsentRedirect
+// @Failure      400  {object}  OAuthError
+// @Failure      401  {object}  Problem
+// @Failure      403  {object}  Problem
+// @Failure      500  {object}  OAuthError
+// @Router       /oauth/authorize [post]
+func (h *Handler) oauthConsent(c *gin.Context) {
+	identity, ok := refuseDelegated(c)
+	if !ok {
+		return
+	}
+
+	var inp domain.ConsentInput
+	if err := c.ShouldBindJSON(&inp); err != nil {
+		oauthInvalidRequest(c, err)
+		return
+	}
+
+	redirectTo, err := h.oauthService.Consent(c.Request.Context(), identity.UserID, &inp)
+	if err != nil {
+		oauthError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, ConsentRedirect{RedirectTo: redirectTo})
+}
+
+// RegisterOAuthClient godoc
+// @Summary      Register an OAuth app
+// @Description  register a third-party application, the secret of confidential clients is shown only once
+// @Tags         oauth
+// @Accept       json
+// @Produce      json
+// @Param        client  body  domain.RegisterOAuthClientInput  true  "client payload"
+// @Success      201  {object}  RegisteredOAuthClient
+// @Failure      400  {object}  Problem
+// @Failure      401  {object}  Problem
+// @Failure      403  {object}  Problem
+// @Failure      422  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Router       /oauth/clients [post]
+func (h *Handler) registerOAuthClient(c *gin.Context) {
+	identity, ok := refuseDelegated(c)
+	if !ok {
+		return
+	}
+
+	var inp domain.RegisterOAuthClientInput
+	if err := c.ShouldBindJSON(&inp); err != nil {
+		newProblem(c, err)
+
+		return
+	}
+
+	client, secret, err := h.oauthService.RegisterClient(c.Request.Context(), identity.UserID, &inp)
+	if err != nil {
+		newProblem(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, RegisteredOAuthClient{ClientSecret: secret, Client: client})
+}
+
+// ListOAuthClients godoc
+// @Summary      List OAuth apps
+// @Description  get the applications registered by the current user
+// @Tags         oauth
+// @Produce      json
+// @Success      200  {array}   domain.OAuthClient
+// @Failure      401  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Router       /oauth/clients [get]
+func (h *Handler) getOAuthClients(c *gin.Context) {
+	identity, _ := getIdentity(c)
+
+	clients, err := h.oauthService.Clients(c.Request.Context(), identity.UserID)
+	if err != nil {
+		newProblem(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, clients)
+}
+
+// DeleteOAuthClient godoc
+// @Summary      Delete an OAuth app
+// @Description  delete an application of the current user with its tokens and consents
+// @Tags         oauth
+// @Produce      json
+// @Param        id   path      int  true  "Client ID"
+// @Success      204
+// @Failure      400  {object}  Problem
+// @Failure      401  {object}  Problem
+// @Failure      403  {object}  Problem
+// @Failure      404  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Router       /oauth/clients/{id} [delete]
+func (h *Handler) deleteOAuthClient(c *gin.Context) {
+	identity, ok := refuseDelegated(c)
+	if !ok {
+		return
+	}
+
+	var uri Uri
+	if err := c.ShouldBindUri(&uri); err != nil {
+		newProblem(c, err)
+		return
+	}
+
+	if err := h.oauthService.DeleteClient(c.Request.Context(), identity.UserID, uri.ID); err != nil {
+		newProblem(c, err)
+		return
+	}
+
+	c.JSON(http.StatusNoContent, gin.H{})
+}
+
+// ListOAuthConsents godoc
+// @Summary      List OAuth consents
+// @Description  get the applications the current user granted access to
+// @Tags         oauth
+// @Produce      json
+// @Success      200  {array}   domain.OAuthConsent
+// @Failure      401  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Router       /oauth/consents [get]
+func (h *Handler) getOAuthConsents(c *gin.Context) {
+	identity, _ := getIdentity(c)
+
+	consents, err := h.oauthService.Consents(c.Request.Context(), identity.UserID)
+	if err != nil {
+		newProblem(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, consents)
+}
+
+// RevokeOAuthConsent godoc
+// @Summary      Revoke an OAuth consent
+// @Description  withdraw the access granted to an application and revoke its tokens
+// @Tags         oauth
+// @Produce      json
+// @Param        client_id   path      string  true  "Client ID"
+// @Success      204
+// @Failure      401  {object}  Problem
+// @Failure      403  {object}  Problem
+// @Failure      404  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Router       /oauth/consents/{client_id} [delete]
+func (h *Handler) revokeOAuthConsent(c *gin.Context) {
+	identity, ok := refuseDelegated(c)
+	if !ok {
+		return
+	}
+
+	var uri OAuthConsentUri
+	if err := c.ShouldBindUri(&uri); err != nil {
+		newProblem(c, err)
+		return
+	}
+
+	if err := h.oauthService.RevokeConsent(c.Request.Context(), identity.UserID, uri.ClientID); err != nil {
+		newProblem(c, err)
+		return
+	}
+
+	c.JSON(http.StatusNoContent, gin.H{})
+}
@@ -0,0 +1,102 @@
+package rest
+
+import (
+	"bytes"
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/magiconair/properties/assert"
+	"github.com/wilfridterry/contact-list/internal/domain"
+	mock_rest "github.com/wilfridterry/contact-list/internal/transport/rest/mocks"
+	"go.uber.org/mock/gomock"
+)
+
+func TestHandler_oauthToken(t *testing.T) {
+	type mockBehavior func(s *mock_rest.MockOAuth, inp domain.TokenInput)
+
+	testTable := []struct {
+		name                 string
+		body                 string
+		basicUser            string
+		basicPassword        string
+		inputToken           domain.TokenInput
+		mockBehavior         mockBehavior
+		expectedStatusCode   int
+		expectedResponseBody string
+	}{
+		{
+			name:          "Client credentials with Basic auth",
+			body:          "grant_type=client_credentials&scope=contacts%3Aread",
+			basicUser:     "client",
+			basicPassword: "ocs_secret",
+			inputToken: domain.TokenInput{
+				GrantType:    "client_credentials",
+				Scope:        "contacts:read",
+				ClientID:     "client",
+				ClientSecret: "ocs_secret",
+			},
+			mockBehavior: func(s *mock_rest.MockOAuth, inp domain.TokenInput) {
+				s.EXPECT().Token(context.Background(), &inp).Return(&domain.TokenResponse{
+					AccessToken: "oat_token",
+					TokenType:   "Bearer",
+					ExpiresIn:   3600,
+					Scope:       "contacts:read",
+				}, nil)
+			},
+			expectedStatusCode:   200,
+			expectedResponseBody: `{"access_token":"oat_token","token_type":"Bearer","expires_in":3600,"scope":"contacts:read"}`,
+		},
+		{
+			name: "Invalid client",
+			body: "grant_type=authorization_code&code=code&client_id=client&client_secret=wrong",
+			inputToken: domain.TokenInput{
+				GrantType:    "authorization_code",
+				Code:         "code",
+				ClientID:     "client",
+				ClientSecret: "wrong",
+			},
+			mockBehavior: func(s *mock_rest.MockOAuth, inp domain.TokenInput) {
+				s.EXPECT().Token(context.Background(), &inp).Return(nil, domain.ErrInvalidOAuthClient)
+			},
+			expectedStatusCode:   401,
+			expectedResponseBody: `{"error":"invalid_client","error_description":"client authentication failed"}`,
+		},
+		{
+			name:                 "Missing grant type",
+			body:                 "code=code",
+			mockBehavior:         func(s *mock_rest.MockOAuth, inp domain.TokenInput) {},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"error":"invalid_request","error_description":"invalid or missing parameter: grant_type"}`,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			oauth := mock_rest.NewMockOAuth(c)
+			testCase.mockBehavior(oauth, testCase.inputToken)
+
+			handler := NewHandler(&mock_rest.MockContacts{}, &mock_rest.MockAuth{}, &mock_rest.MockAPIKeys{}, &mock_rest.MockOIDC{}, oauth, &mock_rest.MockPrivacy{}, &mock_rest.MockIdempotency{}, &mock_rest.MockWebhooks{}, &mock_rest.MockCardDAV{}, &mock_rest.MockGraphQL{}, &mock_rest.MockHealth{})
+
+			r := gin.New()
+			r.POST("/oauth/token", handler.oauthToken)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("POST", "/oauth/token", bytes.NewBufferString(testCase.body))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			if testCase.basicUser != "" {
+				req.SetBasicAuth(testCase.basicUser, testCase.basicPassword)
+			}
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, w.Code, testCase.expectedStatusCode)
+			assert.Equal(t, w.Body.String(), testCase.expectedResponseBody)
+			assert.Equal(t, w.Header().Get("Cache-Control"), "no-store")
+		})
+	}
+}
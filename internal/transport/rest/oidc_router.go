@@ -0,0 +1,153 @@
+package rest
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/wilfridterry/contact-list/internal/domain"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	oidcStateCookie     = "oidc-state"
+	oidcStateCookiePath = "/api/v1/auth/oidc"
+	oidcStateCookieTTL  = 600
+)
+
+type OIDCUri struct {
+	Provider string `uri:"provider" binding:"required"`
+}
+
+type OIDCCallbackQuery struct {
+	Code  string `form:"code" binding:"required"`
+	State string `form:"state" binding:"required"`
+}
+
+// OIDCLogin godoc
+// @Summary      sign in with an identity provider
+// @Description  redirect to the OpenID Connect provider (authorization code flow with PKCE)
+// @Tags         auth
+// @Param        provider   path      string  true  "Provider name"
+// @Success      302
+// @Failure      404  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Router       /auth/oidc/{provider}/login [get]
+func (h *Handler) oidcLogin(c *gin.Context) {
+	var uri OIDCUri
+	if err := c.ShouldBindUri(&uri); err != nil {
+		newProblem(c, err)
+		return
+	}
+
+	redirectURL, stateToken, err := h.oidcService.Begin(c.Request.Context(), uri.Provider, 0)
+	if err != nil {
+		newProblem(c, err)
+		return
+	}
+
+	c.SetCookie(oidcStateCookie, stateToken, oidcStateCookieTTL, oidcStateCookiePath, "", true, true)
+	c.Redirect(http.StatusFound, redirectURL)
+}
+
+type IdentityLink struct {
+	RedirectURL string `json:"redirect_url"`
+}
+
+// LinkIdentity godoc
+// @Summary      link an identity provider
+// @Description  start the OpenID Connect flow linking the provider account to the signed in user, send the user to redirect_url, the callback then links it and signs in
+// @Tags         me
+// @Produce      json
+// @Param        provider   path      string  true  "Provider name"
+// @Success      200  {object}  IdentityLink
+// @Failure      401  {object}  Problem
+// @Failure      403  {object}  Problem
+// @Failure      404  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Router       /me/identities/{provider} [post]
+func (h *Handler) linkIdentity(c *gin.Context) {
+	var uri OIDCUri
+	if err := c.ShouldBindUri(&uri); err != nil {
+		newProblem(c, err)
+		return
+	}
+
+	identity, ok := refuseDelegated(c)
+	if !ok {
+		return
+	}
+
+	redirectURL, stateToken, err := h.oidcService.Begin(c.Request.Context(), uri.Provider, identity.UserID)
+	if err != nil {
+		newProblem(c, err)
+		return
+	}
+
+	c.SetCookie(oidcStateCookie, stateToken, oidcStateCookieTTL, oidcStateCookiePath, "", true, true)
+	c.JSON(http.StatusOK, IdentityLink{RedirectURL: redirectURL})
+}
+
+// OIDCCallback godoc
+// @Summary      identity provider callback
+// @Description  complete the OpenID Connect sign in and issue tokens, a provider account which is not linked yet while an account with its email exists has to be linked from that account first
+// @Tags         auth
+// @Produce      json
+// @Param        provider   path      string  true  "Provider name"
+// @Param        code       query     string  true  "Authorization code"
+// @Param        state      query     string  true  "State"
+// @Success      200
+// @Success      202  {object}  MFAChallenge
+// @Failure      400  {object}  Problem
+// @Failure      403  {object}  Problem
+// @Failure      404  {object}  Problem
+// @Failure      409  {object}  Problem
+// @Failure      429  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Router       /auth/oidc/{provider}/callback [get]
+func (h *Handler) oidcCallback(c *gin.Context) {
+	var uri OIDCUri
+	if err := c.ShouldBindUri(&uri); err != nil {
+		newProblem(c, err)
+		return
+	}
+
+	var query OIDCCallbackQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		newProblem(c, err)
+		return
+	}
+
+	stateToken, err := c.Cookie(oidcStateCookie)
+	if err != nil {
+		newProblem(c, domain.ErrInvalidOIDCState)
+		return
+	}
+
+	c.SetCookie(oidcStateCookie, "", -1, oidcStateCookiePath, "", true, true)
+
+	accessToken, refreshToken, err := h.oidcService.Complete(c.Request.Context(), uri.Provider, stateToken, query.State, query.Code, c.ClientIP())
+	if err != nil {
+		var mfaErr *domain.MFARequiredError
+		if errors.As(err, &mfaErr) {
+			c.JSON(http.StatusAccepted, MFAChallenge{
+				MFARequired: true,
+				Challenge:   mfaErr.Challenge,
+				ExpiresAt:   mfaErr.ExpiresAt,
+			})
+			return
+		}
+
+		var lockErr *domain.LoginLockedError
+		if errors.As(err, &lockErr) {
+			tooManyAttempts(c, lockErr)
+			return
+		}
+
+		newProblem(c, err)
+		return
+	}
+
+	c.SetCookie("refresh-token", refreshToken, 3600, "/", "localhost", true, true)
+	c.JSON(http.StatusOK, gin.H{"token": accessToken})
+}
@@ -0,0 +1,141 @@
+package rest
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+
+	"github.com/wilfridterry/contact-list/internal/domain"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ExportMe godoc
+// @Summary      Export my data
+// @Description  download everything held about the current user as a ZIP of JSON files
+// @Tags         me
+// @Produce      application/zip
+// @Success      200  {file}    file
+// @Failure      401  {object}  Problem
+// @Failure      403  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Router       /me/export [get]
+func (h *Handler) exportMe(c *gin.Context) {
+	identity, ok := refuseDelegated(c)
+	if !ok {
+		return
+	}
+
+	h.export(c, identity.UserID)
+}
+
+// ExportUser godoc
+// @Summary      Export the data of a user
+// @Description  download everything held about a user as a ZIP of JSON files
+// @Tags         admin
+// @Produce      application/zip
+// @Param        id   path      int  true  "User ID"
+// @Success      200  {file}    file
+// @Failure      400  {object}  Problem
+// @Failure      403  {object}  PermissionProblem
+// @Failure      404  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Router       /admin/users/{id}/export [get]
+func (h *Handler) exportUser(c *gin.Context) {
+	var uri Uri
+	if err := c.ShouldBindUri(&uri); err != nil {
+		newProblem(c, err)
+		return
+	}
+
+	h.export(c, uri.ID)
+}
+
+// EraseUser godoc
+// @Summary      Erase a user
+// @Description  delete or pseudonymize a user, leaving only a tombstone audit record
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        id     path  int                true  "User ID"
+// @Param        input  body  domain.EraseInput  true  "erasure mode"
+// @Param        Idempotency-Key  header  string  false  "retries with the same key run the request once"
+// @Success      204
+// @Failure      400  {object}  Problem
+// @Failure      403  {object}  PermissionProblem
+// @Failure      404  {object}  Problem
+// @Failure      409  {object}  Problem
+// @Failure      422  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Router       /admin/users/{id}/erase [post]
+func (h *Handler) eraseUser(c *gin.Context) {
+	var uri Uri
+	if err := c.ShouldBindUri(&uri); err != nil {
+		newProblem(c, err)
+		return
+	}
+
+	var inp domain.EraseInput
+	if err := c.ShouldBindJSON(&inp); err != nil {
+		newProblem(c, err)
+
+		return
+	}
+
+	if err := h.privacyService.EraseUser(c.Request.Context(), uri.ID, inp.Mode); err != nil {
+		newProblem(c, err)
+		return
+	}
+
+	c.JSON(http.StatusNoContent, gin.H{})
+}
+
+// EraseContact godoc
+// @Summary      Erase a contact
+// @Description  delete or pseudonymize a contact, leaving only a tombstone audit record
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        id     path  int                true  "Contact ID"
+// @Param        input  body  domain.EraseInput  true  "erasure mode"
+// @Param        Idempotency-Key  header  string  false  "retries with the same key run the request once"
+// @Success      204
+// @Failure      400  {object}  Problem
+// @Failure      403  {object}  PermissionProblem
+// @Failure      404  {object}  Problem
+// @Failure      409  {object}  Problem
+// @Failure      422  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Router       /admin/contacts/{id}/erase [post]
+func (h *Handler) eraseContact(c *gin.Context) {
+	var uri Uri
+	if err := c.ShouldBindUri(&uri); err != nil {
+		newProblem(c, err)
+		return
+	}
+
+	var inp domain.EraseInput
+	if err := c.ShouldBindJSON(&inp); err != nil {
+		newProblem(c, err)
+
+		return
+	}
+
+	if err := h.privacyService.EraseContact(c.Request.Context(), uri.ID, inp.Mode); err != nil {
+		newProblem(c, err)
+		return
+	}
+
+	c.JSON(http.StatusNoContent, gin.H{})
+}
+
+func (h *Handler) export(c *gin.Context, userId int64) {
+	var buf bytes.Buffer
+	if err := h.privacyService.Export(c.Request.Context(), userId, &buf); err != nil {
+		newProblem(c, err)
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="user-%d-export.zip"`, userId))
+	c.Data(http.StatusOK, "application/zip", buf.Bytes())
+}
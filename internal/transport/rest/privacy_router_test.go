@@ -0,0 +1,69 @@
+package rest
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/magiconair/properties/assert"
+	"github.com/wilfridterry/contact-list/internal/domain"
+	mock_rest "github.com/wilfridterry/contact-list/internal/transport/rest/mocks"
+	"go.uber.org/mock/gomock"
+)
+
+func TestHandler_eraseUser(t *testing.T) {
+	type mockBehavior func(s *mock_rest.MockPrivacy)
+
+	testTable := []struct {
+		name               string
+		inputBody          string
+		mockBehavior       mockBehavior
+		expectedStatusCode int
+	}{
+		{
+			name:      "OK",
+			inputBody: `{"mode":"pseudonymize"}`,
+			mockBehavior: func(s *mock_rest.MockPrivacy) {
+				s.EXPECT().EraseUser(gomock.Any(), int64(1), domain.ErasurePseudonymize).Return(nil)
+			},
+			expectedStatusCode: 204,
+		},
+		{
+			name:      "Not found",
+			inputBody: `{"mode":"delete"}`,
+			mockBehavior: func(s *mock_rest.MockPrivacy) {
+				s.EXPECT().EraseUser(gomock.Any(), int64(1), domain.ErasureDelete).Return(domain.ErrNotFoundUser)
+			},
+			expectedStatusCode: 404,
+		},
+		{
+			name:               "Unknown mode",
+			inputBody:          `{"mode":"shred"}`,
+			mockBehavior:       func(s *mock_rest.MockPrivacy) {},
+			expectedStatusCode: 422,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			privacy := mock_rest.NewMockPrivacy(c)
+			testCase.mockBehavior(privacy)
+
+			handler := NewHandler(&mock_rest.MockContacts{}, &mock_rest.MockAuth{}, &mock_rest.MockAPIKeys{}, &mock_rest.MockOIDC{}, &mock_rest.MockOAuth{}, privacy, &mock_rest.MockIdempotency{}, &mock_rest.MockWebhooks{}, &mock_rest.MockCardDAV{}, &mock_rest.MockGraphQL{}, &mock_rest.MockHealth{})
+
+			r := gin.New()
+			r.POST("/admin/users/:id/erase", handler.eraseUser)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("POST", "/admin/users/1/erase", bytes.NewBufferString(testCase.inputBody))
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, w.Code, testCase.expectedStatusCode)
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package rest
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/wilfridterry/contact-list/internal/apperror"
+	"github.com/wilfridterry/contact-list/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	problemContentType = "application/problem+json"
+	problemTypePrefix  = "urn:contact-list:problem:"
+)
+
+// Problem is the RFC 7807 body of every error response. Code is the stable
+// error code clients should branch on, Errors lists invalid request fields.
+type Problem struct {
+	Type     string                `json:"type" example:"urn:contact-list:problem:contact_not_found"`
+	Title    string                `json:"title" example:"Not Found"`
+	Status   int                   `json:"status" example:"404"`
+	Detail   string                `json:"detail" example:"contact not found"`
+	Instance string                `json:"instance" example:"/api/v1/contacts/1"`
+	Code     string                `json:"code" example:"contact_not_found"`
+	Errors   []apperror.FieldError `json:"errors,omitempty"`
+}
+
+func init() {
+	// Report validation errors with the names clients send, not Go field names.
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(requestFieldName)
+	}
+}
+
+func requestFieldName(field reflect.StructField) string {
+	for _, tag := range []string{"json", "form", "uri"} {
+		name := strings.SplitN(field.Tag.Get(tag), ",", 2)[0]
+		if name == "-" {
+			return ""
+		}
+
+		if name != "" {
+			return name
+		}
+	}
+
+	return field.Name
+}
+
+// newProblem aborts the request with err rendered as problem details.
+func newProblem(c *gin.Context, err error) {
+	appErr := apperror.From(err)
+
+	if appErr.Status >= http.StatusInternalServerError {
+		logger.FromContext(c.Request.Context()).WithFields(log.Fields{
+			"method": c.Request.Method,
+			"path":   c.FullPath(),
+		}).Error("request failed:", err)
+	}
+
+	c.Header("Content-Type", problemContentType)
+	c.AbortWithStatusJSON(appErr.Status, newProblemBody(c, appErr))
+}
+
+func newProblemBody(c *gin.Context, appErr *apperror.Error) Problem {
+	return Problem{
+		Type:     problemTypePrefix + appErr.Code,
+		Title:    http.StatusText(appErr.Status),
+		Status:   appErr.Status,
+		Detail:   appErr.Message,
+		Instance: c.Request.URL.Path,
+		Code:     appErr.Code,
+		Errors:   appErr.Fields,
+	}
+}
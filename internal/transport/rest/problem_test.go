@@ -0,0 +1,63 @@
+package rest
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/magiconair/properties/assert"
+	"github.com/wilfridterry/contact-list/internal/apperror"
+	"github.com/wilfridterry/contact-list/internal/domain"
+)
+
+func TestNewProblem_validation(t *testing.T) {
+	r := gin.New()
+	r.POST("/sign-up", func(c *gin.Context) {
+		var inp domain.SignUpInput
+		if err := c.ShouldBindJSON(&inp); err != nil {
+			newProblem(c, err)
+			return
+		}
+	})
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("POST", "/sign-up", bytes.NewBufferString(`{"name":"Test","email":"test"}`))
+
+	r.ServeHTTP(w, req)
+
+	var actual Problem
+	json.Unmarshal(w.Body.Bytes(), &actual)
+
+	assert.Equal(t, w.Code, 422)
+	assert.Equal(t, w.Header().Get("Content-Type"), "application/problem+json")
+	assert.Equal(t, actual.Code, apperror.CodeValidation)
+	assert.Equal(t, actual.Instance, "/sign-up")
+	assert.Equal(t, actual.Errors, []apperror.FieldError{
+		{Field: "email", Code: "email", Message: "must be a valid email"},
+		{Field: "password", Code: "required", Message: "is required"},
+	})
+}
+
+func TestNewProblem_malformedBody(t *testing.T) {
+	r := gin.New()
+	r.POST("/sign-up", func(c *gin.Context) {
+		var inp domain.SignUpInput
+		if err := c.ShouldBindJSON(&inp); err != nil {
+			newProblem(c, err)
+			return
+		}
+	})
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("POST", "/sign-up", bytes.NewBufferString(`{"name":`))
+
+	r.ServeHTTP(w, req)
+
+	var actual Problem
+	json.Unmarshal(w.Body.Bytes(), &actual)
+
+	assert.Equal(t, w.Code, 400)
+	assert.Equal(t, actual.Code, apperror.CodeMalformedRequest)
+}
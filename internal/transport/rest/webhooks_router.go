@@ -0,0 +1,208 @@
+package rest
+
+import (
+	"net/http"
+
+	"github.com/wilfridterry/contact-list/internal/domain"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CreatedWebhook struct {
+	Secret  string          `json:"secret"`
+	Webhook *domain.Webhook `json:"webhook"`
+}
+
+type DeliveryUri struct {
+	ID         int64 `uri:"id" binding:"required"`
+	DeliveryID int64 `uri:"delivery_id" binding:"required"`
+}
+
+// CreateWebhook godoc
+// @Summary      Register a webhook
+// @Description  post contact events of the given types to the url, every delivery is signed with the secret (generated when empty), which is shown only once
+// @Tags         webhooks
+// @Accept       json
+// @Produce      json
+// @Param        webhook  body  domain.CreateWebhookInput  true  "Webhook payload"
+// @Success      201  {object}  CreatedWebhook
+// @Failure      400  {object}  Problem
+// @Failure      401  {object}  Problem
+// @Failure      403  {object}  PermissionProblem
+// @Failure      422  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Router       /webhooks [post]
+func (h *Handler) createWebhook(c *gin.Context) {
+	identity, ok := refuseDelegated(c)
+	if !ok {
+		return
+	}
+
+	var inp domain.CreateWebhookInput
+	if err := c.ShouldBindJSON(&inp); err != nil {
+		newProblem(c, err)
+		return
+	}
+
+	webhook, secret, err := h.webhookService.Create(c.Request.Context(), identity.UserID, &inp)
+	if err != nil {
+		newProblem(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, CreatedWebhook{Secret: secret, Webhook: webhook})
+}
+
+// ListWebhooks godoc
+// @Summary      List webhooks
+// @Description  get webhooks of the current user
+// @Tags         webhooks
+// @Accept       json
+// @Produce      json
+// @Success      200  {array}   domain.Webhook
+// @Failure      401  {object}  Problem
+// @Failure      403  {object}  PermissionProblem
+// @Failure      500  {object}  Problem
+// @Router       /webhooks [get]
+func (h *Handler) getWebhooks(c *gin.Context) {
+	identity, _ := getIdentity(c)
+
+	webhooks, err := h.webhookService.All(c.Request.Context(), identity.UserID)
+	if err != nil {
+		newProblem(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, webhooks)
+}
+
+// DeleteWebhook godoc
+// @Summary      Delete a webhook
+// @Description  delete a webhook of the current user with its deliveries
+// @Tags         webhooks
+// @Accept       json
+// @Produce      json
+// @Param        id   path      int  true  "Webhook ID"
+// @Success      204
+// @Failure      400  {object}  Problem
+// @Failure      401  {object}  Problem
+// @Failure      403  {object}  PermissionProblem
+// @Failure      404  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Router       /webhooks/{id} [delete]
+func (h *Handler) deleteWebhook(c *gin.Context) {
+	var uri Uri
+	if err := c.ShouldBindUri(&uri); err != nil {
+		newProblem(c, err)
+		return
+	}
+
+	identity, ok := refuseDelegated(c)
+	if !ok {
+		return
+	}
+
+	if err := h.webhookService.Delete(c.Request.Context(), identity.UserID, uri.ID); err != nil {
+		newProblem(c, err)
+		return
+	}
+
+	c.JSON(http.StatusNoContent, gin.H{})
+}
+
+// ListWebhookDeliveries godoc
+// @Summary      List deliveries of a webhook
+// @Description  get the latest 100 deliveries of a webhook, newest first
+// @Tags         webhooks
+// @Accept       json
+// @Produce      json
+// @Param        id   path      int  true  "Webhook ID"
+// @Success      200  {array}   domain.WebhookDelivery
+// @Failure      400  {object}  Problem
+// @Failure      401  {object}  Problem
+// @Failure      403  {object}  PermissionProblem
+// @Failure      404  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Router       /webhooks/{id}/deliveries [get]
+func (h *Handler) getWebhookDeliveries(c *gin.Context) {
+	var uri Uri
+	if err := c.ShouldBindUri(&uri); err != nil {
+		newProblem(c, err)
+		return
+	}
+
+	identity, _ := getIdentity(c)
+
+	deliveries, err := h.webhookService.Deliveries(c.Request.Context(), identity.UserID, uri.ID)
+	if err != nil {
+		newProblem(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, deliveries)
+}
+
+// GetWebhookDelivery godoc
+// @Summary      Get a webhook delivery
+// @Description  get a delivery with the log of its attempts
+// @Tags         webhooks
+// @Accept       json
+// @Produce      json
+// @Param        id           path  int  true  "Webhook ID"
+// @Param        delivery_id  path  int  true  "Delivery ID"
+// @Success      200  {object}  domain.WebhookDelivery
+// @Failure      400  {object}  Problem
+// @Failure      401  {object}  Problem
+// @Failure      403  {object}  PermissionProblem
+// @Failure      404  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Router       /webhooks/{id}/deliveries/{delivery_id} [get]
+func (h *Handler) getWebhookDelivery(c *gin.Context) {
+	var uri DeliveryUri
+	if err := c.ShouldBindUri(&uri); err != nil {
+		newProblem(c, err)
+		return
+	}
+
+	identity, _ := getIdentity(c)
+
+	delivery, err := h.webhookService.Delivery(c.Request.Context(), identity.UserID, uri.ID, uri.DeliveryID)
+	if err != nil {
+		newProblem(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, delivery)
+}
+
+// RedeliverWebhook godoc
+// @Summary      Redeliver a webhook delivery
+// @Description  send a delivery again, also a dead or succeeded one, with a fresh set of attempts
+// @Tags         webhooks
+// @Accept       json
+// @Produce      json
+// @Param        id           path  int  true  "Webhook ID"
+// @Param        delivery_id  path  int  true  "Delivery ID"
+// @Success      202
+// @Failure      400  {object}  Problem
+// @Failure      401  {object}  Problem
+// @Failure      403  {object}  PermissionProblem
+// @Failure      404  {object}  Problem
+// @Failure      500  {object}  Problem
+// @Router       /webhooks/{id}/deliveries/{delivery_id}/redeliver [post]
+func (h *Handler) redeliverWebhook(c *gin.Context) {
+	var uri DeliveryUri
+	if err := c.ShouldBindUri(&uri); err != nil {
+		newProblem(c, err)
+		return
+	}
+
+	identity, _ := getIdentity(c)
+
+	if err := h.webhookService.Redeliver(c.Request.Context(), identity.UserID, uri.ID, uri.DeliveryID); err != nil {
+		newProblem(c, err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "Queued."})
+}
@@ -1,10 +1,31 @@
 package amqplog
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"sync"
+	"time"
 
 	amqp "github.com/rabbitmq/amqp091-go"
+	log "github.com/sirupsen/logrus"
+	"github.com/wilfridterry/contact-list/pkg/tracing"
+)
+
+var (
+	ErrNotConnected = errors.New("amqp: not connected")
+	ErrBufferFull   = errors.New("amqp: buffer is full, message dropped")
+	ErrNacked       = errors.New("amqp: message rejected by the broker")
+)
+
+const (
+	defaultConfirmTimeout = 5 * time.Second
+	defaultCloseTimeout   = 10 * time.Second
+	defaultBackoffBase    = time.Second
+	defaultBackoffMax     = 30 * time.Second
+	defaultBufferSize     = 1000
+	maxBackoffShift       = 16
 )
 
 type ConfigOptions struct {
@@ -13,90 +34,537 @@ type ConfigOptions struct {
 	Host     string
 	Port     int
 	Queue    string
+	// Exchange is the exchange messages are published to, the default one
+	// routes them straight to Queue. The queue is bound to a named exchange
+	// with every routing key and messages are published with the first one,
+	// the keys default to the queue name.
+	Exchange     string
+	ExchangeType string
+	RoutingKeys  []string
+	// ConfirmTimeout bounds the wait for the broker to confirm a message,
+	// which Log callers wait for while the broker is connected.
+	ConfirmTimeout time.Duration
+	// CloseTimeout bounds the time Close keeps publishing, and reconnecting
+	// for, the buffered messages before dropping them.
+	CloseTimeout time.Duration
+	// The n-th reconnection attempt in a row waits BackoffBase * 2^(n-1), at
+	// most BackoffMax.
+	BackoffBase time.Duration
+	BackoffMax  time.Duration
+	// BufferSize messages at most are kept while the broker is unreachable.
+	BufferSize int
+}
+
+func (cf ConfigOptions) withDefaults() ConfigOptions {
+	if cf.ExchangeType == "" {
+		cf.ExchangeType = amqp.ExchangeDirect
+	}
+
+	if len(cf.RoutingKeys) == 0 {
+		cf.RoutingKeys = []string{cf.Queue}
+	}
+
+	if cf.ConfirmTimeout <= 0 {
+		cf.ConfirmTimeout = defaultConfirmTimeout
+	}
+
+	if cf.CloseTimeout <= 0 {
+		cf.CloseTimeout = defaultCloseTimeout
+	}
+
+	if cf.BackoffBase <= 0 {
+		cf.BackoffBase = defaultBackoffBase
+	}
+
+	if cf.BackoffMax <= 0 {
+		cf.BackoffMax = defaultBackoffMax
+	}
+
+	if cf.BufferSize <= 0 {
+		cf.BufferSize = defaultBufferSize
+	}
+
+	return cf
+}
+
+func (cf ConfigOptions) backoff(attempts int) time.Duration {
+	shift := attempts - 1
+	if shift > maxBackoffShift {
+		shift = maxBackoffShift
+	}
+
+	delay := cf.BackoffBase << shift
+	if delay > cf.BackoffMax || delay <= 0 {
+		delay = cf.BackoffMax
+	}
+
+	return delay
 }
 
+// Client publishes messages with publisher confirms. It reconnects on its own
+// and buffers the messages it can not publish meanwhile.
 type Client struct {
-	conn *amqp.Connection
-	ch   *amqp.Channel
-	cf   *ConfigOptions
+	cf ConfigOptions
+
+	mu      sync.RWMutex
+	session *session
+
+	buffer *buffer
+	flush  chan struct{}
+
+	done      chan struct{}
+	closeOnce sync.Once
+	wg        sync.WaitGroup
+}
+
+// session is a connection with its channel, in confirm mode and with the
+// topology declared.
+type session struct {
+	conn       *amqp.Connection
+	ch         *amqp.Channel
+	connClosed chan *amqp.Error
+	chClosed   chan *amqp.Error
+}
+
+func (s *session) close() {
+	s.ch.Close()
+	s.conn.Close()
 }
 
+// New connects to the broker and keeps reconnecting whenever the connection
+// drops. The client is returned even when the first attempt fails, along with
+// the error, and messages are buffered until a later attempt succeeds.
 func New(cf *ConfigOptions) (*Client, error) {
-	addr := fmt.Sprintf("amqp://%s:%s@%s:%d/", cf.Username, cf.Password, cf.Host, cf.Port)
-	conn, err := amqp.Dial(addr)
-	if err != nil {
-		return nil, err
+	c := &Client{
+		cf:    cf.withDefaults(),
+		flush: make(chan struct{}, 1),
+		done:  make(chan struct{}),
 	}
+	c.buffer = newBuffer(c.cf.BufferSize)
 
-	ch, err := conn.Channel()
+	s, err := c.connect()
+
+	c.wg.Add(1)
+	go c.run(s)
+
+	return c, err
+}
+
+// Close publishes the buffered messages, reconnecting if needed, then closes
+// the connection. Messages still buffered after CloseTimeout are dropped.
+func (c *Client) Close() {
+	if c == nil {
+		return
+	}
+
+	c.closeOnce.Do(func() {
+		close(c.done)
+		c.wg.Wait()
+
+		if s := c.setSession(nil); s != nil {
+			s.close()
+		}
+
+		if n := c.buffer.len(); n > 0 {
+			log.WithFields(log.Fields{
+				"queue":    c.cf.Queue,
+				"messages": n,
+			}).Warn("amqp client closed with unpublished messages")
+		}
+	})
+}
+
+// Ping reports whether the connection and the channel are open. A client which
+// failed to connect may be nil.
+func (c *Client) Ping(ctx context.Context) error {
+	s := c.currentSession()
+	if s == nil {
+		return ErrNotConnected
+	}
+
+	if s.conn.IsClosed() || s.ch.IsClosed() {
+		return amqp.ErrClosed
+	}
+
+	return ctx.Err()
+}
+
+// Log publishes the message, along with the trace context of ctx, and waits
+// for the broker to confirm it. It returns nil once the message is confirmed
+// or, when the broker is unreachable, buffered to be published later. While
+// connected the caller waits up to ConfirmTimeout for the confirmation.
+func (c *Client) Log(ctx context.Context, msg map[string]any) (err error) {
+	if c == nil {
+		return ErrNotConnected
+	}
+
+	_, span, headers := tracing.StartPublish(ctx, c.cf.Queue)
+	defer func() { tracing.End(span, err) }()
+
+	publishing, err := newPublishing(msg, headers)
 	if err != nil {
-		return nil, err
+		return err
+	}
+
+	// Messages wait behind the buffered ones, so they are published in order.
+	if c.buffer.len() == 0 {
+		err = c.publish(ctx, publishing)
+		if err == nil || errors.Is(err, ErrNacked) {
+			return err
+		}
 	}
 
-	return &Client{conn: conn, ch: ch, cf: cf}, nil
+	if !c.buffer.push(publishing) {
+		return ErrBufferFull
+	}
+
+	select {
+	case c.flush <- struct{}{}:
+	default:
+	}
+
+	return nil
 }
 
-func (c *Client) Close() {
-	if c.ch != nil {
-		c.ch.Close()
+// Publish is Log without the buffer: it returns nil only once the broker
+// confirmed the message, for messages which must not be lost with the
+// process.
+func (c *Client) Publish(ctx context.Context, msg map[string]any) (err error) {
+	if c == nil {
+		return ErrNotConnected
 	}
-	if c.conn != nil {
-		c.conn.Close()
+
+	_, span, headers := tracing.StartPublish(ctx, c.cf.Queue)
+	defer func() { tracing.End(span, err) }()
+
+	publishing, err := newPublishing(msg, headers)
+	if err != nil {
+		return err
 	}
+
+	return c.publish(ctx, publishing)
 }
 
-func (c *Client) Log(msg map[string]any) error {
-	q, err := c.ch.QueueDeclare(
-		c.cf.Queue, // name
-		false,      // durable
-		false,      // delete when unused
+func newPublishing(msg map[string]any, headers map[string]any) (amqp.Publishing, error) {
+	msgBts, err := json.Marshal(msg)
+	if err != nil {
+		return amqp.Publishing{}, err
+	}
+
+	return amqp.Publishing{
+		ContentType:  "application/json",
+		DeliveryMode: amqp.Persistent,
+		Timestamp:    time.Now(),
+		Headers:      amqp.Table(headers),
+		Body:         msgBts,
+	}, nil
+}
+
+// GetLogs consumes the queue. The deliveries channel is closed when the
+// connection drops, it is up to the consumer to call GetLogs again.
+func (c *Client) GetLogs() (<-chan amqp.Delivery, error) {
+	s := c.currentSession()
+	if s == nil {
+		return nil, ErrNotConnected
+	}
+
+	return s.ch.Consume(
+		c.cf.Queue, // queue
+		"",         // consumer
+		false,      // auto-ack
 		false,      // exclusive
+		false,      // no-local
 		false,      // no-wait
-		nil,        // arguments
+		nil,        // args
 	)
+}
+
+func (c *Client) publish(ctx context.Context, publishing amqp.Publishing) error {
+	s := c.currentSession()
+	if s == nil {
+		return ErrNotConnected
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.cf.ConfirmTimeout)
+	defer cancel()
 
+	confirmation, err := s.ch.PublishWithDeferredConfirmWithContext(
+		ctx,
+		c.cf.Exchange,       // exchange
+		c.cf.RoutingKeys[0], // routing key
+		false,               // mandatory
+		false,               // immediate
+		publishing,
+	)
 	if err != nil {
 		return err
 	}
 
-	msgBts, err := json.Marshal(msg)
+	acked, err := confirmation.WaitContext(ctx)
 	if err != nil {
 		return err
 	}
 
-	return c.ch.Publish(
-		"",     // exchange
-		q.Name, // routing key
-		false,  // mandatory
-		false,
-		amqp.Publishing{
-			ContentType: "application/json",
-			Body:        msgBts,
-		},
-	)
+	if !acked {
+		return ErrNacked
+	}
+
+	return nil
 }
 
-func (c *Client) GetLogs() (<-chan amqp.Delivery, error) {
-	q, err := c.ch.QueueDeclare(
+// run reconnects whenever the session drops, until the client is closed.
+func (c *Client) run(s *session) {
+	defer c.wg.Done()
+
+	attempts := 0
+	for {
+		if s != nil {
+			attempts = 0
+
+			if closed := c.serve(s); closed {
+				c.drain(s)
+				return
+			}
+
+			c.setSession(nil)
+			s.close()
+		}
+
+		attempts++
+
+		select {
+		case <-c.done:
+			c.drain(nil)
+			return
+		case <-time.After(c.cf.backoff(attempts)):
+		}
+
+		var err error
+		if s, err = c.connect(); err != nil {
+			log.WithFields(log.Fields{
+				"queue":   c.cf.Queue,
+				"attempt": attempts,
+			}).Warn("failed to reconnect to the amqp broker:", err)
+		}
+	}
+}
+
+// serve flushes the buffer while the session is up. It reports whether it
+// returned because the client is closed rather than the session dropped.
+func (c *Client) serve(s *session) bool {
+	ticker := time.NewTicker(c.cf.BackoffBase)
+	defer ticker.Stop()
+
+	c.flushBuffer(context.Background())
+
+	for {
+		select {
+		case <-c.done:
+			return true
+		case err := <-s.connClosed:
+			log.WithField("queue", c.cf.Queue).Warn("amqp connection closed:", err)
+			return false
+		case err := <-s.chClosed:
+			log.WithField("queue", c.cf.Queue).Warn("amqp channel closed:", err)
+			return false
+		case <-c.flush:
+			c.flushBuffer(context.Background())
+		case <-ticker.C:
+			c.flushBuffer(context.Background())
+		}
+	}
+}
+
+// drain publishes the buffered messages once the client is closed. It keeps
+// reconnecting until they are all published or CloseTimeout is over.
+func (c *Client) drain(s *session) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.cf.CloseTimeout)
+	defer cancel()
+
+	attempts := 0
+	for c.buffer.len() > 0 && ctx.Err() == nil {
+		if s == nil {
+			var err error
+			if s, err = c.connect(); err != nil {
+				log.WithField("queue", c.cf.Queue).Warn("failed to reconnect to the amqp broker:", err)
+			}
+		}
+
+		if s != nil {
+			c.flushBuffer(ctx)
+			if c.buffer.len() == 0 {
+				return
+			}
+
+			if s.conn.IsClosed() || s.ch.IsClosed() {
+				c.setSession(nil)
+				s.close()
+				s = nil
+			}
+		}
+
+		attempts++
+
+		select {
+		case <-ctx.Done():
+		case <-time.After(c.cf.backoff(attempts)):
+		}
+	}
+}
+
+// flushBuffer publishes the buffered messages in order. It stops at the first
+// failure but for a rejected message, which is dropped.
+func (c *Client) flushBuffer(ctx context.Context) {
+	for {
+		publishing, ok := c.buffer.peek()
+		if !ok {
+			return
+		}
+
+		err := c.publish(ctx, publishing)
+		if errors.Is(err, ErrNacked) {
+			log.WithField("queue", c.cf.Queue).Error("buffered message dropped:", err)
+		} else if err != nil {
+			return
+		}
+
+		c.buffer.pop()
+	}
+}
+
+func (c *Client) connect() (*session, error) {
+	addr := fmt.Sprintf("amqp://%s:%s@%s:%d/", c.cf.Username, c.cf.Password, c.cf.Host, c.cf.Port)
+	conn, err := amqp.Dial(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	ch, err := c.declare(conn)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	s := &session{
+		conn:       conn,
+		ch:         ch,
+		connClosed: conn.NotifyClose(make(chan *amqp.Error, 1)),
+		chClosed:   ch.NotifyClose(make(chan *amqp.Error, 1)),
+	}
+	c.setSession(s)
+
+	return s, nil
+}
+
+// queueDeclarer declares queues, as amqp.Channel does.
+type queueDeclarer interface {
+	QueueDeclare(name string, durable, autoDelete, exclusive, noWait bool, args amqp.Table) (amqp.Queue, error)
+	QueueDeclarePassive(name string, durable, autoDelete, exclusive, noWait bool, args amqp.Table) (amqp.Queue, error)
+}
+
+// declare opens the channel in confirm mode and sets up the durable queue and,
+// when publishing to a named exchange, the exchange and the bindings of the
+// queue.
+func (c *Client) declare(conn *amqp.Connection) (*amqp.Channel, error) {
+	ch, err := openChannel(conn)
+	if err != nil {
+		return nil, err
+	}
+
+	declarer, q, err := c.declareQueue(ch, func() (queueDeclarer, error) {
+		return openChannel(conn)
+	})
+	if err != nil {
+		return nil, err
+	}
+	ch = declarer.(*amqp.Channel)
+
+	if c.cf.Exchange == "" {
+		return ch, nil
+	}
+
+	if err := ch.ExchangeDeclare(
+		c.cf.Exchange,     // name
+		c.cf.ExchangeType, // type
+		true,              // durable
+		false,             // auto-deleted
+		false,             // internal
+		false,             // no-wait
+		nil,               // arguments
+	); err != nil {
+		return nil, err
+	}
+
+	for _, key := range c.cf.RoutingKeys {
+		if err := ch.QueueBind(q.Name, key, c.cf.Exchange, false, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	return ch, nil
+}
+
+// declareQueue declares the durable queue. The broker refuses to declare a
+// queue which exists with other settings, like the non durable queue of
+// earlier versions, and closes the channel. The existing queue is then used
+// as it is on the channel reopen returns, until it is migrated.
+func (c *Client) declareQueue(ch queueDeclarer, reopen func() (queueDeclarer, error)) (queueDeclarer, amqp.Queue, error) {
+	q, err := ch.QueueDeclare(
 		c.cf.Queue, // name
-		false,       // durable
+		true,       // durable
 		false,      // delete when unused
 		false,      // exclusive
 		false,      // no-wait
 		nil,        // arguments
 	)
 
+	var amqpErr *amqp.Error
+	if !errors.As(err, &amqpErr) || amqpErr.Code != amqp.PreconditionFailed {
+		return ch, q, err
+	}
+
+	log.WithField("queue", c.cf.Queue).Warn("amqp queue exists with other settings and is used as it is, messages are lost if it is not durable: delete it once drained or configure another queue")
+
+	if ch, err = reopen(); err != nil {
+		return nil, q, err
+	}
+
+	q, err = ch.QueueDeclarePassive(c.cf.Queue, false, false, false, false, nil)
+
+	return ch, q, err
+}
+
+func openChannel(conn *amqp.Connection) (*amqp.Channel, error) {
+	ch, err := conn.Channel()
 	if err != nil {
 		return nil, err
 	}
 
-	return c.ch.Consume(
-		q.Name, // queue
-		"",     // consumer
-		false,  // auto-ack
-		false,  // exclusive
-		false,  // no-local
-		false,  // no-wait
-		nil,    // args
-	)
+	if err := ch.Confirm(false); err != nil {
+		return nil, err
+	}
+
+	return ch, nil
+}
+
+func (c *Client) currentSession() *session {
+	if c == nil {
+		return nil
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.session
+}
+
+// setSession replaces the session and returns the previous one.
+func (c *Client) setSession(s *session) *session {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	prev := c.session
+	c.session = s
+
+	return prev
 }
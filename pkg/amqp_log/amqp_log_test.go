@@ -0,0 +1,171 @@
+package amqplog
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+func TestConfigOptions_withDefaults(t *testing.T) {
+	cf := ConfigOptions{Queue: "audit"}.withDefaults()
+
+	if cf.ExchangeType != amqp.ExchangeDirect {
+		t.Errorf("exchange type %q", cf.ExchangeType)
+	}
+	if len(cf.RoutingKeys) != 1 || cf.RoutingKeys[0] != "audit" {
+		t.Errorf("routing keys %v", cf.RoutingKeys)
+	}
+	if cf.ConfirmTimeout != defaultConfirmTimeout || cf.CloseTimeout != defaultCloseTimeout || cf.BackoffBase != defaultBackoffBase || cf.BackoffMax != defaultBackoffMax || cf.BufferSize != defaultBufferSize {
+		t.Errorf("unexpected defaults %+v", cf)
+	}
+}
+
+func TestConfigOptions_backoff(t *testing.T) {
+	cf := ConfigOptions{BackoffBase: time.Second, BackoffMax: 10 * time.Second}
+
+	testTable := []struct {
+		attempts int
+		expected time.Duration
+	}{
+		{attempts: 1, expected: time.Second},
+		{attempts: 2, expected: 2 * time.Second},
+		{attempts: 4, expected: 8 * time.Second},
+		{attempts: 5, expected: 10 * time.Second},
+		{attempts: 100, expected: 10 * time.Second},
+	}
+
+	for _, testCase := range testTable {
+		if got := cf.backoff(testCase.attempts); got != testCase.expected {
+			t.Errorf("attempt %d: got %s, want %s", testCase.attempts, got, testCase.expected)
+		}
+	}
+}
+
+func TestBuffer(t *testing.T) {
+	b := newBuffer(2)
+
+	if !b.push(amqp.Publishing{Body: []byte("1")}) || !b.push(amqp.Publishing{Body: []byte("2")}) {
+		t.Fatal("push failed below the size")
+	}
+	if b.push(amqp.Publishing{Body: []byte("3")}) {
+		t.Fatal("push succeeded above the size")
+	}
+
+	for _, expected := range []string{"1", "2"} {
+		publishing, ok := b.peek()
+		if !ok || string(publishing.Body) != expected {
+			t.Fatalf("got %q, want %q", publishing.Body, expected)
+		}
+		b.pop()
+	}
+
+	if _, ok := b.peek(); ok || b.len() != 0 {
+		t.Fatal("buffer not empty")
+	}
+}
+
+func TestClient_Log(t *testing.T) {
+	t.Run("Nil client", func(t *testing.T) {
+		var c *Client
+
+		if err := c.Log(context.Background(), map[string]any{}); !errors.Is(err, ErrNotConnected) {
+			t.Errorf("got %v", err)
+		}
+	})
+
+	t.Run("Broker unreachable", func(t *testing.T) {
+		c, err := New(&ConfigOptions{Host: "127.0.0.1", Port: 1, Queue: "audit", BufferSize: 2, BackoffBase: time.Hour, CloseTimeout: time.Millisecond})
+		if err == nil {
+			t.Fatal("expected the first connection to fail")
+		}
+		defer c.Close()
+
+		if err := c.Ping(context.Background()); !errors.Is(err, ErrNotConnected) {
+			t.Errorf("ping: got %v", err)
+		}
+
+		for i := 0; i < 2; i++ {
+			if err := c.Log(context.Background(), map[string]any{"i": i}); err != nil {
+				t.Fatalf("message %d: %v", i, err)
+			}
+		}
+
+		if err := c.Log(context.Background(), map[string]any{"i": 2}); !errors.Is(err, ErrBufferFull) {
+			t.Errorf("got %v, want %v", err, ErrBufferFull)
+		}
+
+		publishing, _ := c.buffer.peek()
+		if publishing.DeliveryMode != amqp.Persistent || string(publishing.Body) != `{"i":0}` {
+			t.Errorf("unexpected publishing %+v", publishing)
+		}
+	})
+}
+
+func TestClient_Publish(t *testing.T) {
+	c, err := New(&ConfigOptions{Host: "127.0.0.1", Port: 1, Queue: "audit", BackoffBase: time.Hour})
+	if err == nil {
+		t.Fatal("expected the first connection to fail")
+	}
+	defer c.Close()
+
+	if err := c.Publish(context.Background(), map[string]any{"i": 0}); !errors.Is(err, ErrNotConnected) {
+		t.Errorf("got %v, want %v", err, ErrNotConnected)
+	}
+
+	if n := c.buffer.len(); n != 0 {
+		t.Errorf("%d messages buffered", n)
+	}
+}
+
+func TestClient_Close(t *testing.T) {
+	c, err := New(&ConfigOptions{Host: "127.0.0.1", Port: 1, Queue: "audit", BackoffBase: time.Millisecond, CloseTimeout: 100 * time.Millisecond})
+	if err == nil {
+		t.Fatal("expected the first connection to fail")
+	}
+
+	if err := c.Log(context.Background(), map[string]any{"i": 0}); err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	c.Close()
+
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond || elapsed > 5*time.Second {
+		t.Errorf("closed after %s, want about the close timeout", elapsed)
+	}
+}
+
+// declaredQueues is a broker where the queue exists already, not durable.
+type declaredQueues struct {
+	passive []string
+}
+
+func (q *declaredQueues) QueueDeclare(string, bool, bool, bool, bool, amqp.Table) (amqp.Queue, error) {
+	return amqp.Queue{}, &amqp.Error{Code: amqp.PreconditionFailed, Reason: "PRECONDITION_FAILED - inequivalent arg 'durable'"}
+}
+
+func (q *declaredQueues) QueueDeclarePassive(name string, _, _, _, _ bool, _ amqp.Table) (amqp.Queue, error) {
+	q.passive = append(q.passive, name)
+
+	return amqp.Queue{Name: name}, nil
+}
+
+func TestClient_declareQueue_existingQueue(t *testing.T) {
+	c := &Client{cf: ConfigOptions{Queue: "audit"}.withDefaults()}
+
+	closed, reopened := &declaredQueues{}, &declaredQueues{}
+
+	ch, q, err := c.declareQueue(closed, func() (queueDeclarer, error) {
+		return reopened, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ch != reopened || q.Name != "audit" || len(reopened.passive) != 1 || len(closed.passive) != 0 {
+		t.Errorf("the existing queue was not declared passively on a new channel")
+	}
+}
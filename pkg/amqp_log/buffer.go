@@ -0,0 +1,62 @@
+package amqplog
+
+import (
+	"sync"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// buffer is a bounded FIFO of the messages waiting for the broker.
+type buffer struct {
+	mu    sync.Mutex
+	items []amqp.Publishing
+	size  int
+}
+
+func newBuffer(size int) *buffer {
+	return &buffer{size: size}
+}
+
+// push appends the message unless the buffer is full.
+func (b *buffer) push(publishing amqp.Publishing) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if len(b.items) >= b.size {
+		return false
+	}
+
+	b.items = append(b.items, publishing)
+
+	return true
+}
+
+func (b *buffer) peek() (amqp.Publishing, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if len(b.items) == 0 {
+		return amqp.Publishing{}, false
+	}
+
+	return b.items[0], true
+}
+
+func (b *buffer) pop() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if len(b.items) == 0 {
+		return
+	}
+
+	b.items[0] = amqp.Publishing{}
+	b.items = b.items[1:]
+}
+
+func (b *buffer) len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return len(b.items)
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 type ConnectionConfig struct {
@@ -14,19 +15,54 @@ type ConnectionConfig struct {
 	Username string
 	Password string
 	SSLMode  bool
+	// MaxConns caps the pool, pgxpool picks the size when it is zero.
+	MaxConns int32
+	// Tracers are told about every query run on the connection.
+	Tracers []pgx.QueryTracer
 }
 
-func NewConnection(ctx context.Context, cf *ConnectionConfig) (*pgx.Conn, error) {
+// queryTracers passes the queries on to each of the tracers.
+type queryTracers []pgx.QueryTracer
+
+func (t queryTracers) TraceQueryStart(ctx context.Context, conn *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	for _, tracer := range t {
+		ctx = tracer.TraceQueryStart(ctx, conn, data)
+	}
+
+	return ctx
+}
+
+func (t queryTracers) TraceQueryEnd(ctx context.Context, conn *pgx.Conn, data pgx.TraceQueryEndData) {
+	for _, tracer := range t {
+		tracer.TraceQueryEnd(ctx, conn, data)
+	}
+}
+
+// NewPool opens a pool of connections. Each query, and each transaction, runs
+// on a connection of its own, so requests and background workers can share the
+// pool.
+func NewPool(ctx context.Context, cf *ConnectionConfig) (*pgxpool.Pool, error) {
 	connString := fmt.Sprintf("postgres://%s:%s@%s:%d/%s?sslmode=disable", cf.Username, cf.Password, cf.Host, cf.Port, cf.Database)
-	conn, err := pgx.Connect(ctx, connString)
+	config, err := pgxpool.ParseConfig(connString)
+	if err != nil {
+		return nil, err
+	}
+	if len(cf.Tracers) > 0 {
+		config.ConnConfig.Tracer = queryTracers(cf.Tracers)
+	}
+	if cf.MaxConns > 0 {
+		config.MaxConns = cf.MaxConns
+	}
 
+	pool, err := pgxpool.NewWithConfig(ctx, config)
 	if err != nil {
 		return nil, err
 	}
 
-	if err = conn.Ping(ctx); err != nil {
+	if err = pool.Ping(ctx); err != nil {
+		pool.Close()
 		return nil, err
 	}
 
-	return conn, nil
+	return pool, nil
 }
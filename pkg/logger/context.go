@@ -0,0 +1,45 @@
+package logger
+
+import (
+	"context"
+
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type fieldsKey struct{}
+
+// WithFields returns a context whose log entries carry the fields, on top of
+// those ctx carries already.
+func WithFields(ctx context.Context, fields logrus.Fields) context.Context {
+	carried, _ := ctx.Value(fieldsKey{}).(logrus.Fields)
+
+	merged := make(logrus.Fields, len(carried)+len(fields))
+	for k, v := range carried {
+		merged[k] = v
+	}
+	for k, v := range fields {
+		merged[k] = v
+	}
+
+	return context.WithValue(ctx, fieldsKey{}, merged)
+}
+
+// FromContext returns an entry with the fields carried by ctx and, when ctx is
+// traced, the trace and span IDs.
+func FromContext(ctx context.Context) *logrus.Entry {
+	entry := logrus.WithContext(ctx)
+
+	if fields, ok := ctx.Value(fieldsKey{}).(logrus.Fields); ok {
+		entry = entry.WithFields(fields)
+	}
+
+	if span := trace.SpanContextFromContext(ctx); span.IsValid() {
+		entry = entry.WithFields(logrus.Fields{
+			"trace_id": span.TraceID().String(),
+			"span_id":  span.SpanID().String(),
+		})
+	}
+
+	return entry
+}
@@ -0,0 +1,142 @@
+// Package logger sets up the logrus standard logger the app logs with and
+// carries log fields, such as the request and user IDs, in contexts.
+package logger
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+const (
+	FormatJSON = "json"
+	FormatText = "text"
+
+	OutputStdout = "stdout"
+	OutputStderr = "stderr"
+	OutputFile   = "file"
+)
+
+type Config struct {
+	// Format is json or text.
+	Format string
+	Level  string
+	// Outputs are any of stdout, stderr and file.
+	Outputs []string
+	File    string
+	// The file is rotated once it grows past MaxSizeMB megabytes or gets
+	// older than RotateEvery, rotated files are removed after MaxAge or
+	// when there are more than MaxBackups of them. Zero turns a limit off,
+	// the size limit defaults to 100 megabytes. MaxAge is rounded up to
+	// whole days.
+	MaxSizeMB   int
+	RotateEvery time.Duration
+	MaxAge      time.Duration
+	MaxBackups  int
+}
+
+// Init configures the standard logger. Secrets are redacted from every entry
+// whatever the format. The closer stops the rotation and closes the file.
+func Init(cf Config) (io.Closer, error) {
+	level := logrus.InfoLevel
+	if cf.Level != "" {
+		var err error
+		if level, err = logrus.ParseLevel(cf.Level); err != nil {
+			return nil, err
+		}
+	}
+
+	var formatter logrus.Formatter
+	switch cf.Format {
+	case FormatJSON, "":
+		formatter = &logrus.JSONFormatter{}
+	case FormatText:
+		formatter = &logrus.TextFormatter{FullTimestamp: true}
+	default:
+		return nil, fmt.Errorf("logger: unknown format %q", cf.Format)
+	}
+
+	closer := &rotator{stop: make(chan struct{})}
+	writers := make([]io.Writer, 0, len(cf.Outputs))
+
+	for _, output := range cf.Outputs {
+		switch output {
+		case OutputStdout:
+			writers = append(writers, os.Stdout)
+		case OutputStderr:
+			writers = append(writers, os.Stderr)
+		case OutputFile:
+			closer.file = &lumberjack.Logger{
+				Filename:   cf.File,
+				MaxSize:    cf.MaxSizeMB,
+				MaxAge:     maxAgeDays(cf.MaxAge),
+				MaxBackups: cf.MaxBackups,
+			}
+			writers = append(writers, closer.file)
+		default:
+			return nil, fmt.Errorf("logger: unknown output %q", output)
+		}
+	}
+
+	if len(writers) == 0 {
+		return nil, errors.New("logger: no outputs")
+	}
+
+	logrus.SetFormatter(&redactingFormatter{formatter})
+	logrus.SetLevel(level)
+	logrus.SetOutput(io.MultiWriter(writers...))
+
+	if closer.file != nil && cf.RotateEvery > 0 {
+		go closer.run(cf.RotateEvery)
+	}
+
+	return closer, nil
+}
+
+// maxAgeDays rounds the age up to the whole days lumberjack counts in, a
+// shorter age must not turn into zero, which keeps the files forever.
+func maxAgeDays(age time.Duration) int {
+	if age <= 0 {
+		return 0
+	}
+
+	return int((age + 24*time.Hour - 1) / (24 * time.Hour))
+}
+
+// rotator rotates the log file on a schedule, lumberjack only does once the
+// file is too big.
+type rotator struct {
+	file *lumberjack.Logger
+	stop chan struct{}
+}
+
+func (r *rotator) run(every time.Duration) {
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.stop:
+			return
+		case <-ticker.C:
+			if err := r.file.Rotate(); err != nil {
+				logrus.WithField("error", err).Error("failed to rotate the log file")
+			}
+		}
+	}
+}
+
+func (r *rotator) Close() error {
+	close(r.stop)
+
+	if r.file == nil {
+		return nil
+	}
+
+	return r.file.Close()
+}
@@ -0,0 +1,204 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestRedact(t *testing.T) {
+	testTable := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "Bearer",
+			input:    "Authorization: Bearer abc.def",
+			expected: "Authorization: Bearer [REDACTED]",
+		},
+		{
+			name:     "API key header",
+			input:    "ApiKey ck_1a2b3c_s3cr3t",
+			expected: "ApiKey [REDACTED]",
+		},
+		{
+			name:     "JWT",
+			input:    "token eyJhbGciOiJIUzI1NiJ9.eyJpZCI6MX0.c2lnbmF0dXJl expired",
+			expected: "token [REDACTED] expired",
+		},
+		{
+			name:     "OAuth tokens",
+			input:    "refresh ort_abc123 with ocs_xyz",
+			expected: "refresh [REDACTED] with [REDACTED]",
+		},
+		{
+			name:     "Query",
+			input:    "/oauth/callback?code=abc&state=xyz&password=hunter2",
+			expected: "/oauth/callback?code=[REDACTED]&state=xyz&password=[REDACTED]",
+		},
+		{
+			name:     "OAuth parameters",
+			input:    "grant_type=refresh_token&refresh_token=ort_1&client_secret=hunter2 access_token=abc",
+			expected: "grant_type=refresh_token&refresh_token=[REDACTED]&client_secret=[REDACTED] access_token=[REDACTED]",
+		},
+		{
+			name:     "Nothing",
+			input:    "contact 42 not found",
+			expected: "contact 42 not found",
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			if got := Redact(testCase.input); got != testCase.expected {
+				t.Errorf("got %q, want %q", got, testCase.expected)
+			}
+		})
+	}
+}
+
+func TestRedactingFormatter(t *testing.T) {
+	var buf bytes.Buffer
+
+	log := logrus.New()
+	log.SetOutput(&buf)
+	log.SetFormatter(&redactingFormatter{&logrus.JSONFormatter{}})
+
+	fields := logrus.Fields{
+		"password":      "hunter2",
+		"refresh_token": "anything",
+		"Authorization": "Basic dXNlcjpwYXNz",
+		"error":         errors.New("invalid token oat_abc123"),
+		"user_id":       int64(7),
+	}
+	log.WithFields(fields).Info("signed in with eyJhbGciOiJIUzI1NiJ9.eyJpZCI6MX0.c2ln")
+
+	var entry map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]any{
+		"password":      "[REDACTED]",
+		"refresh_token": "[REDACTED]",
+		"Authorization": "[REDACTED]",
+		"error":         "invalid token [REDACTED]",
+		"user_id":       float64(7),
+		"msg":           "signed in with [REDACTED]",
+	}
+	for k, v := range expected {
+		if entry[k] != v {
+			t.Errorf("%s: got %v, want %v", k, entry[k], v)
+		}
+	}
+
+	if fields["password"] != "hunter2" {
+		t.Error("the fields of the caller were changed")
+	}
+}
+
+func TestFromContext(t *testing.T) {
+	ctx := WithFields(context.Background(), logrus.Fields{"request_id": "abc"})
+	ctx = WithFields(ctx, logrus.Fields{"user_id": int64(1)})
+
+	entry := FromContext(ctx)
+	if entry.Data["request_id"] != "abc" || entry.Data["user_id"] != int64(1) {
+		t.Errorf("unexpected fields %v", entry.Data)
+	}
+
+	if len(FromContext(context.Background()).Data) != 0 {
+		t.Error("fields without WithFields")
+	}
+}
+
+func TestInit(t *testing.T) {
+	out, level, formatter := logrus.StandardLogger().Out, logrus.GetLevel(), logrus.StandardLogger().Formatter
+	defer func() {
+		logrus.SetOutput(out)
+		logrus.SetLevel(level)
+		logrus.SetFormatter(formatter)
+	}()
+
+	path := filepath.Join(t.TempDir(), "logs", "app.log")
+
+	closer, err := Init(Config{Format: FormatJSON, Level: "warn", Outputs: []string{OutputFile}, File: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logrus.Info("skipped")
+	logrus.WithField("secret", "s3cr3t").Warn("written")
+
+	if err := closer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("got %d lines, want 1", len(lines))
+	}
+
+	var entry map[string]any
+	if err := json.Unmarshal([]byte(lines[0]), &entry); err != nil {
+		t.Fatal(err)
+	}
+
+	if entry["msg"] != "written" || entry["secret"] != "[REDACTED]" || entry["level"] != "warning" {
+		t.Errorf("unexpected entry %v", entry)
+	}
+}
+
+func TestInit_Invalid(t *testing.T) {
+	testTable := []struct {
+		name string
+		cf   Config
+	}{
+		{name: "Format", cf: Config{Format: "xml", Outputs: []string{OutputStdout}}},
+		{name: "Level", cf: Config{Level: "loud", Outputs: []string{OutputStdout}}},
+		{name: "Output", cf: Config{Outputs: []string{"syslog"}}},
+		{name: "No outputs", cf: Config{}},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			if _, err := Init(testCase.cf); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
+
+func TestMaxAgeDays(t *testing.T) {
+	testTable := []struct {
+		age      time.Duration
+		expected int
+	}{
+		{age: 0, expected: 0},
+		{age: time.Hour, expected: 1},
+		{age: 24 * time.Hour, expected: 1},
+		{age: 36 * time.Hour, expected: 2},
+		{age: 7 * 24 * time.Hour, expected: 7},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.age.String(), func(t *testing.T) {
+			if got := maxAgeDays(testCase.age); got != testCase.expected {
+				t.Errorf("got %d, want %d", got, testCase.expected)
+			}
+		})
+	}
+}
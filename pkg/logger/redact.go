@@ -0,0 +1,80 @@
+package logger
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+const redacted = "[REDACTED]"
+
+// secretKeys are parts of field names whose values are never logged.
+var secretKeys = []string{"password", "secret", "token", "authorization", "cookie", "api_key", "apikey", "credential", "challenge"}
+
+// secretPatterns find secrets within messages and other fields: credentials
+// sent in headers, JWTs, API keys and OAuth tokens, and key=value pairs.
+var secretPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)\b(Bearer|Basic|ApiKey)\s+[^\s"',;]+`),
+	regexp.MustCompile(`\beyJ[\w-]+\.[\w-]+\.[\w-]*`),
+	regexp.MustCompile(`\b(ck|oat|ort|ocs)_[\w-]+`),
+	regexp.MustCompile(`(?i)\b\w*(password|secret|token|code)=[^\s&"',;]+`),
+}
+
+// redactingFormatter hides secrets before the entry reaches the formatter. The
+// entry is copied, the fields of the caller are left alone.
+type redactingFormatter struct {
+	next logrus.Formatter
+}
+
+func (f *redactingFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	e := *entry
+	e.Message = Redact(entry.Message)
+	e.Data = make(logrus.Fields, len(entry.Data))
+
+	for k, v := range entry.Data {
+		e.Data[k] = redactField(k, v)
+	}
+
+	return f.next.Format(&e)
+}
+
+func redactField(key string, value any) any {
+	lower := strings.ToLower(key)
+	for _, secret := range secretKeys {
+		if strings.Contains(lower, secret) {
+			return redacted
+		}
+	}
+
+	switch v := value.(type) {
+	case string:
+		return Redact(v)
+	case error:
+		return Redact(v.Error())
+	case fmt.Stringer:
+		return Redact(v.String())
+	default:
+		return value
+	}
+}
+
+// Redact replaces the secrets found in s.
+func Redact(s string) string {
+	for _, pattern := range secretPatterns {
+		s = pattern.ReplaceAllStringFunc(s, func(match string) string {
+			if name, _, ok := strings.Cut(match, "="); ok {
+				return name + "=" + redacted
+			}
+
+			if scheme, _, ok := strings.Cut(match, " "); ok {
+				return scheme + " " + redacted
+			}
+
+			return redacted
+		})
+	}
+
+	return s
+}
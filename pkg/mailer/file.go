@@ -0,0 +1,48 @@
+package mailer
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// File writes every message as an .eml file into the directory instead of
+// delivering it. It is meant for local development and tests.
+type File struct {
+	dir  string
+	from string
+}
+
+func NewFile(dir, from string) *File {
+	return &File{dir: dir, from: from}
+}
+
+func (m *File) Send(ctx context.Context, msg Message) error {
+	if err := os.MkdirAll(m.dir, 0755); err != nil {
+		return err
+	}
+
+	// The address is typed by users, so it is hashed rather than trusted as
+	// part of the path.
+	sum := sha256.Sum256([]byte(msg.To))
+	name := fmt.Sprintf("%d-%s.eml", time.Now().UnixNano(), hex.EncodeToString(sum[:8]))
+	path := filepath.Join(m.dir, name)
+
+	if err := os.WriteFile(path, build(m.from, msg), 0644); err != nil {
+		return err
+	}
+
+	log.WithFields(log.Fields{
+		"to":      msg.To,
+		"subject": msg.Subject,
+		"path":    path,
+	}).Info("mail written to file")
+
+	return nil
+}
@@ -0,0 +1,55 @@
+package mailer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFile_Send(t *testing.T) {
+	dir := t.TempDir()
+	m := NewFile(dir, "noreply@test.com")
+
+	err := m.Send(context.Background(), Message{
+		To:      "user@test.com",
+		Subject: "Verify your email",
+		Body:    "token",
+	})
+	if err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.eml"))
+	if len(files) != 1 {
+		t.Fatalf("Send() wrote %d files, want 1", len(files))
+	}
+
+	content, _ := os.ReadFile(files[0])
+	for _, want := range []string{"From: noreply@test.com", "To: user@test.com", "Subject: Verify your email", "\r\n\r\ntoken"} {
+		if !strings.Contains(string(content), want) {
+			t.Errorf("Send() content does not contain %q", want)
+		}
+	}
+}
+
+func TestFile_Send_addressOutsideDir(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "mail")
+	m := NewFile(dir, "noreply@test.com")
+
+	if err := m.Send(context.Background(), Message{To: "x/../../user@test.com", Subject: "Verify your email"}); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.eml"))
+	if len(files) != 1 {
+		t.Fatalf("Send() wrote %d files in the mail directory, want 1", len(files))
+	}
+
+	outside, _ := filepath.Glob(filepath.Join(root, "*.eml"))
+	if len(outside) != 0 {
+		t.Errorf("Send() wrote %v outside the mail directory", outside)
+	}
+}
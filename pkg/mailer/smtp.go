@@ -0,0 +1,107 @@
+package mailer
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/smtp"
+)
+
+type SMTPConfig struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+}
+
+type SMTP struct {
+	cf *SMTPConfig
+}
+
+func NewSMTP(cf *SMTPConfig) *SMTP {
+	return &SMTP{cf}
+}
+
+// Send delivers the message like smtp.SendMail, the connection is closed once
+// the context is done so a stalled server does not hold up the caller.
+func (m *SMTP) Send(ctx context.Context, msg Message) error {
+	addr := net.JoinHostPort(m.cf.Host, fmt.Sprint(m.cf.Port))
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := conn.SetDeadline(deadline); err != nil {
+			return err
+		}
+	}
+
+	stop := context.AfterFunc(ctx, func() {
+		conn.Close()
+	})
+	defer stop()
+
+	if err := m.send(conn, msg); err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return fmt.Errorf("%w: %v", ctxErr, err)
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+func (m *SMTP) send(conn net.Conn, msg Message) error {
+	c, err := smtp.NewClient(conn, m.cf.Host)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: m.cf.Host}); err != nil {
+			return err
+		}
+	}
+
+	if m.cf.Username != "" {
+		if ok, _ := c.Extension("AUTH"); !ok {
+			return errors.New("smtp: server doesn't support AUTH")
+		}
+
+		if err := c.Auth(smtp.PlainAuth("", m.cf.Username, m.cf.Password, m.cf.Host)); err != nil {
+			return err
+		}
+	}
+
+	if err := c.Mail(m.cf.From); err != nil {
+		return err
+	}
+
+	if err := c.Rcpt(msg.To); err != nil {
+		return err
+	}
+
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+
+	if _, err := w.Write(build(m.cf.From, msg)); err != nil {
+		return err
+	}
+
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	return c.Quit()
+}
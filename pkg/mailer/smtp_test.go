@@ -0,0 +1,113 @@
+package mailer
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// serveSMTP answers a single SMTP session and returns the message data. A
+// stalled server greets nobody and waits for the client to hang up.
+func serveSMTP(t *testing.T, stalled bool) (*SMTPConfig, <-chan string) {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+
+	data := make(chan string, 1)
+
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		r := bufio.NewReader(conn)
+		if stalled {
+			_, _ = r.ReadString('\n')
+			return
+		}
+
+		reply := func(line string) { _, _ = conn.Write([]byte(line + "\r\n")) }
+		reply("220 test ready")
+
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+
+			switch cmd := strings.ToUpper(strings.TrimSpace(line)); {
+			case strings.HasPrefix(cmd, "EHLO"):
+				reply("250 test")
+			case cmd == "DATA":
+				reply("354 go ahead")
+
+				var b strings.Builder
+				for {
+					line, err := r.ReadString('\n')
+					if err != nil || line == ".\r\n" {
+						break
+					}
+					b.WriteString(line)
+				}
+				data <- b.String()
+				reply("250 queued")
+			case cmd == "QUIT":
+				reply("221 bye")
+				return
+			default:
+				reply("250 ok")
+			}
+		}
+	}()
+
+	addr := l.Addr().(*net.TCPAddr)
+
+	return &SMTPConfig{Host: "127.0.0.1", Port: addr.Port, From: "noreply@test.com"}, data
+}
+
+func TestSMTP_Send(t *testing.T) {
+	cf, data := serveSMTP(t, false)
+
+	err := NewSMTP(cf).Send(context.Background(), Message{To: "user@test.com", Subject: "Verify your email", Body: "token"})
+	if err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	content := <-data
+	for _, want := range []string{"From: noreply@test.com", "To: user@test.com", "Subject: Verify your email", "\r\n\r\ntoken"} {
+		if !strings.Contains(content, want) {
+			t.Errorf("Send() content does not contain %q", want)
+		}
+	}
+}
+
+func TestSMTP_Send_stalledServer(t *testing.T) {
+	cf, _ := serveSMTP(t, true)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- NewSMTP(cf).Send(ctx, Message{To: "user@test.com", Subject: "Verify your email", Body: "token"})
+	}()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Send() error = %v, want %v", err, context.DeadlineExceeded)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Send() did not give up once the context was done")
+	}
+}
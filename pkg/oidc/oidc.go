@@ -0,0 +1,132 @@
+package oidc
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	gooidc "github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+)
+
+var (
+	ErrMissingIDToken = errors.New("token response has no id_token")
+	ErrInvalidNonce   = errors.New("id_token nonce mismatch")
+)
+
+type Config struct {
+	Name         string
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+}
+
+// Claims are the identity claims of the verified id_token.
+type Claims struct {
+	Subject       string `json:"sub"`
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
+	Name          string `json:"name"`
+}
+
+// Provider is an OpenID Connect relying party for a single identity provider
+// using the authorization code flow with PKCE. The discovery document is
+// fetched on first use, so an unavailable provider does not block start up.
+type Provider struct {
+	cf Config
+
+	mu       sync.Mutex
+	oauth    *oauth2.Config
+	verifier *gooidc.IDTokenVerifier
+}
+
+func NewProvider(cf Config) *Provider {
+	return &Provider{cf: cf}
+}
+
+func (p *Provider) Name() string {
+	return p.cf.Name
+}
+
+// AuthCodeURL returns the url of the provider the user has to be redirected to.
+func (p *Provider) AuthCodeURL(ctx context.Context, state, nonce, verifier string) (string, error) {
+	oauth, _, err := p.init()
+	if err != nil {
+		return "", err
+	}
+
+	return oauth.AuthCodeURL(state, gooidc.Nonce(nonce), oauth2.S256ChallengeOption(verifier)), nil
+}
+
+// Exchange redeems the authorization code and verifies the returned id_token.
+func (p *Provider) Exchange(ctx context.Context, code, verifier, nonce string) (*Claims, error) {
+	oauth, idVerifier, err := p.init()
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := oauth.Exchange(ctx, code, oauth2.VerifierOption(verifier))
+	if err != nil {
+		return nil, err
+	}
+
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		return nil, ErrMissingIDToken
+	}
+
+	idToken, err := idVerifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		return nil, err
+	}
+
+	if idToken.Nonce != nonce {
+		return nil, ErrInvalidNonce
+	}
+
+	var claims Claims
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, err
+	}
+
+	return &claims, nil
+}
+
+func (p *Provider) init() (*oauth2.Config, *gooidc.IDTokenVerifier, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.oauth != nil {
+		return p.oauth, p.verifier, nil
+	}
+
+	// The discovered provider keeps using the context for fetching keys, so it
+	// must not be bound to the request.
+	provider, err := gooidc.NewProvider(context.Background(), p.cf.Issuer)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	scopes := p.cf.Scopes
+	if len(scopes) == 0 {
+		scopes = []string{gooidc.ScopeOpenID, "email", "profile"}
+	}
+
+	p.oauth = &oauth2.Config{
+		ClientID:     p.cf.ClientID,
+		ClientSecret: p.cf.ClientSecret,
+		RedirectURL:  p.cf.RedirectURL,
+		Endpoint:     provider.Endpoint(),
+		Scopes:       scopes,
+	}
+	p.verifier = provider.Verifier(&gooidc.Config{ClientID: p.cf.ClientID})
+
+	return p.oauth, p.verifier, nil
+}
+
+// GenerateVerifier returns a random PKCE code verifier.
+func GenerateVerifier() string {
+	return oauth2.GenerateVerifier()
+}
@@ -0,0 +1,142 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// stubIdP is a minimal OpenID provider which issues one authorization code.
+type stubIdP struct {
+	server    *httptest.Server
+	key       *rsa.PrivateKey
+	clientID  string
+	code      string
+	challenge string
+	nonce     string
+}
+
+func newStubIdP(t *testing.T, clientID string) *stubIdP {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	idp := &stubIdP{key: key, clientID: clientID, code: "code"}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{
+			"issuer":                                idp.server.URL,
+			"authorization_endpoint":                idp.server.URL + "/authorize",
+			"token_endpoint":                        idp.server.URL + "/token",
+			"jwks_uri":                              idp.server.URL + "/jwks",
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"alg": "RS256",
+				"use": "sig",
+				"kid": "test",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+
+		sum := sha256.Sum256([]byte(r.Form.Get("code_verifier")))
+		if r.Form.Get("code") != idp.code || base64.RawURLEncoding.EncodeToString(sum[:]) != idp.challenge {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+			"iss":            idp.server.URL,
+			"aud":            idp.clientID,
+			"sub":            "external-1",
+			"email":          "user@test.com",
+			"email_verified": true,
+			"name":           "Test User",
+			"nonce":          idp.nonce,
+			"iat":            time.Now().Unix(),
+			"exp":            time.Now().Add(time.Minute).Unix(),
+		})
+		token.Header["kid"] = "test"
+
+		idToken, err := token.SignedString(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"access_token": "access",
+			"token_type":   "Bearer",
+			"expires_in":   60,
+			"id_token":     idToken,
+		})
+	})
+
+	idp.server = httptest.NewServer(mux)
+	t.Cleanup(idp.server.Close)
+
+	return idp
+}
+
+func TestProvider_Exchange(t *testing.T) {
+	idp := newStubIdP(t, "client")
+
+	p := NewProvider(Config{
+		Name:        "stub",
+		Issuer:      idp.server.URL,
+		ClientID:    "client",
+		RedirectURL: "http://localhost/callback",
+	})
+
+	verifier := GenerateVerifier()
+
+	authURL, err := p.AuthCodeURL(context.Background(), "state", "nonce", verifier)
+	if err != nil {
+		t.Fatalf("AuthCodeURL() error = %v", err)
+	}
+
+	u, _ := url.Parse(authURL)
+	query := u.Query()
+	if query.Get("state") != "state" || query.Get("code_challenge_method") != "S256" {
+		t.Fatalf("AuthCodeURL() got = %s", authURL)
+	}
+
+	idp.challenge = query.Get("code_challenge")
+	idp.nonce = query.Get("nonce")
+
+	claims, err := p.Exchange(context.Background(), "code", verifier, "nonce")
+	if err != nil {
+		t.Fatalf("Exchange() error = %v", err)
+	}
+
+	want := Claims{Subject: "external-1", Email: "user@test.com", EmailVerified: true, Name: "Test User"}
+	if *claims != want {
+		t.Errorf("Exchange() got = %v, want = %v", *claims, want)
+	}
+
+	if _, err := p.Exchange(context.Background(), "code", GenerateVerifier(), "nonce"); err == nil {
+		t.Errorf("Exchange() accepted a wrong code verifier")
+	}
+}
@@ -0,0 +1,114 @@
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Defaults of RFC 6238 understood by every authenticator app.
+const (
+	Digits = 6
+	Period = 30 * time.Second
+
+	secretSize = 20
+)
+
+var encoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateSecret returns a random base32 encoded shared secret.
+func GenerateSecret() (string, error) {
+	b := make([]byte, secretSize)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return encoding.EncodeToString(b), nil
+}
+
+// Code returns the one-time password for the secret at the given time.
+func Code(secret string, t time.Time) (string, error) {
+	key, err := encoding.DecodeString(strings.ToUpper(strings.TrimRight(secret, "=")))
+	if err != nil {
+		return "", err
+	}
+
+	return hotp(key, uint64(Step(t))), nil
+}
+
+// Step returns the time step, the number of periods since the epoch, of t.
+func Step(t time.Time) int64 {
+	return t.Unix() / int64(Period.Seconds())
+}
+
+// Validate checks the code against the secret allowing the given number of
+// periods of clock drift in both directions.
+func Validate(secret, code string, t time.Time, skew int) bool {
+	_, ok := Match(secret, code, t, skew)
+
+	return ok
+}
+
+// Match is Validate returning the time step the code belongs to, callers keep
+// the last accepted step to refuse a code presented again.
+func Match(secret, code string, t time.Time, skew int) (int64, bool) {
+	if len(code) != Digits {
+		return 0, false
+	}
+
+	for i := -skew; i <= skew; i++ {
+		at := t.Add(time.Duration(i) * Period)
+
+		expected, err := Code(secret, at)
+		if err != nil {
+			return 0, false
+		}
+
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return Step(at), true
+		}
+	}
+
+	return 0, false
+}
+
+// ProvisioningURI returns the otpauth:// URI which authenticator apps accept
+// as QR code payload.
+func ProvisioningURI(issuer, account, secret string) string {
+	params := url.Values{}
+	params.Set("secret", secret)
+	params.Set("issuer", issuer)
+	params.Set("algorithm", "SHA1")
+	params.Set("digits", fmt.Sprint(Digits))
+	params.Set("period", fmt.Sprint(int(Period.Seconds())))
+
+	label := url.PathEscape(issuer + ":" + account)
+
+	return "otpauth://totp/" + label + "?" + params.Encode()
+}
+
+func hotp(key []byte, counter uint64) string {
+	msg := make([]byte, 8)
+	binary.BigEndian.PutUint64(msg, counter)
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg)
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	mod := uint32(1)
+	for i := 0; i < Digits; i++ {
+		mod *= 10
+	}
+
+	return fmt.Sprintf("%0*d", Digits, value%mod)
+}
@@ -0,0 +1,67 @@
+package totp
+
+import (
+	"encoding/base32"
+	"testing"
+	"time"
+)
+
+// Test vectors from RFC 6238 Appendix B (SHA1, truncated to 6 digits).
+func TestCode(t *testing.T) {
+	secret := base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+
+	testCases := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+
+	for _, testCase := range testCases {
+		got, err := Code(secret, time.Unix(testCase.unix, 0))
+		if err != nil {
+			t.Fatalf("Code() error = %v", err)
+		}
+
+		if got != testCase.want {
+			t.Errorf("Code(%d) got = %s, want = %s", testCase.unix, got, testCase.want)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatalf("GenerateSecret() error = %v", err)
+	}
+
+	now := time.Now()
+	previous, _ := Code(secret, now.Add(-Period))
+
+	if !Validate(secret, previous, now, 1) {
+		t.Errorf("Validate() rejected code of the previous period")
+	}
+
+	if Validate(secret, previous, now.Add(Period*2), 1) {
+		t.Errorf("Validate() accepted a stale code")
+	}
+}
+
+func TestMatch(t *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatalf("GenerateSecret() error = %v", err)
+	}
+
+	now := time.Now()
+	previous, _ := Code(secret, now.Add(-Period))
+
+	step, ok := Match(secret, previous, now, 1)
+	if !ok || step != Step(now)-1 {
+		t.Errorf("Match() got = %d, %v, want = %d, true", step, ok, Step(now)-1)
+	}
+}
@@ -0,0 +1,60 @@
+package tracing
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+// headersCarrier passes trace context in AMQP message headers.
+type headersCarrier map[string]any
+
+func (c headersCarrier) Get(key string) string {
+	value, _ := c[key].(string)
+
+	return value
+}
+
+func (c headersCarrier) Set(key, value string) {
+	c[key] = value
+}
+
+func (c headersCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for key := range c {
+		keys = append(keys, key)
+	}
+
+	return keys
+}
+
+var _ propagation.TextMapCarrier = headersCarrier{}
+
+// StartPublish starts a span for a message published to the RabbitMQ queue.
+// The headers returned carry its context to the consumer.
+func StartPublish(ctx context.Context, queue string) (context.Context, trace.Span, map[string]any) {
+	ctx, span := otel.Tracer(instrumentation).Start(ctx, queue+" publish",
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(semconv.MessagingSystemRabbitmq, semconv.MessagingOperationTypePublish, semconv.MessagingDestinationName(queue)),
+	)
+
+	headers := headersCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, headers)
+
+	return ctx, span, headers
+}
+
+// End ends the span, marking it failed when err is not nil.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	span.End()
+}
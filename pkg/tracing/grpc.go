@@ -0,0 +1,72 @@
+package tracing
+
+import (
+	"context"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+// metadataCarrier passes trace context in gRPC metadata, which keeps keys lower
+// case as the W3C headers already are.
+type metadataCarrier metadata.MD
+
+func (c metadataCarrier) Get(key string) string {
+	if values := metadata.MD(c).Get(key); len(values) > 0 {
+		return values[0]
+	}
+
+	return ""
+}
+
+func (c metadataCarrier) Set(key, value string) {
+	metadata.MD(c).Set(key, value)
+}
+
+func (c metadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for key := range c {
+		keys = append(keys, key)
+	}
+
+	return keys
+}
+
+var _ propagation.TextMapCarrier = metadataCarrier{}
+
+// UnaryClientInterceptor starts a span for every call and sends its context
+// along in the metadata.
+func UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		service, name, _ := strings.Cut(strings.TrimPrefix(method, "/"), "/")
+
+		ctx, span := otel.Tracer(instrumentation).Start(ctx, service+"/"+name,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(semconv.RPCSystemGRPC, semconv.RPCService(service), semconv.RPCMethod(name)),
+		)
+		defer span.End()
+
+		md, _ := metadata.FromOutgoingContext(ctx)
+		md = md.Copy()
+		otel.GetTextMapPropagator().Inject(ctx, metadataCarrier(md))
+
+		err := invoker(metadata.NewOutgoingContext(ctx, md), method, req, reply, cc, opts...)
+
+		code := status.Code(err)
+		span.SetAttributes(semconv.RPCGRPCStatusCodeKey.Int(int(code)))
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, code.String())
+		}
+
+		return err
+	}
+}
@@ -0,0 +1,47 @@
+package tracing
+
+import (
+	"context"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+const instrumentation = "github.com/wilfridterry/contact-list/pkg/tracing"
+
+// QueryTracer starts a span for every query run on a pgx connection. The SQL
+// is recorded as is, arguments are sent apart from it and so left out.
+type QueryTracer struct{}
+
+func (QueryTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	operation, _, _ := strings.Cut(strings.TrimSpace(data.SQL), " ")
+	operation = strings.ToUpper(operation)
+
+	name := "postgresql"
+	if operation != "" && operation != ";" {
+		name = operation
+	}
+
+	ctx, _ = otel.Tracer(instrumentation).Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(semconv.DBSystemPostgreSQL, semconv.DBOperationName(operation), semconv.DBQueryText(data.SQL)),
+	)
+
+	return ctx
+}
+
+func (QueryTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	span := trace.SpanFromContext(ctx)
+
+	if data.Err != nil {
+		span.RecordError(data.Err)
+		span.SetStatus(codes.Error, data.Err.Error())
+	}
+
+	span.End()
+}
@@ -0,0 +1,120 @@
+// Package tracing sets up OpenTelemetry tracing and instruments the clients the
+// app talks to its dependencies with. Trace context travels in the W3C
+// traceparent and tracestate headers, or their gRPC metadata and AMQP header
+// counterparts.
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+const (
+	ExporterNone   = "none"
+	ExporterOTLP   = "otlp"
+	ExporterStdout = "stdout"
+	ExporterFile   = "file"
+)
+
+type Config struct {
+	ServiceName string
+	// Exporter is one of none, otlp, stdout or file.
+	Exporter string
+	// Endpoint is the base URL of an OTLP/HTTP collector, spans are sent to
+	// its /v1/traces path.
+	Endpoint string
+	// File is where the file exporter appends spans, one JSON object each.
+	File string
+	// SampleRatio is the share of new traces recorded, traces started by
+	// the caller follow its decision.
+	SampleRatio float64
+}
+
+// Provider is the tracer provider installed for the app. Shutdown flushes the
+// spans not exported yet.
+type Provider struct {
+	*sdktrace.TracerProvider
+	closer io.Closer
+}
+
+func (p *Provider) Shutdown(ctx context.Context) error {
+	if p.TracerProvider == nil {
+		return nil
+	}
+
+	err := p.TracerProvider.Shutdown(ctx)
+	if p.closer != nil {
+		err = errors.Join(err, p.closer.Close())
+	}
+
+	return err
+}
+
+// NewProvider installs the W3C propagators and, unless the exporter is none, a
+// provider exporting the spans. Without one spans are not recorded but the
+// incoming trace context is still passed on.
+func NewProvider(ctx context.Context, cf Config) (*Provider, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	var (
+		exporter sdktrace.SpanExporter
+		closer   io.Closer
+		err      error
+	)
+
+	switch cf.Exporter {
+	case ExporterNone, "":
+		return &Provider{}, nil
+	case ExporterOTLP:
+		exporter, err = otlptrace.New(ctx, newOTLPClient(cf.Endpoint))
+	case ExporterStdout:
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	case ExporterFile:
+		var file *os.File
+		file, err = openFile(cf.File)
+		if err == nil {
+			closer = file
+			exporter, err = stdouttrace.New(stdouttrace.WithWriter(file))
+		}
+	default:
+		err = fmt.Errorf("tracing: unknown exporter %q", cf.Exporter)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	ratio := cf.SampleRatio
+	if ratio <= 0 {
+		ratio = 1
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio))),
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(cf.ServiceName))),
+	)
+	otel.SetTracerProvider(provider)
+
+	return &Provider{TracerProvider: provider, closer: closer}, nil
+}
+
+func openFile(path string) (*os.File, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+
+	return os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+}
@@ -0,0 +1,181 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+)
+
+// decodeExportRequest reads the resource spans out of an ExportTraceServiceRequest.
+func decodeExportRequest(t *testing.T, body []byte) []*tracepb.ResourceSpans {
+	var spans []*tracepb.ResourceSpans
+
+	for len(body) > 0 {
+		num, typ, n := protowire.ConsumeTag(body)
+		if n < 0 || num != 1 || typ != protowire.BytesType {
+			t.Fatalf("unexpected field %d of type %d", num, typ)
+		}
+		body = body[n:]
+
+		b, n := protowire.ConsumeBytes(body)
+		if n < 0 {
+			t.Fatal("truncated resource spans")
+		}
+		body = body[n:]
+
+		var rs tracepb.ResourceSpans
+		if err := proto.Unmarshal(b, &rs); err != nil {
+			t.Fatal(err)
+		}
+		spans = append(spans, &rs)
+	}
+
+	return spans
+}
+
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+
+	previous, propagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previous)
+		otel.SetTextMapPropagator(propagator)
+	})
+
+	return recorder
+}
+
+func TestNewProvider_otlp(t *testing.T) {
+	var (
+		path, contentType string
+		body              []byte
+	)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path, contentType = r.URL.Path, r.Header.Get("Content-Type")
+		body, _ = io.ReadAll(r.Body)
+	}))
+	defer server.Close()
+
+	provider, err := NewProvider(context.Background(), Config{ServiceName: "contact-list", Exporter: ExporterOTLP, Endpoint: server.URL + "/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, span := provider.Tracer("test").Start(context.Background(), "Contacts.Create")
+	span.End()
+
+	if err := provider.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if path != "/v1/traces" || contentType != "application/x-protobuf" {
+		t.Fatalf("got %s with %s", path, contentType)
+	}
+
+	spans := decodeExportRequest(t, body)
+	if len(spans) != 1 || len(spans[0].ScopeSpans) != 1 || spans[0].ScopeSpans[0].Spans[0].Name != "Contacts.Create" {
+		t.Fatalf("unexpected spans %v", spans)
+	}
+
+	if service := spans[0].Resource.Attributes[0]; service.Key != "service.name" || service.Value.GetStringValue() != "contact-list" {
+		t.Fatalf("unexpected resource attribute %v", service)
+	}
+}
+
+func TestNewProvider_file(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "traces", "traces.json")
+
+	provider, err := NewProvider(context.Background(), Config{Exporter: ExporterFile, File: file})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, span := provider.Tracer("test").Start(context.Background(), "Contacts.Delete")
+	span.End()
+
+	if err := provider.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var exported struct{ Name string }
+	if err := json.Unmarshal(data, &exported); err != nil || exported.Name != "Contacts.Delete" {
+		t.Fatalf("unexpected file content %s", data)
+	}
+}
+
+func TestNewProvider_unknownExporter(t *testing.T) {
+	if _, err := NewProvider(context.Background(), Config{Exporter: "jaeger"}); err == nil {
+		t.Fatal("expected an error")
+	}
+}
+
+func TestUnaryClientInterceptor(t *testing.T) {
+	recorder := recordSpans(t)
+	interceptor := UnaryClientInterceptor()
+
+	var sent metadata.MD
+	invoker := func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		sent, _ = metadata.FromOutgoingContext(ctx)
+		return nil
+	}
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "x-request-id", "1")
+	if err := interceptor(ctx, "/audit.AuditService/Log", nil, nil, nil, invoker); err != nil {
+		t.Fatal(err)
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 1 || spans[0].Name() != "audit.AuditService/Log" || spans[0].SpanKind() != trace.SpanKindClient {
+		t.Fatalf("unexpected spans %v", spans)
+	}
+
+	traceparent := "00-" + spans[0].SpanContext().TraceID().String() + "-" + spans[0].SpanContext().SpanID().String() + "-01"
+	if got := sent.Get("traceparent"); len(got) != 1 || got[0] != traceparent {
+		t.Fatalf("got traceparent %v, want %s", got, traceparent)
+	}
+
+	if got := sent.Get("x-request-id"); len(got) != 1 {
+		t.Fatal("metadata of the caller was dropped")
+	}
+}
+
+func TestStartPublish(t *testing.T) {
+	recorder := recordSpans(t)
+
+	_, span, headers := StartPublish(context.Background(), "audit")
+	End(span, nil)
+
+	spans := recorder.Ended()
+	if len(spans) != 1 || spans[0].Name() != "audit publish" || spans[0].SpanKind() != trace.SpanKindProducer {
+		t.Fatalf("unexpected spans %v", spans)
+	}
+
+	traceparent := "00-" + spans[0].SpanContext().TraceID().String() + "-" + spans[0].SpanContext().SpanID().String() + "-01"
+	if headers["traceparent"] != traceparent {
+		t.Fatalf("got headers %v, want traceparent %s", headers, traceparent)
+	}
+}
@@ -0,0 +1,299 @@
+// Package vcard reads and writes single vCards (RFC 2426 and RFC 6350).
+// Property values are kept as they appear in the card, use Text and
+// Components to unescape them.
+package vcard
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	begin = "BEGIN"
+	end   = "END"
+	kind  = "VCARD"
+
+	// maxLineLength is the length in octets lines are folded at.
+	maxLineLength = 75
+)
+
+var ErrInvalidCard = errors.New("invalid vcard")
+
+// Property is a content line of a card. The name is upper case and without the
+// group, parameter names are upper case as well.
+type Property struct {
+	Name   string
+	Params map[string][]string
+	Value  string
+}
+
+// Card holds the properties between BEGIN:VCARD and END:VCARD in order.
+type Card []Property
+
+// Get returns the first property with the name or nil.
+func (c Card) Get(name string) *Property {
+	for i := range c {
+		if c[i].Name == name {
+			return &c[i]
+		}
+	}
+
+	return nil
+}
+
+// Value returns the raw value of the first property with the name.
+func (c Card) Value(name string) string {
+	if p := c.Get(name); p != nil {
+		return p.Value
+	}
+
+	return ""
+}
+
+// Decode parses a card. Folded lines are joined and groups dropped.
+func Decode(data []byte) (Card, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+
+	lines := make([]string, 0)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if line == "" {
+			continue
+		}
+
+		if (line[0] == ' ' || line[0] == '\t') && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+
+		lines = append(lines, line)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(lines) < 2 {
+		return nil, ErrInvalidCard
+	}
+
+	card := make(Card, 0, len(lines)-2)
+	for i, line := range lines {
+		p, err := parseLine(line)
+		if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case i == 0:
+			if p.Name != begin || !strings.EqualFold(p.Value, kind) {
+				return nil, ErrInvalidCard
+			}
+		case i == len(lines)-1:
+			if p.Name != end || !strings.EqualFold(p.Value, kind) {
+				return nil, ErrInvalidCard
+			}
+		case p.Name == begin || p.Name == end:
+			return nil, ErrInvalidCard
+		default:
+			card = append(card, p)
+		}
+	}
+
+	return card, nil
+}
+
+// Encode writes the card with CRLF line endings and folds long lines.
+func (c Card) Encode() []byte {
+	var b bytes.Buffer
+
+	writeLine(&b, begin+":"+kind)
+	for _, p := range c {
+		var line strings.Builder
+		line.WriteString(p.Name)
+
+		for _, name := range sortedKeys(p.Params) {
+			line.WriteString(";" + name + "=")
+			for i, value := range p.Params[name] {
+				if i > 0 {
+					line.WriteString(",")
+				}
+
+				if strings.ContainsAny(value, ";:,") {
+					value = `"` + value + `"`
+				}
+				line.WriteString(value)
+			}
+		}
+
+		line.WriteString(":" + p.Value)
+		writeLine(&b, line.String())
+	}
+	writeLine(&b, end+":"+kind)
+
+	return b.Bytes()
+}
+
+// Text unescapes a text value.
+func Text(value string) string {
+	var b strings.Builder
+
+	escaped := false
+	for _, r := range value {
+		if !escaped {
+			if r == '\\' {
+				escaped = true
+			} else {
+				b.WriteRune(r)
+			}
+			continue
+		}
+
+		escaped = false
+		if r == 'n' || r == 'N' {
+			b.WriteRune('\n')
+		} else {
+			b.WriteRune(r)
+		}
+	}
+
+	return b.String()
+}
+
+// Escape escapes text for a property value.
+func Escape(text string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`, ",", `\,`, ";", `\;`).Replace(text)
+}
+
+// Components splits a structured value such as N or ADR at the unescaped
+// semicolons and unescapes each component.
+func Components(value string) []string {
+	components := make([]string, 0)
+
+	start := 0
+	escaped := false
+	for i, r := range value {
+		switch {
+		case escaped:
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == ';':
+			components = append(components, Text(value[start:i]))
+			start = i + 1
+		}
+	}
+
+	return append(components, Text(value[start:]))
+}
+
+// Structured joins escaped components into a structured value.
+func Structured(components ...string) string {
+	escaped := make([]string, len(components))
+	for i, c := range components {
+		escaped[i] = Escape(c)
+	}
+
+	return strings.Join(escaped, ";")
+}
+
+func parseLine(line string) (Property, error) {
+	p := Property{Params: map[string][]string{}}
+
+	// The value starts at the first colon outside of a quoted parameter value.
+	quoted := false
+	colon := -1
+	for i := 0; i < len(line) && colon < 0; i++ {
+		switch line[i] {
+		case '"':
+			quoted = !quoted
+		case ':':
+			if !quoted {
+				colon = i
+			}
+		}
+	}
+
+	if colon <= 0 {
+		return p, ErrInvalidCard
+	}
+
+	p.Value = line[colon+1:]
+
+	parts := splitUnquoted(line[:colon], ';')
+	p.Name = strings.ToUpper(parts[0])
+	if dot := strings.LastIndexByte(p.Name, '.'); dot >= 0 {
+		p.Name = p.Name[dot+1:]
+	}
+
+	if p.Name == "" {
+		return p, ErrInvalidCard
+	}
+
+	for _, param := range parts[1:] {
+		name, value, ok := strings.Cut(param, "=")
+		if !ok {
+			// vCard 2.1 style parameters without a name are types.
+			name, value = "TYPE", param
+		}
+
+		name = strings.ToUpper(name)
+		for _, v := range splitUnquoted(value, ',') {
+			p.Params[name] = append(p.Params[name], strings.Trim(v, `"`))
+		}
+	}
+
+	return p, nil
+}
+
+func splitUnquoted(s string, sep byte) []string {
+	parts := make([]string, 0)
+
+	quoted := false
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '"':
+			quoted = !quoted
+		case sep:
+			if !quoted {
+				parts = append(parts, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+
+	return append(parts, s[start:])
+}
+
+func writeLine(b *bytes.Buffer, line string) {
+	limit := maxLineLength
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+
+		b.WriteString(line[:cut] + "\r\n ")
+		line = line[cut:]
+		// The leading space of a continuation line counts.
+		limit = maxLineLength - 1
+	}
+
+	b.WriteString(line + "\r\n")
+}
+
+func sortedKeys(params map[string][]string) []string {
+	keys := make([]string, 0, len(params))
+	for k := range params {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
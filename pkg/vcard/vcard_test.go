@@ -0,0 +1,100 @@
+package vcard
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDecode(t *testing.T) {
+	data := "BEGIN:VCARD\r\n" +
+		"VERSION:3.0\r\n" +
+		"N:Doe;John;;;\r\n" +
+		"FN:John Doe\r\n" +
+		"item1.EMAIL;type=INTERNET;type=pref:john@example.com\r\n" +
+		"TEL;TYPE=CELL,VOICE:+1 555\r\n" +
+		"ADR;TYPE=HOME:;;1 Main St\\, Apt 2;Springfield;;;\r\n" +
+		"NOTE:a long note that is fol\r\n" +
+		" ded\r\n" +
+		"END:VCARD\r\n"
+
+	card, err := Decode([]byte(data))
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+
+	if got := card.Value("FN"); got != "John Doe" {
+		t.Errorf("FN = %q, want %q", got, "John Doe")
+	}
+
+	if got := Components(card.Value("N")); !reflect.DeepEqual(got, []string{"Doe", "John", "", "", ""}) {
+		t.Errorf("N = %q", got)
+	}
+
+	email := card.Get("EMAIL")
+	if email == nil || email.Value != "john@example.com" || !reflect.DeepEqual(email.Params["TYPE"], []string{"INTERNET", "pref"}) {
+		t.Errorf("EMAIL = %+v", email)
+	}
+
+	if tel := card.Get("TEL"); tel == nil || !reflect.DeepEqual(tel.Params["TYPE"], []string{"CELL", "VOICE"}) {
+		t.Errorf("TEL = %+v", tel)
+	}
+
+	if got := Components(card.Value("ADR"))[2]; got != "1 Main St, Apt 2" {
+		t.Errorf("ADR street = %q", got)
+	}
+
+	if got := card.Value("NOTE"); got != "a long note that is folded" {
+		t.Errorf("NOTE = %q", got)
+	}
+}
+
+func TestDecode_Invalid(t *testing.T) {
+	tests := []string{
+		"",
+		"BEGIN:VCARD\r\nFN:John\r\n",
+		"BEGIN:VCALENDAR\r\nEND:VCALENDAR\r\n",
+		"BEGIN:VCARD\r\nFN John\r\nEND:VCARD\r\n",
+		"BEGIN:VCARD\r\nBEGIN:VCARD\r\nEND:VCARD\r\nEND:VCARD\r\n",
+	}
+
+	for _, data := range tests {
+		if _, err := Decode([]byte(data)); err != ErrInvalidCard {
+			t.Errorf("Decode(%q) error = %v, want %v", data, err, ErrInvalidCard)
+		}
+	}
+}
+
+func TestCard_Encode(t *testing.T) {
+	card := Card{
+		{Name: "VERSION", Value: "3.0"},
+		{Name: "N", Value: Structured("Doe", "John", "", "", "")},
+		{Name: "EMAIL", Params: map[string][]string{"TYPE": {"INTERNET"}}, Value: Escape("john@example.com")},
+		{Name: "NOTE", Value: Escape(strings.Repeat("é", 60) + ", done")},
+	}
+
+	data := card.Encode()
+
+	for _, line := range strings.Split(strings.TrimSuffix(string(data), "\r\n"), "\r\n") {
+		if len(line) > maxLineLength {
+			t.Errorf("line %q is longer than %d octets", line, maxLineLength)
+		}
+	}
+
+	decoded, err := Decode(data)
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+
+	if got := Text(decoded.Value("NOTE")); got != strings.Repeat("é", 60)+", done" {
+		t.Errorf("NOTE = %q", got)
+	}
+
+	if got := Components(decoded.Value("N")); !reflect.DeepEqual(got, []string{"Doe", "John", "", "", ""}) {
+		t.Errorf("N = %q", got)
+	}
+
+	if got := decoded.Get("EMAIL").Params["TYPE"]; !reflect.DeepEqual(got, []string{"INTERNET"}) {
+		t.Errorf("EMAIL TYPE = %q", got)
+	}
+}
@@ -0,0 +1,140 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// Headers of every delivery. The signature lets the receiver check that the
+// payload comes from us and was not changed, the timestamp is signed as well
+// so an old delivery can not be replayed.
+const (
+	DeliveryHeader  = "X-Webhook-Delivery"
+	EventHeader     = "X-Webhook-Event"
+	TimestampHeader = "X-Webhook-Timestamp"
+	SignatureHeader = "X-Webhook-Signature"
+
+	signaturePrefix = "sha256="
+	maxResponseBody = 64 << 10
+	defaultTimeout  = 10 * time.Second
+)
+
+var ErrForbiddenAddress = errors.New("webhook address is not allowed")
+
+type Config struct {
+	Timeout time.Duration
+	// AllowPrivateNetworks lets deliveries reach loopback and private
+	// addresses, which are refused by default so webhooks can not be used to
+	// probe the internal network.
+	AllowPrivateNetworks bool
+}
+
+type Request struct {
+	URL        string
+	Secret     string
+	DeliveryID int64
+	Event      string
+	Payload    []byte
+}
+
+// Client posts signed JSON payloads to webhook receivers.
+type Client struct {
+	http *http.Client
+	now  func() time.Time
+}
+
+func NewClient(cf Config) *Client {
+	if cf.Timeout <= 0 {
+		cf.Timeout = defaultTimeout
+	}
+
+	dialer := &net.Dialer{Timeout: cf.Timeout}
+	if !cf.AllowPrivateNetworks {
+		dialer.Control = refusePrivateAddress
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialer.DialContext
+	transport.Proxy = nil
+
+	return &Client{
+		http: &http.Client{
+			Timeout:   cf.Timeout,
+			Transport: transport,
+			// A redirect is reported as the response, receivers must answer
+			// at the registered url.
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		now: time.Now,
+	}
+}
+
+// Send posts the payload and returns the status code of the response. An error
+// means that no response was received.
+func (c *Client) Send(ctx context.Context, r Request) (int, error) {
+	timestamp := c.now().Unix()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.URL, bytes.NewReader(r.Payload))
+	if err != nil {
+		return 0, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "contact-list-webhooks")
+	req.Header.Set(DeliveryHeader, strconv.FormatInt(r.DeliveryID, 10))
+	req.Header.Set(EventHeader, r.Event)
+	req.Header.Set(TimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(SignatureHeader, Sign(r.Secret, timestamp, r.Payload))
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxResponseBody))
+
+	return resp.StatusCode, nil
+}
+
+// Sign returns the signature header value of the payload sent at timestamp,
+// the hex encoded HMAC-SHA256 of "<timestamp>.<payload>".
+func Sign(secret string, timestamp int64, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10) + "."))
+	mac.Write(payload)
+
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks the signature of a received payload, as receivers should.
+func Verify(secret string, timestamp int64, payload []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, timestamp, payload)), []byte(signature))
+}
+
+func refusePrivateAddress(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsMulticast() {
+		return ErrForbiddenAddress
+	}
+
+	return nil
+}
@@ -0,0 +1,120 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestClient_Send(t *testing.T) {
+	payload := []byte(`{"type":"contact.created","contact_id":1}`)
+
+	var received *http.Request
+	var body []byte
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer receiver.Close()
+
+	c := NewClient(Config{AllowPrivateNetworks: true})
+	c.now = func() time.Time { return time.Unix(1700000000, 0) }
+
+	status, err := c.Send(context.Background(), Request{
+		URL:        receiver.URL,
+		Secret:     "secret",
+		DeliveryID: 7,
+		Event:      "contact.created",
+		Payload:    payload,
+	})
+	if err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	if status != http.StatusAccepted {
+		t.Errorf("Send() status = %d, want %d", status, http.StatusAccepted)
+	}
+
+	if string(body) != string(payload) {
+		t.Errorf("Send() body = %s, want %s", body, payload)
+	}
+
+	if got := received.Header.Get(DeliveryHeader); got != "7" {
+		t.Errorf("Send() %s = %q, want %q", DeliveryHeader, got, "7")
+	}
+
+	if got := received.Header.Get(EventHeader); got != "contact.created" {
+		t.Errorf("Send() %s = %q, want %q", EventHeader, got, "contact.created")
+	}
+
+	timestamp, _ := strconv.ParseInt(received.Header.Get(TimestampHeader), 10, 64)
+	if timestamp != 1700000000 {
+		t.Errorf("Send() %s = %d, want %d", TimestampHeader, timestamp, 1700000000)
+	}
+
+	if !Verify("secret", timestamp, body, received.Header.Get(SignatureHeader)) {
+		t.Errorf("Send() signature %q does not verify", received.Header.Get(SignatureHeader))
+	}
+
+	if Verify("other", timestamp, body, received.Header.Get(SignatureHeader)) {
+		t.Error("Send() signature verifies with another secret")
+	}
+}
+
+func TestClient_SendFailures(t *testing.T) {
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer failing.Close()
+
+	redirecting := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, failing.URL, http.StatusFound)
+	}))
+	defer redirecting.Close()
+
+	tests := []struct {
+		name       string
+		cf         Config
+		url        string
+		wantStatus int
+		wantErr    error
+	}{
+		{
+			name:       "receiver error",
+			cf:         Config{AllowPrivateNetworks: true},
+			url:        failing.URL,
+			wantStatus: http.StatusServiceUnavailable,
+		},
+		{
+			name:       "redirect is not followed",
+			cf:         Config{AllowPrivateNetworks: true},
+			url:        redirecting.URL,
+			wantStatus: http.StatusFound,
+		},
+		{
+			name:    "private address",
+			cf:      Config{},
+			url:     failing.URL,
+			wantErr: ErrForbiddenAddress,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, err := NewClient(tt.cf).Send(context.Background(), Request{URL: tt.url, Secret: "secret", Payload: []byte("{}")})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Send() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if status != tt.wantStatus {
+				t.Errorf("Send() status = %d, want %d", status, tt.wantStatus)
+			}
+		})
+	}
+}